@@ -4,6 +4,7 @@ go 1.15
 
 require (
 	github.com/fhs/gompd/v2 v2.2.0
+	github.com/godbus/dbus/v5 v5.0.3
 	github.com/gotk3/gotk3 v0.5.2
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7
 	github.com/pkg/errors v0.9.1
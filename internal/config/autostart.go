@@ -0,0 +1,70 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// AutostartEnabled returns whether the application is set up to start automatically on login
+func AutostartEnabled() bool {
+	_, err := os.Stat(getAutostartFile())
+	return err == nil
+}
+
+// SetAutostart installs or removes the XDG autostart entry of the application
+func SetAutostart(enabled bool) error {
+	file := getAutostartFile()
+	if !enabled {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		log.Debugf("Removed autostart entry %s", file)
+		return nil
+	}
+
+	// Create the autostart directory if it doesn't exist
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, []byte(autostartEntry()), 0644); err != nil {
+		return err
+	}
+	log.Debugf("Installed autostart entry %s", file)
+	return nil
+}
+
+// autostartEntry returns the content of the application's autostart .desktop file
+func autostartEntry() string {
+	return fmt.Sprintf(
+		"[Desktop Entry]\n"+
+			"Type=Application\n"+
+			"Name=%s\n"+
+			"Icon=%s\n"+
+			"Exec=ymuse\n"+
+			"Terminal=false\n"+
+			"X-GNOME-Autostart-enabled=true\n",
+		AppMetadata.Name, AppMetadata.Icon)
+}
+
+// getAutostartFile returns the full path of the application's autostart .desktop file
+func getAutostartFile() string {
+	return path.Join(glib.GetUserConfigDir(), "autostart", "ymuse.desktop")
+}
@@ -16,14 +16,19 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/yktoo/ymuse/internal/util"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
+	"reflect"
+	"strings"
 	"sync"
+	"time"
 )
 
 // AppMetadata stores application-wide metadata such as version, license etc.
@@ -56,6 +61,40 @@ var AppMetadata = &struct {
 		"limitations under the License.\n",
 }
 
+// Library sort orders
+const (
+	LibrarySortByName     = "name"     // Sort library items by name
+	LibrarySortByModified = "modified" // Sort library items by last modification time, newest first
+	LibrarySortByDate     = "date"     // Sort library items by the Date tag, oldest first
+)
+
+// Library view types, each of which can be displayed as a list or a grid
+const (
+	LibraryViewFolders = "folders" // Filesystem, playlists, search results and other lists of items
+	LibraryViewAlbums  = "albums"  // Tag-based views: genres, artists, albums etc.
+)
+
+// Actions performed on tracks, playlists and streams clicked in the library or streams lists
+const (
+	ClickActionPlay    = "play"    // Insert after the current track and start playing
+	ClickActionNext    = "next"    // Insert after the current track
+	ClickActionAppend  = "append"  // Append to the queue
+	ClickActionReplace = "replace" // Replace the content of the queue
+)
+
+// Key binding profiles
+const (
+	KeymapDefault = "default" // Standard GTK key bindings
+	KeymapVim     = "vim"     // Vim-style key bindings on top of the standard ones
+)
+
+// Theme variants
+const (
+	ThemeSystem = "system" // Follow the desktop's preference
+	ThemeDark   = "dark"   // Always prefer the dark variant of the theme
+	ThemeLight  = "light"  // Always prefer the light variant of the theme
+)
+
 // Dimensions represents window dimensions
 type Dimensions struct {
 	X, Y, Width, Height int
@@ -69,33 +108,108 @@ type ColumnSpec struct {
 
 // StreamSpec describes settings for an Internet stream
 type StreamSpec struct {
-	Name string // Stream name
-	URI  string // Stream URI
+	Name  string // Stream name
+	URI   string // Stream URI
+	Genre string // Stream genre, optional
+}
+
+// PodcastSpec describes a podcast subscription
+type PodcastSpec struct {
+	Name string // Podcast name
+	URL  string // URL of the podcast's RSS feed
+}
+
+// SmartPlaylistSpec describes a smart playlist, whose tracks are selected by a search expression
+type SmartPlaylistSpec struct {
+	Name  string // Smart playlist name
+	Query string // MPD filter expression selecting the tracks, eg. (Genre == "Jazz")
+}
+
+// BookmarkSpec describes a bookmarked library path
+type BookmarkSpec struct {
+	Name string // Bookmark name
+	Path string // Serialised library path
 }
 
 // Config represents (storable) application configuration
 type Config struct {
-	MpdNetwork             string       // Network to use to connect to MPD, either 'tcp' or 'unix'
-	MpdSocketPath          string       // Path to the MPD's Unix socket (only if MpdNetwork == 'unix')
-	MpdHost                string       // MPD's IP address or hostname (only if MpdNetwork == 'tcp')
-	MpdPort                int          // MPD's port number (only if MpdNetwork == 'tcp')
-	MpdPassword            string       // MPD's password (optional)
-	MpdAutoConnect         bool         // Whether to automatically connect to MPD on startup
-	MpdAutoReconnect       bool         // Whether to automatically reconnect to MPD after connection is lost
-	QueueColumns           []ColumnSpec // Displayed queue columns
-	QueueToolbar           bool         // Whether the queue toolbar is visible
-	DefaultSortAttrID      int          // ID of MPD attribute used as a default for queue sorting
-	TrackDefaultReplace    bool         // Whether the default action for double-clicking a track is replace rather than append
-	PlaylistDefaultReplace bool         // Whether the default action for double-clicking a playlist is replace rather than append
-	StreamDefaultReplace   bool         // Whether the default action for double-clicking a stream is replace rather than append
-	PlayerTitleTemplate    string       // Track's title formatting template for the player
-	PlayerAlbumArtTracks   bool         // Whether to display the current track's album art in the player
-	PlayerAlbumArtStreams  bool         // Whether to display the current stream's album art in the player
-	MaxSearchResults       int          // Maximum number of displayed search results
-	Streams                []StreamSpec // Registered stream specifications
-	LibraryPath            string       // Last selected library path
+	MpdNetwork             string              // Network to use to connect to MPD, either 'tcp' or 'unix'
+	MpdSocketPath          string              // Path to the MPD's Unix socket (only if MpdNetwork == 'unix')
+	MpdHost                string              // MPD's IP address or hostname (only if MpdNetwork == 'tcp')
+	MpdPort                int                 // MPD's port number (only if MpdNetwork == 'tcp')
+	MpdPassword            string              // MPD's password (optional)
+	MpdAutoConnect         bool                // Whether to automatically connect to MPD on startup
+	MpdAutoReconnect       bool                // Whether to automatically reconnect to MPD after connection is lost
+	MpdMusicDir            string              // Local path to MPD's music directory, used for accepting dropped files and finding cover files (optional)
+	MpdAlbumArtURL         string              // Base URL of an HTTP server mirroring MPD's music directory, used for fetching cover files (optional)
+	MpdAlbumArt            bool                // Whether album art is fetched from MPD through its protocol
+	MpdHTTPStreamURL       string              // URL of the stream of MPD's httpd output, played when listening locally (optional, defaults to port 8000 on MpdHost)
+	ListenBrainzToken      string              // ListenBrainz user token for submitting listens (optional)
+	LastFmAPIKey           string              // Last.fm API key (optional)
+	LastFmSecret           string              // Last.fm API shared secret (optional)
+	LastFmUser             string              // Name of the logged-in Last.fm user
+	LastFmSessionKey       string              // Key of the Last.fm session, empty if not logged in
+	QueueColumns           []ColumnSpec        // Displayed queue columns
+	QueueToolbar           bool                // Whether the queue toolbar is visible
+	QueueAlbumArt          bool                // Whether album art thumbnails are displayed in the queue
+	TrayIcon               bool                // Whether a status icon is shown in the system tray
+	CloseToTray            bool                // Whether closing the main window hides it to the tray, provided the tray icon is shown
+	StartHidden            bool                // Whether the main window is kept hidden in the tray on startup, provided the tray icon is shown
+	KeymapProfile          string              // Key binding profile for the lists: one of the Keymap* constants
+	Theme                  string              // Theme variant to use: one of the Theme* constants
+	CustomCSSFile          string              // Path to a CSS file with user style overrides (optional)
+	DefaultSortAttrID      int                 // ID of MPD attribute used as a default for queue sorting
+	TrackDoubleClick       string              // Action on double-clicking a track or folder in the library: one of the ClickAction* constants
+	TrackMiddleClick       string              // Action on middle-clicking a track or folder in the library: one of the ClickAction* constants
+	PlaylistDoubleClick    string              // Action on double-clicking a playlist in the library: one of the ClickAction* constants
+	PlaylistMiddleClick    string              // Action on middle-clicking a playlist in the library: one of the ClickAction* constants
+	PlaylistExportPrefix   string              // Path prefix prepended to track URIs when exporting playlists (eg. "/sdcard/Music")
+	PlaylistShowModified   bool                // Whether to display last modification time of stored playlists
+	FavoritesPlaylist      string              // Name of the stored playlist holding starred tracks
+	PlaylistFolders        map[string]string   // Virtual folders of stored playlists: folder names keyed by playlist name
+	PlaylistFoldersFolded  map[string]bool     // Names of the playlist folders collapsed in the library
+	PlaylistRecentTargets  []string            // Playlists tracks have most recently been added to, latest first
+	StreamDoubleClick      string              // Action on double-clicking a stream or a podcast episode: one of the ClickAction* constants
+	StreamMiddleClick      string              // Action on middle-clicking a stream or a podcast episode: one of the ClickAction* constants
+	PlayerTitleTemplate    string              // Track's title formatting template for the player
+	PlayerAlbumArtTracks   bool                // Whether to display the current track's album art in the player
+	PlayerAlbumArtStreams  bool                // Whether to display the current stream's album art in the player
+	PlayerAlbumArtFiles    []string            // Shell patterns of cover file names looked up next to the track in MpdMusicDir or at MpdAlbumArtURL (eg. "cover.jpg")
+	PlayerAlbumArtOnline   bool                // Whether album art missing locally and in MPD is looked up online
+	AlbumArtCacheSize      int                 // Maximum size of the album art cache in megabytes
+	PlayerAutoResume       bool                // Whether long tracks automatically resume at their stored position, rather than offering it
+	NotifyTrackChange      bool                // Whether to show a desktop notification when the played track changes
+	NotifySuppressFocused  bool                // Whether track change notifications are suppressed while the main window is focused
+	MediaKeys              bool                // Whether hardware media keys are grabbed through the settings daemon of the desktop
+	InhibitSuspend         bool                // Whether session idle and suspend are inhibited while a local MPD is playing
+	PauseOnLock            bool                // Whether playback is paused when the screen gets locked
+	ResumeOnUnlock         bool                // Whether playback paused on screen lock is resumed on unlock
+	PauseOnSuspend         bool                // Whether playback is paused when the machine is about to suspend
+	MaxSearchResults       int                 // Maximum number of displayed search results
+	LibraryFuzzySearch     bool                // Whether library search and filter match items fuzzily
+	LibraryRegexSearch     bool                // Whether library search and filter patterns are regular expressions
+	QueueRegexFilter       bool                // Whether the queue filter pattern is a regular expression
+	SearchIgnoreDiacritics bool                // Whether search and filter ignore diacritics, so that "Dvorak" matches "Dvořák"
+	SortIgnoreArticles     bool                // Whether leading articles ("The", "A" etc.) are ignored when sorting artists
+	Streams                []StreamSpec        // Registered stream specifications
+	SmartPlaylists         []SmartPlaylistSpec // Defined smart playlists
+	Podcasts               []PodcastSpec       // Subscribed podcasts
+	LibraryPath            string              // Last selected library path
+	LibrarySelectedItem    string              // Last selected item in the library path (serialised)
+	LibrarySortBy          string              // Sort order of library items: one of the LibrarySortBy* constants
+	LibraryFoldersFirst    bool                // Whether folders are listed before files in the library
+	LibraryShowModified    bool                // Whether to display last modification time of library items
+	LibraryRecentDays      int                 // Number of days a track is considered recently added
+	LibraryExcludePatterns []string            // Shell patterns of file and folder names hidden from the library (eg. "*.cue")
+	LibraryAddFileTypes    string              // Last used file types for adding a folder filtered (eg. "flac; dsf")
+	LibraryBookmarks       []BookmarkSpec      // Bookmarked library paths
+	LibraryGridViews       map[string]bool     // Library view types (LibraryView* constants) displayed as a grid rather than a list
+	LibraryPreviewExpanded bool                // Whether the preview of the selected playlist in the library is expanded
+	ArtistPaneVisible      bool                // Whether the pane with information about the current artist is visible
 
 	MainWindowDimensions Dimensions // Main window dimensions
+
+	savedData []byte // Settings as last loaded or saved, serialised, to tell changes made outside the application
 }
 
 // Config singleton with all the defaults
@@ -134,11 +248,18 @@ func newConfig() *Config {
 			{ID: MTAttrLength},
 			{ID: MTAttrGenre},
 		},
-		QueueToolbar:           true,
-		DefaultSortAttrID:      MTAttrPath,
-		TrackDefaultReplace:    false,
-		PlaylistDefaultReplace: true,
-		StreamDefaultReplace:   true,
+		QueueToolbar:         true,
+		KeymapProfile:        KeymapDefault,
+		Theme:                ThemeSystem,
+		DefaultSortAttrID:    MTAttrPath,
+		TrackDoubleClick:     ClickActionAppend,
+		TrackMiddleClick:     ClickActionNext,
+		PlaylistDoubleClick:  ClickActionReplace,
+		PlaylistMiddleClick:  ClickActionAppend,
+		PlaylistShowModified: true,
+		FavoritesPlaylist:    "Favorites",
+		StreamDoubleClick:    ClickActionReplace,
+		StreamMiddleClick:    ClickActionPlay,
 		PlayerTitleTemplate: glib.Local(
 			"{{- if or .Title .Album | or .Artist -}}\n" +
 				"<big><b>{{ .Title | default \"(unknown title)\" }}</b></big>\n" +
@@ -151,9 +272,20 @@ func newConfig() *Config {
 				"{{- else -}}\n" +
 				"<i>(no track)</i>\n" +
 				"{{- end -}}\n"),
-		PlayerAlbumArtTracks:  true,
-		PlayerAlbumArtStreams: false,
-		MaxSearchResults:      500,
+		PlayerAlbumArtTracks:   true,
+		PlayerAlbumArtStreams:  false,
+		PlayerAlbumArtFiles:    []string{"cover.jpg", "cover.png", "folder.jpg", "folder.png", "front.jpg", "front.png"},
+		MpdAlbumArt:            true,
+		AlbumArtCacheSize:      100,
+		NotifyTrackChange:      true,
+		NotifySuppressFocused:  true,
+		MaxSearchResults:       500,
+		SearchIgnoreDiacritics: true,
+		LibrarySortBy:          LibrarySortByName,
+		LibraryFoldersFirst:    true,
+		LibraryRecentDays:      30,
+		LibraryAddFileTypes:    "flac",
+		LibraryPreviewExpanded: true,
 		Streams: []StreamSpec{
 			{Name: "BBC World News", URI: "http://bbcwssc.ic.llnwd.net/stream/bbcwssc_mp1_ws-einws"},
 		},
@@ -161,20 +293,195 @@ func newConfig() *Config {
 	}
 }
 
-// Load reads the config from the default file
+// Load reads the config from GSettings if the settings are stored there, from the default file otherwise
 func (c *Config) Load() {
+	errCheck(c.load(), "Failed to load configuration")
+}
+
+// load reads the config from GSettings or the default file, returning an error if it can't be read or parsed
+func (c *Config) load() error {
+	var err error
+	if s := enabledGSettings(); s != nil {
+		err = c.loadGSettings(s)
+	} else {
+		err = c.loadFile()
+	}
+	if err == nil {
+		c.markSaved()
+	}
+	return err
+}
+
+// loadFile reads the config from the default file
+func (c *Config) loadFile() error {
 	// Try to read the file
 	file := c.getConfigFile()
 	data, err := ioutil.ReadFile(file)
-	if errCheck(err, "Couldn't read file") {
-		return
+	if err != nil {
+		return fmt.Errorf("couldn't read file: %v", err)
 	}
 
 	// Unmarshal the config
-	if errCheck(json.Unmarshal(data, &c), "json.Unmarshal() failed") {
-		return
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("couldn't parse %s: %v", file, err)
 	}
+	c.migrateDefaultReplace(data)
 	log.Debugf("Loaded configuration from %s", file)
+	return nil
+}
+
+// Reload reads the stored config again and applies the settings that have changed in storage since they were last
+// loaded or saved (see merge). The config is left intact if it can't be read or parsed
+func (c *Config) Reload() error {
+	fresh := newConfig()
+	if err := fresh.load(); err != nil {
+		return err
+	}
+	return c.merge(fresh)
+}
+
+// ReloadIfChanged reloads the config (see Reload) if the stored settings differ from what has last been loaded or
+// saved, and returns whether they do
+func (c *Config) ReloadIfChanged() (bool, error) {
+	fresh := newConfig()
+	if err := fresh.load(); err != nil {
+		return false, err
+	}
+	if bytes.Equal(fresh.savedData, c.savedData) {
+		return false, nil
+	}
+	if err := c.merge(fresh); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// merge copies over the settings from the freshly loaded config that differ from the ones last loaded or saved, which
+// means they've been changed in storage. All other settings are kept, including those only changed in memory so far
+func (c *Config) merge(fresh *Config) error {
+	// Restore the settings as they were last loaded or saved, or the defaults if there were none
+	stored := newConfig()
+	if c.savedData != nil {
+		stored = &Config{}
+		if err := json.Unmarshal(c.savedData, stored); err != nil {
+			return err
+		}
+	}
+
+	// Compare the settings in their serialised form, as that's what's stored
+	cv, fv, sv := reflect.ValueOf(c).Elem(), reflect.ValueOf(fresh).Elem(), reflect.ValueOf(stored).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		if cv.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		freshData, err := json.Marshal(fv.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+		storedData, err := json.Marshal(sv.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(freshData, storedData) {
+			cv.Field(i).Set(fv.Field(i))
+		}
+	}
+	c.savedData = fresh.savedData
+	return nil
+}
+
+// markSaved remembers the current settings as the stored ones, to tell changes made outside the application later
+func (c *Config) markSaved() {
+	data, err := json.Marshal(c)
+	if !errCheck(err, "json.Marshal() failed") {
+		c.savedData = data
+	}
+}
+
+// Watch polls the default config file for modifications every interval, and invokes onChange (in a separate goroutine)
+// each time the file's modification time changes. It also invokes onChange (on the main loop, at most once an interval)
+// when GSettings keys change. Returns a function that stops watching
+func (c *Config) Watch(interval time.Duration, onChange func()) func() {
+	file := c.getConfigFile()
+	modTime := fileModTime(file)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if t := fileModTime(file); !t.Equal(modTime) {
+					modTime = t
+					onChange()
+				}
+			}
+		}
+	}()
+
+	// Coalesce GSettings changes, as they come key by key
+	var handle glib.SignalHandle
+	s := getGSettings()
+	if s != nil {
+		pending := false
+		var err error
+		handle, err = s.Connect("changed", func() {
+			if !pending {
+				pending = true
+				_, err := glib.TimeoutAdd(uint(interval/time.Millisecond), func() {
+					pending = false
+					onChange()
+				})
+				errCheck(err, "glib.TimeoutAdd() failed")
+			}
+		})
+		if errCheck(err, "Connect(changed) failed") {
+			s = nil
+		}
+	}
+	return func() {
+		close(done)
+		if s != nil {
+			s.HandlerDisconnect(handle)
+		}
+	}
+}
+
+// fileModTime returns the modification time of the given file, or zero time if it can't be determined
+func fileModTime(file string) time.Time {
+	if fi, err := os.Stat(file); err == nil {
+		return fi.ModTime()
+	}
+	return time.Time{}
+}
+
+// migrateDefaultReplace translates the replace-or-append double click settings of older versions, if the given config
+// data has any, into double click actions
+func (c *Config) migrateDefaultReplace(data []byte) {
+	var old struct {
+		TrackDefaultReplace, PlaylistDefaultReplace, StreamDefaultReplace *bool
+	}
+	if json.Unmarshal(data, &old) != nil {
+		return
+	}
+	for _, m := range []struct {
+		replace *bool
+		action  *string
+	}{
+		{old.TrackDefaultReplace, &c.TrackDoubleClick},
+		{old.PlaylistDefaultReplace, &c.PlaylistDoubleClick},
+		{old.StreamDefaultReplace, &c.StreamDoubleClick},
+	} {
+		switch {
+		case m.replace == nil:
+		case *m.replace:
+			*m.action = ClickActionReplace
+		default:
+			*m.action = ClickActionAppend
+		}
+	}
 }
 
 // MpdNetworkAddress returns the MPD network and the address string
@@ -185,24 +492,72 @@ func (c *Config) MpdNetworkAddress() (string, string) {
 	return "tcp", fmt.Sprintf("%s:%d", c.MpdHost, c.MpdPort)
 }
 
-// Save writes out the config to the default file
+// HTTPStreamURL returns the URL of the stream of MPD's httpd output, either configured or guessed from MPD's address
+func (c *Config) HTTPStreamURL() string {
+	if c.MpdHTTPStreamURL != "" {
+		return c.MpdHTTPStreamURL
+	}
+	host := c.MpdHost
+	if c.MpdNetwork == "unix" || host == "" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, "8000")
+}
+
+// MpdIsLocal returns whether MPD runs on the local machine, judging by its address
+func (c *Config) MpdIsLocal() bool {
+	if c.MpdNetwork == "unix" {
+		return true
+	}
+	if c.MpdHost == "" || strings.EqualFold(c.MpdHost, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(c.MpdHost); ip != nil {
+		return ip.IsLoopback()
+	}
+	hostname, err := os.Hostname()
+	return err == nil && strings.EqualFold(c.MpdHost, hostname)
+}
+
+// Save writes out the config to GSettings if the settings are stored there, to the default file otherwise
 func (c *Config) Save() {
+	errCheck(c.save(), "Failed to save configuration")
+}
+
+// save writes out the config to GSettings or the default file, returning an error if it fails
+func (c *Config) save() error {
+	var err error
+	if enabledGSettings() != nil {
+		err = c.saveGSettings()
+	} else {
+		err = c.saveFile()
+	}
+	if err == nil {
+		c.markSaved()
+	}
+	return err
+}
+
+// saveFile writes out the config to the default file
+func (c *Config) saveFile() error {
 	// Create the config directory if it doesn't exist
-	if errCheck(os.MkdirAll(c.getConfigDir(), 0755), "MkdirAll() failed") {
-		return
+	if err := os.MkdirAll(c.getConfigDir(), 0755); err != nil {
+		return err
 	}
 
 	// Serialise the config
 	data, err := json.MarshalIndent(c, "", "    ")
-	if errCheck(err, "json.MarshalIndent() failed") {
-		return
+	if err != nil {
+		return err
 	}
 
 	// Save the config
 	file := c.getConfigFile()
-	if !errCheck(ioutil.WriteFile(file, data, 0600), "WriteFile() failed") {
-		log.Debugf("Saved configuration to %s", file)
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		return err
 	}
+	log.Debugf("Saved configuration to %s", file)
+	return nil
 }
 
 // getConfigDir returns the full path to the config directory
@@ -0,0 +1,211 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+// #cgo pkg-config: gio-2.0
+// #include <stdlib.h>
+// #include <gio/gio.h>
+import "C"
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+	"unsafe"
+)
+
+// gsettingsEnabledKey is the GSettings key telling whether the settings are stored in GSettings rather than in the
+// config file
+const gsettingsEnabledKey = "use-gsettings"
+
+var (
+	gsettings       *glib.Settings       // Application's GSettings, nil if the schema isn't installed
+	gsettingsSchema *glib.SettingsSchema // Installed schema of the application's GSettings, nil if none
+	gsettingsOnce   sync.Once
+	stringsType     = reflect.TypeOf([]string(nil))
+)
+
+// getGSettings returns the application's GSettings, or nil if its schema isn't installed
+func getGSettings() *glib.Settings {
+	gsettingsOnce.Do(func() {
+		// Instantiating settings with a missing schema aborts the program, so look it up first
+		if src := glib.SettingsSchemaSourceGetDefault(); src != nil {
+			if gsettingsSchema = src.Lookup(AppMetadata.ID, true); gsettingsSchema != nil {
+				gsettings = glib.SettingsNew(AppMetadata.ID)
+			}
+		}
+	})
+	return gsettings
+}
+
+// enabledGSettings returns the application's GSettings if the settings are stored there, nil otherwise
+func enabledGSettings() *glib.Settings {
+	if s := getGSettings(); s != nil && s.GetBoolean(gsettingsEnabledKey) {
+		return s
+	}
+	return nil
+}
+
+// GSettingsAvailable returns whether the application's GSettings schema is installed
+func GSettingsAvailable() bool {
+	return getGSettings() != nil
+}
+
+// GSettingsEnabled returns whether the settings are stored in GSettings rather than in the config file
+func GSettingsEnabled() bool {
+	return enabledGSettings() != nil
+}
+
+// SetGSettingsEnabled switches the settings storage between GSettings and the config file, and writes the current
+// settings out to the newly selected one. The storage is left as is if they can't be written
+func SetGSettingsEnabled(enabled bool) error {
+	s := getGSettings()
+	if s == nil {
+		return fmt.Errorf("GSettings schema %s isn't installed", AppMetadata.ID)
+	}
+	if !s.SetBoolean(gsettingsEnabledKey, enabled) {
+		return fmt.Errorf("GSettings key %s isn't writable", gsettingsEnabledKey)
+	}
+	if err := GetConfig().save(); err != nil {
+		s.SetBoolean(gsettingsEnabledKey, !enabled)
+		return err
+	}
+	return nil
+}
+
+// loadGSettings reads the config from the given GSettings. Settings whose keys have never been set are left intact
+func (c *Config) loadGSettings(s *glib.Settings) error {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key, ok := gsettingsFieldKey(field)
+		if !ok || !gsettingsHasUserValue(s, key) {
+			continue
+		}
+
+		// Basic types are stored as such, everything else as JSON
+		f := v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Bool:
+			f.SetBool(s.GetBoolean(key))
+		case field.Type.Kind() == reflect.Int:
+			f.SetInt(int64(s.GetInt(key)))
+		case field.Type.Kind() == reflect.String:
+			f.SetString(s.GetString(key))
+		case field.Type == stringsType:
+			if strv := s.GetStrv(key); len(strv) > 0 {
+				f.Set(reflect.ValueOf(strv))
+			} else {
+				f.Set(reflect.Zero(field.Type))
+			}
+		default:
+			if err := json.Unmarshal([]byte(s.GetString(key)), f.Addr().Interface()); err != nil {
+				return fmt.Errorf("couldn't parse GSettings key %s: %v", key, err)
+			}
+		}
+	}
+	log.Debugf("Loaded configuration from GSettings schema %s", AppMetadata.ID)
+	return nil
+}
+
+// saveGSettings writes the config out to the application's GSettings, applying all the keys at once
+func (c *Config) saveGSettings() error {
+	// Use a separate instance as there's no leaving the delayed-apply mode
+	s := glib.SettingsNew(AppMetadata.ID)
+	s.Delay()
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key, ok := gsettingsFieldKey(field)
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Bool:
+			ok = s.SetBoolean(key, f.Bool())
+		case field.Type.Kind() == reflect.Int:
+			ok = s.SetInt(key, int(f.Int()))
+		case field.Type.Kind() == reflect.String:
+			ok = s.SetString(key, f.String())
+		case field.Type == stringsType:
+			ok = s.SetStrv(key, f.Interface().([]string))
+		default:
+			data, err := json.Marshal(f.Interface())
+			if err != nil {
+				s.Revert()
+				return err
+			}
+			ok = s.SetString(key, string(data))
+		}
+		if !ok {
+			s.Revert()
+			return fmt.Errorf("couldn't write GSettings key %s", key)
+		}
+	}
+	s.Apply()
+	glib.SettingsSync()
+	log.Debugf("Saved configuration to GSettings schema %s", AppMetadata.ID)
+	return nil
+}
+
+// gsettingsFieldKey returns the name of the GSettings key storing the given config field, and whether the field is
+// stored at all. Unexported fields aren't, neither are those lacking a key in the installed schema, as accessing it
+// would abort the program
+func gsettingsFieldKey(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	key := gsettingsKey(field.Name)
+	if !gsettingsSchema.HasKey(key) {
+		log.Warningf("GSettings schema %s lacks key %s", AppMetadata.ID, key)
+		return "", false
+	}
+	return key, true
+}
+
+// gsettingsKey returns the name of the GSettings key storing the config field with the given name, eg.
+// "mpd-http-stream-url" for MpdHTTPStreamURL
+func gsettingsKey(field string) string {
+	var b strings.Builder
+	r := []rune(field)
+	for i, c := range r {
+		// Start a new word at a capital following a lowercase letter or a digit, or ending an abbreviation
+		if i > 0 && unicode.IsUpper(c) &&
+			(unicode.IsLower(r[i-1]) || unicode.IsDigit(r[i-1]) ||
+				unicode.IsUpper(r[i-1]) && i+1 < len(r) && unicode.IsLower(r[i+1])) {
+			b.WriteRune('-')
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
+
+// gsettingsHasUserValue returns whether the given GSettings key has been set, as opposed to having the schema's
+// default value
+func gsettingsHasUserValue(s *glib.Settings, key string) bool {
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+	v := C.g_settings_get_user_value((*C.GSettings)(unsafe.Pointer(s.Native())), (*C.gchar)(cKey))
+	if v == nil {
+		return false
+	}
+	C.g_variant_unref(v)
+	return true
+}
@@ -51,6 +51,8 @@ const (
 	QueueColumnFontWeight
 	QueueColumnBgColor
 	QueueColumnVisible
+	QueueColumnStarIcon
+	QueueColumnAlbumArt
 )
 
 // MpdTrackAttribute describes an MPD's track attribute
@@ -68,29 +70,29 @@ type MpdTrackAttribute struct {
 
 // MpdTrackAttributes contains all known MPD's track attributes
 var MpdTrackAttributes = map[int]MpdTrackAttribute{
-	MTAttrArtist:          {"Artist", "Artist", "Artist", false, true, 200, 0, nil, nil},
-	MTAttrArtistSort:      {"Artist", "Artist (for sorting)", "Artistsort", false, false, 200, 0, nil, nil},
-	MTAttrAlbum:           {"Album", "Album", "Album", false, true, 200, 0, nil, nil},
-	MTAttrAlbumSort:       {"Album", "Album (for sorting)", "Albumsort", false, false, 200, 0, nil, nil},
-	MTAttrAlbumArtist:     {"Album artist", "Album artist", "Albumartist", false, true, 200, 0, nil, nil},
-	MTAttrAlbumArtistSort: {"Album artist", "Album artist (for sorting)", "Albumartistsort", false, false, 200, 0, nil, nil},
-	MTAttrDisc:            {"Disc", "Disc", "Disc", false, true, 50, 1, nil, nil},
-	MTAttrTrack:           {"Track", "Track title", "Title", false, true, 200, 0, nil, []int{MTAttrName, MTAttrPath}},
-	MTAttrNumber:          {"#", "Track number", "Track", true, true, 50, 1, nil, nil},
-	MTAttrLength:          {"Length", "Track length", "duration", true, false, 60, 1, util.FormatSecondsStr, nil},
-	MTAttrPath:            {"Path", "Directory and file name", "file", false, true, 200, 0, nil, nil},
-	MTAttrDirectory:       {"Directory", "File path", "file", false, false, 200, 0, path.Dir, nil},
-	MTAttrFile:            {"File", "File name", "file", false, false, 200, 0, path.Base, nil},
-	MTAttrYear:            {"Year", "Year", "Date", true, true, 50, 1, nil, nil},
-	MTAttrGenre:           {"Genre", "Genre", "Genre", false, true, 200, 0, nil, nil},
-	MTAttrName:            {"Name", "Stream name", "Name", false, true, 200, 0, nil, nil},
-	MTAttrComposer:        {"Composer", "Composer", "Composer", false, true, 200, 0, nil, nil},
-	MTAttrPerformer:       {"Performer", "Performer", "Performer", false, true, 200, 0, nil, nil},
-	MTAttrConductor:       {"Conductor", "Conductor", "Conductor", false, false, 200, 0, nil, nil},
-	MTAttrWork:            {"Work", "Work", "Work", false, false, 200, 0, nil, nil},
-	MTAttrGrouping:        {"Grouping", "Grouping", "Grouping", false, false, 200, 0, nil, nil},
-	MTAttrComment:         {"Comment", "Comment", "Comment", false, true, 200, 0, nil, nil},
-	MTAttrLabel:           {"Label", "Label", "Label", false, true, 200, 0, nil, nil},
+	MTAttrArtist:          {util.LocalNoop("Artist"), util.LocalNoop("Artist"), "Artist", false, true, 200, 0, nil, nil},
+	MTAttrArtistSort:      {util.LocalNoop("Artist"), util.LocalNoop("Artist (for sorting)"), "Artistsort", false, false, 200, 0, nil, nil},
+	MTAttrAlbum:           {util.LocalNoop("Album"), util.LocalNoop("Album"), "Album", false, true, 200, 0, nil, nil},
+	MTAttrAlbumSort:       {util.LocalNoop("Album"), util.LocalNoop("Album (for sorting)"), "Albumsort", false, false, 200, 0, nil, nil},
+	MTAttrAlbumArtist:     {util.LocalNoop("Album artist"), util.LocalNoop("Album artist"), "Albumartist", false, true, 200, 0, nil, nil},
+	MTAttrAlbumArtistSort: {util.LocalNoop("Album artist"), util.LocalNoop("Album artist (for sorting)"), "Albumartistsort", false, false, 200, 0, nil, nil},
+	MTAttrDisc:            {util.LocalNoop("Disc"), util.LocalNoop("Disc"), "Disc", false, true, 50, 1, nil, nil},
+	MTAttrTrack:           {util.LocalNoop("Track"), util.LocalNoop("Track title"), "Title", false, true, 200, 0, nil, []int{MTAttrName, MTAttrPath}},
+	MTAttrNumber:          {util.LocalNoop("#"), util.LocalNoop("Track number"), "Track", true, true, 50, 1, nil, nil},
+	MTAttrLength:          {util.LocalNoop("Length"), util.LocalNoop("Track length"), "duration", true, false, 60, 1, util.FormatSecondsStr, nil},
+	MTAttrPath:            {util.LocalNoop("Path"), util.LocalNoop("Directory and file name"), "file", false, true, 200, 0, nil, nil},
+	MTAttrDirectory:       {util.LocalNoop("Directory"), util.LocalNoop("File path"), "file", false, false, 200, 0, path.Dir, nil},
+	MTAttrFile:            {util.LocalNoop("File"), util.LocalNoop("File name"), "file", false, false, 200, 0, path.Base, nil},
+	MTAttrYear:            {util.LocalNoop("Year"), util.LocalNoop("Year"), "Date", true, true, 50, 1, nil, nil},
+	MTAttrGenre:           {util.LocalNoop("Genre"), util.LocalNoop("Genre"), "Genre", false, true, 200, 0, util.FormatTagValues, nil},
+	MTAttrName:            {util.LocalNoop("Name"), util.LocalNoop("Stream name"), "Name", false, true, 200, 0, nil, nil},
+	MTAttrComposer:        {util.LocalNoop("Composer"), util.LocalNoop("Composer"), "Composer", false, true, 200, 0, nil, nil},
+	MTAttrPerformer:       {util.LocalNoop("Performer"), util.LocalNoop("Performer"), "Performer", false, true, 200, 0, nil, nil},
+	MTAttrConductor:       {util.LocalNoop("Conductor"), util.LocalNoop("Conductor"), "Conductor", false, false, 200, 0, nil, nil},
+	MTAttrWork:            {util.LocalNoop("Work"), util.LocalNoop("Work"), "Work", false, false, 200, 0, nil, nil},
+	MTAttrGrouping:        {util.LocalNoop("Grouping"), util.LocalNoop("Grouping"), "Grouping", false, false, 200, 0, nil, nil},
+	MTAttrComment:         {util.LocalNoop("Comment"), util.LocalNoop("Comment"), "Comment", false, true, 200, 0, nil, nil},
+	MTAttrLabel:           {util.LocalNoop("Label"), util.LocalNoop("Label"), "Label", false, true, 200, 0, nil, nil},
 }
 
 // MpdTrackAttributeIds stores attribute IDs sorted in desired display order
@@ -0,0 +1,449 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	coverArtArchiveURL        = "https://coverartarchive.org" // Base URL of the Cover Art Archive API
+	albumArtRetryPeriod       = 30 * 24 * time.Hour           // Period an album without online artwork isn't looked up again
+	albumArtImageFileSuffix   = ".img"                        // Suffix of cached album art files
+	albumArtMissingFileSuffix = ".none"                       // Suffix of files marking albums without online artwork
+)
+
+// AlbumArtCache stores album art fetched from MPD or online in files, so that it isn't fetched over and over. Albums
+// without online artwork are marked as such, so that they aren't looked up again for a while. The cache size is kept
+// within a limit by evicting the least recently used images
+type AlbumArtCache struct {
+	dir string // Directory holding the cached files
+}
+
+// NewAlbumArtCache creates and returns a new AlbumArtCache instance in the user's cache directory
+func NewAlbumArtCache() *AlbumArtCache {
+	return &AlbumArtCache{dir: path.Join(glib.GetUserCacheDir(), "ymuse", "covers")}
+}
+
+// Get returns the cached artwork for the album with the given key (see AlbumArtKey). missing is true if the album is
+// known to have no online artwork
+func (c *AlbumArtCache) Get(key string) (data []byte, missing bool) {
+	base := c.baseName(key)
+	if data, err := ioutil.ReadFile(base + albumArtImageFileSuffix); err == nil && len(data) > 0 {
+		// Mark the image as recently used
+		now := time.Now()
+		errCheck(os.Chtimes(base+albumArtImageFileSuffix, now, now), "Chtimes() failed")
+		return data, false
+	}
+	if fi, err := os.Stat(base + albumArtMissingFileSuffix); err == nil && time.Since(fi.ModTime()) < albumArtRetryPeriod {
+		return nil, true
+	}
+	return nil, false
+}
+
+// File returns the path of the cached image for the album with the given key, or an empty string if it isn't cached.
+// The file can be evicted from the cache at any time, so it's only suitable for handing over to other processes, such
+// as media widgets and notification daemons
+func (c *AlbumArtCache) File(key string) string {
+	file := c.baseName(key) + albumArtImageFileSuffix
+	if fi, err := os.Stat(file); err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 {
+		return ""
+	}
+	return file
+}
+
+// Put stores the artwork for the album with the given key in the cache. If data is empty, the album is marked as
+// having no online artwork instead
+func (c *AlbumArtCache) Put(key string, data []byte) error {
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	// Write either the image or the marker
+	base := c.baseName(key)
+	if len(data) == 0 {
+		return ioutil.WriteFile(base+albumArtMissingFileSuffix, nil, 0644)
+	}
+	if err := ioutil.WriteFile(base+albumArtImageFileSuffix, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Remove(base + albumArtMissingFileSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Size returns the total size of the cached images in bytes
+func (c *AlbumArtCache) Size() (int64, error) {
+	infos, err := c.images()
+	var size int64
+	for _, fi := range infos {
+		size += fi.Size()
+	}
+	return size, err
+}
+
+// Trim evicts the least recently used images until the total size of the cache doesn't exceed maxSize bytes. Expired
+// markers of albums without artwork are removed as well
+func (c *AlbumArtCache) Trim(maxSize int64) error {
+	// Drop expired markers
+	markers, err := filepath.Glob(path.Join(c.dir, "*"+albumArtMissingFileSuffix))
+	if err != nil {
+		return err
+	}
+	for _, m := range markers {
+		if fi, err := os.Stat(m); err == nil && time.Since(fi.ModTime()) >= albumArtRetryPeriod {
+			if err := os.Remove(m); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	// Sum up image sizes
+	infos, err := c.images()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, fi := range infos {
+		size += fi.Size()
+	}
+
+	// Evict the least recently used images first
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().Before(infos[j].ModTime()) })
+	for _, fi := range infos {
+		if size <= maxSize {
+			break
+		}
+		if err := os.Remove(path.Join(c.dir, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= fi.Size()
+	}
+	return nil
+}
+
+// Clear removes all cached images and markers
+func (c *AlbumArtCache) Clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+// images returns file information about all cached images
+func (c *AlbumArtCache) images() ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var result []os.FileInfo
+	for _, fi := range infos {
+		if fi.Mode().IsRegular() && strings.HasSuffix(fi.Name(), albumArtImageFileSuffix) {
+			result = append(result, fi)
+		}
+	}
+	return result, nil
+}
+
+// baseName returns the full path of the cached files for the given album key, without a suffix
+func (c *AlbumArtCache) baseName(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return path.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// AlbumArtKey returns the key identifying the album of the given track for caching and online artwork lookup: its
+// MusicBrainz ID, if any, or its artist and title otherwise. Returns an empty string if the album can't be identified
+func AlbumArtKey(attrs mpd.Attrs) string {
+	if id := attrs["MUSICBRAINZ_ALBUMID"]; IsMusicBrainzID(id) {
+		return "mbid:" + id
+	}
+	artist, album := strings.TrimSpace(trackAlbumArtist(attrs)), strings.TrimSpace(attrs["Album"])
+	if artist == "" || album == "" {
+		return ""
+	}
+	return strings.ToLower(artist + "\n" + album)
+}
+
+// AlbumArtSettings is a snapshot of the settings album art is fetched with. It's taken on the GTK thread and handed
+// over to the fetching goroutines, so that they don't read the config while it can be reloaded
+type AlbumArtSettings struct {
+	MusicDir  string   // Local path to MPD's music directory, optional
+	MirrorURL string   // Base URL of an HTTP server mirroring MPD's music directory, optional
+	FileNames []string // Shell patterns of cover file names
+	FromMPD   bool     // Whether album art is fetched from MPD through its protocol
+	Online    bool     // Whether album art missing locally and in MPD is looked up online
+	CacheSize int64    // Maximum size of the album art cache in bytes
+	LastFm    *LastFm  // Last.fm client used for looking album art up online
+}
+
+// NewAlbumArtSettings returns the current album art settings. Must be called on the GTK thread
+func NewAlbumArtSettings() *AlbumArtSettings {
+	cfg := config.GetConfig()
+	return &AlbumArtSettings{
+		MusicDir:  cfg.MpdMusicDir,
+		MirrorURL: cfg.MpdAlbumArtURL,
+		FileNames: append([]string(nil), cfg.PlayerAlbumArtFiles...),
+		FromMPD:   cfg.MpdAlbumArt,
+		Online:    cfg.PlayerAlbumArtOnline,
+		CacheSize: int64(cfg.AlbumArtCacheSize) << 20,
+		LastFm:    NewLastFmFromConfig(),
+	}
+}
+
+// FetchAlbumArt returns the artwork for the given track. A cover file next to the track in the local music directory
+// is preferred, if the directory is configured, as it's much faster to load than through MPD. Otherwise the cover file
+// is fetched from the HTTP mirror of the music directory and then from MPD, if either is enabled. If there's still no
+// artwork, it's looked up online, if enabled. Artwork fetched remotely is stored in the given cache. Returns nil if
+// there's no artwork. This function does network I/O and must not be called on the GTK thread
+func FetchAlbumArt(connector *Connector, cache *AlbumArtCache, settings *AlbumArtSettings, attrs mpd.Attrs) ([]byte, error) {
+	uri := attrs["file"]
+	if localPath, ok := util.URIToLocalPath(settings.MusicDir, uri); ok {
+		if file := FindAlbumArtFile(filepath.Dir(localPath), settings.FileNames); file != "" {
+			data, err := ioutil.ReadFile(file)
+			if !errCheck(err, "Failed to read cover file") && len(data) > 0 {
+				log.Debugf("Loaded album art from %s", file)
+				return data, nil
+			}
+		}
+	}
+
+	// Try the cache before fetching the artwork remotely, unless it's a stream
+	key := ""
+	if !util.IsStreamURI(uri) {
+		key = AlbumArtKey(attrs)
+	}
+	missing := false
+	if key != "" {
+		var data []byte
+		if data, missing = cache.Get(key); data != nil {
+			return data, nil
+		}
+	}
+
+	// Try the HTTP mirror of the music directory, if any. Failures aren't fatal as MPD may still have the artwork
+	if settings.MirrorURL != "" && !util.IsStreamURI(uri) {
+		data, err := FetchHTTPAlbumArt(settings.MirrorURL, uri, settings.FileNames)
+		if !errCheck(err, "Failed to fetch cover file over HTTP") && len(data) > 0 {
+			if key != "" {
+				CacheAlbumArt(cache, key, data, settings.CacheSize)
+			}
+			return data, nil
+		}
+	}
+
+	// Ask MPD, if enabled
+	if settings.FromMPD {
+		data, err := connector.GetAlbumArt(uri)
+		if err != nil || key == "" {
+			return data, err
+		}
+		if len(data) > 0 {
+			CacheAlbumArt(cache, key, data, settings.CacheSize)
+			return data, nil
+		}
+	}
+
+	// Look the artwork up online, unless it's known to be missing there
+	if !settings.Online || missing || key == "" {
+		return nil, nil
+	}
+	log.Debugf("Looking up album art online for %s", uri)
+	data, err := FetchOnlineAlbumArt(settings.LastFm, coverArtArchiveURL, attrs)
+	if err != nil {
+		return nil, err
+	}
+	CacheAlbumArt(cache, key, data, settings.CacheSize)
+	return data, nil
+}
+
+// FetchHTTPAlbumArt fetches the cover file of the track with the given URI from an HTTP server mirroring MPD's music
+// directory at the given base URL. The given cover file names are tried in order; wildcard patterns are skipped as
+// they can't be resolved over HTTP. Returns nil if there's no cover file
+func FetchHTTPAlbumArt(baseURL, uri string, names []string) ([]byte, error) {
+	// Escape each element of the track's directory
+	prefix := strings.TrimSuffix(baseURL, "/") + "/"
+	if dir := path.Dir(uri); dir != "." {
+		for _, s := range strings.Split(dir, "/") {
+			prefix += url.PathEscape(s) + "/"
+		}
+	}
+
+	for _, name := range names {
+		if strings.ContainsAny(name, "*?[\\") {
+			continue
+		}
+		data, err := fetchImage(prefix + url.PathEscape(name))
+		if err != nil || len(data) > 0 {
+			return data, err
+		}
+	}
+	return nil, nil
+}
+
+// AlbumArtFile returns the path of an image file with the artwork for the given track: a cover file next to the track
+// in the local music directory or the image in the given cache. Returns an empty string if there's no such file, in
+// which case the artwork needs to be fetched first (see FetchAlbumArt). Unlike FetchAlbumArt, this function doesn't
+// talk to MPD and can be called on the GTK thread
+func AlbumArtFile(cache *AlbumArtCache, attrs mpd.Attrs) string {
+	uri := attrs["file"]
+	cfg := config.GetConfig()
+	if localPath, ok := util.URIToLocalPath(cfg.MpdMusicDir, uri); ok {
+		if file := FindAlbumArtFile(filepath.Dir(localPath), cfg.PlayerAlbumArtFiles); file != "" {
+			return file
+		}
+	}
+	if util.IsStreamURI(uri) {
+		return ""
+	}
+	if key := AlbumArtKey(attrs); key != "" {
+		return cache.File(key)
+	}
+	return ""
+}
+
+// CacheAlbumArt stores the artwork for the album with the given key in the cache (see AlbumArtCache.Put), keeping the
+// cache within the given size limit in bytes
+func CacheAlbumArt(cache *AlbumArtCache, key string, data []byte, maxSize int64) {
+	if !errCheck(cache.Put(key, data), "Failed to cache album art") {
+		errCheck(cache.Trim(maxSize), "Failed to trim album art cache")
+	}
+}
+
+// FindAlbumArtFile returns the path of a file in the given directory matching any of the given shell patterns, which
+// are tried in order and matched ignoring case. Returns an empty string if there's no such file
+func FindAlbumArtFile(dir string, patterns []string) string {
+	if len(patterns) == 0 {
+		return ""
+	}
+
+	// List the files in the directory
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Debugf("Failed to read directory %s: %v", dir, err)
+		return ""
+	}
+
+	// Look for a matching file, one pattern at a time
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		for _, fi := range infos {
+			if fi.Mode().IsRegular() {
+				if ok, err := filepath.Match(pattern, strings.ToLower(fi.Name())); err == nil && ok {
+					return filepath.Join(dir, fi.Name())
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// FetchOnlineAlbumArt looks up the artwork for the album of the given track online: in the Cover Art Archive (at the
+// given API base URL) by the album's MusicBrainz ID, if any, then on Last.fm by the album's artist and title, if the
+// client has an API key. Returns nil if no artwork is found
+func FetchOnlineAlbumArt(lastFm *LastFm, coverArtArchiveBaseURL string, attrs mpd.Attrs) ([]byte, error) {
+	// Try the Cover Art Archive first
+	if id := attrs["MUSICBRAINZ_ALBUMID"]; IsMusicBrainzID(id) {
+		data, err := fetchImage(fmt.Sprintf("%s/release/%s/front-500", coverArtArchiveBaseURL, id))
+		if err != nil || len(data) > 0 {
+			return data, err
+		}
+	}
+
+	// Fall back to Last.fm
+	artist, album := trackAlbumArtist(attrs), attrs["Album"]
+	if !lastFm.HasAPIKey() || artist == "" || album == "" {
+		return nil, nil
+	}
+	imageURL, err := lastFm.AlbumImageURL(artist, album)
+	if err != nil {
+		var lfErr *LastFmError
+		if errors.As(err, &lfErr) && lfErr.Code == lastFmErrorNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if imageURL == "" {
+		return nil, nil
+	}
+	return fetchImage(imageURL)
+}
+
+// AlbumArtLoader runs album art fetches in the background, limiting the number of concurrent fetches and merging
+// requests for the same item
+type AlbumArtLoader struct {
+	sem     chan struct{}                  // Semaphore limiting the number of concurrent fetches
+	pending map[string][]func(data []byte) // Callbacks of the fetches in progress, keyed by item key
+	mutex   sync.Mutex
+}
+
+// NewAlbumArtLoader creates and returns a new AlbumArtLoader instance running at most concurrency fetches at a time
+func NewAlbumArtLoader(concurrency int) *AlbumArtLoader {
+	return &AlbumArtLoader{
+		sem:     make(chan struct{}, concurrency),
+		pending: make(map[string][]func(data []byte)),
+	}
+}
+
+// Load runs fetch in a background goroutine and passes the fetched image, nil if there's none or the fetch failed, to
+// onLoaded. If a fetch for the same key is already in progress, onLoaded gets its result instead. onLoaded is called
+// in the background goroutine as well
+func (l *AlbumArtLoader) Load(key string, fetch func() ([]byte, error), onLoaded func(data []byte)) {
+	// Join a fetch in progress, if any
+	l.mutex.Lock()
+	callbacks, ok := l.pending[key]
+	l.pending[key] = append(callbacks, onLoaded)
+	l.mutex.Unlock()
+	if ok {
+		return
+	}
+
+	go func() {
+		// Wait for a free slot and fetch the image
+		l.sem <- struct{}{}
+		data, err := fetch()
+		<-l.sem
+		if err != nil {
+			log.Debugf("Failed to fetch album art for %s: %v", key, err)
+			data = nil
+		}
+
+		// Notify everyone waiting
+		l.mutex.Lock()
+		callbacks := l.pending[key]
+		delete(l.pending, key)
+		l.mutex.Unlock()
+		for _, f := range callbacks {
+			f(data)
+		}
+	}()
+}
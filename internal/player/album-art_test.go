@@ -0,0 +1,367 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"errors"
+	"github.com/fhs/gompd/v2/mpd"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFindAlbumArtFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-album-art")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"01 - Intro.flac", "Folder.JPG", "front.png"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "cover.jpg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		patterns []string
+		want     string
+	}{
+		{"no patterns", dir, nil, ""},
+		{"no match", dir, []string{"cover.*", "album.jpg"}, ""},
+		{"directories skipped", dir, []string{"cover.jpg"}, ""},
+		{"case ignored", dir, []string{"folder.jpg"}, "Folder.JPG"},
+		{"wildcard", dir, []string{"front.*"}, "front.png"},
+		{"pattern order", dir, []string{"cover.jpg", "front.png", "folder.jpg"}, "front.png"},
+		{"missing directory", filepath.Join(dir, "missing"), []string{"*"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want != "" {
+				want = filepath.Join(dir, want)
+			}
+			if got := FindAlbumArtFile(tt.dir, tt.patterns); got != want {
+				t.Errorf("FindAlbumArtFile() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAlbumArtCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-covers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &AlbumArtCache{dir: dir}
+
+	if data, missing := c.Get("album"); data != nil || missing {
+		t.Errorf("Get() = %q, %v on an empty cache, want nil, false", data, missing)
+	}
+
+	// Albums without artwork are marked
+	if err := c.Put("album", nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if data, missing := c.Get("album"); data != nil || !missing {
+		t.Errorf("Get() = %q, %v, want nil, true", data, missing)
+	}
+
+	// The marker expires
+	old := time.Now().Add(-albumArtRetryPeriod - time.Hour)
+	if err := os.Chtimes(c.baseName("album")+albumArtMissingFileSuffix, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if data, missing := c.Get("album"); data != nil || missing {
+		t.Errorf("Get() = %q, %v for an expired marker, want nil, false", data, missing)
+	}
+
+	// Storing the artwork replaces the marker
+	if err := c.Put("album", nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := c.Put("album", []byte("image")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if data, missing := c.Get("album"); string(data) != "image" || missing {
+		t.Errorf("Get() = %q, %v, want \"image\", false", data, missing)
+	}
+
+	// Only images have a file
+	if file := c.File("album"); file != c.baseName("album")+albumArtImageFileSuffix {
+		t.Errorf("File() = %q, want the cached image", file)
+	}
+	if err := c.Put("other", nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if file := c.File("other"); file != "" {
+		t.Errorf("File() = %q for a marked album, want \"\"", file)
+	}
+}
+
+func TestAlbumArtCacheTrim(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-covers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &AlbumArtCache{dir: dir}
+
+	// An empty (nonexistent) cache has zero size
+	if size, err := c.Size(); err != nil || size != 0 {
+		t.Errorf("Size() = %d, %v, want 0, nil", size, err)
+	}
+
+	// Store three images of 10 bytes each, used in turn over time
+	for i, key := range []string{"a", "b", "c"} {
+		if err := c.Put(key, []byte("0123456789")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		used := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(c.baseName(key)+albumArtImageFileSuffix, used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Put("none", nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if size, err := c.Size(); err != nil || size != 30 {
+		t.Errorf("Size() = %d, %v, want 30, nil", size, err)
+	}
+
+	// Using an image makes it the most recently used one
+	if data, _ := c.Get("a"); string(data) != "0123456789" {
+		t.Fatalf("Get() = %q", data)
+	}
+
+	// Trimming evicts the least recently used images only
+	if err := c.Trim(25); err != nil {
+		t.Fatalf("Trim() error = %v", err)
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if data, _ := c.Get(key); (data != nil) != want {
+			t.Errorf("Get(%q) = %q after Trim(), want present = %v", key, data, want)
+		}
+	}
+	if _, missing := c.Get("none"); !missing {
+		t.Error("Trim() removed a fresh marker")
+	}
+
+	// Clearing removes everything
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if size, err := c.Size(); err != nil || size != 0 {
+		t.Errorf("Size() = %d, %v after Clear(), want 0, nil", size, err)
+	}
+	if _, missing := c.Get("none"); missing {
+		t.Error("Clear() kept a marker")
+	}
+}
+
+func TestAlbumArtKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs mpd.Attrs
+		want  string
+	}{
+		{"no album", mpd.Attrs{"Artist": "Queen"}, ""},
+		{"no artist", mpd.Attrs{"Album": "Jazz"}, ""},
+		{"artist", mpd.Attrs{"Artist": "Queen", "Album": "Jazz"}, "queen\njazz"},
+		{"album artist", mpd.Attrs{"Artist": "Freddie Mercury", "AlbumArtist": "Queen", "Album": " Jazz "}, "queen\njazz"},
+		{"MBID", mpd.Attrs{"Artist": "Queen", "Album": "Jazz", "MUSICBRAINZ_ALBUMID": "2a3d5c80-3e2b-4c1b-8b4c-5f6e7d8c9b0a"}, "mbid:2a3d5c80-3e2b-4c1b-8b4c-5f6e7d8c9b0a"},
+		{"invalid MBID", mpd.Attrs{"Artist": "Queen", "Album": "Jazz", "MUSICBRAINZ_ALBUMID": "nope"}, "queen\njazz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AlbumArtKey(tt.attrs); got != tt.want {
+				t.Errorf("AlbumArtKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchOnlineAlbumArt(t *testing.T) {
+	const mbidFound, mbidMissing = "11111111-2222-3333-4444-555555555555", "66666666-7777-8888-9999-000000000000"
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/caa/release/" + mbidFound + "/front-500":
+			_, _ = w.Write([]byte("caa image"))
+		case "/lastfm.png":
+			_, _ = w.Write([]byte("lastfm image"))
+		case "/lastfm":
+			q := r.URL.Query()
+			switch {
+			case q.Get("method") != "album.getInfo":
+				http.Error(w, "bad method", http.StatusBadRequest)
+			case q.Get("album") == "Jazz":
+				_, _ = w.Write([]byte(`{"album":{"image":[{"#text":"` + server.URL + `/small.png","size":"small"},` +
+					`{"#text":"` + server.URL + `/lastfm.png","size":"extralarge"},{"#text":"","size":"mega"}]}}`))
+			case q.Get("album") == "Bare":
+				_, _ = w.Write([]byte(`{"album":{"image":[{"#text":"","size":"small"}]}}`))
+			default:
+				_, _ = w.Write([]byte(`{"error":6,"message":"Album not found"}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	lastFm := &LastFm{baseURL: server.URL + "/lastfm", apiKey: "key"}
+
+	tests := []struct {
+		name    string
+		lastFm  *LastFm
+		attrs   mpd.Attrs
+		want    string
+		wantErr bool
+	}{
+		{"Cover Art Archive", lastFm, mpd.Attrs{"Artist": "Queen", "Album": "Jazz", "MUSICBRAINZ_ALBUMID": mbidFound}, "caa image", false},
+		{"Last.fm fallback", lastFm, mpd.Attrs{"Artist": "Queen", "Album": "Jazz", "MUSICBRAINZ_ALBUMID": mbidMissing}, "lastfm image", false},
+		{"Last.fm only", lastFm, mpd.Attrs{"Artist": "Queen", "Album": "Jazz"}, "lastfm image", false},
+		{"no Last.fm key", &LastFm{}, mpd.Attrs{"Artist": "Queen", "Album": "Jazz"}, "", false},
+		{"no image", lastFm, mpd.Attrs{"Artist": "Queen", "Album": "Bare"}, "", false},
+		{"unknown album", lastFm, mpd.Attrs{"Artist": "Queen", "Album": "Nothing"}, "", false},
+		{"no album", lastFm, mpd.Attrs{"Artist": "Queen"}, "", false},
+		{"Last.fm failure", &LastFm{baseURL: server.URL + "/missing", apiKey: "key"}, mpd.Attrs{"Artist": "Queen", "Album": "Jazz"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FetchOnlineAlbumArt(tt.lastFm, server.URL+"/caa", tt.attrs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchOnlineAlbumArt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("FetchOnlineAlbumArt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchHTTPAlbumArt(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.EscapedPath())
+		switch r.URL.Path {
+		case "/music/Artist/Album #1/folder.jpg", "/music/cover.png":
+			_, _ = w.Write([]byte("image"))
+		case "/music/Broken/cover.jpg":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		uri       string
+		want      string
+		wantErr   bool
+		wantPaths []string
+	}{
+		{"found", "Artist/Album #1/01.flac", "image", false, []string{"/music/Artist/Album%20%231/cover.jpg", "/music/Artist/Album%20%231/cover.png", "/music/Artist/Album%20%231/folder.jpg"}},
+		{"top level", "01.flac", "image", false, []string{"/music/cover.jpg", "/music/cover.png"}},
+		{"not found", "Other/01.flac", "", false, []string{"/music/Other/cover.jpg", "/music/Other/cover.png", "/music/Other/folder.jpg"}},
+		{"error", "Broken/01.flac", "", true, []string{"/music/Broken/cover.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			got, err := FetchHTTPAlbumArt(srv.URL+"/music/", tt.uri, []string{"cover.jpg", "cover.*", "cover.png", "folder.jpg"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchHTTPAlbumArt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("FetchHTTPAlbumArt() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(requested, tt.wantPaths) {
+				t.Errorf("FetchHTTPAlbumArt() requested %v, want %v", requested, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestAlbumArtLoader(t *testing.T) {
+	l := NewAlbumArtLoader(2)
+	release := make(chan struct{})
+	var fetches, running, maxRunning int32
+
+	// fetch blocks until released, keeping track of the number of concurrent fetches
+	fetch := func(data string) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			atomic.AddInt32(&fetches, 1)
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+			if data == "" {
+				return nil, errors.New("failed")
+			}
+			return []byte(data), nil
+		}
+	}
+
+	// Request a and b twice each, and the failing c once
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	got := make(map[string][]string)
+	load := func(key, data string) {
+		wg.Add(1)
+		l.Load(key, fetch(data), func(d []byte) {
+			mutex.Lock()
+			got[key] = append(got[key], string(d))
+			mutex.Unlock()
+			wg.Done()
+		})
+	}
+	load("a", "image a")
+	load("a", "image a")
+	load("b", "image b")
+	load("b", "image b")
+	load("c", "")
+	close(release)
+	wg.Wait()
+
+	if fetches != 3 {
+		t.Errorf("fetches = %d, want 3", fetches)
+	}
+	if maxRunning > 2 {
+		t.Errorf("max concurrent fetches = %d, want at most 2", maxRunning)
+	}
+	want := map[string][]string{"a": {"image a", "image a"}, "b": {"image b", "image b"}, "c": {""}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+)
+
+const (
+	appControlPath  = "/com/yktoo/ymuse/Control" // Path of the control object
+	appControlIface = "com.yktoo.ymuse.Control"  // Control interface
+)
+
+// appControlPages lists the names of the main window's pages that can be switched to
+var appControlPages = []string{"queue", "library", "streams", "podcasts"}
+
+// AppControl exposes Ymuse-specific operations not covered by MPRIS on the D-Bus session bus, for scripting purposes,
+// under the bus name of the application suffixed with ".Control"
+type AppControl struct {
+	connector      *Connector          // Connector to query MPD through
+	conn           *dbus.Conn          // Session bus connection
+	onAction       func(action string) // Callback for activating an application action
+	onLoadPlaylist func(name string)   // Callback for replacing the queue with a stored playlist and playing it
+}
+
+// appControl implements the com.yktoo.ymuse.Control interface
+type appControl struct {
+	c *AppControl
+}
+
+// NewAppControl connects to the session bus, exports the control object and claims the bus name. onAction and
+// onLoadPlaylist are invoked on the GTK thread with the name of the application action to activate and the name of
+// the playlist to play, respectively
+func NewAppControl(connector *Connector, onAction func(action string), onLoadPlaylist func(name string)) (*AppControl, error) {
+	conn, err := connectBus(dbus.SessionBusPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &AppControl{connector: connector, conn: conn, onAction: onAction, onLoadPlaylist: onLoadPlaylist}
+	if err := c.export(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// Claim the bus name once everything is in place
+	busName := appControlBusName()
+	reply, err := conn.RequestName(busName, dbus.NameFlagDoNotQueue)
+	if err == nil && reply != dbus.RequestNameReplyPrimaryOwner {
+		err = fmt.Errorf("bus name %s is already taken", busName)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	log.Debugf("Registered on the session bus as %s", busName)
+	return c, nil
+}
+
+// Close releases the bus name and disconnects from the session bus
+func (c *AppControl) Close() {
+	errCheck(c.conn.Close(), "Failed to close session bus connection")
+}
+
+// export exports the control object along with its introspection data
+func (c *AppControl) export() error {
+	ctl := &appControl{c}
+	if err := c.conn.Export(ctl, appControlPath, appControlIface); err != nil {
+		return err
+	}
+	node := &introspect.Node{
+		Name: appControlPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: appControlIface, Methods: introspect.Methods(ctl)},
+		},
+	}
+	return c.conn.Export(introspect.NewIntrospectable(node), appControlPath, "org.freedesktop.DBus.Introspectable")
+}
+
+// appControlBusName returns the well-known bus name of the control service
+func appControlBusName() string {
+	return config.AppMetadata.ID + ".Control"
+}
+
+// Raise shows the main window to the user, raising and focusing it
+func (a *appControl) Raise() *dbus.Error {
+	util.WhenIdle("AppControl.Raise()", a.c.onAction, "show")
+	return nil
+}
+
+// ToggleWindow hides the main window if it's visible and focused, and presents it otherwise
+func (a *appControl) ToggleWindow() *dbus.Error {
+	util.WhenIdle("AppControl.ToggleWindow()", a.c.onAction, "toggle")
+	return nil
+}
+
+// ShowPage switches the main window to the page with the given name: queue, library, streams or podcasts
+func (a *appControl) ShowPage(name string) *dbus.Error {
+	for _, p := range appControlPages {
+		if p == name {
+			util.WhenIdle("AppControl.ShowPage()", a.c.onAction, "page."+name)
+			return nil
+		}
+	}
+	return dbus.MakeFailedError(fmt.Errorf("unknown page %s", name))
+}
+
+// ListPlaylists returns the names of the playlists stored in MPD
+func (a *appControl) ListPlaylists() ([]string, *dbus.Error) {
+	if connected, _ := a.c.connector.ConnectStatus(); !connected {
+		return nil, dbus.MakeFailedError(errors.New("not connected to MPD"))
+	}
+	return a.c.connector.GetPlaylists(), nil
+}
+
+// LoadPlaylist replaces the queue with the stored playlist with the given name and starts playing it
+func (a *appControl) LoadPlaylist(name string) *dbus.Error {
+	playlists, err := a.ListPlaylists()
+	if err != nil {
+		return err
+	}
+	for _, p := range playlists {
+		if p == name {
+			util.WhenIdle("AppControl.LoadPlaylist()", a.c.onLoadPlaylist, name)
+			return nil
+		}
+	}
+	return dbus.MakeFailedError(fmt.Errorf("unknown playlist %s", name))
+}
+
+// Connect connects to MPD using the configured settings
+func (a *appControl) Connect() *dbus.Error {
+	util.WhenIdle("AppControl.Connect()", a.c.onAction, "mpd.connect")
+	return nil
+}
+
+// Disconnect disconnects from MPD
+func (a *appControl) Disconnect() *dbus.Error {
+	util.WhenIdle("AppControl.Disconnect()", a.c.onAction, "mpd.disconnect")
+	return nil
+}
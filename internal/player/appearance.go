@@ -0,0 +1,97 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"strings"
+)
+
+// preferDarkProperty is the GTK setting telling whether the dark variant of the theme is preferred
+const preferDarkProperty = "gtk-application-prefer-dark-theme"
+
+// Appearance applies the theme variant preference and the user style sheet to the default screen
+type Appearance struct {
+	systemDark  bool             // Dark variant preference of the desktop, as it was before any changes
+	settings    *gtk.Settings    // Default GTK settings
+	cssProvider *gtk.CssProvider // Provider of the user style sheet, nil if none is loaded
+}
+
+// NewAppearance creates and returns a new Appearance instance, remembering the desktop's theme variant preference
+func NewAppearance() (*Appearance, error) {
+	settings, err := gtk.SettingsGetDefault()
+	if err != nil {
+		return nil, err
+	}
+	a := &Appearance{settings: settings}
+	if v, err := settings.GetProperty(preferDarkProperty); err == nil {
+		a.systemDark, _ = v.(bool)
+	}
+	return a, nil
+}
+
+// Apply sets the theme variant preference and (re)loads the user style sheet from the given file, or removes it if
+// the file name is empty
+func (a *Appearance) Apply(theme, cssFile string) error {
+	if err := a.settings.SetProperty(preferDarkProperty, preferDarkTheme(theme, a.systemDark)); err != nil {
+		return err
+	}
+	return a.loadCSS(strings.TrimSpace(cssFile))
+}
+
+// loadCSS replaces the currently loaded user style sheet with the one in the given file, if any
+func (a *Appearance) loadCSS(file string) error {
+	screen, err := gdk.ScreenGetDefault()
+	if err != nil {
+		return err
+	}
+
+	// Drop the current style sheet
+	if a.cssProvider != nil {
+		gtk.RemoveProviderForScreen(screen, a.cssProvider)
+		a.cssProvider = nil
+	}
+	if file == "" {
+		return nil
+	}
+
+	// Load the new one, with a priority allowing it to override both the theme and the application's own styles
+	provider, err := gtk.CssProviderNew()
+	if err != nil {
+		return err
+	}
+	if err := provider.LoadFromPath(file); err != nil {
+		return err
+	}
+	gtk.AddProviderForScreen(screen, provider, uint(gtk.STYLE_PROVIDER_PRIORITY_USER))
+	a.cssProvider = provider
+	log.Debugf("Loaded user style sheet from %s", file)
+	return nil
+}
+
+// preferDarkTheme returns whether the dark theme variant is to be preferred given the selected theme (one of the
+// config.Theme* constants) and the desktop's preference
+func preferDarkTheme(theme string, systemDark bool) bool {
+	switch theme {
+	case config.ThemeDark:
+		return true
+	case config.ThemeLight:
+		return false
+	}
+	return systemDark
+}
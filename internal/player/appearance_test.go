@@ -0,0 +1,45 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/yktoo/ymuse/internal/config"
+	"testing"
+)
+
+func TestPreferDarkTheme(t *testing.T) {
+	tests := []struct {
+		theme      string
+		systemDark bool
+		want       bool
+	}{
+		{config.ThemeSystem, false, false},
+		{config.ThemeSystem, true, true},
+		{config.ThemeDark, false, true},
+		{config.ThemeDark, true, true},
+		{config.ThemeLight, false, false},
+		{config.ThemeLight, true, false},
+		{"", true, true},
+		{"bogus", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.theme, func(t *testing.T) {
+			if got := preferDarkTheme(tt.theme, tt.systemDark); got != tt.want {
+				t.Errorf("preferDarkTheme(%q, %v) = %v, want %v", tt.theme, tt.systemDark, got, tt.want)
+			}
+		})
+	}
+}
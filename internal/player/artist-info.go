@@ -0,0 +1,218 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/util"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	wikipediaURL          = "https://en.wikipedia.org/api/rest_v1" // Base URL of the Wikipedia REST API
+	artistInfoMaxAge      = 30 * 24 * time.Hour                    // Period cached artist info is considered fresh
+	imageMaxSize          = 5 << 20                                // Maximum size of a downloaded image in bytes
+	artistInfoFileSuffix  = ".json"                                // Suffix of cached artist info files
+	artistImageFileSuffix = ".img"                                 // Suffix of cached artist image files
+)
+
+// wikipediaArtistSuffixes lists the suffixes tried in turn to find an artist's Wikipedia page when the plain name leads
+// to no or an ambiguous page
+var wikipediaArtistSuffixes = []string{"", " (band)", " (musician)", " (singer)", " (composer)"}
+
+// ArtistInfo represents information about an artist
+type ArtistInfo struct {
+	Name     string    // Artist name as requested
+	Bio      string    // Short biography, plain text
+	Source   string    // Name of the source of the biography, such as "Wikipedia"
+	PageURL  string    // URL of the page with the full biography
+	ImageURL string    // URL of the artist's image, empty if none
+	Fetched  time.Time // Moment the information has been fetched
+}
+
+// ArtistInfoCache stores artist information and images in files, so that they aren't fetched over and over
+type ArtistInfoCache struct {
+	dir string // Directory holding the cached files
+}
+
+// NewArtistInfoCache creates and returns a new ArtistInfoCache instance in the user's cache directory
+func NewArtistInfoCache() *ArtistInfoCache {
+	return &ArtistInfoCache{dir: path.Join(glib.GetUserCacheDir(), "ymuse", "artists")}
+}
+
+// Get returns the cached information and image (nil if none) for the artist with the given name. ok is false if there's
+// nothing cached or the cached information is stale
+func (c *ArtistInfoCache) Get(name string) (info *ArtistInfo, image []byte, ok bool) {
+	base := c.baseName(name)
+	data, err := ioutil.ReadFile(base + artistInfoFileSuffix)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errCheck(err, "Failed to read artist info")
+		}
+		return nil, nil, false
+	}
+	info = &ArtistInfo{}
+	if errCheck(json.Unmarshal(data, info), "json.Unmarshal() failed") || time.Since(info.Fetched) > artistInfoMaxAge {
+		return nil, nil, false
+	}
+	if info.ImageURL != "" {
+		if image, err = ioutil.ReadFile(base + artistImageFileSuffix); err != nil {
+			return nil, nil, false
+		}
+	}
+	return info, image, true
+}
+
+// Put stores the given information and image (can be nil) in the cache
+func (c *ArtistInfoCache) Put(info *ArtistInfo, image []byte) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	// Write the image first, so that the info never refers to a missing image
+	base := c.baseName(info.Name)
+	if image != nil {
+		if err := ioutil.WriteFile(base+artistImageFileSuffix, image, 0644); err != nil {
+			return err
+		}
+	} else if err := os.Remove(base + artistImageFileSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(base+artistInfoFileSuffix, data, 0644)
+}
+
+// baseName returns the full path of the cached files for the given artist, without a suffix
+func (c *ArtistInfoCache) baseName(name string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(strings.TrimSpace(name))))
+	return path.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// FetchArtistInfo retrieves information about the artist with the given name. The biography is taken from Last.fm, if
+// the client has an API key, and from Wikipedia (at the given API base URL) otherwise; the image always comes from
+// Wikipedia. Returns the information and the image, nil if there's none
+func FetchArtistInfo(lastFm *LastFm, wikipediaBaseURL, name string) (*ArtistInfo, []byte, error) {
+	info := &ArtistInfo{Name: name, Fetched: time.Now()}
+
+	// Try Last.fm first: it's more likely to find the right artist
+	var lastFmErr error
+	if lastFm.HasAPIKey() {
+		info.Bio, info.PageURL, lastFmErr = lastFm.ArtistBio(name)
+		if info.Bio != "" {
+			info.Source = "Last.fm"
+		}
+	}
+
+	// Look the artist up on Wikipedia
+	summary, err := fetchWikipediaArtist(wikipediaBaseURL, name)
+	if err != nil {
+		// Only fail if there's nothing at all
+		if info.Bio == "" {
+			if lastFmErr != nil {
+				err = lastFmErr
+			}
+			return nil, nil, err
+		}
+		log.Debugf("Failed to fetch Wikipedia summary: %v", err)
+	}
+	if summary == nil && info.Bio == "" && lastFmErr != nil {
+		// Nothing found: report a Last.fm failure, unless Last.fm simply doesn't know the artist
+		var lfErr *LastFmError
+		if !errors.As(lastFmErr, &lfErr) || lfErr.Code != lastFmErrorNotFound {
+			return nil, nil, lastFmErr
+		}
+	}
+	if summary != nil {
+		if info.Bio == "" && summary.Extract != "" {
+			info.Bio, info.Source, info.PageURL = summary.Extract, "Wikipedia", summary.ContentURLs.Desktop.Page
+		}
+		if summary.Thumbnail != nil {
+			info.ImageURL = summary.Thumbnail.Source
+		}
+	}
+
+	// Download the image, proceeding without one on failure
+	var image []byte
+	if info.ImageURL != "" {
+		if image, err = fetchImage(info.ImageURL); err != nil || image == nil {
+			log.Debugf("Failed to fetch artist image: %v", err)
+			info.ImageURL = ""
+		}
+	}
+	return info, image, nil
+}
+
+// wikipediaSummary represents a page summary returned by the Wikipedia REST API
+type wikipediaSummary struct {
+	Type      string `json:"type"`    // Page type: "standard", "disambiguation" etc.
+	Extract   string `json:"extract"` // Plain-text extract of the page
+	Thumbnail *struct {
+		Source string `json:"source"` // Image URL
+	} `json:"thumbnail"`
+	ContentURLs struct {
+		Desktop struct {
+			Page string `json:"page"` // Page URL
+		} `json:"desktop"`
+	} `json:"content_urls"`
+}
+
+// fetchWikipediaArtist returns the summary of the Wikipedia page about the given artist, or nil if there's no such page
+func fetchWikipediaArtist(baseURL, name string) (*wikipediaSummary, error) {
+	for _, suffix := range wikipediaArtistSuffixes {
+		title := strings.ReplaceAll(name+suffix, " ", "_")
+		summary := &wikipediaSummary{}
+		if err := util.HTTPGetJSON(baseURL+"/page/summary/"+url.PathEscape(title), summary); util.IsHTTPNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		} else if summary.Type != "disambiguation" {
+			return summary, nil
+		}
+	}
+	return nil, nil
+}
+
+// fetchImage downloads the image at the given URL. Returns nil if the URL is not found
+func fetchImage(uri string) ([]byte, error) {
+	resp, err := util.HTTPGet(uri)
+	if util.IsHTTPNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, imageMaxSize+1))
+	if err == nil && len(data) > imageMaxSize {
+		err = fmt.Errorf("image exceeds %d bytes", imageMaxSize)
+	}
+	return data, err
+}
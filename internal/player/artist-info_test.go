@@ -0,0 +1,116 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArtistInfoCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-artists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &ArtistInfoCache{dir: dir}
+
+	if _, _, ok := c.Get("Queen"); ok {
+		t.Error("Get() succeeded on an empty cache")
+	}
+
+	// Entries are found regardless of case
+	info := &ArtistInfo{Name: "Queen", Bio: "British rock band", ImageURL: "https://example.com/queen.jpg", Fetched: time.Now().UTC().Round(0)}
+	if err := c.Put(info, []byte("image")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, image, ok := c.Get(" queen"); !ok || !reflect.DeepEqual(got, info) || string(image) != "image" {
+		t.Errorf("Get() = %+v, %q, %v, want %+v, \"image\", true", got, image, ok, info)
+	}
+
+	// Replacing the entry drops the image
+	info.ImageURL = ""
+	if err := c.Put(info, nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, image, ok := c.Get("Queen"); !ok || image != nil {
+		t.Errorf("Get() = %q, %v, want nil, true", image, ok)
+	}
+
+	// Stale entries are ignored
+	info.Fetched = time.Now().Add(-artistInfoMaxAge - time.Hour)
+	if err := c.Put(info, nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, _, ok := c.Get("Queen"); ok {
+		t.Error("Get() succeeded for a stale entry")
+	}
+}
+
+func TestFetchArtistInfo(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page/summary/Queen":
+			_, _ = w.Write([]byte(`{"type":"disambiguation","extract":"Queen may refer to:"}`))
+		case "/page/summary/Queen_(band)":
+			_, _ = w.Write([]byte(`{"type":"standard","extract":"Queen are a British rock band.",
+				"thumbnail":{"source":"` + server.URL + `/queen.jpg"},"content_urls":{"desktop":{"page":"https://en.wikipedia.org/wiki/Queen_(band)"}}}`))
+		case "/queen.jpg":
+			_, _ = w.Write([]byte("image"))
+		case "/lastfm":
+			if r.URL.Query().Get("method") == "artist.getInfo" && r.URL.Query().Get("artist") == "Queen" {
+				_, _ = w.Write([]byte(`{"artist":{"name":"Queen","url":"https://www.last.fm/music/Queen","bio":{"summary":"Queen &amp; friends. <a href=\"https://www.last.fm/music/Queen\">Read more on Last.fm</a>"}}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"error":6,"message":"The artist you supplied could not be found"}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	lastFm := &LastFm{baseURL: server.URL + "/lastfm", apiKey: "key"}
+
+	tests := []struct {
+		name      string
+		lastFm    *LastFm
+		artist    string
+		wantBio   string
+		wantPage  string
+		wantImage string
+		wantErr   bool
+	}{
+		{"Wikipedia only", &LastFm{}, "Queen", "Queen are a British rock band.", "https://en.wikipedia.org/wiki/Queen_(band)", "image", false},
+		{"Last.fm and Wikipedia", lastFm, "Queen", "Queen & friends.", "https://www.last.fm/music/Queen", "image", false},
+		{"unknown", lastFm, "Nobody", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, image, err := FetchArtistInfo(tt.lastFm, server.URL, tt.artist)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchArtistInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if info.Bio != tt.wantBio || info.PageURL != tt.wantPage || string(image) != tt.wantImage {
+				t.Errorf("FetchArtistInfo() = %+v, %q, want bio %q, page %q, image %q", info, image, tt.wantBio, tt.wantPage, tt.wantImage)
+			}
+		})
+	}
+}
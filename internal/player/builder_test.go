@@ -78,9 +78,34 @@ func TestBuilder_BindWidgets(t *testing.T) {
 			content: generated.GetPrefsGlade(),
 			target:  &PrefsDialog{},
 		},
+		{
+			name:    "happy flow for SongInfo",
+			content: generated.GetSongInfoGlade(),
+			target:  &SongInfo{},
+		},
+		{
+			name:    "happy flow for ListeningStats",
+			content: generated.GetListeningStatsGlade(),
+			target:  &ListeningStats{},
+		},
+		{
+			name:    "happy flow for HeardOnRadio",
+			content: generated.GetHeardOnRadioGlade(),
+			target:  &HeardOnRadio{},
+		},
+		{
+			name:    "happy flow for RadioDirectory",
+			content: generated.GetRadioDirectoryGlade(),
+			target:  &RadioDirectory{},
+		},
+		{
+			name:    "happy flow for MiniPlayer",
+			content: generated.GetMiniPlayerGlade(),
+			target:  &MiniPlayer{},
+		},
 		{
 			name:    "happy flow for Shortcuts",
-			content: generated.GetShortcutsGlade(),
+			content: shortcutsWindowXML(append([]shortcut{actionShortcut("quit", []string{"<Ctrl>Q"})}, keyShortcuts...)),
 			target:  &struct{ ShortcutsWindow *gtk.ShortcutsWindow }{},
 		},
 	}
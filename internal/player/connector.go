@@ -134,6 +134,38 @@ func (c *Connector) GetPlaylists() []string {
 	return names
 }
 
+// GetAlbumArt fetches the artwork for the track with the given URI from MPD: the picture embedded in the track, if any,
+// otherwise the cover file in the track's directory. This works with remote servers as well, since the image is
+// transferred over the MPD protocol. Returns nil if there's no artwork
+func (c *Connector) GetAlbumArt(uri string) ([]byte, error) {
+	var data []byte
+	var err error
+	c.IfConnected(func(client *mpd.Client) {
+		// Try the embedded picture first. MPD before 0.22 doesn't support readpicture, so any error here is ignored
+		data, err = readMpdBinary(func(offset int) ([]byte, int, error) {
+			return client.Command("readpicture %s %d", uri, offset).Binary()
+		})
+		if err != nil {
+			log.Debugf("readpicture failed for %s: %v", uri, err)
+			data, err = nil, nil
+		}
+		if len(data) > 0 {
+			return
+		}
+
+		// Fall back to the cover file
+		data, err = readMpdBinary(func(offset int) ([]byte, int, error) {
+			return client.Command("albumart %s %d", uri, offset).Binary()
+		})
+	})
+
+	// Missing artwork isn't an error
+	if mpdErr, ok := err.(mpd.Error); ok && mpdErr.Code == mpd.ErrorNoExist {
+		return nil, nil
+	}
+	return data, err
+}
+
 // IfConnected runs MPD client code if there's a connection with MPD
 func (c *Connector) IfConnected(funcIfConnected func(client *mpd.Client)) {
 	c.mpdClientMutex.RLock()
@@ -363,3 +395,24 @@ func (c *Connector) watch() {
 		}
 	}
 }
+
+// readMpdBinary assembles a binary response MPD transfers in chunks. readChunk is called with the offset of each next
+// chunk and returns the chunk along with the total data size
+func readMpdBinary(readChunk func(offset int) ([]byte, int, error)) ([]byte, error) {
+	var data []byte
+	for {
+		chunk, size, err := readChunk(len(data))
+		if err != nil {
+			return nil, err
+		}
+
+		// Accumulate the data. An empty chunk before the end means the data is truncated
+		data = append(data, chunk...)
+		if len(data) >= size {
+			return data, nil
+		}
+		if len(chunk) == 0 {
+			return nil, errors.Errorf("binary data truncated at %d of %d bytes", len(data), size)
+		}
+	}
+}
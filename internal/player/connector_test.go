@@ -0,0 +1,87 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadMpdBinary(t *testing.T) {
+	// chunked serves the data in chunks of the given size, recording the requested offsets
+	chunked := func(data []byte, chunkSize int, offsets *[]int) func(offset int) ([]byte, int, error) {
+		return func(offset int) ([]byte, int, error) {
+			*offsets = append(*offsets, offset)
+			end := offset + chunkSize
+			if end > len(data) {
+				end = len(data)
+			}
+			return data[offset:end], len(data), nil
+		}
+	}
+	data := []byte("0123456789abcdefghij")
+
+	tests := []struct {
+		name        string
+		chunkSize   int
+		wantOffsets []int
+	}{
+		{"single chunk", 100, []int{0}},
+		{"exact chunks", 10, []int{0, 10}},
+		{"partial last chunk", 8, []int{0, 8, 16}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var offsets []int
+			got, err := readMpdBinary(chunked(data, tt.chunkSize, &offsets))
+			if err != nil {
+				t.Fatalf("readMpdBinary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Errorf("readMpdBinary() = %q, want %q", got, data)
+			}
+			if !reflect.DeepEqual(offsets, tt.wantOffsets) {
+				t.Errorf("readMpdBinary() offsets = %v, want %v", offsets, tt.wantOffsets)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		wantErr := errors.New("boom")
+		got, err := readMpdBinary(func(offset int) ([]byte, int, error) {
+			if offset > 0 {
+				return nil, 0, wantErr
+			}
+			return data[:5], len(data), nil
+		})
+		if err != wantErr || got != nil {
+			t.Errorf("readMpdBinary() = (%q, %v), want (nil, %v)", got, err, wantErr)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		got, err := readMpdBinary(func(offset int) ([]byte, int, error) {
+			if offset > 0 {
+				return nil, len(data), nil
+			}
+			return data[:5], len(data), nil
+		})
+		if err == nil || got != nil {
+			t.Errorf("readMpdBinary() = (%q, %v), want an error", got, err)
+		}
+	})
+}
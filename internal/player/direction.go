@@ -0,0 +1,35 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+// #cgo pkg-config: gtk+-3.0
+// #include <gtk/gtk.h>
+import "C"
+
+// isRTL returns whether the user interface is laid out right-to-left, which GTK determines from the current locale.
+// Boxes, grids, tree views and scales mirror themselves accordingly, so this is only needed where the direction is
+// hard-coded otherwise, such as in keyboard shortcuts
+func isRTL() bool {
+	return C.gtk_widget_get_default_direction() == C.GTK_TEXT_DIR_RTL
+}
+
+// textDirection returns the direction of the user interface in the form used by desktop protocols, "ltr" or "rtl"
+func textDirection() string {
+	if isRTL() {
+		return "rtl"
+	}
+	return "ltr"
+}
@@ -0,0 +1,82 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"sort"
+)
+
+// Favorites is a set of starred tracks, kept in a stored playlist
+type Favorites struct {
+	uris    []string        // URIs of the tracks in the playlist, in playlist order
+	starred map[string]bool // Set of starred URIs
+}
+
+// NewFavorites creates and returns a new Favorites instance given the contents of the favourites playlist
+func NewFavorites(attrs []mpd.Attrs) *Favorites {
+	f := &Favorites{starred: make(map[string]bool)}
+	for _, a := range attrs {
+		uri := a["file"]
+		f.uris = append(f.uris, uri)
+		f.starred[uri] = true
+	}
+	return f
+}
+
+// IsStarred returns whether the track with the given URI is starred. Safe to call on a nil instance
+func (f *Favorites) IsStarred(uri string) bool {
+	return f != nil && f.starred[uri]
+}
+
+// Toggle works out the changes needed to toggle the star of the given tracks: if they're all starred already, they're
+// to be unstarred, otherwise the missing ones are to be starred. Returns the URIs to append to the playlist and the
+// playlist positions to remove, in descending order so that they can be removed one after another
+func (f *Favorites) Toggle(uris []string) (add []string, removePos []int) {
+	// Check whether all tracks are starred
+	all := len(uris) > 0
+	for _, uri := range uris {
+		if !f.IsStarred(uri) {
+			all = false
+			break
+		}
+	}
+
+	// Unstar: remove every occurrence of the tracks from the playlist
+	if all {
+		unstar := make(map[string]bool, len(uris))
+		for _, uri := range uris {
+			unstar[uri] = true
+		}
+		for i, uri := range f.uris {
+			if unstar[uri] {
+				removePos = append(removePos, i)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(removePos)))
+		return
+	}
+
+	// Star: add the tracks that aren't in the playlist yet, once each
+	added := make(map[string]bool, len(uris))
+	for _, uri := range uris {
+		if uri != "" && !f.IsStarred(uri) && !added[uri] {
+			add = append(add, uri)
+			added[uri] = true
+		}
+	}
+	return
+}
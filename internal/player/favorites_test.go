@@ -0,0 +1,60 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"reflect"
+	"testing"
+)
+
+func TestFavorites_Toggle(t *testing.T) {
+	f := NewFavorites([]mpd.Attrs{{"file": "a.flac"}, {"file": "b.flac"}, {"file": "a.flac"}, {"file": "c.flac"}})
+	tests := []struct {
+		name          string
+		uris          []string
+		wantAdd       []string
+		wantRemovePos []int
+	}{
+		{"nothing", nil, nil, nil},
+		{"star one", []string{"d.flac"}, []string{"d.flac"}, nil},
+		{"star missing ones", []string{"a.flac", "d.flac", "e.flac", "d.flac"}, []string{"d.flac", "e.flac"}, nil},
+		{"unstar one", []string{"c.flac"}, nil, []int{3}},
+		{"unstar duplicates", []string{"a.flac", "b.flac"}, nil, []int{2, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAdd, gotRemovePos := f.Toggle(tt.uris)
+			if !reflect.DeepEqual(gotAdd, tt.wantAdd) {
+				t.Errorf("Toggle() add = %v, want %v", gotAdd, tt.wantAdd)
+			}
+			if !reflect.DeepEqual(gotRemovePos, tt.wantRemovePos) {
+				t.Errorf("Toggle() removePos = %v, want %v", gotRemovePos, tt.wantRemovePos)
+			}
+		})
+	}
+}
+
+func TestFavorites_IsStarred(t *testing.T) {
+	var nilFavs *Favorites
+	if nilFavs.IsStarred("a.flac") {
+		t.Errorf("IsStarred() on nil = true, want false")
+	}
+	f := NewFavorites([]mpd.Attrs{{"file": "a.flac"}})
+	if !f.IsStarred("a.flac") || f.IsStarred("b.flac") {
+		t.Errorf("IsStarred() gives wrong results")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/generated"
+	"github.com/yktoo/ymuse/internal/util"
+)
+
+// HeardOnRadio represents the dialog listing songs logged while listening to the radio
+type HeardOnRadio struct {
+	HeardOnRadioDialog      *gtk.MessageDialog
+	HeardOnRadioGrid        *gtk.Grid
+	HeardOnRadioClearButton *gtk.Button
+
+	radioLog *RadioLog // Log of the songs heard on the radio
+	grid     *propertyGrid
+}
+
+// HeardOnRadioDialog creates, shows and disposes of a "heard on radio" dialog instance
+func HeardOnRadioDialog(parent gtk.IWindow, radioLog *RadioLog) {
+	// Load the dialog layout and map the widgets
+	d := &HeardOnRadio{radioLog: radioLog}
+	builder, err := NewBuilder(generated.GetHeardOnRadioGlade())
+	if err == nil {
+		err = builder.BindWidgets(d)
+	}
+
+	// Check for errors
+	if errCheck(err, "HeardOnRadioDialog(): failed to initialise dialog") {
+		util.ErrorDialog(parent, fmt.Sprint(glib.Local("Failed to load UI widgets"), err))
+		return
+	}
+	defer d.HeardOnRadioDialog.Destroy()
+	d.grid = &propertyGrid{grid: d.HeardOnRadioGrid}
+
+	// Map the handlers to callback functions
+	builder.ConnectSignals(map[string]interface{}{
+		"on_HeardOnRadioClearButton_clicked": d.onClear,
+	})
+
+	// Set up and show the dialog
+	d.update()
+	d.HeardOnRadioDialog.SetTransientFor(parent)
+	d.HeardOnRadioDialog.ShowAll()
+	d.HeardOnRadioDialog.Run()
+}
+
+// update repopulates the list of logged songs, most recent first, grouped by day
+func (d *HeardOnRadio) update() {
+	d.grid.clear()
+	entries := d.radioLog.Entries()
+	if len(entries) == 0 {
+		d.grid.addHeader(glib.Local("No songs remembered yet"))
+	}
+	day := ""
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		t := e.Time.Local()
+		if s := t.Format("2006-01-02"); s != day {
+			day = s
+			d.grid.addHeader(day)
+		}
+		d.grid.addProperty(t.Format("15:04"), fmt.Sprintf("%s — %s", e.Title, util.Default(e.URI, e.Station)))
+	}
+	d.HeardOnRadioClearButton.SetSensitive(len(entries) > 0)
+	d.HeardOnRadioGrid.ShowAll()
+}
+
+// onClear empties the log upon confirmation
+func (d *HeardOnRadio) onClear() {
+	if !util.ConfirmDialog(d.HeardOnRadioDialog, glib.Local("Clear list"), glib.Local("Are you sure you want to forget all remembered songs?")) {
+		return
+	}
+	if err := d.radioLog.Clear(); err != nil {
+		util.ErrorDialog(d.HeardOnRadioDialog, fmt.Sprint(glib.Local("Failed to clear the list"), err))
+	}
+	d.update()
+}
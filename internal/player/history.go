@@ -0,0 +1,246 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/util"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+const (
+	historyMinTrackLength = 30.0  // Tracks shorter than this number of seconds are never recorded
+	historyMinPlayed      = 240.0 // Tracks played for this number of seconds are always recorded
+	historyMaxPosJump     = 5.0   // Larger position changes between two heartbeats are considered seeks
+)
+
+// HistoryEntry represents a single track play
+type HistoryEntry struct {
+	Time   time.Time // Moment the track has been played
+	URI    string    // Track's URI
+	Artist string    // Track's artist
+	Album  string    // Track's album
+	Title  string    // Track's title
+	Played float64   // Number of seconds the track has been listened to
+}
+
+// HistoryCount represents a number of plays of an item (artist, album, track)
+type HistoryCount struct {
+	Name  string // Item name
+	Count int    // Number of plays
+}
+
+// HistoryStats represents listening statistics over a period of time
+type HistoryStats struct {
+	Plays      int            // Total number of plays
+	TotalTime  float64        // Total listening time in seconds
+	TopArtists []HistoryCount // Most played artists
+	TopAlbums  []HistoryCount // Most played albums
+	TopTracks  []HistoryCount // Most played tracks
+}
+
+// PlayHistory keeps track of the tracks played and stores them in a file
+type PlayHistory struct {
+	entries   []HistoryEntry // Recorded plays, oldest first
+	curTrack  mpd.Attrs      // Track currently being played
+	curPlayed float64        // Number of seconds the current track has been listened to
+	lastPos   float64        // Last known play position in the current track
+
+	onRecorded func(e *HistoryEntry) // Callback for a newly recorded play, can be nil
+}
+
+// NewPlayHistory creates a new PlayHistory instance and loads the previously recorded plays. onRecorded is called
+// whenever a play gets recorded
+func NewPlayHistory(onRecorded func(e *HistoryEntry)) *PlayHistory {
+	h := &PlayHistory{onRecorded: onRecorded}
+	h.load()
+	return h
+}
+
+// Entries returns all recorded plays, oldest first
+func (h *PlayHistory) Entries() []HistoryEntry {
+	return h.entries
+}
+
+// Stats returns listening statistics for the plays since the given moment, with at most limit items in each top list
+func (h *PlayHistory) Stats(since time.Time, limit int) *HistoryStats {
+	return computeHistoryStats(h.entries, since, limit)
+}
+
+// SetTrack notifies the history of the track currently being played (nil if none). When the track changes, the
+// previous one is recorded if it has been listened to long enough
+func (h *PlayHistory) SetTrack(attrs mpd.Attrs) {
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+
+	// Ignore if it's still the same track
+	if h.curTrack != nil && attrs != nil && attrs["Id"] == h.curTrack["Id"] && attrs["file"] == h.curTrack["file"] {
+		return
+	}
+
+	// Record the previous track, if any
+	h.commit()
+
+	// Start tracking the new one
+	h.curTrack = attrs
+	h.curPlayed = 0
+	h.lastPos = 0
+}
+
+// Progress notifies the history of the current play position, in seconds
+func (h *PlayHistory) Progress(pos float64) {
+	// Only count normal playback progress, not seeks
+	if delta := pos - h.lastPos; delta > 0 && delta <= historyMaxPosJump {
+		h.curPlayed += delta
+	}
+	h.lastPos = pos
+}
+
+// commit records the current track if it's been listened to long enough: for at least half of its length or for
+// historyMinPlayed seconds
+func (h *PlayHistory) commit() {
+	if h.curTrack == nil || util.IsStreamURI(h.curTrack["file"]) {
+		return
+	}
+	length := util.ParseFloatDef(h.curTrack["duration"], 0)
+	if length < historyMinTrackLength || h.curPlayed < length/2 && h.curPlayed < historyMinPlayed {
+		return
+	}
+
+	// Add a new entry
+	e := HistoryEntry{
+		Time:   time.Now(),
+		URI:    h.curTrack["file"],
+		Artist: h.curTrack["Artist"],
+		Album:  h.curTrack["Album"],
+		Title:  h.curTrack["Title"],
+		Played: h.curPlayed,
+	}
+	h.entries = append(h.entries, e)
+	h.append(&e)
+
+	// Notify the listener
+	if h.onRecorded != nil {
+		h.onRecorded(&e)
+	}
+}
+
+// getFile returns the full path of the history file
+func (h *PlayHistory) getFile() string {
+	return path.Join(glib.GetUserDataDir(), "ymuse", "history.jsonl")
+}
+
+// load reads the previously recorded plays from the history file
+func (h *PlayHistory) load() {
+	file, err := os.Open(h.getFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errCheck(err, "Couldn't open history file")
+		}
+		return
+	}
+	defer file.Close()
+
+	// Each line is a JSON-encoded entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if !errCheck(json.Unmarshal(scanner.Bytes(), &e), "json.Unmarshal() failed") {
+			h.entries = append(h.entries, e)
+		}
+	}
+	errCheck(scanner.Err(), "Failed to read history file")
+	log.Debugf("Loaded %d history entries", len(h.entries))
+}
+
+// append writes out the given entry to the end of the history file
+func (h *PlayHistory) append(e *HistoryEntry) {
+	data, err := json.Marshal(e)
+	if errCheck(err, "json.Marshal() failed") {
+		return
+	}
+
+	// Create the directory if it doesn't exist
+	fileName := h.getFile()
+	if errCheck(os.MkdirAll(path.Dir(fileName), 0755), "MkdirAll() failed") {
+		return
+	}
+
+	// Append the entry
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if errCheck(err, "Couldn't open history file") {
+		return
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	errCheck(err, "Failed to write history file")
+}
+
+// computeHistoryStats calculates listening statistics for the entries recorded since the given moment
+func computeHistoryStats(entries []HistoryEntry, since time.Time, limit int) *HistoryStats {
+	stats := &HistoryStats{}
+	artists, albums, tracks := map[string]int{}, map[string]int{}, map[string]int{}
+	for _, e := range entries {
+		if e.Time.Before(since) {
+			continue
+		}
+		stats.Plays++
+		stats.TotalTime += e.Played
+
+		// Count artists, albums and tracks
+		artist := util.Default(glib.Local("(unknown artist)"), e.Artist)
+		artists[artist]++
+		if e.Album != "" {
+			albums[fmt.Sprintf("%s — %s", e.Album, artist)]++
+		}
+		if e.Title != "" {
+			tracks[fmt.Sprintf("%s — %s", e.Title, artist)]++
+		} else {
+			tracks[e.URI]++
+		}
+	}
+	stats.TopArtists = topHistoryCounts(artists, limit)
+	stats.TopAlbums = topHistoryCounts(albums, limit)
+	stats.TopTracks = topHistoryCounts(tracks, limit)
+	return stats
+}
+
+// topHistoryCounts returns at most limit items with the highest counts from the given map, ordered by count descending
+// and then by name
+func topHistoryCounts(counts map[string]int, limit int) []HistoryCount {
+	result := make([]HistoryCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, HistoryCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if limit >= 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
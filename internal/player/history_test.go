@@ -0,0 +1,88 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_computeHistoryStats(t *testing.T) {
+	now := time.Now()
+	entries := []HistoryEntry{
+		{Time: now.AddDate(0, 0, -40), URI: "a/1.mp3", Artist: "A", Album: "X", Title: "One", Played: 100},
+		{Time: now.AddDate(0, 0, -10), URI: "b/1.mp3", Artist: "B", Album: "Y", Title: "Uno", Played: 200},
+		{Time: now.AddDate(0, 0, -5), URI: "a/1.mp3", Artist: "A", Album: "X", Title: "One", Played: 100},
+		{Time: now.AddDate(0, 0, -2), URI: "a/2.mp3", Artist: "A", Album: "X", Title: "Two", Played: 150},
+		{Time: now.AddDate(0, 0, -1), URI: "c/1.mp3", Played: 60},
+	}
+	tests := []struct {
+		name  string
+		since time.Time
+		limit int
+		want  *HistoryStats
+	}{
+		{
+			name:  "all time",
+			limit: -1,
+			want: &HistoryStats{
+				Plays:      5,
+				TotalTime:  610,
+				TopArtists: []HistoryCount{{"A", 3}, {"(unknown artist)", 1}, {"B", 1}},
+				TopAlbums:  []HistoryCount{{"X — A", 3}, {"Y — B", 1}},
+				TopTracks:  []HistoryCount{{"One — A", 2}, {"Two — A", 1}, {"Uno — B", 1}, {"c/1.mp3", 1}},
+			},
+		},
+		{
+			name:  "last week, limited",
+			since: now.AddDate(0, 0, -7),
+			limit: 1,
+			want: &HistoryStats{
+				Plays:      3,
+				TotalTime:  310,
+				TopArtists: []HistoryCount{{"A", 2}},
+				TopAlbums:  []HistoryCount{{"X — A", 2}},
+				TopTracks:  []HistoryCount{{"One — A", 1}},
+			},
+		},
+		{
+			name:  "nothing played",
+			since: now,
+			limit: 10,
+			want:  &HistoryStats{TopArtists: []HistoryCount{}, TopAlbums: []HistoryCount{}, TopTracks: []HistoryCount{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeHistoryStats(entries, tt.since, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("computeHistoryStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlayHistory_Progress(t *testing.T) {
+	h := &PlayHistory{}
+	h.SetTrack(nil)
+	for _, pos := range []float64{1, 2, 3, 60, 61, 62, 10, 11} {
+		h.Progress(pos)
+	}
+	// Jumps (seeks) must not be counted: 1 + 1 + 1 + 1 + 1 + 1
+	if h.curPlayed != 6 {
+		t.Errorf("Progress(): curPlayed = %v, want 6", h.curPlayed)
+	}
+}
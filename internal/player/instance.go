@@ -0,0 +1,79 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+// #cgo pkg-config: gio-2.0
+// #include <gio/gio.h>
+import "C"
+import (
+	"github.com/gotk3/gotk3/glib"
+	"unsafe"
+)
+
+func init() {
+	// Let the application's command-line signal handlers receive ApplicationCommandLine rather than a bare Object
+	glib.RegisterGValueMarshalers([]glib.TypeMarshaler{
+		{T: glib.Type(C.g_application_command_line_get_type()), F: marshalApplicationCommandLine},
+	})
+}
+
+// ApplicationCommandLine is a command line an instance of the application has been started with. Instances started
+// while another one is running hand their command line over to that one, which handles it in its command-line signal
+type ApplicationCommandLine struct {
+	*glib.Object
+}
+
+func marshalApplicationCommandLine(p uintptr) (interface{}, error) {
+	c := C.g_value_get_object((*C.GValue)(unsafe.Pointer(p)))
+	return &ApplicationCommandLine{glib.Take(unsafe.Pointer(c))}, nil
+}
+
+func (c *ApplicationCommandLine) native() *C.GApplicationCommandLine {
+	return (*C.GApplicationCommandLine)(unsafe.Pointer(c.Object.Native()))
+}
+
+// Arguments returns the arguments of the command line, without the program name
+func (c *ApplicationCommandLine) Arguments() []string {
+	var argc C.int
+	argv := C.g_application_command_line_get_arguments(c.native(), &argc)
+	defer C.g_strfreev(argv)
+	var args []string
+	for i := 1; i < int(argc); i++ {
+		arg := *(**C.gchar)(unsafe.Pointer(uintptr(unsafe.Pointer(argv)) + uintptr(i)*unsafe.Sizeof(*argv)))
+		args = append(args, C.GoString((*C.char)(arg)))
+	}
+	return args
+}
+
+// Cwd returns the working directory of the command line's instance, or an empty string if it's unknown
+func (c *ApplicationCommandLine) Cwd() string {
+	if cwd := C.g_application_command_line_get_cwd(c.native()); cwd != nil {
+		return C.GoString((*C.char)(cwd))
+	}
+	return ""
+}
+
+// FileURIs returns the URIs of the given array of n GFile pointers, which the application's open signal is emitted with
+func FileURIs(files unsafe.Pointer, n int) []string {
+	uris := make([]string, n)
+	for i := range uris {
+		file := *(**C.GFile)(unsafe.Pointer(uintptr(files) + uintptr(i)*unsafe.Sizeof((*C.GFile)(nil))))
+		uri := C.g_file_get_uri(file)
+		uris[i] = C.GoString((*C.char)(uri))
+		C.g_free(C.gpointer(uri))
+	}
+	return uris
+}
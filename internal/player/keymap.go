@@ -0,0 +1,163 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+)
+
+// vimCommand is a command of the Vim-style key binding profile
+type vimCommand int
+
+const (
+	vcNone    vimCommand = iota // Key press isn't a Vim-style command
+	vcPending                   // Key press starts a multi-key command
+	vcDown                      // j: move to the next item
+	vcUp                        // k: move to the previous item
+	vcFirst                     // gg: move to the first item
+	vcLast                      // G: move to the last item
+	vcSearch                    // /: start searching
+	vcDelete                    // dd: remove the selected items
+	vcPaste                     // p: paste the last removed items
+)
+
+// vimKeymap translates key presses into Vim-style commands, keeping track of multi-key commands being typed
+type vimKeymap struct {
+	pending uint // Key value of the first key of the multi-key command being typed, 0 if none
+}
+
+// vimKeymapEnabled returns whether the Vim-style key binding profile is selected
+func vimKeymapEnabled() bool {
+	return config.GetConfig().KeymapProfile == config.KeymapVim
+}
+
+// feed processes a key press with the given key value and modifier state, and returns the resulting command
+func (k *vimKeymap) feed(keyVal uint, state gdk.ModifierType) vimCommand {
+	pending := k.pending
+	k.pending = 0
+
+	// Commands are typed without modifiers, save for Shift producing capitals and symbols
+	if state&^gdk.SHIFT_MASK != 0 {
+		return vcNone
+	}
+	switch keyVal {
+	case gdk.KEY_j:
+		return vcDown
+	case gdk.KEY_k:
+		return vcUp
+	case gdk.KEY_G:
+		return vcLast
+	case gdk.KEY_slash:
+		return vcSearch
+	case gdk.KEY_p:
+		return vcPaste
+	case gdk.KEY_g, gdk.KEY_d:
+		// A repeated key completes the command, a single one starts it
+		switch {
+		case pending != keyVal:
+			k.pending = keyVal
+			return vcPending
+		case keyVal == gdk.KEY_g:
+			return vcFirst
+		default:
+			return vcDelete
+		}
+	}
+	return vcNone
+}
+
+// isMove returns whether the command is a cursor movement
+func (c vimCommand) isMove() bool {
+	return c == vcDown || c == vcUp || c == vcFirst || c == vcLast
+}
+
+// vimCursorIndex returns the index of the item the given cursor movement command leads to in a list of count items,
+// skipping the items that aren't shown, or -1 if there's none. cur is the index of the current item, -1 if none
+func vimCursorIndex(cmd vimCommand, cur, count int, shown func(i int) bool) int {
+	// Without a current item, moving up or down starts from the top
+	if cur < 0 && (cmd == vcDown || cmd == vcUp) {
+		cmd = vcFirst
+	}
+
+	from, step := 0, 1
+	switch cmd {
+	case vcDown:
+		from = cur + 1
+	case vcUp:
+		from, step = cur-1, -1
+	case vcFirst:
+	case vcLast:
+		from, step = count-1, -1
+	default:
+		return -1
+	}
+	for i := from; i >= 0 && i < count; i += step {
+		if shown(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// vimMoveListBox moves the selection in the list box according to the given cursor movement command
+func vimMoveListBox(listBox *gtk.ListBox, cmd vimCommand) {
+	// Find the current row: the last selected one when moving down, the first one otherwise
+	cur, count := -1, 0
+	for ; ; count++ {
+		row := listBox.GetRowAtIndex(count)
+		if row == nil {
+			break
+		}
+		if row.IsSelected() && (cur < 0 || cmd == vcDown) {
+			cur = count
+		}
+	}
+
+	// Rows hidden by the list's filter aren't mapped
+	idx := vimCursorIndex(cmd, cur, count, func(i int) bool { return listBox.GetRowAtIndex(i).GetMapped() })
+	if idx >= 0 {
+		row := listBox.GetRowAtIndex(idx)
+		listBox.UnselectAll()
+		listBox.SelectRow(row)
+		row.GrabFocus()
+	}
+}
+
+// vimMoveFlowBox moves the selection in the flow box according to the given cursor movement command
+func vimMoveFlowBox(flowBox *gtk.FlowBox, cmd vimCommand) {
+	// Find the current child: the last selected one when moving down, the first one otherwise
+	cur, count := -1, 0
+	for ; ; count++ {
+		child := flowBox.GetChildAtIndex(count)
+		if child == nil {
+			break
+		}
+		if child.IsSelected() && (cur < 0 || cmd == vcDown) {
+			cur = count
+		}
+	}
+
+	// Children hidden by the filter aren't mapped
+	idx := vimCursorIndex(cmd, cur, count, func(i int) bool { return flowBox.GetChildAtIndex(i).GetMapped() })
+	if idx >= 0 {
+		child := flowBox.GetChildAtIndex(idx)
+		flowBox.UnselectAll()
+		flowBox.SelectChild(child)
+		child.GrabFocus()
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/gotk3/gotk3/gdk"
+	"testing"
+)
+
+func TestVimKeymapFeed(t *testing.T) {
+	type key struct {
+		val   uint
+		state gdk.ModifierType
+	}
+	tests := []struct {
+		name string
+		keys []key
+		want []vimCommand
+	}{
+		{"down", []key{{gdk.KEY_j, 0}}, []vimCommand{vcDown}},
+		{"up", []key{{gdk.KEY_k, 0}}, []vimCommand{vcUp}},
+		{"first", []key{{gdk.KEY_g, 0}, {gdk.KEY_g, 0}}, []vimCommand{vcPending, vcFirst}},
+		{"last", []key{{gdk.KEY_G, gdk.SHIFT_MASK}}, []vimCommand{vcLast}},
+		{"search", []key{{gdk.KEY_slash, 0}}, []vimCommand{vcSearch}},
+		{"delete", []key{{gdk.KEY_d, 0}, {gdk.KEY_d, 0}}, []vimCommand{vcPending, vcDelete}},
+		{"paste", []key{{gdk.KEY_p, 0}}, []vimCommand{vcPaste}},
+		{"ctrl", []key{{gdk.KEY_j, gdk.CONTROL_MASK}}, []vimCommand{vcNone}},
+		{"other key", []key{{gdk.KEY_x, 0}}, []vimCommand{vcNone}},
+		{"mixed sequence", []key{{gdk.KEY_d, 0}, {gdk.KEY_g, 0}, {gdk.KEY_g, 0}}, []vimCommand{vcPending, vcPending, vcFirst}},
+		{"interrupted sequence", []key{{gdk.KEY_d, 0}, {gdk.KEY_j, 0}, {gdk.KEY_d, 0}}, []vimCommand{vcPending, vcDown, vcPending}},
+		{"repeated", []key{{gdk.KEY_g, 0}, {gdk.KEY_g, 0}, {gdk.KEY_g, 0}}, []vimCommand{vcPending, vcFirst, vcPending}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var k vimKeymap
+			for i, key := range tt.keys {
+				if got := k.feed(key.val, key.state); got != tt.want[i] {
+					t.Errorf("feed() #%d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVimCursorIndex(t *testing.T) {
+	// Items 1 and 4 are hidden
+	shown := func(i int) bool { return i != 1 && i != 4 }
+	tests := []struct {
+		name string
+		cmd  vimCommand
+		cur  int
+		want int
+	}{
+		{"down", vcDown, 2, 3},
+		{"down skipping hidden", vcDown, 0, 2},
+		{"down at the end", vcDown, 5, -1},
+		{"down to the end", vcDown, 3, 5},
+		{"up", vcUp, 3, 2},
+		{"up skipping hidden", vcUp, 2, 0},
+		{"up at the start", vcUp, 0, -1},
+		{"down without current", vcDown, -1, 0},
+		{"up without current", vcUp, -1, 0},
+		{"first", vcFirst, 3, 0},
+		{"last", vcLast, 0, 5},
+		{"not a movement", vcSearch, 0, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vimCursorIndex(tt.cmd, tt.cur, 6, shown); got != tt.want {
+				t.Errorf("vimCursorIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Nothing to move to in an empty list
+	if got := vimCursorIndex(vcLast, -1, 0, shown); got != -1 {
+		t.Errorf("vimCursorIndex() = %v, want -1", got)
+	}
+}
@@ -0,0 +1,319 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	lastFmURL     = "https://ws.audioscrobbler.com/2.0/" // URL of the Last.fm API
+	lastFmAuthURL = "https://www.last.fm/api/auth/"      // URL of the Last.fm page the user grants access on
+	lastFmSimilar = 50                                   // Number of similar tracks or artists requested
+
+	lastFmErrorNotFound = 6 // Last.fm error code reported for invalid parameters, such as an unknown artist
+)
+
+// lastFmReadMoreRegex matches the link to the full biography Last.fm appends to biography summaries
+var lastFmReadMoreRegex = regexp.MustCompile(`(?s)\s*<a [^>]*>Read more on Last\.fm</a>\.?\s*$`)
+
+// htmlTagRegex matches an HTML tag
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// LastFmTrack identifies a track on Last.fm
+type LastFmTrack struct {
+	Artist string // Track's artist
+	Title  string // Track's title
+}
+
+// LastFmError is an error reported by the Last.fm API
+type LastFmError struct {
+	Code    int    // Last.fm error code
+	Message string // Error message
+}
+
+func (e *LastFmError) Error() string {
+	return fmt.Sprintf("Last.fm error %d: %s", e.Code, e.Message)
+}
+
+// LastFm is a client of the Last.fm API. Authenticated calls require the user to have granted access to the
+// application, which yields a session key
+type LastFm struct {
+	baseURL    string // URL of the Last.fm API
+	apiKey     string // API key of the application
+	secret     string // Shared secret of the application, used for signing calls
+	sessionKey string // Key of the user's session, empty if not logged in
+}
+
+// NewLastFm creates and returns a new LastFm client using the given API account and session key
+func NewLastFm(apiKey, secret, sessionKey string) *LastFm {
+	return &LastFm{baseURL: lastFmURL, apiKey: apiKey, secret: secret, sessionKey: sessionKey}
+}
+
+// NewLastFmFromConfig creates and returns a new LastFm client using the API account and the session stored in the
+// configuration
+func NewLastFmFromConfig() *LastFm {
+	cfg := config.GetConfig()
+	return NewLastFm(cfg.LastFmAPIKey, cfg.LastFmSecret, cfg.LastFmSessionKey)
+}
+
+// HasAPIKey returns whether the client can make calls not requiring authentication
+func (f *LastFm) HasAPIKey() bool {
+	return f.apiKey != ""
+}
+
+// IsConfigured returns whether the client has an API account to sign calls with
+func (f *LastFm) IsConfigured() bool {
+	return f.apiKey != "" && f.secret != ""
+}
+
+// IsLoggedIn returns whether the client has a user session for authenticated calls
+func (f *LastFm) IsLoggedIn() bool {
+	return f.IsConfigured() && f.sessionKey != ""
+}
+
+// GetToken requests a new authentication token, which needs to be authorised by the user on the page returned by
+// AuthURL() before requesting a session
+func (f *LastFm) GetToken() (string, error) {
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := f.call(http.MethodGet, "auth.getToken", url.Values{}, true, &result); err != nil {
+		return "", err
+	}
+	return result.Token, nil
+}
+
+// AuthURL returns the URL of the page the user authorises the given token on
+func (f *LastFm) AuthURL(token string) string {
+	return lastFmAuthURL + "?" + url.Values{"api_key": {f.apiKey}, "token": {token}}.Encode()
+}
+
+// GetSession exchanges an authorised token for a session. Returns the user name and the session key
+func (f *LastFm) GetSession(token string) (string, string, error) {
+	var result struct {
+		Session struct {
+			Name string `json:"name"`
+			Key  string `json:"key"`
+		} `json:"session"`
+	}
+	if err := f.call(http.MethodGet, "auth.getSession", url.Values{"token": {token}}, true, &result); err != nil {
+		return "", "", err
+	}
+	return result.Session.Name, result.Session.Key, nil
+}
+
+// IsLoved returns whether the given track is loved by the given user
+func (f *LastFm) IsLoved(user string, track LastFmTrack) (bool, error) {
+	var result struct {
+		Track struct {
+			UserLoved string `json:"userloved"`
+		} `json:"track"`
+	}
+	params := url.Values{"artist": {track.Artist}, "track": {track.Title}, "username": {user}}
+	if err := f.call(http.MethodGet, "track.getInfo", params, false, &result); err != nil {
+		return false, err
+	}
+	return result.Track.UserLoved == "1", nil
+}
+
+// SetLoved loves or unloves the given track on behalf of the logged-in user
+func (f *LastFm) SetLoved(track LastFmTrack, loved bool) error {
+	method := "track.unlove"
+	if loved {
+		method = "track.love"
+	}
+	params := url.Values{"artist": {track.Artist}, "track": {track.Title}, "sk": {f.sessionKey}}
+	return f.call(http.MethodPost, method, params, true, nil)
+}
+
+// SimilarTracks returns the tracks similar to the given one, the most similar first
+func (f *LastFm) SimilarTracks(track LastFmTrack) ([]LastFmTrack, error) {
+	var result struct {
+		SimilarTracks struct {
+			Track []struct {
+				Name   string `json:"name"`
+				Artist struct {
+					Name string `json:"name"`
+				} `json:"artist"`
+			} `json:"track"`
+		} `json:"similartracks"`
+	}
+	params := url.Values{
+		"artist":      {track.Artist},
+		"track":       {track.Title},
+		"autocorrect": {"1"},
+		"limit":       {strconv.Itoa(lastFmSimilar)},
+	}
+	if err := f.call(http.MethodGet, "track.getSimilar", params, false, &result); err != nil {
+		return nil, err
+	}
+	var tracks []LastFmTrack
+	for _, t := range result.SimilarTracks.Track {
+		tracks = append(tracks, LastFmTrack{Artist: t.Artist.Name, Title: t.Name})
+	}
+	return tracks, nil
+}
+
+// SimilarArtists returns the names of the artists similar to the given one, the most similar first
+func (f *LastFm) SimilarArtists(artist string) ([]string, error) {
+	var result struct {
+		SimilarArtists struct {
+			Artist []struct {
+				Name string `json:"name"`
+			} `json:"artist"`
+		} `json:"similarartists"`
+	}
+	params := url.Values{"artist": {artist}, "autocorrect": {"1"}, "limit": {strconv.Itoa(lastFmSimilar)}}
+	if err := f.call(http.MethodGet, "artist.getSimilar", params, false, &result); err != nil {
+		return nil, err
+	}
+	var artists []string
+	for _, a := range result.SimilarArtists.Artist {
+		artists = append(artists, a.Name)
+	}
+	return artists, nil
+}
+
+// ArtistBio returns a short biography of the given artist as plain text, and the URL of the artist's page. The
+// biography is empty if Last.fm doesn't have one
+func (f *LastFm) ArtistBio(artist string) (string, string, error) {
+	var result struct {
+		Artist struct {
+			URL string `json:"url"`
+			Bio struct {
+				Summary string `json:"summary"`
+			} `json:"bio"`
+		} `json:"artist"`
+	}
+	params := url.Values{"artist": {artist}, "autocorrect": {"1"}}
+	if err := f.call(http.MethodGet, "artist.getInfo", params, false, &result); err != nil {
+		return "", "", err
+	}
+	bio := lastFmReadMoreRegex.ReplaceAllString(result.Artist.Bio.Summary, "")
+	bio = strings.TrimSpace(html.UnescapeString(htmlTagRegex.ReplaceAllString(bio, "")))
+	return bio, result.Artist.URL, nil
+}
+
+// AlbumImageURL returns the URL of the largest available cover image of the given album, or an empty string if there's
+// none
+func (f *LastFm) AlbumImageURL(artist, album string) (string, error) {
+	var result struct {
+		Album struct {
+			Image []struct {
+				URL  string `json:"#text"`
+				Size string `json:"size"`
+			} `json:"image"`
+		} `json:"album"`
+	}
+	params := url.Values{"artist": {artist}, "album": {album}, "autocorrect": {"1"}}
+	if err := f.call(http.MethodGet, "album.getInfo", params, false, &result); err != nil {
+		return "", err
+	}
+
+	// Images are listed from the smallest to the largest
+	imageURL := ""
+	for _, img := range result.Album.Image {
+		if img.URL != "" {
+			imageURL = img.URL
+		}
+	}
+	return imageURL, nil
+}
+
+// call invokes an API method with the given parameters, signing the call if needed, and decodes the JSON response into
+// result, unless it's nil
+func (f *LastFm) call(httpMethod, method string, params url.Values, signed bool, result interface{}) error {
+	params.Set("method", method)
+	params.Set("api_key", f.apiKey)
+	if signed {
+		params.Set("api_sig", f.signature(params))
+	}
+	params.Set("format", "json")
+
+	// Run the request
+	var req *http.Request
+	var err error
+	if httpMethod == http.MethodPost {
+		req, err = http.NewRequest(http.MethodPost, f.baseURL, strings.NewReader(params.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequest(httpMethod, f.baseURL+"?"+params.Encode(), nil)
+	}
+	if err != nil {
+		return err
+	}
+	// Errors are reported in the response body, usually along with an error status
+	var data []byte
+	var httpErr *util.HTTPError
+	resp, err := util.HTTPDo(req)
+	switch {
+	case err == nil:
+		defer resp.Body.Close()
+		if data, err = ioutil.ReadAll(resp.Body); err != nil {
+			return err
+		}
+	case errors.As(err, &httpErr):
+		data = []byte(httpErr.Body)
+	default:
+		return err
+	}
+	var lfErr struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &lfErr) == nil && lfErr.Error != 0 {
+		return &LastFmError{Code: lfErr.Error, Message: lfErr.Message}
+	}
+	if err != nil || result == nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// signature returns the signature of a call with the given parameters: an MD5 hash of all parameters concatenated in
+// alphabetical order, followed by the shared secret
+func (f *LastFm) signature(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteString(params.Get(k))
+	}
+	sb.WriteString(f.secret)
+	sum := md5.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
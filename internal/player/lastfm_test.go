@@ -0,0 +1,115 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestLastFm_signature(t *testing.T) {
+	f := &LastFm{secret: "secret"}
+	// md5("api_keykeymethodauth.getTokensecret")
+	params := url.Values{"method": {"auth.getToken"}, "api_key": {"key"}}
+	if got, want := f.signature(params), "b4705499705a550b07ca058a15bde9b0"; got != want {
+		t.Errorf("signature() = %v, want %v", got, want)
+	}
+}
+
+func TestLastFm_Love(t *testing.T) {
+	f := &LastFm{apiKey: "key", secret: "secret", sessionKey: "session"}
+	var loved bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params := r.Form
+		sig := params.Get("api_sig")
+		params.Del("api_sig")
+		params.Del("format")
+		switch method := params.Get("method"); {
+		case params.Get("api_key") != "key":
+			_, _ = w.Write([]byte(`{"error":10,"message":"Invalid API key"}`))
+		case method == "track.getInfo":
+			if loved {
+				_, _ = w.Write([]byte(`{"track":{"name":"Title","userloved":"1"}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"track":{"name":"Title","userloved":"0"}}`))
+			}
+		case r.Method != http.MethodPost || sig != f.signature(params) || params.Get("sk") != "session":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":13,"message":"Invalid method signature supplied"}`))
+		case method == "track.love" || method == "track.unlove":
+			loved = method == "track.love"
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	f.baseURL = server.URL
+	track := LastFmTrack{Artist: "Artist", Title: "Title"}
+
+	for _, want := range []bool{true, false} {
+		if err := f.SetLoved(track, want); err != nil {
+			t.Fatalf("SetLoved(%v) error = %v", want, err)
+		}
+		if got, err := f.IsLoved("user", track); err != nil || got != want {
+			t.Errorf("IsLoved() = %v, %v, want %v, nil", got, err, want)
+		}
+	}
+
+	// Errors are reported as LastFmError
+	f.apiKey = "wrong"
+	var lfErr *LastFmError
+	if err := f.SetLoved(track, true); !errors.As(err, &lfErr) || lfErr.Code != 10 {
+		t.Errorf("SetLoved() error = %v, want Last.fm error 10", err)
+	}
+}
+
+func TestLastFm_Similar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("method") == "track.getSimilar" && q.Get("artist") == "Metallica" && q.Get("track") == "One":
+			_, _ = w.Write([]byte(`{"similartracks":{"track":[{"name":"Fade to Black","match":1,"artist":{"name":"Metallica"}},{"name":"Ace of Spades","match":0.8,"artist":{"name":"Motörhead"}}]}}`))
+		case q.Get("method") == "artist.getSimilar" && q.Get("artist") == "Metallica":
+			_, _ = w.Write([]byte(`{"similarartists":{"artist":[{"name":"Megadeth","match":"1"},{"name":"Slayer","match":"0.9"}]}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":6,"message":"Track not found"}`))
+		}
+	}))
+	defer server.Close()
+	f := &LastFm{baseURL: server.URL, apiKey: "key"}
+
+	tracks, err := f.SimilarTracks(LastFmTrack{Artist: "Metallica", Title: "One"})
+	want := []LastFmTrack{{Artist: "Metallica", Title: "Fade to Black"}, {Artist: "Motörhead", Title: "Ace of Spades"}}
+	if err != nil || !reflect.DeepEqual(tracks, want) {
+		t.Errorf("SimilarTracks() = %v, %v, want %v, nil", tracks, err, want)
+	}
+	if artists, err := f.SimilarArtists("Metallica"); err != nil || !reflect.DeepEqual(artists, []string{"Megadeth", "Slayer"}) {
+		t.Errorf("SimilarArtists() = %v, %v, want [Megadeth Slayer], nil", artists, err)
+	}
+	if _, err := f.SimilarTracks(LastFmTrack{Artist: "Nobody", Title: "Nothing"}); err == nil {
+		t.Error("SimilarTracks() succeeded for an unknown track, want error")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"reflect"
+	"sync"
+)
+
+const (
+	launcherEntryPath   = "/com/yktoo/ymuse/LauncherEntry"                  // Path of the launcher entry object
+	launcherEntryIface  = "com.canonical.Unity.LauncherEntry"               // Launcher entry interface
+	launcherEntryAppURI = "application://" + mprisDesktopEntry + ".desktop" // URI identifying the application's launcher
+)
+
+// LauncherEntry publishes the play progress and the number of queued tracks on the D-Bus session bus through the
+// Unity LauncherEntry API, so that docks and task bars can display them over the application's icon. Updates must
+// happen on the GTK thread, D-Bus calls are served in the background
+type LauncherEntry struct {
+	conn  *dbus.Conn              // Session bus connection
+	props map[string]dbus.Variant // Current launcher entry properties
+	mutex sync.Mutex              // Mutex guarding the properties, which are also read by D-Bus calls
+}
+
+// launcherEntry implements the com.canonical.Unity.LauncherEntry interface
+type launcherEntry struct {
+	e *LauncherEntry
+}
+
+// NewLauncherEntry connects to the session bus and exports the launcher entry
+func NewLauncherEntry() (*LauncherEntry, error) {
+	conn, err := connectBus(dbus.SessionBusPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &LauncherEntry{
+		conn: conn,
+		props: map[string]dbus.Variant{
+			"progress":         dbus.MakeVariant(0.0),
+			"progress-visible": dbus.MakeVariant(false),
+			"count":            dbus.MakeVariant(int64(0)),
+			"count-visible":    dbus.MakeVariant(false),
+		},
+	}
+	entry := &launcherEntry{e}
+	if err := conn.Export(entry, launcherEntryPath, launcherEntryIface); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	node := &introspect.Node{
+		Name: launcherEntryPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    launcherEntryIface,
+				Methods: introspect.Methods(entry),
+				Signals: []introspect.Signal{{Name: "Update", Args: []introspect.Arg{{Name: "app_uri", Type: "s"}, {Name: "properties", Type: "a{sv}"}}}},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), launcherEntryPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close disconnects from the session bus
+func (e *LauncherEntry) Close() {
+	errCheck(e.conn.Close(), "Failed to close session bus connection")
+}
+
+// SetProgress updates the play progress of the current track, ranging from 0 to 1, and whether it's displayed
+func (e *LauncherEntry) SetProgress(progress float64, visible bool) {
+	e.update(map[string]dbus.Variant{
+		"progress":         dbus.MakeVariant(progress),
+		"progress-visible": dbus.MakeVariant(visible),
+	})
+}
+
+// SetCount updates the number of tracks in the queue, which is only displayed if positive
+func (e *LauncherEntry) SetCount(count int) {
+	e.update(map[string]dbus.Variant{
+		"count":         dbus.MakeVariant(int64(count)),
+		"count-visible": dbus.MakeVariant(count > 0),
+	})
+}
+
+// update stores the given properties and announces those that have changed
+func (e *LauncherEntry) update(props map[string]dbus.Variant) {
+	changed := make(map[string]dbus.Variant)
+	e.mutex.Lock()
+	for name, value := range props {
+		if !reflect.DeepEqual(e.props[name], value) {
+			e.props[name] = value
+			changed[name] = value
+		}
+	}
+	e.mutex.Unlock()
+
+	if len(changed) > 0 {
+		errCheck(
+			e.conn.Emit(launcherEntryPath, launcherEntryIface+".Update", launcherEntryAppURI, changed),
+			"Failed to emit launcher entry Update signal")
+	}
+}
+
+// Query implements com.canonical.Unity.LauncherEntry.Query, returning all the current properties
+func (l *launcherEntry) Query() (string, map[string]dbus.Variant, *dbus.Error) {
+	l.e.mutex.Lock()
+	defer l.e.mutex.Unlock()
+	props := make(map[string]dbus.Variant, len(l.e.props))
+	for name, value := range l.e.props {
+		props[name] = value
+	}
+	return launcherEntryAppURI, props, nil
+}
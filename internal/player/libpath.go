@@ -21,8 +21,13 @@ import (
 	"github.com/gotk3/gotk3/glib"
 	"github.com/yktoo/ymuse/internal/config"
 	"github.com/yktoo/ymuse/internal/util"
+	"math"
 	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // LibraryPathElement represents one element in the library path
@@ -47,6 +52,12 @@ type AttributeHolder interface {
 	AttributeValue() string // Attribute's value
 }
 
+// FilterHolder represents an AttributeHolder that provides its own MPD filter expression instead of an exact match on
+// the attribute value
+type FilterHolder interface {
+	Filter() string
+}
+
 // AttributeHolderParent represents an object that can be a parent for AttributeHolder
 type AttributeHolderParent interface {
 	ChildAttributeID() int                    // Child attribute's ID
@@ -58,22 +69,54 @@ type DetailsHolder interface {
 	Details() string
 }
 
+// NumberHolder represents an object that has an ordinal number, such as a track number
+type NumberHolder interface {
+	Number() string
+}
+
+// FormatHolder represents an object that has an audio format, such as "FLAC 24/96"
+type FormatHolder interface {
+	AudioFormat() string
+}
+
 // PlaylistHolder represents an object that references a playlist
 type PlaylistHolder interface {
 	PlaylistName() string
 }
 
+// QueryHolder represents an object whose tracks are selected with an MPD filter expression
+type QueryHolder interface {
+	Query() string
+}
+
+// ModifiedHolder represents an object that possesses a last modification time
+type ModifiedHolder interface {
+	LastModified() time.Time // Last modification time, or zero time if unknown
+}
+
 var elementConstructors = map[string]func() LibraryPathElement{
 	"lvlup":      NewLevelUpLibElement,
+	"header":     NewHeaderLibElement,
 	"filesystem": NewFilesystemLibElement,
 	"dir":        NewDirLibElement,
 	"file":       NewFileLibElement,
 	"playlists":  NewPlaylistsLibElement,
 	"playlist":   NewPlaylistLibElement,
+	"plfolder":   NewPlaylistFolderLibElement,
+	"recent":     NewRecentLibElement,
+	"smartlists": NewSmartPlaylistsLibElement,
+	"smartlist":  NewSmartPlaylistLibElement,
 	"genres":     NewGenresLibElement,
 	"genre":      NewGenreLibElement,
 	"artists":    NewArtistsLibElement,
 	"artist":     NewArtistLibElement,
+	"composers":  NewComposersLibElement,
+	"composer":   NewComposerLibElement,
+	"performers": NewPerformersLibElement,
+	"performer":  NewPerformerLibElement,
+	"years":      NewYearsLibElement,
+	"decade":     NewDecadeLibElement,
+	"year":       NewYearLibElement,
 	"albums":     NewAlbumsLibElement,
 	"album":      NewAlbumLibElement,
 	"track":      NewTrackLibElement,
@@ -116,22 +159,27 @@ func MarshalLibPathElement(e LibraryPathElement) string {
 func AttrsToElements(attrs []mpd.Attrs, uriPrefix string) []LibraryPathElement {
 	result := make([]LibraryPathElement, 0, len(attrs))
 	for _, a := range attrs {
+		modified, _ := time.Parse(time.RFC3339, a["Last-Modified"])
 		if dir, ok := a["directory"]; ok {
 			result = append(result, &DirLibElement{
-				uri:   dir,
-				title: strings.TrimPrefix(dir, uriPrefix),
+				uri:      dir,
+				title:    strings.TrimPrefix(dir, uriPrefix),
+				modified: modified,
 			})
 
 		} else if file, ok := a["file"]; ok {
 			result = append(result, &FileLibElement{
-				uri:    file,
-				title:  strings.TrimPrefix(file, uriPrefix),
-				length: util.ParseFloatDef(a["duration"], 0.0),
+				uri:      file,
+				title:    strings.TrimPrefix(file, uriPrefix),
+				format:   util.AudioFormatBadge(file, a["Format"]),
+				length:   util.ParseFloatDef(a["duration"], 0.0),
+				modified: modified,
 			})
 
 		} else if playlist, ok := a["playlist"]; ok {
 			result = append(result, &PlaylistLibElement{
-				name: strings.TrimPrefix(playlist, uriPrefix),
+				name:     strings.TrimPrefix(playlist, uriPrefix),
+				modified: modified,
 			})
 
 		} else {
@@ -141,6 +189,570 @@ func AttrsToElements(attrs []mpd.Attrs, uriPrefix string) []LibraryPathElement {
 	return result
 }
 
+// AlbumTracksToElements converts the provided album tracks into a list of file elements ordered by disc and track
+// number. Tracks of multi-disc albums are grouped under disc headers, and tracks belonging to a classical work under a
+// header bearing the work's name. The list ends with a header holding the number of tracks and their total duration
+func AlbumTracksToElements(attrs []mpd.Attrs) []LibraryPathElement {
+	SortAlbumTracks(attrs)
+
+	// Check whether there are multiple discs
+	discs := make(map[string]bool)
+	for _, a := range attrs {
+		discs[a["Disc"]] = true
+	}
+	multiDisc := len(discs) > 1
+
+	result := make([]LibraryPathElement, 0, len(attrs))
+	work, disc, discStarted := "", "", false
+	count, total := 0, 0.0
+	for _, a := range attrs {
+		file, ok := a["file"]
+		if !ok {
+			continue
+		}
+
+		// Insert a header whenever the disc changes
+		if multiDisc && (!discStarted || a["Disc"] != disc) {
+			disc, discStarted = a["Disc"], true
+			work = ""
+			if n := trackNumber(disc); n != math.MaxInt32 {
+				result = append(result, NewHeaderLibElementVal(fmt.Sprintf(glib.Local("Disc %d"), n)))
+			} else {
+				result = append(result, NewHeaderLibElementVal(glib.Local("Unknown disc")))
+			}
+		}
+
+		// Insert a header whenever the work changes
+		if a["Work"] != work {
+			work = a["Work"]
+			if work != "" {
+				result = append(result, NewHeaderLibElementVal(work))
+			}
+		}
+
+		modified, _ := time.Parse(time.RFC3339, a["Last-Modified"])
+		number := ""
+		if n := trackNumber(a["Track"]); n != math.MaxInt32 {
+			number = strconv.Itoa(n)
+		}
+		length := util.ParseFloatDef(a["duration"], 0.0)
+		result = append(result, &FileLibElement{
+			uri:      file,
+			title:    albumTrackTitle(a),
+			number:   number,
+			format:   util.AudioFormatBadge(file, a["Format"]),
+			length:   length,
+			modified: modified,
+		})
+		count++
+		total += length
+	}
+
+	// Add the total
+	if count > 0 {
+		result = append(result, &HeaderLibElement{
+			title:   fmt.Sprintf(util.LocalN("%d track", "%d tracks", count), count),
+			details: util.FormatSeconds(total),
+		})
+	}
+	return result
+}
+
+// PlaylistTracksToElements converts the provided playlist tracks into a list of file elements numbered by their position
+// in the playlist. The list ends with a header holding the number of tracks and their total duration
+func PlaylistTracksToElements(attrs []mpd.Attrs) []LibraryPathElement {
+	result := make([]LibraryPathElement, 0, len(attrs)+1)
+	total := 0.0
+	for i, a := range attrs {
+		length := util.ParseFloatDef(a["duration"], 0.0)
+		result = append(result, &FileLibElement{
+			uri:    a["file"],
+			title:  playlistTrackTitle(a),
+			number: strconv.Itoa(i + 1),
+			format: util.AudioFormatBadge(a["file"], a["Format"]),
+			length: length,
+		})
+		total += length
+	}
+
+	// Add the total
+	if len(attrs) > 0 {
+		result = append(result, &HeaderLibElement{
+			title:   fmt.Sprintf(util.LocalN("%d track", "%d tracks", len(attrs)), len(attrs)),
+			details: util.FormatSeconds(total),
+		})
+	}
+	return result
+}
+
+// PlaylistPreview returns a summary of the provided playlist tracks (their number and total duration) along with the
+// display lines for at most maxTracks first tracks. If there are more tracks, the last line tells how many are left out
+func PlaylistPreview(attrs []mpd.Attrs, maxTracks int) (string, []string) {
+	var lines []string
+	total := 0.0
+	for i, a := range attrs {
+		length := util.ParseFloatDef(a["duration"], 0.0)
+		total += length
+		if i < maxTracks {
+			line := fmt.Sprintf("%d. %s", i+1, playlistTrackTitle(a))
+			if length > 0 {
+				line += " (" + util.FormatSeconds(length) + ")"
+			}
+			lines = append(lines, line)
+		}
+	}
+	if more := len(attrs) - maxTracks; more > 0 {
+		lines = append(lines, fmt.Sprintf(util.LocalN("…and %d more", "…and %d more", more), more))
+	}
+	return fmt.Sprintf(util.LocalN("%d track, %s", "%d tracks, %s", len(attrs)), len(attrs), util.FormatSeconds(total)), lines
+}
+
+// GroupPlaylistElements arranges the provided playlist elements into virtual folders, given the folder names keyed by
+// playlist name. Playlists outside any folder come first, followed by every folder (ordered by name) with its playlists.
+// The order of playlists within a group is retained
+func GroupPlaylistElements(elements []LibraryPathElement, folders map[string]string) []LibraryPathElement {
+	var result []LibraryPathElement
+	grouped := make(map[string][]LibraryPathElement)
+	for _, e := range elements {
+		folder := ""
+		if ph, ok := e.(PlaylistHolder); ok {
+			folder = folders[ph.PlaylistName()]
+		}
+		if folder == "" {
+			result = append(result, e)
+		} else {
+			grouped[folder] = append(grouped[folder], e)
+		}
+	}
+
+	// Add the folders, each followed by its playlists
+	names := make([]string, 0, len(grouped))
+	for name := range grouped {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+	for _, name := range names {
+		result = append(result, &PlaylistFolderLibElement{
+			name:    name,
+			details: fmt.Sprintf(util.LocalN("%d playlist", "%d playlists", len(grouped[name])), len(grouped[name])),
+		})
+		result = append(result, grouped[name]...)
+	}
+	return result
+}
+
+// SortAlbumTracks sorts the provided tracks by disc and track number, keeping those without a number at the end. Tracks
+// of every album are kept together, with the albums ordered as they first appear in the list
+func SortAlbumTracks(attrs []mpd.Attrs) {
+	// albumKey returns a string that identifies the album of the given track
+	albumKey := func(a mpd.Attrs) string {
+		return trackAlbumArtist(a) + pathFieldSeparator + a["Album"]
+	}
+
+	// Remember where each album first appears
+	albumIndex := make(map[string]int)
+	for i, a := range attrs {
+		if _, ok := albumIndex[albumKey(a)]; !ok {
+			albumIndex[albumKey(a)] = i
+		}
+	}
+
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if ai, aj := albumIndex[albumKey(attrs[i])], albumIndex[albumKey(attrs[j])]; ai != aj {
+			return ai < aj
+		}
+		if di, dj := trackNumber(attrs[i]["Disc"]), trackNumber(attrs[j]["Disc"]); di != dj {
+			return di < dj
+		}
+		return trackNumber(attrs[i]["Track"]) < trackNumber(attrs[j]["Track"])
+	})
+}
+
+// albumTrackTitle returns a display title for the given album track. Movements of a work are labelled with the
+// movement's name, since the work itself is displayed in the group header
+func albumTrackTitle(attrs mpd.Attrs) string {
+	switch {
+	case attrs["Work"] != "" && attrs["Movement"] != "":
+		if n := attrs["MovementNumber"]; n != "" {
+			return n + ". " + attrs["Movement"]
+		}
+		return attrs["Movement"]
+	case attrs["Title"] != "":
+		return attrs["Title"]
+	}
+	return path.Base(attrs["file"])
+}
+
+// playlistTrackTitle returns a display title for the given playlist track: its artist and title, if known, otherwise
+// its file name, or the whole URI for streams
+func playlistTrackTitle(attrs mpd.Attrs) string {
+	uri := attrs["file"]
+	switch {
+	case attrs["Title"] != "" && attrs["Artist"] != "":
+		return attrs["Artist"] + " — " + attrs["Title"]
+	case attrs["Title"] != "":
+		return attrs["Title"]
+	case util.IsStreamURI(uri):
+		return uri
+	}
+	return path.Base(uri)
+}
+
+// trackNumber parses the given track or disc number value, which can also be given as "number/total". Returns
+// math.MaxInt32 if the value can't be parsed
+func trackNumber(s string) int {
+	if i := strings.Index(s, "/"); i >= 0 {
+		s = s[:i]
+	}
+	return util.AtoiDef(strings.TrimSpace(s), math.MaxInt32)
+}
+
+// quoteFilterValue quotes and escapes the given string for use as a value in an MPD filter expression
+func quoteFilterValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// UniqueTagValues splits the given multi-value tag values and returns the individual values without duplicates
+// (compared case-insensitively), sorted alphabetically
+func UniqueTagValues(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, s := range values {
+		parts := util.SplitTagValues(s)
+		// Preserve the empty value, it's displayed as "(unknown)"
+		if len(parts) == 0 {
+			parts = []string{""}
+		}
+		for _, v := range parts {
+			if key := strings.ToLower(v); !seen[key] {
+				seen[key] = true
+				result = append(result, v)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i]) < strings.ToLower(result[j])
+	})
+	return result
+}
+
+// isCompilationArtist returns whether the given album artist name denotes a compilation
+func isCompilationArtist(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "various artists", "various", "va", "v.a.":
+		return true
+	}
+	return false
+}
+
+// trackAttr returns the value of the given attribute of a track. Attribute names are compared case-insensitively, since
+// MPD's spelling (eg. "AlbumArtist") can differ from the one in config.MpdTrackAttributes
+func trackAttr(attrs mpd.Attrs, attrID int) string {
+	name := config.MpdTrackAttributes[attrID].AttrName
+	if v, ok := attrs[name]; ok {
+		return v
+	}
+	for k, v := range attrs {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// trackAlbumArtist returns the album artist of the given track, falling back to the track artist the same way MPD does
+func trackAlbumArtist(attrs mpd.Attrs) string {
+	if s := trackAttr(attrs, config.MTAttrAlbumArtist); s != "" {
+		return s
+	}
+	return trackAttr(attrs, config.MTAttrArtist)
+}
+
+// trackGenre returns the (first) genre of the given track
+func trackGenre(attrs mpd.Attrs) string {
+	if genres := util.SplitTagValues(attrs[config.MpdTrackAttributes[config.MTAttrGenre].AttrName]); len(genres) > 0 {
+		return genres[0]
+	}
+	return ""
+}
+
+// FolderPathElements returns a library path leading to the folder containing the given file URI
+func FolderPathElements(uri string) []LibraryPathElement {
+	elements := []LibraryPathElement{NewFilesystemLibElement()}
+	dir := ""
+	for _, name := range strings.Split(path.Dir(uri), "/") {
+		if name == "." || name == "" {
+			continue
+		}
+		dir = path.Join(dir, name)
+		elements = append(elements, &DirLibElement{uri: dir, title: name})
+	}
+	return elements
+}
+
+// dateYear extracts the year from the given date value (such as "1985" or "1985-03-01"), or returns an empty string if
+// the value doesn't start with a year
+func dateYear(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	for _, c := range date[:4] {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return date[:4]
+}
+
+// dateFilter returns an MPD filter expression matching the dates starting with the first prefixLen characters of the
+// given year. An empty year matches tracks without a date
+func dateFilter(attrID int, year string, prefixLen int) string {
+	attrName := config.MpdTrackAttributes[attrID].AttrName
+	if len(year) < prefixLen {
+		return fmt.Sprintf("(%s == \"\")", attrName)
+	}
+	return fmt.Sprintf("(%s =~ %s)", attrName, quoteFilterValue("^"+regexp.QuoteMeta(year[:prefixLen])))
+}
+
+// UniqueElements removes the elements having identical marshalled form from the given list, keeping the first one
+func UniqueElements(elements []LibraryPathElement) []LibraryPathElement {
+	seen := make(map[string]bool)
+	result := elements[:0]
+	for _, e := range elements {
+		if m := MarshalLibPathElement(e); !seen[m] {
+			seen[m] = true
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// PinCompilationElements moves compilation artist elements to the top of the list, keeping the order otherwise intact
+func PinCompilationElements(elements []LibraryPathElement) {
+	isCompilation := func(e LibraryPathElement) bool {
+		ae, ok := e.(*ArtistLibElement)
+		return ok && ae.IsCompilation()
+	}
+	sort.SliceStable(elements, func(i, j int) bool {
+		return isCompilation(elements[i]) && !isCompilation(elements[j])
+	})
+}
+
+// isArtistAttrID returns whether the given attribute ID refers to an artist or album artist
+func isArtistAttrID(attrID int) bool {
+	return attrID == config.MTAttrArtist || attrID == config.MTAttrAlbumArtist
+}
+
+// isArtistAttrName returns whether the given MPD attribute name refers to an artist or album artist
+func isArtistAttrName(name string) bool {
+	return name == config.MpdTrackAttributes[config.MTAttrArtist].AttrName ||
+		name == config.MpdTrackAttributes[config.MTAttrAlbumArtist].AttrName
+}
+
+// SortElementsIgnoringArticles sorts the given elements by their labels case-insensitively, ignoring leading articles
+// (so that "The Beatles" is placed under B)
+func SortElementsIgnoringArticles(elements []LibraryPathElement) {
+	key := func(e LibraryPathElement) string { return strings.ToLower(util.StripLeadingArticle(e.Label())) }
+	sort.SliceStable(elements, func(i, j int) bool {
+		return key(elements[i]) < key(elements[j])
+	})
+}
+
+// ElementCounts holds statistics of the tracks belonging to a library element
+type ElementCounts struct {
+	Tracks   int             // Number of tracks
+	Albums   int             // Number of distinct albums
+	Duration float64         // Total duration of the tracks, in seconds
+	albums   map[string]bool // Keys of the albums counted so far
+}
+
+// CountChildTracks groups the given tracks by the children of the parent element they belong to, and returns the
+// statistics of each child, keyed by its lowercase marshalled form (see elementCountKey)
+func CountChildTracks(parent AttributeHolderParent, tracks []mpd.Attrs) map[string]*ElementCounts {
+	result := make(map[string]*ElementCounts)
+	for _, a := range tracks {
+		// Genres may hold multiple values, and a track counts towards each of them
+		values := []string{trackAttr(a, parent.ChildAttributeID())}
+		switch parent.ChildAttributeID() {
+		case config.MTAttrAlbumArtist:
+			values[0] = trackAlbumArtist(a)
+		case config.MTAttrGenre:
+			if genres := util.SplitTagValues(values[0]); len(genres) > 0 {
+				values = genres
+			}
+		}
+
+		albumKey := trackAlbumArtist(a) + pathFieldSeparator + trackAttr(a, config.MTAttrAlbum)
+		for _, v := range values {
+			child := parent.NewChild(v)
+			if child == nil {
+				continue
+			}
+			key := elementCountKey(child)
+			c, ok := result[key]
+			if !ok {
+				c = &ElementCounts{albums: make(map[string]bool)}
+				result[key] = c
+			}
+			c.Tracks++
+			c.Duration += util.ParseFloatDef(a["duration"], 0)
+			if !c.albums[albumKey] {
+				c.albums[albumKey] = true
+				c.Albums++
+			}
+		}
+	}
+	return result
+}
+
+// SetElementCountDetails sets the details of the given elements to their track counts: the number of tracks and their
+// duration for albums, and the number of albums for anything else
+func SetElementCountDetails(elements []LibraryPathElement, counts map[string]*ElementCounts) {
+	for _, e := range elements {
+		ds, ok := e.(interface{ SetDetails(string) })
+		if !ok {
+			continue
+		}
+		c, ok := counts[elementCountKey(e)]
+		if !ok {
+			continue
+		}
+		if _, ok := e.(*AlbumLibElement); ok {
+			ds.SetDetails(fmt.Sprintf(util.LocalN("%d track, %s", "%d tracks, %s", c.Tracks), c.Tracks, util.FormatSeconds(c.Duration)))
+		} else {
+			ds.SetDetails(fmt.Sprintf(util.LocalN("%d album", "%d albums", c.Albums), c.Albums))
+		}
+	}
+}
+
+// elementCountKey returns the key of the given element in the counts map
+func elementCountKey(e LibraryPathElement) string {
+	return strings.ToLower(MarshalLibPathElement(e))
+}
+
+// SortLibraryAttrs sorts the provided list of MPD Attributes in place according to the given sort order (one of the
+// config.LibrarySortBy* constants). If foldersFirst is true, directories are placed before all other items
+func SortLibraryAttrs(attrs []mpd.Attrs, sortBy string, foldersFirst bool) {
+	// nameOf returns the lowercase name of the item represented by the given attributes
+	nameOf := func(a mpd.Attrs) string {
+		for _, key := range []string{"directory", "file", "playlist"} {
+			if v, ok := a[key]; ok {
+				return strings.ToLower(v)
+			}
+		}
+		return ""
+	}
+
+	sort.SliceStable(attrs, func(i, j int) bool {
+		ai, aj := attrs[i], attrs[j]
+
+		// Put folders first, if needed
+		if foldersFirst {
+			_, di := ai["directory"]
+			_, dj := aj["directory"]
+			if di != dj {
+				return di
+			}
+		}
+
+		// Compare by the requested attribute, if any. Timestamps are in ISO 8601 format so can be compared as strings
+		key, desc := "", false
+		switch sortBy {
+		case config.LibrarySortByModified:
+			key, desc = "Last-Modified", true
+		case config.LibrarySortByDate:
+			key = "Date"
+		}
+		if vi, vj := ai[key], aj[key]; key != "" && vi != vj {
+			switch {
+			// Items lacking the attribute go last
+			case vi == "":
+				return false
+			case vj == "":
+				return true
+			case desc:
+				return vi > vj
+			default:
+				return vi < vj
+			}
+		}
+
+		// Fall back to sorting by name
+		return nameOf(ai) < nameOf(aj)
+	})
+}
+
+// filterFileAttrs returns only those entries in the given list of MPD Attributes that represent files
+func filterFileAttrs(attrs []mpd.Attrs) []mpd.Attrs {
+	result := make([]mpd.Attrs, 0, len(attrs))
+	for _, a := range attrs {
+		if _, ok := a["file"]; ok {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// ExcludeLibraryAttrs returns only those entries in the given list of MPD Attributes whose path has no component
+// matching any of the given shell patterns (such as "*.cue" or "covers"). Patterns are matched case-insensitively
+func ExcludeLibraryAttrs(attrs []mpd.Attrs, patterns []string) []mpd.Attrs {
+	if len(patterns) == 0 {
+		return attrs
+	}
+	result := make([]mpd.Attrs, 0, len(attrs))
+	for _, a := range attrs {
+		uri, ok := a["file"]
+		if !ok {
+			uri, ok = a["directory"]
+		}
+		if !ok {
+			uri = a["playlist"]
+		}
+		if !isExcludedURI(uri, patterns) {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// FilterAttrsByFormat returns only those file entries in the given list of MPD Attributes that match any of the given
+// file types. A file type is either a file extension (such as "flac" or ".mp3") or a word of the audio format badge
+// (such as "24/96" or "DSD64"), and is matched case-insensitively. If no file type is given, all files are returned
+func FilterAttrsByFormat(attrs []mpd.Attrs, types []string) []mpd.Attrs {
+	files := filterFileAttrs(attrs)
+	if len(types) == 0 {
+		return files
+	}
+	result := make([]mpd.Attrs, 0, len(files))
+	for _, a := range files {
+		file := a["file"]
+		words := append(strings.Fields(util.AudioFormatBadge(file, a["Format"])), strings.TrimPrefix(path.Ext(file), "."))
+	TypeLoop:
+		for _, t := range types {
+			t = strings.TrimPrefix(t, ".")
+			for _, w := range words {
+				if strings.EqualFold(t, w) {
+					result = append(result, a)
+					break TypeLoop
+				}
+			}
+		}
+	}
+	return result
+}
+
+// isExcludedURI returns whether any component of the given URI matches any of the given shell patterns
+func isExcludedURI(uri string, patterns []string) bool {
+	for _, name := range strings.Split(strings.ToLower(uri), "/") {
+		for _, p := range patterns {
+			if matched, _ := path.Match(strings.ToLower(p), name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //----------------------------------------------------------------------------------------------------------------------
 // LibraryPath
 //----------------------------------------------------------------------------------------------------------------------
@@ -165,8 +777,12 @@ func (p *LibraryPath) Append(e LibraryPathElement) {
 func (p *LibraryPath) AsFilter(extraElements ...LibraryPathElement) (result []string) {
 	// Iterate all elements, including extras
 	for _, e := range append(p.elements, extraElements...) {
-		// Select only those associated with attributes
-		if ah, oka := e.(AttributeHolder); oka {
+		// Elements providing a filter expression are added as is
+		if fh, okf := e.(FilterHolder); okf {
+			result = append(result, fh.Filter())
+
+		} else if ah, oka := e.(AttributeHolder); oka {
+			// Other elements associated with attributes are added as a name/value pair
 			// For each element, add two elements to the slice: the name and the value
 			result = append(
 				result,
@@ -263,6 +879,7 @@ func (p *LibraryPath) Unmarshal(s string) error {
 type BaseAttrHolder struct {
 	attrID    int
 	attrValue string
+	details   string // Optional details, such as the number of albums
 }
 
 func (h *BaseAttrHolder) AttributeID() int {
@@ -273,6 +890,15 @@ func (h *BaseAttrHolder) AttributeValue() string {
 	return h.attrValue
 }
 
+func (h *BaseAttrHolder) Details() string {
+	return h.details
+}
+
+// SetDetails updates the element's details text
+func (h *BaseAttrHolder) SetDetails(details string) {
+	h.details = details
+}
+
 //----------------------------------------------------------------------------------------------------------------------
 // LevelUpLibElement - a LibraryPathElement that looks as a ".." and is used to navigate to the parent
 //----------------------------------------------------------------------------------------------------------------------
@@ -311,6 +937,56 @@ func (e *LevelUpLibElement) Unmarshal(string) error {
 	return nil
 }
 
+//----------------------------------------------------------------------------------------------------------------------
+// HeaderLibElement - a non-selectable LibraryPathElement that serves as a header for a group of items
+//----------------------------------------------------------------------------------------------------------------------
+
+type HeaderLibElement struct {
+	title   string
+	details string // Optional details, such as the total duration of the tracks above
+}
+
+func NewHeaderLibElement() LibraryPathElement {
+	return &HeaderLibElement{}
+}
+
+func NewHeaderLibElementVal(title string) LibraryPathElement {
+	return &HeaderLibElement{title: title}
+}
+
+func (e *HeaderLibElement) Icon() string {
+	return ""
+}
+
+func (e *HeaderLibElement) Label() string {
+	return e.title
+}
+
+func (e *HeaderLibElement) IsFolder() bool {
+	return false
+}
+
+func (e *HeaderLibElement) IsPlayable() bool {
+	return false
+}
+
+func (e *HeaderLibElement) Prefix() string {
+	return "header"
+}
+
+func (e *HeaderLibElement) Marshal() string {
+	return e.title
+}
+
+func (e *HeaderLibElement) Unmarshal(data string) error {
+	e.title = data
+	return nil
+}
+
+func (e *HeaderLibElement) Details() string {
+	return e.details
+}
+
 //----------------------------------------------------------------------------------------------------------------------
 // FilesystemLibElement
 //----------------------------------------------------------------------------------------------------------------------
@@ -358,8 +1034,9 @@ func (e *FilesystemLibElement) URI() string {
 //----------------------------------------------------------------------------------------------------------------------
 
 type DirLibElement struct {
-	uri   string // URI of the directory
-	title string // Title of the directory
+	uri      string    // URI of the directory
+	title    string    // Title of the directory
+	modified time.Time // Last modification time of the directory
 }
 
 func NewDirLibElement() LibraryPathElement {
@@ -403,14 +1080,21 @@ func (e *DirLibElement) URI() string {
 	return e.uri
 }
 
+func (e *DirLibElement) LastModified() time.Time {
+	return e.modified
+}
+
 //----------------------------------------------------------------------------------------------------------------------
 // FileLibElement
 //----------------------------------------------------------------------------------------------------------------------
 
 type FileLibElement struct {
-	uri    string  // URI of the file
-	title  string  // Title of the track
-	length float64 // Length of the track in seconds
+	uri      string    // URI of the file
+	title    string    // Title of the track
+	number   string    // Track number, if known
+	format   string    // Audio format badge, if known
+	length   float64   // Length of the track in seconds
+	modified time.Time // Last modification time of the file
 }
 
 func NewFileLibElement() LibraryPathElement {
@@ -455,6 +1139,18 @@ func (e *FileLibElement) URI() string {
 	return e.uri
 }
 
+func (e *FileLibElement) LastModified() time.Time {
+	return e.modified
+}
+
+func (e *FileLibElement) Number() string {
+	return e.number
+}
+
+func (e *FileLibElement) AudioFormat() string {
+	return e.format
+}
+
 func (e *FileLibElement) Details() string {
 	if e.length > 0 {
 		return util.FormatSeconds(e.length)
@@ -509,7 +1205,9 @@ func (e *PlaylistsLibElement) NewChild(name string) LibraryPathElement {
 //----------------------------------------------------------------------------------------------------------------------
 
 type PlaylistLibElement struct {
-	name string // Playlist name
+	name     string    // Playlist name
+	modified time.Time // Last modification time of the playlist
+	details  string    // Optional details, such as the number of tracks
 }
 
 func NewPlaylistLibElement() LibraryPathElement {
@@ -529,7 +1227,7 @@ func (e *PlaylistLibElement) Label() string {
 }
 
 func (e *PlaylistLibElement) IsFolder() bool {
-	return false
+	return true
 }
 
 func (e *PlaylistLibElement) IsPlayable() bool {
@@ -557,75 +1255,271 @@ func (e *PlaylistLibElement) PlaylistName() string {
 	return e.name
 }
 
+func (e *PlaylistLibElement) LastModified() time.Time {
+	return e.modified
+}
+
+func (e *PlaylistLibElement) Details() string {
+	return e.details
+}
+
 //----------------------------------------------------------------------------------------------------------------------
-// GenresLibElement
+// PlaylistFolderLibElement
 //----------------------------------------------------------------------------------------------------------------------
 
-type GenresLibElement struct{}
-
-func NewGenresLibElement() LibraryPathElement {
-	return &GenresLibElement{}
+// PlaylistFolderLibElement is a non-selectable LibraryPathElement heading a virtual folder of stored playlists
+type PlaylistFolderLibElement struct {
+	name    string // Folder name
+	details string // Optional details, such as the number of playlists
 }
 
-func (e *GenresLibElement) Icon() string {
-	return "ymuse-genres"
+func NewPlaylistFolderLibElement() LibraryPathElement {
+	return &PlaylistFolderLibElement{}
 }
 
-func (e *GenresLibElement) Label() string {
-	return glib.Local("Genres")
+func (e *PlaylistFolderLibElement) Icon() string {
+	return "folder"
 }
 
-func (e *GenresLibElement) IsFolder() bool {
-	return true
+func (e *PlaylistFolderLibElement) Label() string {
+	return e.name
 }
 
-func (e *GenresLibElement) IsPlayable() bool {
+func (e *PlaylistFolderLibElement) IsFolder() bool {
 	return false
 }
 
-func (e *GenresLibElement) Prefix() string {
-	return "genres"
+func (e *PlaylistFolderLibElement) IsPlayable() bool {
+	return false
 }
 
-func (e *GenresLibElement) Marshal() string {
-	return ""
+func (e *PlaylistFolderLibElement) Prefix() string {
+	return "plfolder"
 }
 
-func (e *GenresLibElement) Unmarshal(string) error {
-	return nil
+func (e *PlaylistFolderLibElement) Marshal() string {
+	return e.name
 }
 
-func (e *GenresLibElement) ChildAttributeID() int {
-	return config.MTAttrGenre
+func (e *PlaylistFolderLibElement) Unmarshal(data string) error {
+	e.name = data
+	return nil
 }
 
-func (e *GenresLibElement) NewChild(value string) LibraryPathElement {
-	return NewGenreLibElementVal(value)
+func (e *PlaylistFolderLibElement) Details() string {
+	return e.details
 }
 
 //----------------------------------------------------------------------------------------------------------------------
-// GenreLibElement
+// RecentLibElement
 //----------------------------------------------------------------------------------------------------------------------
 
-type GenreLibElement struct {
-	BaseAttrHolder
+type RecentLibElement struct{}
+
+func NewRecentLibElement() LibraryPathElement {
+	return &RecentLibElement{}
 }
 
-func NewGenreLibElement() LibraryPathElement {
-	return NewGenreLibElementVal("")
+func (e *RecentLibElement) Icon() string {
+	return "document-open-recent"
 }
 
-func NewGenreLibElementVal(value string) LibraryPathElement {
-	return &GenreLibElement{BaseAttrHolder{attrID: config.MTAttrGenre, attrValue: value}}
+func (e *RecentLibElement) Label() string {
+	return glib.Local("Recently added")
 }
 
-func (e *GenreLibElement) Icon() string {
-	return "ymuse-genre"
+func (e *RecentLibElement) IsFolder() bool {
+	return true
 }
 
-func (e *GenreLibElement) Label() string {
-	if e.attrValue == "" {
-		return glib.Local("(unknown)")
+func (e *RecentLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *RecentLibElement) Prefix() string {
+	return "recent"
+}
+
+func (e *RecentLibElement) Marshal() string {
+	return ""
+}
+
+func (e *RecentLibElement) Unmarshal(string) error {
+	return nil
+}
+
+// Query returns a filter expression selecting tracks modified within the configured number of days
+func (e *RecentLibElement) Query() string {
+	since := time.Now().AddDate(0, 0, -config.GetConfig().LibraryRecentDays)
+	return fmt.Sprintf("(modified-since '%s')", since.UTC().Format(time.RFC3339))
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// SmartPlaylistsLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type SmartPlaylistsLibElement struct{}
+
+func NewSmartPlaylistsLibElement() LibraryPathElement {
+	return &SmartPlaylistsLibElement{}
+}
+
+func (e *SmartPlaylistsLibElement) Icon() string {
+	return "folder-saved-search"
+}
+
+func (e *SmartPlaylistsLibElement) Label() string {
+	return glib.Local("Smart playlists")
+}
+
+func (e *SmartPlaylistsLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *SmartPlaylistsLibElement) IsPlayable() bool {
+	return false
+}
+
+func (e *SmartPlaylistsLibElement) Prefix() string {
+	return "smartlists"
+}
+
+func (e *SmartPlaylistsLibElement) Marshal() string {
+	return ""
+}
+
+func (e *SmartPlaylistsLibElement) Unmarshal(string) error {
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// SmartPlaylistLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type SmartPlaylistLibElement struct {
+	name  string // Smart playlist name
+	query string // Filter expression selecting the tracks
+}
+
+func NewSmartPlaylistLibElement() LibraryPathElement {
+	return &SmartPlaylistLibElement{}
+}
+
+func NewSmartPlaylistLibElementVal(name, query string) LibraryPathElement {
+	return &SmartPlaylistLibElement{name: name, query: query}
+}
+
+func (e *SmartPlaylistLibElement) Icon() string {
+	return "ymuse-playlist"
+}
+
+func (e *SmartPlaylistLibElement) Label() string {
+	return e.name
+}
+
+func (e *SmartPlaylistLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *SmartPlaylistLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *SmartPlaylistLibElement) Prefix() string {
+	return "smartlist"
+}
+
+func (e *SmartPlaylistLibElement) Marshal() string {
+	return e.name + pathFieldSeparator + e.query
+}
+
+func (e *SmartPlaylistLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 2 {
+		return fmt.Errorf("failed to unmarshal SmartPlaylistLibElement: want 2 fields, got %d", len(fields))
+	}
+	e.name = fields[0]
+	e.query = fields[1]
+	return nil
+}
+
+func (e *SmartPlaylistLibElement) Details() string {
+	return e.query
+}
+
+func (e *SmartPlaylistLibElement) Query() string {
+	return e.query
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// GenresLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type GenresLibElement struct{}
+
+func NewGenresLibElement() LibraryPathElement {
+	return &GenresLibElement{}
+}
+
+func (e *GenresLibElement) Icon() string {
+	return "ymuse-genres"
+}
+
+func (e *GenresLibElement) Label() string {
+	return glib.Local("Genres")
+}
+
+func (e *GenresLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *GenresLibElement) IsPlayable() bool {
+	return false
+}
+
+func (e *GenresLibElement) Prefix() string {
+	return "genres"
+}
+
+func (e *GenresLibElement) Marshal() string {
+	return ""
+}
+
+func (e *GenresLibElement) Unmarshal(string) error {
+	return nil
+}
+
+func (e *GenresLibElement) ChildAttributeID() int {
+	return config.MTAttrGenre
+}
+
+func (e *GenresLibElement) NewChild(value string) LibraryPathElement {
+	return NewGenreLibElementVal(value)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// GenreLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type GenreLibElement struct {
+	BaseAttrHolder
+}
+
+func NewGenreLibElement() LibraryPathElement {
+	return NewGenreLibElementVal("")
+}
+
+func NewGenreLibElementVal(value string) LibraryPathElement {
+	return &GenreLibElement{BaseAttrHolder{attrID: config.MTAttrGenre, attrValue: value}}
+}
+
+func (e *GenreLibElement) Icon() string {
+	return "ymuse-genre"
+}
+
+func (e *GenreLibElement) Label() string {
+	if e.attrValue == "" {
+		return glib.Local("(unknown)")
 	}
 	return e.attrValue
 }
@@ -655,8 +1549,20 @@ func (e *GenreLibElement) Unmarshal(data string) error {
 	return nil
 }
 
+// Filter returns an MPD filter expression matching any tracks having the genre among their (possibly multiple) genre
+// values
+func (e *GenreLibElement) Filter() string {
+	attrName := config.MpdTrackAttributes[e.attrID].AttrName
+	if e.attrValue == "" {
+		return fmt.Sprintf("(%s == \"\")", attrName)
+	}
+	sep := "[" + regexp.QuoteMeta(util.TagValueSeparators) + "]"
+	re := `(^|` + sep + `)\s*` + regexp.QuoteMeta(e.attrValue) + `\s*($|` + sep + `)`
+	return fmt.Sprintf("(%s =~ %s)", attrName, quoteFilterValue(re))
+}
+
 func (e *GenreLibElement) ChildAttributeID() int {
-	return config.MTAttrArtist
+	return config.MTAttrAlbumArtist
 }
 
 func (e *GenreLibElement) NewChild(value string) LibraryPathElement {
@@ -702,7 +1608,7 @@ func (e *ArtistsLibElement) Unmarshal(string) error {
 }
 
 func (e *ArtistsLibElement) ChildAttributeID() int {
-	return config.MTAttrArtist
+	return config.MTAttrAlbumArtist
 }
 
 func (e *ArtistsLibElement) NewChild(value string) LibraryPathElement {
@@ -713,6 +1619,8 @@ func (e *ArtistsLibElement) NewChild(value string) LibraryPathElement {
 // ArtistLibElement
 //----------------------------------------------------------------------------------------------------------------------
 
+// ArtistLibElement represents an album artist. MPD falls back to the Artist tag for tracks lacking AlbumArtist, so
+// compilation albums are kept together under their album artist (usually "Various Artists")
 type ArtistLibElement struct {
 	BaseAttrHolder
 }
@@ -722,16 +1630,27 @@ func NewArtistLibElement() LibraryPathElement {
 }
 
 func NewArtistLibElementVal(value string) LibraryPathElement {
-	return &ArtistLibElement{BaseAttrHolder{attrID: config.MTAttrArtist, attrValue: value}}
+	return &ArtistLibElement{BaseAttrHolder{attrID: config.MTAttrAlbumArtist, attrValue: value}}
+}
+
+// IsCompilation returns whether the element represents compilation albums ("Various Artists")
+func (e *ArtistLibElement) IsCompilation() bool {
+	return isCompilationArtist(e.attrValue)
 }
 
 func (e *ArtistLibElement) Icon() string {
+	if e.IsCompilation() {
+		return "ymuse-albums"
+	}
 	return "ymuse-artist"
 }
 
 func (e *ArtistLibElement) Label() string {
-	if e.attrValue == "" {
+	switch {
+	case e.attrValue == "":
 		return glib.Local("(unknown)")
+	case e.IsCompilation():
+		return glib.Local("Various Artists")
 	}
 	return e.attrValue
 }
@@ -769,6 +1688,398 @@ func (e *ArtistLibElement) NewChild(value string) LibraryPathElement {
 	return NewAlbumLibElementVal(value)
 }
 
+//----------------------------------------------------------------------------------------------------------------------
+// ComposersLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type ComposersLibElement struct{}
+
+func NewComposersLibElement() LibraryPathElement {
+	return &ComposersLibElement{}
+}
+
+func (e *ComposersLibElement) Icon() string {
+	return "ymuse-artists"
+}
+
+func (e *ComposersLibElement) Label() string {
+	return glib.Local("Composers")
+}
+
+func (e *ComposersLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *ComposersLibElement) IsPlayable() bool {
+	return false
+}
+
+func (e *ComposersLibElement) Prefix() string {
+	return "composers"
+}
+
+func (e *ComposersLibElement) Marshal() string {
+	return ""
+}
+
+func (e *ComposersLibElement) Unmarshal(string) error {
+	return nil
+}
+
+func (e *ComposersLibElement) ChildAttributeID() int {
+	return config.MTAttrComposer
+}
+
+func (e *ComposersLibElement) NewChild(value string) LibraryPathElement {
+	return NewComposerLibElementVal(value)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// ComposerLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type ComposerLibElement struct {
+	BaseAttrHolder
+}
+
+func NewComposerLibElement() LibraryPathElement {
+	return NewComposerLibElementVal("")
+}
+
+func NewComposerLibElementVal(value string) LibraryPathElement {
+	return &ComposerLibElement{BaseAttrHolder{attrID: config.MTAttrComposer, attrValue: value}}
+}
+
+func (e *ComposerLibElement) Icon() string {
+	return "ymuse-artist"
+}
+
+func (e *ComposerLibElement) Label() string {
+	if e.attrValue == "" {
+		return glib.Local("(unknown)")
+	}
+	return e.attrValue
+}
+
+func (e *ComposerLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *ComposerLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *ComposerLibElement) Prefix() string {
+	return "composer"
+}
+
+func (e *ComposerLibElement) Marshal() string {
+	return e.attrValue
+}
+
+func (e *ComposerLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 1 {
+		return fmt.Errorf("failed to unmarshal ComposerLibElement: want 1 field, got %d", len(fields))
+	}
+	e.attrValue = fields[0]
+	return nil
+}
+
+func (e *ComposerLibElement) ChildAttributeID() int {
+	return config.MTAttrAlbum
+}
+
+func (e *ComposerLibElement) NewChild(value string) LibraryPathElement {
+	return NewAlbumLibElementVal(value)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// PerformersLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type PerformersLibElement struct{}
+
+func NewPerformersLibElement() LibraryPathElement {
+	return &PerformersLibElement{}
+}
+
+func (e *PerformersLibElement) Icon() string {
+	return "ymuse-artists"
+}
+
+func (e *PerformersLibElement) Label() string {
+	return glib.Local("Performers")
+}
+
+func (e *PerformersLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *PerformersLibElement) IsPlayable() bool {
+	return false
+}
+
+func (e *PerformersLibElement) Prefix() string {
+	return "performers"
+}
+
+func (e *PerformersLibElement) Marshal() string {
+	return ""
+}
+
+func (e *PerformersLibElement) Unmarshal(string) error {
+	return nil
+}
+
+func (e *PerformersLibElement) ChildAttributeID() int {
+	return config.MTAttrPerformer
+}
+
+func (e *PerformersLibElement) NewChild(value string) LibraryPathElement {
+	return NewPerformerLibElementVal(value)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// PerformerLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type PerformerLibElement struct {
+	BaseAttrHolder
+}
+
+func NewPerformerLibElement() LibraryPathElement {
+	return NewPerformerLibElementVal("")
+}
+
+func NewPerformerLibElementVal(value string) LibraryPathElement {
+	return &PerformerLibElement{BaseAttrHolder{attrID: config.MTAttrPerformer, attrValue: value}}
+}
+
+func (e *PerformerLibElement) Icon() string {
+	return "ymuse-artist"
+}
+
+func (e *PerformerLibElement) Label() string {
+	if e.attrValue == "" {
+		return glib.Local("(unknown)")
+	}
+	return e.attrValue
+}
+
+func (e *PerformerLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *PerformerLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *PerformerLibElement) Prefix() string {
+	return "performer"
+}
+
+func (e *PerformerLibElement) Marshal() string {
+	return e.attrValue
+}
+
+func (e *PerformerLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 1 {
+		return fmt.Errorf("failed to unmarshal PerformerLibElement: want 1 field, got %d", len(fields))
+	}
+	e.attrValue = fields[0]
+	return nil
+}
+
+func (e *PerformerLibElement) ChildAttributeID() int {
+	return config.MTAttrAlbum
+}
+
+func (e *PerformerLibElement) NewChild(value string) LibraryPathElement {
+	return NewAlbumLibElementVal(value)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// YearsLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type YearsLibElement struct{}
+
+func NewYearsLibElement() LibraryPathElement {
+	return &YearsLibElement{}
+}
+
+func (e *YearsLibElement) Icon() string {
+	return "x-office-calendar"
+}
+
+func (e *YearsLibElement) Label() string {
+	return glib.Local("Years")
+}
+
+func (e *YearsLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *YearsLibElement) IsPlayable() bool {
+	return false
+}
+
+func (e *YearsLibElement) Prefix() string {
+	return "years"
+}
+
+func (e *YearsLibElement) Marshal() string {
+	return ""
+}
+
+func (e *YearsLibElement) Unmarshal(string) error {
+	return nil
+}
+
+func (e *YearsLibElement) ChildAttributeID() int {
+	return config.MTAttrYear
+}
+
+func (e *YearsLibElement) NewChild(value string) LibraryPathElement {
+	// Roll the date up into a decade
+	if year := dateYear(value); year != "" {
+		return NewDecadeLibElementVal(year[:3] + "0")
+	}
+	return NewDecadeLibElementVal("")
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// DecadeLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type DecadeLibElement struct {
+	BaseAttrHolder
+}
+
+func NewDecadeLibElement() LibraryPathElement {
+	return NewDecadeLibElementVal("")
+}
+
+func NewDecadeLibElementVal(value string) LibraryPathElement {
+	return &DecadeLibElement{BaseAttrHolder{attrID: config.MTAttrYear, attrValue: value}}
+}
+
+func (e *DecadeLibElement) Icon() string {
+	return "x-office-calendar"
+}
+
+func (e *DecadeLibElement) Label() string {
+	if e.attrValue == "" {
+		return glib.Local("(unknown)")
+	}
+	return fmt.Sprintf(glib.Local("%ss"), e.attrValue)
+}
+
+func (e *DecadeLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *DecadeLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *DecadeLibElement) Prefix() string {
+	return "decade"
+}
+
+func (e *DecadeLibElement) Marshal() string {
+	return e.attrValue
+}
+
+func (e *DecadeLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 1 {
+		return fmt.Errorf("failed to unmarshal DecadeLibElement: want 1 field, got %d", len(fields))
+	}
+	e.attrValue = fields[0]
+	return nil
+}
+
+// Filter returns an MPD filter expression matching any tracks dated within the decade
+func (e *DecadeLibElement) Filter() string {
+	return dateFilter(e.attrID, e.attrValue, 3)
+}
+
+func (e *DecadeLibElement) ChildAttributeID() int {
+	return config.MTAttrYear
+}
+
+func (e *DecadeLibElement) NewChild(value string) LibraryPathElement {
+	return NewYearLibElementVal(dateYear(value))
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// YearLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type YearLibElement struct {
+	BaseAttrHolder
+}
+
+func NewYearLibElement() LibraryPathElement {
+	return NewYearLibElementVal("")
+}
+
+func NewYearLibElementVal(value string) LibraryPathElement {
+	return &YearLibElement{BaseAttrHolder{attrID: config.MTAttrYear, attrValue: value}}
+}
+
+func (e *YearLibElement) Icon() string {
+	return "x-office-calendar"
+}
+
+func (e *YearLibElement) Label() string {
+	if e.attrValue == "" {
+		return glib.Local("(unknown)")
+	}
+	return e.attrValue
+}
+
+func (e *YearLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *YearLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *YearLibElement) Prefix() string {
+	return "year"
+}
+
+func (e *YearLibElement) Marshal() string {
+	return e.attrValue
+}
+
+func (e *YearLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 1 {
+		return fmt.Errorf("failed to unmarshal YearLibElement: want 1 field, got %d", len(fields))
+	}
+	e.attrValue = fields[0]
+	return nil
+}
+
+// Filter returns an MPD filter expression matching any tracks dated within the year
+func (e *YearLibElement) Filter() string {
+	return dateFilter(e.attrID, e.attrValue, 4)
+}
+
+func (e *YearLibElement) ChildAttributeID() int {
+	return config.MTAttrAlbum
+}
+
+func (e *YearLibElement) NewChild(value string) LibraryPathElement {
+	return NewAlbumLibElementVal(value)
+}
+
 //----------------------------------------------------------------------------------------------------------------------
 // AlbumsLibElement
 //----------------------------------------------------------------------------------------------------------------------
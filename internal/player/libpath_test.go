@@ -0,0 +1,431 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"reflect"
+	"testing"
+)
+
+func TestSortLibraryAttrs(t *testing.T) {
+	attrs := func() []mpd.Attrs {
+		return []mpd.Attrs{
+			{"file": "b.mp3", "Last-Modified": "2020-03-01T10:00:00Z", "Date": "1999"},
+			{"directory": "Zeta", "Last-Modified": "2020-05-01T10:00:00Z"},
+			{"file": "A.mp3", "Last-Modified": "2020-01-01T10:00:00Z"},
+			{"playlist": "c", "Last-Modified": "2020-04-01T10:00:00Z"},
+			{"directory": "alpha", "Last-Modified": "2020-02-01T10:00:00Z"},
+			{"file": "d.mp3", "Date": "1975"},
+		}
+	}
+	names := func(attrs []mpd.Attrs) []string {
+		var r []string
+		for _, a := range attrs {
+			r = append(r, a["directory"]+a["file"]+a["playlist"])
+		}
+		return r
+	}
+	tests := []struct {
+		name         string
+		sortBy       string
+		foldersFirst bool
+		want         []string
+	}{
+		{"by name", config.LibrarySortByName, false, []string{"A.mp3", "alpha", "b.mp3", "c", "d.mp3", "Zeta"}},
+		{"by name, folders first", config.LibrarySortByName, true, []string{"alpha", "Zeta", "A.mp3", "b.mp3", "c", "d.mp3"}},
+		{"by modified", config.LibrarySortByModified, false, []string{"Zeta", "c", "b.mp3", "alpha", "A.mp3", "d.mp3"}},
+		{"by modified, folders first", config.LibrarySortByModified, true, []string{"Zeta", "alpha", "c", "b.mp3", "A.mp3", "d.mp3"}},
+		{"by date", config.LibrarySortByDate, false, []string{"d.mp3", "b.mp3", "A.mp3", "alpha", "c", "Zeta"}},
+		{"by date, folders first", config.LibrarySortByDate, true, []string{"alpha", "Zeta", "d.mp3", "b.mp3", "A.mp3", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := attrs()
+			SortLibraryAttrs(a, tt.sortBy, tt.foldersFirst)
+			if got := names(a); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortLibraryAttrs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortLibraryAttrsPlaylists(t *testing.T) {
+	// Attributes as returned by ListPlaylists()
+	attrs := func() []mpd.Attrs {
+		return []mpd.Attrs{
+			{"playlist": "rock", "Last-Modified": "2020-02-01T10:00:00Z"},
+			{"playlist": "Ambient", "Last-Modified": "2020-03-01T10:00:00Z"},
+			{"playlist": "jazz", "Last-Modified": "2020-01-01T10:00:00Z"},
+		}
+	}
+	tests := []struct {
+		name   string
+		sortBy string
+		want   []string
+	}{
+		{"by name", config.LibrarySortByName, []string{"Ambient", "jazz", "rock"}},
+		{"by modified", config.LibrarySortByModified, []string{"Ambient", "rock", "jazz"}},
+		{"by date falls back to name", config.LibrarySortByDate, []string{"Ambient", "jazz", "rock"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := attrs()
+			SortLibraryAttrs(a, tt.sortBy, false)
+			if got := util.MapAttrsToSlice(a, "playlist"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortLibraryAttrs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinCompilationElements(t *testing.T) {
+	elements := []LibraryPathElement{
+		NewArtistLibElementVal("ABBA"),
+		NewArtistLibElementVal("Beatles"),
+		NewArtistLibElementVal("Various Artists"),
+		NewArtistLibElementVal("Queen"),
+		NewArtistLibElementVal("VA"),
+	}
+	PinCompilationElements(elements)
+	var got []string
+	for _, e := range elements {
+		got = append(got, e.(AttributeHolder).AttributeValue())
+	}
+	want := []string{"Various Artists", "VA", "ABBA", "Beatles", "Queen"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PinCompilationElements() = %v, want %v", got, want)
+	}
+}
+
+func TestSortElementsIgnoringArticles(t *testing.T) {
+	elements := []LibraryPathElement{
+		NewArtistLibElementVal("ABBA"),
+		NewArtistLibElementVal("The Beatles"),
+		NewArtistLibElementVal("a-ha"),
+		NewArtistLibElementVal("Queen"),
+		NewArtistLibElementVal("The Cure"),
+		NewArtistLibElementVal("Beastie Boys"),
+	}
+	SortElementsIgnoringArticles(elements)
+	var got []string
+	for _, e := range elements {
+		got = append(got, e.(AttributeHolder).AttributeValue())
+	}
+	want := []string{"a-ha", "ABBA", "Beastie Boys", "The Beatles", "The Cure", "Queen"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortElementsIgnoringArticles() = %v, want %v", got, want)
+	}
+}
+
+func TestAlbumTracksToElements(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"file": "c.flac", "Track": "3/4", "Title": "Symphony No. 5: II", "Work": "Symphony No. 5", "Movement": "Andante con moto", "MovementNumber": "2"},
+		{"file": "intro.flac", "Title": "Bonus"},
+		{"file": "a.flac", "Track": "1", "Title": "Overture"},
+		{"file": "b.flac", "Track": "2/4", "Title": "Symphony No. 5: I", "Work": "Symphony No. 5", "Movement": "Allegro con brio", "MovementNumber": "1"},
+		{"directory": "ignored"},
+	}
+	var got []string
+	for _, e := range AlbumTracksToElements(attrs) {
+		s := e.Prefix() + ":"
+		if nh, ok := e.(NumberHolder); ok && nh.Number() != "" {
+			s += nh.Number() + ":"
+		}
+		got = append(got, s+e.Label())
+	}
+	want := []string{
+		"file:1:Overture",
+		"header:Symphony No. 5",
+		"file:2:1. Allegro con brio",
+		"file:3:2. Andante con moto",
+		"file:Bonus",
+		"header:4 tracks",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AlbumTracksToElements() = %v, want %v", got, want)
+	}
+}
+
+func TestPlaylistTracksToElements(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"file": "b/2.flac", "Artist": "ABBA", "Title": "Waterloo", "duration": "168"},
+		{"file": "http://example.com/stream"},
+		{"file": "a/1.flac", "Title": "Intro", "duration": "12.5"},
+		{"file": "b/2.flac", "duration": "168"},
+	}
+	var got []string
+	for _, e := range PlaylistTracksToElements(attrs) {
+		s := e.Prefix() + ":"
+		if nh, ok := e.(NumberHolder); ok {
+			s += nh.Number() + ":"
+		}
+		got = append(got, s+e.Label()+":"+e.(DetailsHolder).Details())
+	}
+	want := []string{
+		"file:1:ABBA — Waterloo:2:48",
+		"file:2:http://example.com/stream:",
+		"file:3:Intro:0:12",
+		"file:4:2.flac:2:48",
+		"header:4 tracks:5:48",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PlaylistTracksToElements() = %v, want %v", got, want)
+	}
+	if got := PlaylistTracksToElements(nil); len(got) != 0 {
+		t.Errorf("PlaylistTracksToElements(nil) returned %d elements, want 0", len(got))
+	}
+}
+
+func TestPlaylistPreview(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"file": "b/2.flac", "Artist": "ABBA", "Title": "Waterloo", "duration": "168"},
+		{"file": "http://example.com/stream"},
+		{"file": "a/1.flac", "Title": "Intro", "duration": "12.5"},
+	}
+	tests := []struct {
+		name        string
+		maxTracks   int
+		wantSummary string
+		wantLines   []string
+	}{
+		{"all", 5, "3 tracks, 3:00", []string{"1. ABBA — Waterloo (2:48)", "2. http://example.com/stream", "3. Intro (0:12)"}},
+		{"limited", 1, "3 tracks, 3:00", []string{"1. ABBA — Waterloo (2:48)", "…and 2 more"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, lines := PlaylistPreview(attrs, tt.maxTracks)
+			if summary != tt.wantSummary || !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("PlaylistPreview() = (%v, %v), want (%v, %v)", summary, lines, tt.wantSummary, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestGroupPlaylistElements(t *testing.T) {
+	elements := []LibraryPathElement{
+		NewPlaylistLibElementName("d"),
+		NewPlaylistLibElementName("c"),
+		NewPlaylistLibElementName("b"),
+		NewPlaylistLibElementName("a"),
+	}
+	folders := map[string]string{"a": "rock", "c": "Jazz", "d": "rock", "x": "other"}
+	var got []string
+	for _, e := range GroupPlaylistElements(elements, folders) {
+		if fe, ok := e.(*PlaylistFolderLibElement); ok {
+			got = append(got, "["+fe.Label()+", "+fe.Details()+"]")
+		} else {
+			got = append(got, e.Label())
+		}
+	}
+	want := []string{"b", "[Jazz, 1 playlist]", "c", "[rock, 2 playlists]", "d", "a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupPlaylistElements() = %v, want %v", got, want)
+	}
+}
+
+func TestUniqueTagValues(t *testing.T) {
+	got := UniqueTagValues([]string{"Rock; Pop", "", "jazz", "pop/Soul", "Jazz"})
+	want := []string{"", "jazz", "Pop", "Rock", "Soul"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UniqueTagValues() = %v, want %v", got, want)
+	}
+}
+
+func TestGenreLibElement_Filter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"unknown", "", `(Genre == "")`},
+		{"plain", "Rock", `(Genre =~ "(^|[;/\\|])\\s*Rock\\s*($|[;/\\|])")`},
+		{"special chars", "C++", `(Genre =~ "(^|[;/\\|])\\s*C\\+\\+\\s*($|[;/\\|])")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewGenreLibElementVal(tt.value).(FilterHolder).Filter(); got != tt.want {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartPlaylistLibElement_Marshal(t *testing.T) {
+	e := NewSmartPlaylistLibElementVal("Jazz", `(Genre == "Jazz")`)
+	got, err := UnmarshalLibPathElement(MarshalLibPathElement(e))
+	if err != nil {
+		t.Fatalf("UnmarshalLibPathElement() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Errorf("UnmarshalLibPathElement() = %v, want %v", got, e)
+	}
+	if q := got.(QueryHolder).Query(); q != `(Genre == "Jazz")` {
+		t.Errorf("Query() = %v, want %v", q, `(Genre == "Jazz")`)
+	}
+}
+
+func TestYearsLibElement_NewChild(t *testing.T) {
+	years := NewYearsLibElement().(AttributeHolderParent)
+	var got []string
+	for _, e := range UniqueElements([]LibraryPathElement{
+		years.NewChild(""),
+		years.NewChild("1983"),
+		years.NewChild("1989-05-01"),
+		years.NewChild("1991"),
+		years.NewChild("n/a"),
+	}) {
+		got = append(got, e.(FilterHolder).Filter())
+	}
+	want := []string{`(Date == "")`, `(Date =~ "^198")`, `(Date =~ "^199")`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewChild() filters = %v, want %v", got, want)
+	}
+}
+
+func TestSortAlbumTracks(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"file": "b/2-1.flac", "Album": "B", "Disc": "2", "Track": "1"},
+		{"file": "a/2.flac", "Album": "A", "Track": "2"},
+		{"file": "b/1-2.flac", "Album": "B", "Disc": "1/2", "Track": "2"},
+		{"file": "a/1.flac", "Album": "A", "Track": "1"},
+		{"file": "b/1-1.flac", "Album": "B", "Disc": "1/2", "Track": "1"},
+	}
+	SortAlbumTracks(attrs)
+	got := util.MapAttrsToSlice(attrs, "file")
+	want := []string{"b/1-1.flac", "b/1-2.flac", "b/2-1.flac", "a/1.flac", "a/2.flac"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortAlbumTracks() = %v, want %v", got, want)
+	}
+}
+
+func TestExcludeLibraryAttrs(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"directory": "a/Covers"},
+		{"directory": "a/b"},
+		{"file": "a/b/01.flac"},
+		{"file": "a/b/album.CUE"},
+		{"file": "a/covers/front.flac"},
+		{"playlist": "a/b/list.m3u"},
+	}
+	got := ExcludeLibraryAttrs(attrs, []string{"*.cue", "covers"})
+	var uris []string
+	for _, a := range got {
+		uris = append(uris, a["directory"]+a["file"]+a["playlist"])
+	}
+	want := []string{"a/b", "a/b/01.flac", "a/b/list.m3u"}
+	if !reflect.DeepEqual(uris, want) {
+		t.Errorf("ExcludeLibraryAttrs() = %v, want %v", uris, want)
+	}
+	if got := ExcludeLibraryAttrs(attrs, nil); len(got) != len(attrs) {
+		t.Errorf("ExcludeLibraryAttrs() with no patterns returned %d entries, want %d", len(got), len(attrs))
+	}
+}
+
+func TestFilterAttrsByFormat(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"directory": "a"},
+		{"file": "a/1.flac", "Format": "44100:16:2"},
+		{"file": "a/1.mp3", "Format": "44100:24:2"},
+		{"file": "a/2.FLAC", "Format": "96000:24:2"},
+		{"file": "a/3.dsf", "Format": "dsd64:2"},
+		{"playlist": "a/list.m3u"},
+	}
+	tests := []struct {
+		types []string
+		want  []string
+	}{
+		{nil, []string{"a/1.flac", "a/1.mp3", "a/2.FLAC", "a/3.dsf"}},
+		{[]string{"flac"}, []string{"a/1.flac", "a/2.FLAC"}},
+		{[]string{".MP3", "dsf"}, []string{"a/1.mp3", "a/3.dsf"}},
+		{[]string{"24/96", "dsd64"}, []string{"a/2.FLAC", "a/3.dsf"}},
+		{[]string{"ogg"}, nil},
+	}
+	for _, tt := range tests {
+		var uris []string
+		for _, a := range FilterAttrsByFormat(attrs, tt.types) {
+			uris = append(uris, a["file"])
+		}
+		if !reflect.DeepEqual(uris, tt.want) {
+			t.Errorf("FilterAttrsByFormat(%v) = %v, want %v", tt.types, uris, tt.want)
+		}
+	}
+}
+
+func TestCountChildTracks(t *testing.T) {
+	tracks := []mpd.Attrs{
+		{"file": "a/1.flac", "AlbumArtist": "ABBA", "Album": "Arrival", "Genre": "Pop", "duration": "180"},
+		{"file": "a/2.flac", "AlbumArtist": "ABBA", "Album": "Arrival", "Genre": "Pop; Disco", "duration": "200"},
+		{"file": "b/1.flac", "AlbumArtist": "ABBA", "Album": "Waterloo", "Genre": "Pop", "duration": "170.5"},
+		{"file": "c/1.flac", "Artist": "Queen", "Album": "Jazz", "Genre": "Rock", "duration": "210"},
+	}
+	type count struct {
+		tracks, albums int
+		duration       float64
+	}
+	tests := []struct {
+		name   string
+		parent AttributeHolderParent
+		want   map[LibraryPathElement]count
+	}{
+		{
+			name:   "artists",
+			parent: NewArtistsLibElement().(AttributeHolderParent),
+			want: map[LibraryPathElement]count{
+				NewArtistLibElementVal("ABBA"):  {3, 2, 550.5},
+				NewArtistLibElementVal("Queen"): {1, 1, 210},
+			},
+		},
+		{
+			name:   "albums",
+			parent: NewArtistLibElementVal("ABBA").(AttributeHolderParent),
+			want: map[LibraryPathElement]count{
+				NewAlbumLibElementVal("Arrival"):  {2, 1, 380},
+				NewAlbumLibElementVal("Waterloo"): {1, 1, 170.5},
+				NewAlbumLibElementVal("Jazz"):     {1, 1, 210},
+			},
+		},
+		{
+			name:   "genres",
+			parent: NewGenresLibElement().(AttributeHolderParent),
+			want: map[LibraryPathElement]count{
+				NewGenreLibElementVal("pop"):   {3, 2, 550.5},
+				NewGenreLibElementVal("Disco"): {1, 1, 200},
+				NewGenreLibElementVal("Rock"):  {1, 1, 210},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := CountChildTracks(tt.parent, tracks)
+			if len(counts) != len(tt.want) {
+				t.Errorf("CountChildTracks() returned %d counts, want %d", len(counts), len(tt.want))
+			}
+			for e, want := range tt.want {
+				c, ok := counts[elementCountKey(e)]
+				if !ok {
+					t.Errorf("CountChildTracks() has no count for %s", MarshalLibPathElement(e))
+					continue
+				}
+				if got := (count{c.Tracks, c.Albums, c.Duration}); got != want {
+					t.Errorf("CountChildTracks() for %s = %v, want %v", MarshalLibPathElement(e), got, want)
+				}
+			}
+		})
+	}
+}
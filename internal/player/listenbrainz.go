@@ -0,0 +1,235 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+)
+
+const (
+	listenBrainzURL       = "https://api.listenbrainz.org" // Base URL of the ListenBrainz API
+	listenBrainzBatchSize = 100                            // Maximum number of listens submitted in one request
+)
+
+// errListenRejected is returned when ListenBrainz rejects submitted listens as invalid, so that resubmitting them is
+// pointless
+var errListenRejected = errors.New("listens rejected")
+
+// Listen represents a single track play to be submitted to ListenBrainz
+type Listen struct {
+	ListenedAt int64  // Unix time the track has started playing
+	Artist     string // Track's artist
+	Title      string // Track's title
+	Release    string // Track's album, optional
+}
+
+// ListenBrainz submits listens to ListenBrainz, keeping the ones that couldn't be submitted (eg. while offline) in a
+// file for retrying later. Can be used from any goroutine
+type ListenBrainz struct {
+	baseURL    string     // Base URL of the ListenBrainz API
+	fileName   string     // Full path of the file with pending listens
+	pending    []Listen   // Listens still to be submitted, oldest first
+	mutex      sync.Mutex // Mutex guarding pending
+	flushMutex sync.Mutex // Mutex making sure only one flush runs at a time
+}
+
+// NewListenBrainz creates a new ListenBrainz instance and loads the listens pending submission
+func NewListenBrainz() *ListenBrainz {
+	lb := &ListenBrainz{
+		baseURL:  listenBrainzURL,
+		fileName: path.Join(glib.GetUserDataDir(), "ymuse", "listenbrainz-queue.jsonl"),
+	}
+	lb.load()
+	return lb
+}
+
+// Add queues the given listen for submission
+func (lb *ListenBrainz) Add(l Listen) error {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	lb.pending = append(lb.pending, l)
+	return lb.save()
+}
+
+// Pending returns the number of listens still to be submitted
+func (lb *ListenBrainz) Pending() int {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	return len(lb.pending)
+}
+
+// Flush submits the pending listens, in batches, using the given user token. Listens ListenBrainz rejects as invalid
+// are dropped, other failures leave the remaining listens queued
+func (lb *ListenBrainz) Flush(token string) error {
+	if token == "" {
+		return nil
+	}
+	lb.flushMutex.Lock()
+	defer lb.flushMutex.Unlock()
+
+	for {
+		// Take the next batch
+		lb.mutex.Lock()
+		batch := lb.pending
+		if len(batch) > listenBrainzBatchSize {
+			batch = batch[:listenBrainzBatchSize]
+		}
+		batch = append([]Listen(nil), batch...)
+		lb.mutex.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		// Submit it
+		if err := submitListens(lb.baseURL, token, batch); errors.Is(err, errListenRejected) {
+			log.Warningf("Dropping %d listen(s): %v", len(batch), err)
+		} else if err != nil {
+			return err
+		}
+
+		// Remove the batch from the queue
+		lb.mutex.Lock()
+		lb.pending = lb.pending[len(batch):]
+		err := lb.save()
+		lb.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// load reads the pending listens from the queue file
+func (lb *ListenBrainz) load() {
+	file, err := os.Open(lb.fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errCheck(err, "Couldn't open ListenBrainz queue file")
+		}
+		return
+	}
+	defer file.Close()
+
+	// Each line is a JSON-encoded listen
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var l Listen
+		if !errCheck(json.Unmarshal(scanner.Bytes(), &l), "json.Unmarshal() failed") {
+			lb.pending = append(lb.pending, l)
+		}
+	}
+	errCheck(scanner.Err(), "Failed to read ListenBrainz queue file")
+	log.Debugf("Loaded %d pending listens", len(lb.pending))
+}
+
+// save writes out the pending listens into the queue file, removing the file if there are none. Must be called with
+// the mutex locked
+func (lb *ListenBrainz) save() error {
+	if len(lb.pending) == 0 {
+		if err := os.Remove(lb.fileName); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Serialise the listens
+	var buf bytes.Buffer
+	for _, l := range lb.pending {
+		data, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(path.Dir(lb.fileName), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(lb.fileName, buf.Bytes(), 0600)
+}
+
+// submitListens posts the given listens to the ListenBrainz API at the given base URL on behalf of the user with the
+// given token
+func submitListens(baseURL, token string, listens []Listen) error {
+	// Compose the payload. A single listen has its own type
+	type trackMetadata struct {
+		ArtistName     string            `json:"artist_name"`
+		TrackName      string            `json:"track_name"`
+		ReleaseName    string            `json:"release_name,omitempty"`
+		AdditionalInfo map[string]string `json:"additional_info"`
+	}
+	type payloadItem struct {
+		ListenedAt    int64         `json:"listened_at"`
+		TrackMetadata trackMetadata `json:"track_metadata"`
+	}
+	body := struct {
+		ListenType string        `json:"listen_type"`
+		Payload    []payloadItem `json:"payload"`
+	}{ListenType: "import"}
+	if len(listens) == 1 {
+		body.ListenType = "single"
+	}
+	for _, l := range listens {
+		body.Payload = append(body.Payload, payloadItem{
+			ListenedAt: l.ListenedAt,
+			TrackMetadata: trackMetadata{
+				ArtistName:  l.Artist,
+				TrackName:   l.Title,
+				ReleaseName: l.Release,
+				AdditionalInfo: map[string]string{
+					"submission_client":         config.AppMetadata.Name,
+					"submission_client_version": config.AppMetadata.Version,
+				},
+			},
+		})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	// Run the request
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/1/submit-listens", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := util.HTTPDo(req)
+
+	// Check the response. Bad requests mean the listens themselves are rejected
+	var httpErr *util.HTTPError
+	switch {
+	case err == nil:
+		return resp.Body.Close()
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", errListenRejected, httpErr.Body)
+	default:
+		return err
+	}
+}
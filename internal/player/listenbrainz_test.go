@@ -0,0 +1,115 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestListenBrainz_Flush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-listenbrainz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Mock ListenBrainz API: fails while offline, rejects listens without a title
+	offline := true
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/submit-listens" || r.Method != http.MethodPost || r.Header.Get("Authorization") != "Token secret" {
+			http.Error(w, "unexpected request", http.StatusUnauthorized)
+			return
+		}
+		if offline {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			ListenType string `json:"listen_type"`
+			Payload    []struct {
+				ListenedAt    int64 `json:"listened_at"`
+				TrackMetadata struct {
+					ArtistName string `json:"artist_name"`
+					TrackName  string `json:"track_name"`
+				} `json:"track_metadata"`
+			} `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, p := range body.Payload {
+			if p.TrackMetadata.TrackName == "" {
+				http.Error(w, "track_name missing", http.StatusBadRequest)
+				return
+			}
+		}
+		for _, p := range body.Payload {
+			received = append(received, body.ListenType+":"+p.TrackMetadata.TrackName)
+		}
+	}))
+	defer server.Close()
+
+	fileName := path.Join(dir, "ymuse", "listenbrainz-queue.jsonl")
+	lb := &ListenBrainz{baseURL: server.URL, fileName: fileName}
+	for _, title := range []string{"One", "Two"} {
+		if err := lb.Add(Listen{ListenedAt: 1600000000, Artist: "A", Title: title}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	// Without a token nothing is submitted
+	if err := lb.Flush(""); err != nil || lb.Pending() != 2 {
+		t.Fatalf("Flush(\"\") = %v, pending %d, want nil, 2", err, lb.Pending())
+	}
+
+	// Offline: the listens stay queued, also after a reload
+	if err := lb.Flush("secret"); err == nil {
+		t.Fatal("Flush() succeeded while offline, want error")
+	}
+	lb = &ListenBrainz{baseURL: server.URL, fileName: fileName}
+	lb.load()
+	if lb.Pending() != 2 {
+		t.Fatalf("Pending() after reload = %d, want 2", lb.Pending())
+	}
+
+	// Online: the listens are submitted in one go and the queue file is removed
+	offline = false
+	if err := lb.Flush("secret"); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if lb.Pending() != 0 || len(received) != 2 || received[0] != "import:One" || received[1] != "import:Two" {
+		t.Fatalf("pending %d, received %v, want all submitted", lb.Pending(), received)
+	}
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("queue file still exists: %v", err)
+	}
+
+	// Rejected listens are dropped
+	if err := lb.Add(Listen{ListenedAt: 1600000000, Artist: "A"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := lb.Flush("secret"); err != nil || lb.Pending() != 0 {
+		t.Errorf("Flush() = %v, pending %d, want nil, 0", err, lb.Pending())
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/generated"
+	"github.com/yktoo/ymuse/internal/util"
+	"time"
+)
+
+const listeningStatsTopSize = 10 // Number of items in each top list
+
+// ListeningStats represents the listening statistics dialog
+type ListeningStats struct {
+	ListeningStatsDialog         *gtk.MessageDialog
+	ListeningStatsPeriodComboBox *gtk.ComboBoxText
+	ListeningStatsGrid           *gtk.Grid
+
+	history *PlayHistory // Play history to collect statistics from
+	grid    *propertyGrid
+}
+
+// ListeningStatsDialog creates, shows and disposes of a listening statistics dialog instance
+func ListeningStatsDialog(parent gtk.IWindow, history *PlayHistory) {
+	// Load the dialog layout and map the widgets
+	d := &ListeningStats{history: history}
+	builder, err := NewBuilder(generated.GetListeningStatsGlade())
+	if err == nil {
+		err = builder.BindWidgets(d)
+	}
+
+	// Check for errors
+	if errCheck(err, "ListeningStatsDialog(): failed to initialise dialog") {
+		util.ErrorDialog(parent, fmt.Sprint(glib.Local("Failed to load UI widgets"), err))
+		return
+	}
+	defer d.ListeningStatsDialog.Destroy()
+	d.grid = &propertyGrid{grid: d.ListeningStatsGrid}
+
+	// Map the handlers to callback functions
+	builder.ConnectSignals(map[string]interface{}{
+		"on_ListeningStatsPeriodComboBox_changed": d.update,
+	})
+
+	// Set up and show the dialog
+	d.update()
+	d.ListeningStatsDialog.SetTransientFor(parent)
+	d.ListeningStatsDialog.ShowAll()
+	d.ListeningStatsDialog.Run()
+}
+
+// update repopulates the statistics for the selected period
+func (d *ListeningStats) update() {
+	// Determine the start of the period
+	var since time.Time
+	switch d.ListeningStatsPeriodComboBox.GetActiveID() {
+	case "week":
+		since = time.Now().AddDate(0, 0, -7)
+	case "month":
+		since = time.Now().AddDate(0, 0, -30)
+	case "year":
+		since = time.Now().AddDate(0, 0, -365)
+	}
+	stats := d.history.Stats(since, listeningStatsTopSize)
+
+	// Summary section
+	d.grid.clear()
+	d.grid.addHeader(glib.Local("Summary"))
+	d.grid.addProperty(glib.Local("Tracks played"), fmt.Sprint(stats.Plays))
+	d.grid.addProperty(glib.Local("Listening time"), util.FormatSeconds(stats.TotalTime))
+
+	// Top lists
+	d.addTop(glib.Local("Top artists"), stats.TopArtists)
+	d.addTop(glib.Local("Top albums"), stats.TopAlbums)
+	d.addTop(glib.Local("Top tracks"), stats.TopTracks)
+	d.ListeningStatsGrid.ShowAll()
+}
+
+// addTop adds a section with the given top list, unless it's empty
+func (d *ListeningStats) addTop(title string, counts []HistoryCount) {
+	if len(counts) == 0 {
+		return
+	}
+	d.grid.addHeader(title)
+	for _, c := range counts {
+		d.grid.addProperty(fmt.Sprintf(util.LocalN("%d play", "%d plays", c.Count), c.Count), c.Name)
+	}
+}
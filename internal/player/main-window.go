@@ -23,12 +23,14 @@ import (
 	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
 	"github.com/pkg/errors"
 	"github.com/yktoo/ymuse/internal/config"
 	"github.com/yktoo/ymuse/internal/generated"
 	"github.com/yktoo/ymuse/internal/util"
 	"html"
 	"html/template"
+	"os"
 	"path"
 	"sort"
 	"strconv"
@@ -41,21 +43,40 @@ type MainWindow struct {
 	app       *gtk.Application // Application reference
 	connector *Connector       // Connector instance
 	mapped    bool             // Whether the main window is mapped (~visible)
+	quitting  bool             // Whether the main window is being closed for good rather than hidden to the tray
 
 	// Control widgets
 	AppWindow              *gtk.ApplicationWindow // Main window
 	MainStack              *gtk.Stack
+	MainStackSwitcher      *gtk.StackSwitcher
+	BottomStackSwitcher    *gtk.StackSwitcher
+	MainPaned              *gtk.Paned
+	BottomBarBox           *gtk.Box
 	StatusLabel            *gtk.Label
+	RadioRememberButton    *gtk.Button
+	PlayerResumeButton     *gtk.Button
+	LoveButton             *gtk.Button
+	LoveImage              *gtk.Image
 	PositionLabel          *gtk.Label
 	PlayPauseButton        *gtk.ToolButton
 	RandomButton           *gtk.ToggleToolButton
 	RepeatButton           *gtk.ToggleToolButton
 	ConsumeButton          *gtk.ToggleToolButton
+	ListenButton           *gtk.ToggleToolButton
 	VolumeButton           *gtk.VolumeButton
 	VolumeAdjustment       *gtk.Adjustment
 	PlayPositionScale      *gtk.Scale
 	PlayPositionAdjustment *gtk.Adjustment
 	AlbumArtworkImage      *gtk.Image
+	AppMenuButton          *gtk.MenuButton
+	PlaylistRestoreMenu    *gtk.Menu
+	// Artist pane widgets
+	ArtistPaneToggleButton   *gtk.ToggleButton
+	ArtistPaneScrolledWindow *gtk.ScrolledWindow
+	ArtistPaneImage          *gtk.Image
+	ArtistPaneNameLabel      *gtk.Label
+	ArtistPaneBioLabel       *gtk.Label
+	ArtistPaneSourceLabel    *gtk.Label
 	// Queue widgets
 	QueueBox                         *gtk.Box
 	QueueToolbar                     *gtk.Toolbar
@@ -65,6 +86,16 @@ type MainWindow struct {
 	QueueSavePopoverMenu             *gtk.PopoverMenu
 	QueueMenu                        *gtk.Menu
 	QueueNowPlayingMenuItem          *gtk.MenuItem
+	QueueSongInfoMenuItem            *gtk.MenuItem
+	QueueStarMenuItem                *gtk.MenuItem
+	QueueAddToRecentMenuItem         *gtk.MenuItem
+	QueueSaveBackMenuItem            *gtk.MenuItem
+	QueueAddToPlaylistMenuItem       *gtk.MenuItem
+	QueueAddToPlaylistMenu           *gtk.Menu
+	QueueShowFolderInLibraryMenuItem *gtk.MenuItem
+	QueueOpenFolderMenuItem          *gtk.MenuItem
+	QueueCopyURIMenuItem             *gtk.MenuItem
+	QueueCopyFilePathMenuItem        *gtk.MenuItem
 	QueueShowAlbumInLibraryMenuItem  *gtk.MenuItem
 	QueueShowArtistInLibraryMenuItem *gtk.MenuItem
 	QueueShowGenreInLibraryMenuItem  *gtk.MenuItem
@@ -73,6 +104,7 @@ type MainWindow struct {
 	QueueFilterToolButton            *gtk.ToggleToolButton
 	QueueSearchBar                   *gtk.SearchBar
 	QueueSearchEntry                 *gtk.SearchEntry
+	QueueRegexToggleButton           *gtk.ToggleButton
 	QueueFilterLabel                 *gtk.Label
 	QueueListStore                   *gtk.ListStore
 	QueueTreeModelFilter             *gtk.TreeModelFilter
@@ -90,19 +122,49 @@ type MainWindow struct {
 	LibraryBox                      *gtk.Box
 	LibraryPathBox                  *gtk.Box
 	LibrarySearchBox                *gtk.Box
+	LibraryGridToolButton           *gtk.ToggleToolButton
+	LibraryFuzzyToolButton          *gtk.ToggleToolButton
+	LibraryRegexToolButton          *gtk.ToggleToolButton
 	LibrarySearchToolButton         *gtk.ToggleToolButton
 	LibraryToolStack                *gtk.Stack
 	LibrarySearchEntry              *gtk.SearchEntry
 	LibrarySearchAttrComboBox       *gtk.ComboBoxText
+	LibraryFilterEntry              *gtk.SearchEntry
+	LibraryBookmarksBox             *gtk.Box
+	LibraryBookmarkToolButton       *gtk.ToolButton
 	LibraryListBox                  *gtk.ListBox
+	LibraryFlowBox                  *gtk.FlowBox
 	LibraryInfoLabel                *gtk.Label
+	LibrarySpinner                  *gtk.Spinner
+	LibraryProgressBar              *gtk.ProgressBar
 	LibraryMenu                     *gtk.Menu
 	LibraryAppendMenuItem           *gtk.MenuItem
 	LibraryReplaceMenuItem          *gtk.MenuItem
+	LibraryPlayNowMenuItem          *gtk.MenuItem
+	LibraryPlayNextMenuItem         *gtk.MenuItem
+	LibrarySongInfoMenuItem         *gtk.MenuItem
 	LibraryRenameMenuItem           *gtk.MenuItem
+	LibraryDuplicateMenuItem        *gtk.MenuItem
+	LibraryRemoveDupsMenuItem       *gtk.MenuItem
+	LibraryExportMenuItem           *gtk.MenuItem
+	LibraryMoveToFolderMenuItem     *gtk.MenuItem
+	LibraryRemoveFromFolderMenuItem *gtk.MenuItem
+	LibrarySmartNewMenuItem         *gtk.MenuItem
+	LibrarySmartEditMenuItem        *gtk.MenuItem
+	LibrarySmartSaveMenuItem        *gtk.MenuItem
+	LibrarySmartDeleteMenuItem      *gtk.MenuItem
 	LibraryDeleteMenuItem           *gtk.MenuItem
 	LibraryUpdateSelMenuItem        *gtk.MenuItem
+	LibraryAddToRecentMenuItem      *gtk.MenuItem
 	LibraryAddToPlaylistMenuItem    *gtk.MenuItem
+	LibraryAddToPlaylistMenu        *gtk.Menu
+	LibraryCopyURIMenuItem          *gtk.MenuItem
+	LibraryCopyFilePathMenuItem     *gtk.MenuItem
+	LibraryOpenFolderMenuItem       *gtk.MenuItem
+	// Library sort popup
+	LibrarySortPopoverMenu             *gtk.PopoverMenu
+	LibrarySortByComboBox              *gtk.ComboBoxText
+	LibrarySortFoldersFirstCheckButton *gtk.CheckButton
 	// Streams widgets
 	StreamsBox             *gtk.Box
 	StreamsAddToolButton   *gtk.ToolButton
@@ -118,10 +180,23 @@ type MainWindow struct {
 	StreamPropsPopoverMenu *gtk.PopoverMenu
 	StreamPropsNameEntry   *gtk.Entry
 	StreamPropsUriEntry    *gtk.Entry
+	StreamPropsGenreEntry  *gtk.Entry
+	// Podcasts widgets
+	PodcastsBox                  *gtk.Box
+	PodcastsListBox              *gtk.ListBox
+	PodcastsInfoLabel            *gtk.Label
+	PodcastsMenu                 *gtk.Menu
+	PodcastsAppendMenuItem       *gtk.MenuItem
+	PodcastsReplaceMenuItem      *gtk.MenuItem
+	PodcastsMarkPlayedMenuItem   *gtk.MenuItem
+	PodcastsMarkUnplayedMenuItem *gtk.MenuItem
 
 	// Actions
 	aMPDDisconnect        *glib.SimpleAction
 	aMPDInfo              *glib.SimpleAction
+	aPlaylistAppendRecent *glib.SimpleAction
+	aPlaylistImport       *glib.SimpleAction
+	aPlaylistRestore      *glib.SimpleAction
 	aQueueNowPlaying      *glib.SimpleAction
 	aQueueClear           *glib.SimpleAction
 	aQueueSort            *glib.SimpleAction
@@ -132,18 +207,33 @@ type MainWindow struct {
 	aQueueSave            *glib.SimpleAction
 	aQueueSaveReplace     *glib.SimpleAction
 	aQueueSaveAppend      *glib.SimpleAction
+	aQueueSaveBack        *glib.SimpleAction
 	aLibraryUpdate        *glib.SimpleAction
 	aLibraryUpdateAll     *glib.SimpleAction
 	aLibraryUpdateSel     *glib.SimpleAction
+	aLibraryUpdateCur     *glib.SimpleAction
 	aLibraryRescanAll     *glib.SimpleAction
 	aLibraryRescanSel     *glib.SimpleAction
 	aLibraryRename        *glib.SimpleAction
+	aLibraryDuplicate     *glib.SimpleAction
 	aLibraryDelete        *glib.SimpleAction
+	aLibraryRemoveDups    *glib.SimpleAction
 	aLibraryAddToPlaylist *glib.SimpleAction
+	aLibrarySearchAddAll  *glib.SimpleAction
+	aLibraryAddFolder     *glib.SimpleAction
+	aLibraryAddFiltered   *glib.SimpleAction
+	aLibraryBookmark      *glib.SimpleAction
 	aStreamAdd            *glib.SimpleAction
 	aStreamEdit           *glib.SimpleAction
 	aStreamDelete         *glib.SimpleAction
 	aStreamPropsApply     *glib.SimpleAction
+	aStreamFind           *glib.SimpleAction
+	aStreamImport         *glib.SimpleAction
+	aStreamExport         *glib.SimpleAction
+	aPodcastBack          *glib.SimpleAction
+	aPodcastSubscribe     *glib.SimpleAction
+	aPodcastUnsubscribe   *glib.SimpleAction
+	aPodcastRefresh       *glib.SimpleAction
 	aPlayerPrevious       *glib.SimpleAction
 	aPlayerStop           *glib.SimpleAction
 	aPlayerPlayPause      *glib.SimpleAction
@@ -151,6 +241,14 @@ type MainWindow struct {
 	aPlayerRandom         *glib.SimpleAction
 	aPlayerRepeat         *glib.SimpleAction
 	aPlayerConsume        *glib.SimpleAction
+	aPlayerListen         *glib.SimpleAction
+	aPlayerStar           *glib.SimpleAction
+	aPlayerRadioRemember  *glib.SimpleAction
+	aPlayerResume         *glib.SimpleAction
+	aPlayerLove           *glib.SimpleAction
+	aPlayerSimilar        *glib.SimpleAction
+
+	shortcutActions []string // Names of the application actions registered with a keyboard shortcut, in order
 
 	// Colours
 	colourBgNormal string // Normal background colour
@@ -159,16 +257,89 @@ type MainWindow struct {
 	currentQueueSize  int // Number of items in the play queue
 	currentQueueIndex int // Queue's track index (last) marked as current
 
-	libPath                *LibraryPath // Current library path
-	libPathElementToSelect string       // Library path element to select after list load (serialised)
+	queueSource    *QueueSource // Stored playlist the queue has been loaded from, nil if none
+	queueTrackURIs []string     // URIs of the tracks in the play queue
+	queueTotalSecs float64      // Total duration of the tracks in the play queue
+	queueDiverged  bool         // Whether the queue has diverged from the playlist it's been loaded from
+	queueCutURIs   []string     // URIs of the queue tracks last removed with the Vim-style dd command, to be pasted back
+
+	queueAlbums    map[string]mpd.Attrs   // First track of each album in the play queue, keyed by album key (see AlbumArtKey)
+	queueAlbumRows map[string][]int       // Indices of the play queue rows of each album, keyed by album key
+	queueAlbumArt  map[string]*gdk.Pixbuf // Album art thumbnails loaded for the queue (nil if none), keyed by album key
+
+	history         *PlayHistory     // History of played tracks
+	listenBrainz    *ListenBrainz    // Queue of listens to submit to ListenBrainz
+	favorites       *Favorites       // Tracks starred by the user
+	playlistBackups *PlaylistBackups // Backups of stored playlists made before destructive operations
+	radioLog        *RadioLog        // Songs remembered while listening to the radio
+	radioSong       mpd.Attrs        // Stream being played, if it announces a song title, otherwise nil
+	resume          *ResumeTracker   // Tracker of the resume position of the current long track
+	resumeOfferPos  float64          // Resume position offered for the current track, 0 if none
+	lastFmTrack     LastFmTrack      // Current track as known to Last.fm, empty if it lacks an artist or a title
+	loveState       triBool          // Whether lastFmTrack is loved on Last.fm, tbNone if unknown
+	loveUpdating    bool             // Whether the loved state of lastFmTrack is being changed
+	similarLoading  bool             // Whether tracks similar to the current one are being looked up
+	playerTrack     mpd.Attrs        // Track currently loaded in the player, nil if none
+	mpris           *Mpris           // MPRIS2 service, nil if it couldn't be registered on the session bus
+	notifier        *TrackNotifier   // Sender of desktop notifications of track changes
+	trayIcon        *TrayIcon        // Status icon in the system tray, nil if disabled or unavailable
+	mediaKeys       *MediaKeys       // Grab of the hardware media keys, nil if disabled or unavailable
+	inhibitCookie   uint             // Cookie of the session idle and suspend inhibition, 0 if not inhibited
+	sessionMonitor  *SessionMonitor  // Monitor of screen lock and suspend, nil if not needed or unavailable
+	launcherEntry   *LauncherEntry   // Play progress and queue size published to docks, nil if unavailable
+	appControl      *AppControl      // Scripting interface on the session bus, nil if it couldn't be registered
+	pendingURIs     []string         // URIs requested to be played before the connection to MPD was established
+	daemon          bool             // Whether running in the background, with the window only shown on request
+	miniPlayer      *MiniPlayer      // Popup window summoned in place of the main window in the daemon mode, if any
+	listener        *StreamListener  // Local player of MPD's HTTP stream, nil until listening is first requested
+	configWatchStop func()           // Stops watching the config file for changes, nil if not watching
+	appearance      *Appearance      // Applier of the theme variant and the user style sheet, nil until first applied
+	narrowLayout    triBool          // Whether the layout is reflowed for a narrow window, tbNone until first laid out
+	queueVimKeys    vimKeymap        // State of the Vim-style key bindings in the queue
+	libraryVimKeys  vimKeymap        // State of the Vim-style key bindings in the library
+	streamsVimKeys  vimKeymap        // State of the Vim-style key bindings in the streams list
+	podcastsVimKeys vimKeymap        // State of the Vim-style key bindings in the podcasts list
+
+	artistCache      *ArtistInfoCache // Cache of artist information displayed in the artist pane
+	curArtist        string           // Artist of the current track, empty if none
+	artistPaneArtist string           // Artist displayed in the artist pane
+
+	podcastState       *PodcastState           // Played state of podcast episodes
+	podcastFeeds       map[string]*PodcastFeed // Loaded podcast feeds, keyed by feed URL
+	podcastEpisodeURIs map[string]bool         // URIs of the episodes of all loaded podcasts
+	podcastLoading     map[string]bool         // URLs of the podcast feeds being loaded
+	podcastURL         string                  // Feed URL of the podcast whose episodes are displayed, empty for the podcast list
+	podcastListURL     string                  // Value of podcastURL the podcasts list has last been populated for
+
+	libPath                *LibraryPath         // Current library path
+	libPathElementToSelect string               // Library path element to select after list load (serialised)
+	libSearchURIs          []string             // URIs of all tracks found by the last library search
+	libFilterPattern       string               // Pattern to filter the current library folder's items with
+	libFilterMatch         func(s string) bool  // Function matching item labels against the filter pattern
+	libInfo                string               // Library info text, excluding filter information
+	libQueueingFolder      bool                 // Whether a folder is being recursively added to the queue
+	libDragElements        []LibraryPathElement // Library elements being dragged onto the queue
+	libLoadGen             int                  // Library load generation, incremented on every load to cancel stale ones
+	libGrid                bool                 // Whether the library items are displayed as a grid rather than a list
+	libIndex               *TrackIndex          // Local index of all tracks, used for searches MPD can't do itself
+	libPlaylistStats       *PlaylistStatsCache  // Cached track counts and durations of stored playlists
+	libPreviewRow          *gtk.ListBoxRow      // Row previewing the selected playlist, nil if none
+	libPreviewPlaylist     string               // Name of the playlist being previewed
+	libRenameTimer         glib.SourceHandle    // Timer starting in-place rename after a slow double click, 0 if none
 
 	playerTitleTemplate      *template.Template // Compiled template for player's track title
 	playerCurrentAlbumArtUri string             // URI of the current player's album art
-
-	volumeUpdating  bool // Volume button update (initiated by an MPD event) flag
-	playPosUpdating bool // Play position manual update flag
-	optionsUpdating bool // Options update flag
-	addingStream    bool // Whether the property popover is open to add a stream (rather than edit an existing one)
+	playerAlbumArtMissing    bool               // Whether the current player's track turned out to have no album art
+	albumArtCache            *AlbumArtCache     // Cache of album art fetched from MPD or online
+	albumArtLoader           *AlbumArtLoader    // Loader fetching album art in the background
+
+	volumeUpdating   bool // Volume button update (initiated by an MPD event) flag
+	playPosUpdating  bool // Play position manual update flag
+	optionsUpdating  bool // Options update flag
+	libSortUpdating  bool // Library sort widgets update flag
+	libViewUpdating  bool // Library view mode widgets update flag
+	libMatchUpdating bool // Library matching mode widgets update flag
+	addingStream     bool // Whether the property popover is open to add a stream (rather than edit an existing one)
 }
 
 const (
@@ -179,7 +350,40 @@ const (
 	queueSaveNewPlaylistID = "\u0001new"
 	librarySearchAllAttrID = "\u0001any"
 
+	libraryQueueBatchSize    = 500 // Number of tracks added to the queue at once when adding a folder recursively
+	libraryPopulateBatchSize = 200 // Number of rows added to the library list in one go
+	libraryPathMaxButtons    = 4   // Maximum number of library path element buttons, the middle ones are collapsed otherwise
+	libraryNumberWidthChars  = 3   // Width of the track number column in the library list, in characters
+	libraryDetailsWidthChars = 8   // Minimum width of the details (eg. track length) column in the library list, in characters
+	libraryFormatWidthChars  = 12  // Minimum width of the audio format column in the library list, in characters
+	libraryPreviewMaxTracks  = 10  // Maximum number of tracks displayed in a playlist preview
+	libraryRenameClickDelay  = 700 // Delay in milliseconds after a click on the selected playlist before it's renamed in place
+
+	configWatchInterval = 2 * time.Second // Interval of checking the config file for changes made outside the application
+
+	narrowLayoutWidth = 720 // Window width in pixels below which the layout is reflowed for narrow screens
+
+	playlistImportMaxReported = 20 // Maximum number of unmatched entries reported when importing a playlist
+	playlistRecentTargetsMax  = 5  // Maximum number of remembered playlists tracks have recently been added to
+
+	dndTargetLibraryElements = "application/x-ymuse-library-elements" // Drag-and-drop target for library elements
+	dndInfoLibraryElements   = 0                                      // Drag-and-drop info ID for library elements
+	dndTargetURIList         = "text/uri-list"                        // Drag-and-drop target for files from other apps
+	dndInfoURIList           = 1                                      // Drag-and-drop info ID for files from other apps
+	dndTargetQueueTracks     = "application/x-ymuse-queue-tracks"     // Drag-and-drop target for queue tracks
+	dndInfoQueueTracks       = 2                                      // Drag-and-drop info ID for queue tracks
+
 	playerArtworkSize = 80 // Album artwork size in pixels
+	playerSimilarMax  = 25 // Maximum number of similar tracks added to the queue in one go
+
+	libraryArtworkSize  = 96            // Album artwork size in library grid tiles, in pixels
+	queueArtworkSize    = 32            // Album artwork thumbnail size in the queue, in pixels
+	albumArtConcurrency = 4             // Maximum number of album art fetches running at the same time
+	albumArtPlaceholder = "ymuse-album" // Icon displayed while album art is being loaded
+
+	artistPaneImageWidth = 256 // Width of the artist image in the artist pane, in pixels
+
+	queueStarIcon = "starred-symbolic" // Icon marking starred tracks in the queue
 )
 
 type triBool int
@@ -190,6 +394,30 @@ const (
 	tbTrue
 )
 
+// activation is a way of activating items in a list
+type activation int
+
+const (
+	actOpen    activation = iota // Double click or Enter: open folders and podcasts, queue other items as configured
+	actMiddle                    // Middle click: queue items as configured
+	actAppend                    // Append items to the queue
+	actReplace                   // Replace the content of the queue with items
+)
+
+// clickAction returns the queue action (one of the config.ClickAction* constants) corresponding to the activation,
+// given the actions configured for double and middle click
+func (a activation) clickAction(doubleClick, middleClick string) string {
+	switch a {
+	case actMiddle:
+		return middleClick
+	case actAppend:
+		return config.ClickActionAppend
+	case actReplace:
+		return config.ClickActionReplace
+	}
+	return doubleClick
+}
+
 // NewMainWindow creates and returns a new MainWindow instance
 func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 	// Set up the window
@@ -199,7 +427,7 @@ func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 	}
 
 	// Instantiate a window and bind widgets
-	w := &MainWindow{app: application}
+	w := &MainWindow{app: application, resume: &ResumeTracker{}}
 	if err := builder.BindWidgets(w); err != nil {
 		log.Fatalf("BindWidgets() failed: %v", err)
 	}
@@ -207,6 +435,9 @@ func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 	// Initialise queue filter model
 	w.QueueTreeModelFilter.SetVisibleColumn(config.QueueColumnVisible)
 
+	// Apply the theme variant and the user style sheet before the colours are determined
+	w.applyAppearance()
+
 	// Initialise player settings
 	w.applyPlayerSettings()
 
@@ -216,51 +447,96 @@ func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 	// Map the handlers to callback functions
 	builder.ConnectSignals(map[string]interface{}{
 		"on_MainWindow_delete":                         w.onDelete,
+		"on_MainWindow_focusIn":                        w.onFocusIn,
 		"on_MainWindow_map":                            w.onMap,
 		"on_MainWindow_styleUpdated":                   w.updateStyle,
+		"on_MainWindow_sizeAllocate":                   w.onSizeAllocate,
 		"on_MainStack_switched":                        w.focusMainList,
 		"on_QueueTreeView_buttonPress":                 w.onQueueTreeViewButtonPress,
 		"on_QueueTreeView_keyPress":                    w.onQueueTreeViewKeyPress,
+		"on_QueueTreeView_dragDataGet":                 w.onQueueTreeViewDragDataGet,
+		"on_QueueTreeView_dragDataReceived":            w.onQueueTreeViewDragDataReceived,
 		"on_QueueTreeSelection_changed":                w.updateQueueActions,
 		"on_QueueSearchBar_searchMode":                 w.onQueueSearchMode,
 		"on_QueueSearchEntry_searchChanged":            w.queueFilter,
+		"on_QueueRegexToggleButton_toggled":            w.onQueueRegexToggle,
 		"on_LibraryListBox_buttonPress":                w.onLibraryListBoxButtonPress,
 		"on_LibraryListBox_keyPress":                   w.onLibraryListBoxKeyPress,
-		"on_LibraryListBox_selectionChange":            w.updateLibraryActions,
+		"on_LibraryFlowBox_buttonPress":                w.onLibraryFlowBoxButtonPress,
+		"on_LibraryListBox_dragBegin":                  w.onLibraryListBoxDragBegin,
+		"on_LibraryListBox_dragDataReceived":           w.onLibraryListBoxDragDataReceived,
+		"on_LibraryListBox_selectionChange":            w.onLibrarySelectionChange,
 		"on_LibrarySearchChanged":                      w.updateLibrary,
 		"on_LibrarySearchStop":                         w.onLibraryStopSearch,
+		"on_LibraryFilterEntry_searchChanged":          w.onLibraryFilterChanged,
+		"on_LibraryFilterEntry_stopSearch":             func() { w.LibraryFilterEntry.SetText("") },
+		"on_LibrarySortChanged":                        w.onLibrarySortChanged,
 		"on_StreamsListBox_buttonPress":                w.onStreamListBoxButtonPress,
 		"on_StreamsListBox_keyPress":                   w.onStreamListBoxKeyPress,
 		"on_StreamsListBox_selectionChange":            w.updateStreamsActions,
+		"on_PodcastsListBox_buttonPress":               w.onPodcastsListBoxButtonPress,
+		"on_PodcastsListBox_keyPress":                  w.onPodcastsListBoxKeyPress,
+		"on_PodcastsListBox_selectionChange":           w.updatePodcastsActions,
 		"on_StreamPropsChanged":                        w.onStreamPropsChanged,
 		"on_QueueSavePopoverMenu_validate":             w.onQueueSavePopoverValidate,
 		"on_VolumeButton_valueChanged":                 w.onVolumeValueChanged,
 		"on_PlayPositionScale_buttonEvent":             w.onPlayPositionButtonEvent,
 		"on_PlayPositionScale_valueChanged":            w.updatePlayerSeekBar,
+		"on_StatusEventBox_buttonPress":                w.onStatusEventBoxButtonPress,
 		"on_QueueNowPlayingMenuItem_activate":          w.updateQueueNowPlaying,
+		"on_QueueSongInfoMenuItem_activate":            w.queueSongInfo,
+		"on_QueueStarMenuItem_activate":                w.queueStar,
+		"on_QueueAddToRecentMenuItem_activate":         func() { w.queueAddSelectionToPlaylist(w.recentTargetPlaylist()) },
+		"on_QueueSaveBackMenuItem_activate":            w.queueSaveBack,
+		"on_LibraryAddToRecentMenuItem_activate":       func() { w.libraryAddSelectionToPlaylist(w.recentTargetPlaylist()) },
+		"on_QueueShowFolderInLibraryMenuItem_activate": w.libraryShowFolderFromQueue,
+		"on_QueueOpenFolderMenuItem_activate":          w.queueOpenFolder,
+		"on_QueueCopyURIMenuItem_activate":             func() { w.queueCopy(false) },
+		"on_QueueCopyFilePathMenuItem_activate":        func() { w.queueCopy(true) },
 		"on_QueueShowAlbumInLibraryMenuItem_activate":  w.libraryShowAlbumFromQueue,
 		"on_QueueShowArtistInLibraryMenuItem_activate": w.libraryShowArtistFromQueue,
 		"on_QueueShowGenreInLibraryMenuItem_activate":  w.libraryShowGenreFromQueue,
 		"on_QueueClearMenuItem_activate":               w.queueClear,
 		"on_QueueDeleteMenuItem_activate":              w.queueDelete,
-		"on_LibraryAddToPlaylistMenuItem_activate":     w.libraryAddToPlaylist,
-		"on_LibraryAppendMenuItem_activate":            func() { w.applyLibrarySelection(tbFalse) },
-		"on_LibraryReplaceMenuItem_activate":           func() { w.applyLibrarySelection(tbTrue) },
+		"on_LibraryAppendMenuItem_activate":            func() { w.applyLibrarySelection(actAppend) },
+		"on_LibraryReplaceMenuItem_activate":           func() { w.applyLibrarySelection(actReplace) },
+		"on_LibraryPlayNowMenuItem_activate":           func() { w.queueLibraryElementsNext(true, w.getSelectedLibraryElements()...) },
+		"on_LibraryPlayNextMenuItem_activate":          func() { w.queueLibraryElementsNext(false, w.getSelectedLibraryElements()...) },
+		"on_LibrarySongInfoMenuItem_activate":          w.librarySongInfo,
 		"on_LibraryRenameMenuItem_activate":            w.libraryRename,
+		"on_LibraryDuplicateMenuItem_activate":         w.libraryDuplicate,
+		"on_LibraryRemoveDupsMenuItem_activate":        w.libraryRemoveDuplicates,
+		"on_LibraryExportMenuItem_activate":            w.libraryExport,
+		"on_LibraryMoveToFolderMenuItem_activate":      w.libraryMoveToFolder,
+		"on_LibraryRemoveFromFolderMenuItem_activate":  w.libraryRemoveFromFolder,
+		"on_LibrarySmartNewMenuItem_activate":          w.librarySmartNew,
+		"on_LibrarySmartEditMenuItem_activate":         w.librarySmartEdit,
+		"on_LibrarySmartSaveMenuItem_activate":         w.librarySmartSave,
+		"on_LibrarySmartDeleteMenuItem_activate":       w.librarySmartDelete,
 		"on_LibraryDeleteMenuItem_activate":            w.libraryDelete,
 		"on_LibraryUpdateSelMenuItem_activate":         func() { w.libraryUpdate(false, true) },
-		"on_StreamsAppendMenuItem_activate":            func() { w.applyStreamSelection(tbFalse) },
-		"on_StreamsReplaceMenuItem_activate":           func() { w.applyStreamSelection(tbTrue) },
+		"on_LibraryCopyURIMenuItem_activate":           func() { w.libraryCopy(false) },
+		"on_LibraryCopyFilePathMenuItem_activate":      func() { w.libraryCopy(true) },
+		"on_LibraryOpenFolderMenuItem_activate":        w.libraryOpenFolder,
+		"on_StreamsAppendMenuItem_activate":            func() { w.applyStreamSelection(actAppend) },
+		"on_StreamsReplaceMenuItem_activate":           func() { w.applyStreamSelection(actReplace) },
 		"on_StreamsEditMenuItem_activate":              w.onStreamEdit,
 		"on_StreamsDeleteMenuItem_activate":            w.onStreamDelete,
+		"on_PodcastsAppendMenuItem_activate":           func() { w.applyPodcastSelection(actAppend) },
+		"on_PodcastsReplaceMenuItem_activate":          func() { w.applyPodcastSelection(actReplace) },
+		"on_PodcastsMarkPlayedMenuItem_activate":       func() { w.podcastMarkPlayed(true) },
+		"on_PodcastsMarkUnplayedMenuItem_activate":     func() { w.podcastMarkPlayed(false) },
 	})
 
 	// Register the main window with the app
 	application.AddWindow(w.AppWindow)
 
-	// Restore library path
+	// Restore library path and the selected item in it
 	cfg := config.GetConfig()
-	errCheck(w.libPath.Unmarshal(cfg.LibraryPath), "Failed to restore library path")
+	if cfg.LibraryPath != "" {
+		errCheck(w.libPath.Unmarshal(cfg.LibraryPath), "Failed to restore library path")
+	}
+	w.libPathElementToSelect = cfg.LibrarySelectedItem
 
 	// Restore window dimensions
 	dim := cfg.MainWindowDimensions
@@ -271,12 +547,53 @@ func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 		w.AppWindow.Move(dim.X, dim.Y)
 	}
 
+	// Restore the artist pane
+	w.ArtistPaneToggleButton.SetActive(cfg.ArtistPaneVisible)
+
 	// Instantiate a connector
 	w.connector = NewConnector(w.onConnectorStatusChange, w.onConnectorHeartbeat, w.onConnectorSubsystemChange)
+	w.libIndex = NewTrackIndex(w.connector)
+	w.libPlaylistStats = NewPlaylistStatsCache(w.connector)
+	w.history = NewPlayHistory(w.onPlayRecorded)
+	w.listenBrainz = NewListenBrainz()
+	w.playlistBackups = NewPlaylistBackups()
+	w.radioLog = NewRadioLog()
+	w.artistCache = NewArtistInfoCache()
+	w.albumArtCache = NewAlbumArtCache()
+	w.albumArtLoader = NewAlbumArtLoader(albumArtConcurrency)
+	w.notifier = NewTrackNotifier(w.app)
+	w.queueAlbumArt = make(map[string]*gdk.Pixbuf)
+	w.podcastState = NewPodcastState()
+	w.podcastFeeds = make(map[string]*PodcastFeed)
+	w.podcastEpisodeURIs = make(map[string]bool)
+	w.podcastLoading = make(map[string]bool)
+
+	// Expose the player to desktop media widgets
+	if w.mpris, err = NewMpris(w.connector, w.albumArtCache, w.AppWindow.Present, w.quit, w.playPlaylist); err != nil {
+		log.Warningf("Failed to register MPRIS service: %v", err)
+	}
+	if w.launcherEntry, err = NewLauncherEntry(); err != nil {
+		log.Warningf("Failed to export launcher entry: %v", err)
+	}
+	if w.appControl, err = NewAppControl(w.connector, w.activateAction, w.playPlaylist); err != nil {
+		log.Warningf("Failed to register control service: %v", err)
+	}
+
+	// Show the status icon, grab the media keys and watch the session, if enabled
+	w.initTrayIcon()
+	w.initMediaKeys()
+	w.initSessionMonitor()
 	return w, nil
 }
 
 func (w *MainWindow) onConnectorStatusChange() {
+	// The database may be a different one after reconnecting
+	w.libIndex.Invalidate()
+	w.libPlaylistStats.Invalidate()
+
+	// Play the URIs requested while disconnected
+	util.WhenIdle("onConnectorStatusChange()", w.openPendingURIs)
+
 	// Ignore when not mapped
 	if w.mapped {
 		util.WhenIdle("onConnectorStatusChange()", w.updateAll)
@@ -292,6 +609,12 @@ func (w *MainWindow) onConnectorHeartbeat() {
 
 func (w *MainWindow) onConnectorSubsystemChange(subsystem string) {
 	log.Debugf("onSubsystemChange(%v)", subsystem)
+	// Drop the tracks indexed so far once the database changes
+	if subsystem == "database" {
+		w.libIndex.Invalidate()
+		w.libPlaylistStats.Invalidate()
+	}
+
 	// Ignore when not mapped
 	if !w.mapped {
 		return
@@ -312,7 +635,10 @@ func (w *MainWindow) onConnectorSubsystemChange(subsystem string) {
 			w.updatePlayer()
 		})
 	case "stored_playlist":
-		if _, ok := w.libPath.Last().(*PlaylistsLibElement); ok {
+		util.WhenIdle("updateFavorites()", w.updateFavorites)
+		util.WhenIdle("updateMprisPlaylists()", w.updateMprisPlaylists)
+		switch w.libPath.Last().(type) {
+		case *PlaylistsLibElement, *PlaylistLibElement:
 			util.WhenIdle("updateLibrary()", w.updateLibrary)
 		}
 	}
@@ -321,9 +647,15 @@ func (w *MainWindow) onConnectorSubsystemChange(subsystem string) {
 func (w *MainWindow) onMap() {
 	log.Debug("MainWindow.onMap()")
 
+	// Skip if the window is shown again after having been hidden to the tray
+	if w.mapped {
+		return
+	}
+
 	// Update all lists
 	w.updateAll()
 	w.updateStreams()
+	w.updatePodcasts()
 
 	// Activate the Queue tree view
 	w.focusMainList()
@@ -332,111 +664,295 @@ func (w *MainWindow) onMap() {
 	if config.GetConfig().MpdAutoConnect {
 		w.connect()
 	}
+
+	// Submit any listens left over from the previous session
+	w.listenBrainzFlush()
+
+	// Pick up changes to the config file made while running
+	w.configWatchStop = config.GetConfig().Watch(configWatchInterval, func() {
+		util.WhenIdle("reloadConfig()", w.reloadConfig, false)
+	})
 	w.mapped = true
 }
 
-func (w *MainWindow) onDelete() {
+func (w *MainWindow) onFocusIn() bool {
+	// Take the media keys back from any player focused in the meantime
+	if w.mediaKeys != nil {
+		w.mediaKeys.Grab()
+	}
+	return false
+}
+
+// onSizeAllocate is a signal handler for the main window being resized
+func (w *MainWindow) onSizeAllocate() {
+	narrow := w.AppWindow.GetAllocatedWidth() < narrowLayoutWidth
+	if w.narrowLayout == tbNone || narrow != (w.narrowLayout == tbTrue) {
+		// Changing the layout while it's being allocated would cause another allocation, so defer it
+		util.WhenIdle("updateLayout()", w.updateLayout, narrow)
+	}
+}
+
+func (w *MainWindow) onDelete() bool {
 	log.Debug("MainWindow.onDelete()")
+
+	// Only hide the window if it can be brought back from the tray, or when running in the background
+	if !w.quitting && (w.daemon || w.trayIcon != nil && config.GetConfig().CloseToTray) {
+		w.AppWindow.Hide()
+		return true
+	}
+
 	w.mapped = false
 	cfg := config.GetConfig()
 
-	// Save the current library path
+	// Save the current library path and the selected item in it
 	cfg.LibraryPath = w.libPath.Marshal()
+	cfg.LibrarySelectedItem = ""
+	if e := w.getSelectedLibraryElement(); e != nil {
+		cfg.LibrarySelectedItem = e.Marshal()
+	}
 
 	// Save the current window dimensions in the config
 	x, y := w.AppWindow.GetPosition()
 	width, height := w.AppWindow.GetSize()
 	cfg.MainWindowDimensions = config.Dimensions{X: x, Y: y, Width: width, Height: height}
 
-	// Write out the config
+	// Stop watching the config file and write out the config
+	if w.configWatchStop != nil {
+		w.configWatchStop()
+		w.configWatchStop = nil
+	}
 	cfg.Save()
 
 	// Disconnect from MPD
 	w.disconnect()
-}
-
-func (w *MainWindow) onLibraryAddToPlaylist(_ *gtk.ModelButton, playlist string) {
-	log.Debugf("MainWindow.onLibraryAddToPlaylist(%s)", playlist)
 
-	// Fetch the selected element, which must be playable
-	element := w.getSelectedLibraryElement()
-	if element == nil || !element.IsPlayable() {
-		return
+	// Leave the session bus
+	if w.mpris != nil {
+		w.mpris.Close()
 	}
-
-	// If it's a URI-enabled element
-	if uh, ok := element.(URIHolder); ok {
-		w.libraryAppendPlaylist(playlist, uh.URI())
-		return
+	if w.launcherEntry != nil {
+		w.launcherEntry.Close()
+	}
+	if w.appControl != nil {
+		w.appControl.Close()
 	}
 
-	// Playlist-enabled element
-	if ph, ok := element.(PlaylistHolder); ok {
-		var attrs []mpd.Attrs
-		var err error
-		w.connector.IfConnected(func(client *mpd.Client) {
-			attrs, err = client.PlaylistContents(ph.PlaylistName())
-		})
-		if w.errCheckDialog(err, glib.Local("Failed to add item to the playlist")) {
-			return
-		}
+	// Remove the outdated track notification
+	w.notifier.Withdraw()
 
-		// Extract the URIs and append them to the playlist
-		w.libraryAppendPlaylist(playlist, util.MapAttrsToSlice(attrs, "file")...)
-		return
+	// Remove the tray icon and the mini player
+	if w.trayIcon != nil {
+		w.trayIcon.Close()
+	}
+	if w.miniPlayer != nil {
+		w.miniPlayer.Destroy()
 	}
 
-	// Attribute-enabled path: extend the current path filter with the element and query the tracks
-	if filter := w.libPath.AsFilter(element); len(filter) > 0 {
-		var attrs []mpd.Attrs
-		var err error
-		w.connector.IfConnected(func(client *mpd.Client) {
-			attrs, err = client.Find(filter...)
-		})
-		if w.errCheckDialog(err, glib.Local("Failed to add item to the playlist")) {
-			return
-		}
+	// Stop listening to the stream
+	if w.listener != nil {
+		w.listener.Close()
+	}
 
-		// Extract the URIs and append them to the playlist
-		w.libraryAppendPlaylist(playlist, util.MapAttrsToSlice(attrs, "file")...)
-		return
+	// Let other players have the media keys
+	if w.mediaKeys != nil {
+		w.mediaKeys.Close()
+	}
+
+	// Stop watching the session
+	if w.sessionMonitor != nil {
+		w.sessionMonitor.Close()
 	}
+	return false
+}
 
-	// Oops
-	log.Errorf("element %T cannot be added to a playlist", element)
+func (w *MainWindow) onLibraryAddToPlaylist(_ *gtk.ModelButton, playlist string) {
+	log.Debugf("MainWindow.onLibraryAddToPlaylist(%s)", playlist)
+	w.libraryAddSelectionToPlaylist(playlist)
 }
 
 func (w *MainWindow) onLibraryListBoxButtonPress(_ *gtk.ListBox, event *gdk.Event) {
 	switch btn := gdk.EventButtonNewFromEvent(event); btn.Type() {
 	// Mouse click
 	case gdk.EVENT_BUTTON_PRESS:
-		// Right click
-		if btn.Button() == 3 {
-			w.LibraryListBox.SelectRow(w.LibraryListBox.GetRowAtY(int(btn.Y())))
+		switch btn.Button() {
+		// Left click on the only selected row: rename it in place unless it turns out to be a double click
+		case 1:
+			w.cancelLibraryRenameTimer()
+			row := w.LibraryListBox.GetRowAtY(int(btn.Y()))
+			state := gdk.ModifierType(btn.State()) & gtk.AcceleratorGetDefaultModMask()
+			if row != nil && row.IsSelected() && state == 0 && w.aLibraryRename.GetEnabled() {
+				w.libRenameTimer, _ = glib.TimeoutAdd(libraryRenameClickDelay, func() {
+					w.libRenameTimer = 0
+					w.libraryRenameInPlace()
+				})
+			}
+
+		// Middle click: select the clicked row unless it's already part of the selection, and queue the selection up
+		case 2:
+			w.cancelLibraryRenameTimer()
+			if row := w.LibraryListBox.GetRowAtY(int(btn.Y())); row != nil && !row.IsSelected() {
+				w.LibraryListBox.UnselectAll()
+				w.LibraryListBox.SelectRow(row)
+			}
+			w.applyLibrarySelection(actMiddle)
+
+		// Right click: select the clicked row unless it's already part of the selection
+		case 3:
+			if row := w.LibraryListBox.GetRowAtY(int(btn.Y())); row != nil && !row.IsSelected() {
+				w.LibraryListBox.UnselectAll()
+				w.LibraryListBox.SelectRow(row)
+			}
+			w.updateAddToPlaylistMenu(w.LibraryAddToPlaylistMenu, w.libraryAddSelectionToPlaylist)
+			w.updateAddToRecentMenuItem(w.LibraryAddToRecentMenuItem)
+			w.LibraryMenu.PopupAtPointer(event)
+		}
+	// Double click
+	case gdk.EVENT_DOUBLE_BUTTON_PRESS:
+		w.cancelLibraryRenameTimer()
+		w.applyLibrarySelection(actOpen)
+	}
+}
+
+func (w *MainWindow) onLibraryFlowBoxButtonPress(_ *gtk.FlowBox, event *gdk.Event) {
+	switch btn := gdk.EventButtonNewFromEvent(event); btn.Type() {
+	// Mouse click
+	case gdk.EVENT_BUTTON_PRESS:
+		switch btn.Button() {
+		// Middle click: queue the selected items up
+		case 2:
+			w.applyLibrarySelection(actMiddle)
+
+		// Right click: show the menu for the selected items
+		case 3:
+			w.updateAddToPlaylistMenu(w.LibraryAddToPlaylistMenu, w.libraryAddSelectionToPlaylist)
+			w.updateAddToRecentMenuItem(w.LibraryAddToRecentMenuItem)
 			w.LibraryMenu.PopupAtPointer(event)
 		}
 	// Double click
 	case gdk.EVENT_DOUBLE_BUTTON_PRESS:
-		w.applyLibrarySelection(tbNone)
+		w.applyLibrarySelection(actOpen)
+	}
+}
+
+// onLibraryGridToggle switches the current library view between list and grid presentation
+func (w *MainWindow) onLibraryGridToggle() {
+	if w.libViewUpdating {
+		return
+	}
+
+	// Store the mode for the current view type and reload the library
+	cfg := config.GetConfig()
+	if cfg.LibraryGridViews == nil {
+		cfg.LibraryGridViews = make(map[string]bool)
+	}
+	cfg.LibraryGridViews[w.libraryViewType()] = w.LibraryGridToolButton.GetActive()
+	w.updateLibrary()
+}
+
+// onLibraryFuzzyToggle switches fuzzy matching in library search and filter on or off
+func (w *MainWindow) onLibraryFuzzyToggle() {
+	if !w.libMatchUpdating {
+		w.setLibraryMatchMode(w.LibraryFuzzyToolButton.GetActive(), false)
+	}
+}
+
+// onLibraryRegexToggle switches regular expression matching in library search and filter on or off
+func (w *MainWindow) onLibraryRegexToggle() {
+	if !w.libMatchUpdating {
+		w.setLibraryMatchMode(false, w.LibraryRegexToolButton.GetActive())
+	}
+}
+
+// setLibraryMatchMode stores the library matching mode (the modes are mutually exclusive) and updates the toggles
+// accordingly, then re-applies the filter and re-runs the search, if any
+func (w *MainWindow) setLibraryMatchMode(fuzzy, regex bool) {
+	cfg := config.GetConfig()
+	cfg.LibraryFuzzySearch = fuzzy
+	cfg.LibraryRegexSearch = regex
+	w.libMatchUpdating = true
+	w.LibraryFuzzyToolButton.SetActive(fuzzy)
+	w.LibraryRegexToolButton.SetActive(regex)
+	w.libMatchUpdating = false
+
+	w.onLibraryFilterChanged()
+	if w.LibrarySearchToolButton.GetActive() {
+		w.updateLibrary()
+	}
+}
+
+func (w *MainWindow) onLibrarySelectionChange() {
+	w.cancelLibraryRenameTimer()
+	w.updateLibraryActions()
+	w.updateLibraryPreview()
+}
+
+func (w *MainWindow) onLibrarySortChanged() {
+	if w.libSortUpdating {
+		return
+	}
+
+	// Store the new sort order and reload the library
+	cfg := config.GetConfig()
+	cfg.LibrarySortBy = w.LibrarySortByComboBox.GetActiveID()
+	cfg.LibraryFoldersFirst = w.LibrarySortFoldersFirstCheckButton.GetActive()
+	w.updateLibrary()
+}
+
+func (w *MainWindow) onLibraryListBoxDragBegin() {
+	// Remember the elements being dragged
+	w.cancelLibraryRenameTimer()
+	w.libDragElements = w.getSelectedLibraryElements()
+}
+
+func (w *MainWindow) onLibraryListBoxDragDataReceived(_ *gtk.ListBox, _ *gdk.DragContext, _, y int, data *gtk.SelectionData, info uint) {
+	// NB: GTK finishes the drag automatically owing to DEST_DEFAULT_DROP
+	pl, ok := w.libPath.Last().(*PlaylistLibElement)
+	if !ok || info != dndInfoQueueTracks {
+		return
+	}
+	uris := strings.Fields(string(data.GetData()))
+	if len(uris) == 0 {
+		return
 	}
+
+	// Insert the tracks before the track they've been dropped onto
+	w.playlistInsertURIs(pl.PlaylistName(), w.getPlaylistDropPosition(w.LibraryListBox.GetRowAtY(y)), uris)
 }
 
-func (w *MainWindow) onLibraryListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event) {
+func (w *MainWindow) onLibraryListBoxKeyPress(_ interface{}, event *gdk.Event) bool {
 	evt := gdk.EventKeyNewFromEvent(event)
 	state := gdk.ModifierType(evt.State()) & gtk.AcceleratorGetDefaultModMask()
+
+	// Vim-style bindings, if enabled
+	if vimKeymapEnabled() {
+		switch cmd := w.libraryVimKeys.feed(evt.KeyVal(), state); {
+		case cmd == vcPending:
+			return true
+		case cmd.isMove() && w.libGrid:
+			vimMoveFlowBox(w.LibraryFlowBox, cmd)
+			return true
+		case cmd.isMove():
+			vimMoveListBox(w.LibraryListBox, cmd)
+			return true
+		case cmd == vcSearch:
+			w.LibrarySearchToolButton.SetActive(true)
+			return true
+		}
+	}
+
 	switch evt.KeyVal() {
 	// Enter: we need to go deeper
 	case gdk.KEY_Return:
 		switch state {
 		// Enter: use default mode
 		case 0:
-			w.applyLibrarySelection(tbNone)
+			w.applyLibrarySelection(actOpen)
 		// Ctrl+Enter: replace
 		case gdk.CONTROL_MASK:
-			w.applyLibrarySelection(tbTrue)
+			w.applyLibrarySelection(actReplace)
 		// Shift+Enter: append
 		case gdk.SHIFT_MASK:
-			w.applyLibrarySelection(tbFalse)
+			w.applyLibrarySelection(actAppend)
 		}
 
 	// Backspace: go level up (not in search mode)
@@ -445,6 +961,12 @@ func (w *MainWindow) onLibraryListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event)
 			w.libraryLevelUp()
 		}
 
+	// F2: rename the selected playlist in place
+	case gdk.KEY_F2:
+		if state == 0 {
+			w.libraryRenameInPlace()
+		}
+
 	// Escape: deactivate search mode
 	case gdk.KEY_Escape:
 		if state == 0 {
@@ -457,12 +979,40 @@ func (w *MainWindow) onLibraryListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event)
 			w.LibrarySearchToolButton.SetActive(true)
 		}
 	}
+	return false
+}
+
+// onLibraryFilterChanged re-applies the filter pattern to the library list
+func (w *MainWindow) onLibraryFilterChanged() {
+	w.libFilterPattern = util.EntryText(&w.LibraryFilterEntry.Entry, "")
+	w.libFilterMatch = w.libraryMatcher(&w.LibraryFilterEntry.Entry, w.libFilterPattern)
+	if w.libGrid {
+		w.libraryFilterGrid()
+	} else {
+		w.LibraryListBox.InvalidateFilter()
+	}
+	w.updateLibraryInfo()
+}
+
+// libraryMatcher returns a function matching strings against the given pattern in the current library matching mode.
+// An invalid pattern matches nothing and marks the entry it's been entered in
+func (w *MainWindow) libraryMatcher(entry *gtk.Entry, pattern string) func(s string) bool {
+	cfg := config.GetConfig()
+	match, err := util.NewPatternMatcher(pattern, cfg.LibraryFuzzySearch, cfg.LibraryRegexSearch, cfg.SearchIgnoreDiacritics)
+	util.SetEntryError(entry, err != nil)
+	if err != nil {
+		return func(string) bool { return false }
+	}
+	return match
 }
 
 // onLibrarySearchToggle activates or deactivates library search mode
 func (w *MainWindow) onLibrarySearchToggle() {
 	searchMode := w.LibrarySearchToolButton.GetActive()
 
+	// Folder filter is only available outside search mode
+	w.LibraryFilterEntry.SetVisible(!searchMode)
+
 	// Show the appropriate tool stack's page
 	if searchMode {
 		w.LibraryToolStack.SetVisibleChild(w.LibrarySearchBox)
@@ -490,7 +1040,11 @@ func (w *MainWindow) onLibraryStopSearch() {
 func (w *MainWindow) onLibraryPathChanged() {
 	// Ignore when not mapped
 	if w.mapped {
+		// Reset the folder filter
+		w.LibraryFilterEntry.SetText("")
+		w.libFilterPattern = ""
 		w.updateLibraryPath()
+		w.updateLibraryBookmarks()
 		w.updateLibrary()
 		w.focusMainList()
 	}
@@ -510,6 +1064,181 @@ func (w *MainWindow) onPlayPositionButtonEvent(_ interface{}, event *gdk.Event)
 	}
 }
 
+// onPlayRecorded queues the play just recorded in the history for submitting to ListenBrainz
+func (w *MainWindow) onPlayRecorded(e *HistoryEntry) {
+	// Only submit if the user has configured their token. ListenBrainz requires both artist and title
+	if config.GetConfig().ListenBrainzToken == "" || e.Artist == "" || e.Title == "" {
+		return
+	}
+	l := Listen{
+		ListenedAt: e.Time.Add(-time.Duration(e.Played * float64(time.Second))).Unix(),
+		Artist:     e.Artist,
+		Title:      e.Title,
+		Release:    e.Album,
+	}
+	if !errCheck(w.listenBrainz.Add(l), "Failed to queue listen") {
+		w.listenBrainzFlush()
+	}
+}
+
+// listenBrainzFlush submits the queued listens to ListenBrainz in the background. Listens that couldn't be submitted
+// stay in the queue until the next attempt
+func (w *MainWindow) listenBrainzFlush() {
+	token := config.GetConfig().ListenBrainzToken
+	if token == "" || w.listenBrainz.Pending() == 0 {
+		return
+	}
+	go func() {
+		errCheck(w.listenBrainz.Flush(token), "Failed to submit listens to ListenBrainz")
+	}()
+}
+
+func (w *MainWindow) onArtistPaneToggle() {
+	visible := w.ArtistPaneToggleButton.GetActive()
+	config.GetConfig().ArtistPaneVisible = visible
+	w.ArtistPaneScrolledWindow.SetVisible(visible)
+
+	// Forget the displayed artist when hidden, so that it's reloaded when shown again
+	if !visible {
+		w.artistPaneArtist = ""
+	}
+	w.updateArtistPane()
+}
+
+func (w *MainWindow) onPodcastBack() {
+	url := w.podcastURL
+	if url == "" {
+		return
+	}
+
+	// Go back to the podcast list and select the podcast that's been open
+	w.podcastURL = ""
+	w.updatePodcasts()
+	for i, p := range config.GetConfig().Podcasts {
+		if p.URL == url {
+			w.PodcastsListBox.SelectRow(w.PodcastsListBox.GetRowAtIndex(i))
+			break
+		}
+	}
+	w.focusMainList()
+}
+
+func (w *MainWindow) onPodcastRefresh() {
+	if url := w.getPodcastTargetURL(); url != "" {
+		w.podcastLoad(url, nil)
+	}
+}
+
+func (w *MainWindow) onPodcastSubscribe() {
+	url, ok := util.EditDialog(w.AppWindow, glib.Local("Subscribe to podcast"), "", glib.Local("Subscribe"))
+	if url = strings.TrimSpace(url); !ok || url == "" {
+		return
+	}
+
+	// Check the podcast isn't subscribed to yet
+	for _, p := range config.GetConfig().Podcasts {
+		if p.URL == url {
+			util.ErrorDialog(w.AppWindow, fmt.Sprintf(glib.Local("You're already subscribed to podcast \"%s\"."), p.Name))
+			return
+		}
+	}
+
+	// Load the feed and subscribe once it's been validated
+	w.podcastLoad(url, func(feed *PodcastFeed) {
+		cfg := config.GetConfig()
+		cfg.Podcasts = append(cfg.Podcasts, config.PodcastSpec{Name: util.Default(url, feed.Title), URL: url})
+	})
+}
+
+func (w *MainWindow) onPodcastUnsubscribe() {
+	url := w.getPodcastTargetURL()
+	if url == "" {
+		return
+	}
+
+	// Ask for a confirmation
+	podcasts := &config.GetConfig().Podcasts
+	for i, p := range *podcasts {
+		if p.URL == url {
+			if !util.ConfirmDialog(w.AppWindow, glib.Local("Unsubscribe from podcast"), fmt.Sprintf(glib.Local("Are you sure you want to unsubscribe from podcast \"%s\"?"), p.Name)) {
+				return
+			}
+			*podcasts = append((*podcasts)[:i], (*podcasts)[i+1:]...)
+			break
+		}
+	}
+
+	// Forget the podcast's episodes and get back to the list
+	delete(w.podcastFeeds, url)
+	w.podcastURL = ""
+	w.updatePodcasts()
+	w.focusMainList()
+}
+
+func (w *MainWindow) onPodcastsListBoxButtonPress(_ *gtk.ListBox, event *gdk.Event) {
+	switch btn := gdk.EventButtonNewFromEvent(event); btn.Type() {
+	// Mouse click
+	case gdk.EVENT_BUTTON_PRESS:
+		switch btn.Button() {
+		// Middle click: queue the clicked episode up
+		case 2:
+			if w.podcastURL != "" {
+				w.PodcastsListBox.SelectRow(w.PodcastsListBox.GetRowAtY(int(btn.Y())))
+				w.applyPodcastSelection(actMiddle)
+			}
+
+		// Right click: only episodes have a context menu
+		case 3:
+			if w.podcastURL != "" {
+				w.PodcastsListBox.SelectRow(w.PodcastsListBox.GetRowAtY(int(btn.Y())))
+				w.PodcastsMenu.PopupAtPointer(event)
+			}
+		}
+	// Double click
+	case gdk.EVENT_DOUBLE_BUTTON_PRESS:
+		w.applyPodcastSelection(actOpen)
+	}
+}
+
+func (w *MainWindow) onPodcastsListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event) bool {
+	evt := gdk.EventKeyNewFromEvent(event)
+	state := gdk.ModifierType(evt.State()) & gtk.AcceleratorGetDefaultModMask()
+
+	// Vim-style bindings, if enabled
+	if vimKeymapEnabled() {
+		switch cmd := w.podcastsVimKeys.feed(evt.KeyVal(), state); {
+		case cmd == vcPending:
+			return true
+		case cmd.isMove():
+			vimMoveListBox(w.PodcastsListBox, cmd)
+			return true
+		}
+	}
+
+	switch evt.KeyVal() {
+	// Enter: apply selection
+	case gdk.KEY_Return:
+		switch state {
+		// Enter: use default mode
+		case 0:
+			w.applyPodcastSelection(actOpen)
+		// Ctrl+Enter: replace
+		case gdk.CONTROL_MASK:
+			w.applyPodcastSelection(actReplace)
+		// Shift+Enter: append
+		case gdk.SHIFT_MASK:
+			w.applyPodcastSelection(actAppend)
+		}
+
+	// Backspace: go back to the podcast list
+	case gdk.KEY_BackSpace:
+		if state == 0 {
+			w.onPodcastBack()
+		}
+	}
+	return false
+}
+
 func (w *MainWindow) onQueueSavePopoverValidate() {
 	// Only show new playlist widgets if (new playlist) is selected in the combo box
 	selectedID := w.QueueSavePlaylistComboBox.GetActiveID()
@@ -566,6 +1295,8 @@ func (w *MainWindow) onQueueTreeViewButtonPress(_ *gtk.TreeView, event *gdk.Even
 	case gdk.EVENT_BUTTON_PRESS:
 		// Right click
 		if btn.Button() == 3 {
+			w.updateAddToPlaylistMenu(w.QueueAddToPlaylistMenu, w.queueAddSelectionToPlaylist)
+			w.updateAddToRecentMenuItem(w.QueueAddToRecentMenuItem)
 			w.QueueMenu.PopupAtPointer(event)
 			// Stop event propagation
 			return true
@@ -577,9 +1308,67 @@ func (w *MainWindow) onQueueTreeViewButtonPress(_ *gtk.TreeView, event *gdk.Even
 	return false
 }
 
-func (w *MainWindow) onQueueTreeViewKeyPress(_ *gtk.TreeView, event *gdk.Event) {
-	evt := gdk.EventKeyNewFromEvent(event)
-	state := gdk.ModifierType(evt.State()) & gtk.AcceleratorGetDefaultModMask()
+func (w *MainWindow) onQueueTreeViewDragDataGet(_ *gtk.TreeView, _ *gdk.DragContext, data *gtk.SelectionData, info uint) {
+	// Pass on the URIs of the tracks being dragged, one per line
+	if info != dndInfoQueueTracks {
+		return
+	}
+	var uris []string
+	for _, idx := range w.getQueueSelectedIndices() {
+		if idx < len(w.queueTrackURIs) {
+			uris = append(uris, w.queueTrackURIs[idx])
+		}
+	}
+	data.SetData(gdk.GdkAtomIntern(dndTargetQueueTracks, false), []byte(strings.Join(uris, "\n")))
+}
+
+func (w *MainWindow) onQueueTreeViewDragDataReceived(_ *gtk.TreeView, _ *gdk.DragContext, x, y int, data *gtk.SelectionData, info uint) {
+	// NB: GTK finishes the drag automatically owing to DEST_DEFAULT_DROP
+	var elements []LibraryPathElement
+	switch info {
+	// Library elements dragged from the library page
+	case dndInfoLibraryElements:
+		elements = w.libDragElements
+		w.libDragElements = nil
+
+	// Files dropped from a file manager
+	case dndInfoURIList:
+		elements = w.localPathsToElements(util.ParseURIList(string(data.GetData())))
+	}
+	if len(elements) == 0 {
+		return
+	}
+
+	// Insert the dragged elements at the drop position
+	if uris, ok := w.resolveLibraryElements(elements, glib.Local("Failed to add track(s) to the queue")); ok && len(uris) > 0 {
+		w.queueURIsAt(w.getQueueDropPosition(x, y), false, uris...)
+	}
+}
+
+func (w *MainWindow) onQueueTreeViewKeyPress(_ *gtk.TreeView, event *gdk.Event) bool {
+	evt := gdk.EventKeyNewFromEvent(event)
+	state := gdk.ModifierType(evt.State()) & gtk.AcceleratorGetDefaultModMask()
+
+	// Vim-style bindings, if enabled. Keys that make no command fall through to the tree view's interactive search
+	if vimKeymapEnabled() {
+		switch cmd := w.queueVimKeys.feed(evt.KeyVal(), state); {
+		case cmd == vcPending:
+			return true
+		case cmd.isMove():
+			w.queueMoveCursor(cmd)
+			return true
+		case cmd == vcSearch:
+			w.QueueSearchBar.SetSearchMode(true)
+			return true
+		case cmd == vcDelete:
+			w.queueCut()
+			return true
+		case cmd == vcPaste:
+			w.queuePaste()
+			return true
+		}
+	}
+
 	switch evt.KeyVal() {
 	// Enter: apply current selection
 	case gdk.KEY_Return:
@@ -612,12 +1401,23 @@ func (w *MainWindow) onQueueTreeViewKeyPress(_ *gtk.TreeView, event *gdk.Event)
 			w.QueueSearchBar.SetSearchMode(true)
 		}
 	}
+	return false
+}
+
+func (w *MainWindow) onStatusEventBoxButtonPress(_ *gtk.EventBox, event *gdk.Event) {
+	// Left click on the player title shows the current track's information
+	if btn := gdk.EventButtonNewFromEvent(event); btn.Type() == gdk.EVENT_BUTTON_PRESS && btn.Button() == 1 {
+		if connected, _ := w.connector.ConnectStatus(); connected {
+			w.playerSongInfo()
+		}
+	}
 }
 
 func (w *MainWindow) onStreamAdd() {
 	// Reset property values
 	w.StreamPropsNameEntry.SetText("")
 	w.StreamPropsUriEntry.SetText("")
+	w.StreamPropsGenreEntry.SetText("")
 
 	// Disable the Apply action initially
 	w.aStreamPropsApply.SetEnabled(false)
@@ -658,6 +1458,7 @@ func (w *MainWindow) onStreamEdit() {
 	// Reset property values
 	w.StreamPropsNameEntry.SetText(stream.Name)
 	w.StreamPropsUriEntry.SetText(stream.URI)
+	w.StreamPropsGenreEntry.SetText(stream.Genre)
 
 	// Disable the Apply action initially
 	w.aStreamPropsApply.SetEnabled(false)
@@ -672,35 +1473,54 @@ func (w *MainWindow) onStreamListBoxButtonPress(_ *gtk.ListBox, event *gdk.Event
 	switch btn := gdk.EventButtonNewFromEvent(event); btn.Type() {
 	// Mouse click
 	case gdk.EVENT_BUTTON_PRESS:
+		switch btn.Button() {
+		// Middle click: queue the clicked stream up
+		case 2:
+			w.StreamsListBox.SelectRow(w.StreamsListBox.GetRowAtY(int(btn.Y())))
+			w.applyStreamSelection(actMiddle)
+
 		// Right click
-		if btn.Button() == 3 {
+		case 3:
 			w.StreamsListBox.SelectRow(w.StreamsListBox.GetRowAtY(int(btn.Y())))
 			w.StreamsMenu.PopupAtPointer(event)
 		}
 	// Double click
 	case gdk.EVENT_DOUBLE_BUTTON_PRESS:
-		w.applyStreamSelection(tbNone)
+		w.applyStreamSelection(actOpen)
 	}
 }
 
-func (w *MainWindow) onStreamListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event) {
+func (w *MainWindow) onStreamListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event) bool {
 	evt := gdk.EventKeyNewFromEvent(event)
 	state := gdk.ModifierType(evt.State()) & gtk.AcceleratorGetDefaultModMask()
+
+	// Vim-style bindings, if enabled
+	if vimKeymapEnabled() {
+		switch cmd := w.streamsVimKeys.feed(evt.KeyVal(), state); {
+		case cmd == vcPending:
+			return true
+		case cmd.isMove():
+			vimMoveListBox(w.StreamsListBox, cmd)
+			return true
+		}
+	}
+
 	switch evt.KeyVal() {
 	// Enter: apply selection
 	case gdk.KEY_Return:
 		switch state {
 		// Enter: use default mode
 		case 0:
-			w.applyStreamSelection(tbNone)
+			w.applyStreamSelection(actOpen)
 		// Ctrl+Enter: replace
 		case gdk.CONTROL_MASK:
-			w.applyStreamSelection(tbTrue)
+			w.applyStreamSelection(actReplace)
 		// Shift+Enter: append
 		case gdk.SHIFT_MASK:
-			w.applyStreamSelection(tbFalse)
+			w.applyStreamSelection(actAppend)
 		}
 	}
+	return false
 }
 
 func (w *MainWindow) onStreamPropsApply() {
@@ -712,8 +1532,9 @@ func (w *MainWindow) onStreamPropsApply() {
 
 	// Make a stream spec instance
 	stream := config.StreamSpec{
-		Name: name,
-		URI:  uri,
+		Name:  name,
+		URI:   uri,
+		Genre: util.EntryText(w.StreamPropsGenreEntry, ""),
 	}
 
 	// Adding a stream
@@ -731,6 +1552,68 @@ func (w *MainWindow) onStreamPropsApply() {
 	w.focusMainList()
 }
 
+func (w *MainWindow) onStreamFind() {
+	RadioDirectoryDialog(w.AppWindow, func(uri string) { w.queueStream(tbTrue, uri) }, w.addStream)
+}
+
+func (w *MainWindow) onStreamImport() {
+	fileName, ok := util.OpenFileDialog(w.AppWindow, glib.Local("Import streams"), "*.m3u", "*.m3u8", "*.pls")
+	if !ok {
+		return
+	}
+
+	// Read the stations
+	file, err := os.Open(fileName)
+	if w.errCheckDialog(err, glib.Local("Failed to import streams")) {
+		return
+	}
+	streams, err := ParseStreamList(fileName, file)
+	_ = file.Close()
+	if w.errCheckDialog(err, glib.Local("Failed to import streams")) {
+		return
+	}
+
+	// Add the stations that aren't in the list yet
+	cfg := config.GetConfig()
+	known := make(map[string]bool, len(cfg.Streams))
+	for _, s := range cfg.Streams {
+		known[s.URI] = true
+	}
+	added := 0
+	for _, s := range streams {
+		if !known[s.URI] {
+			cfg.Streams = append(cfg.Streams, s)
+			known[s.URI] = true
+			added++
+		}
+	}
+	w.updateStreams()
+
+	// Report the stations that have been left out, if any
+	if skipped := len(streams) - added; skipped > 0 {
+		imported := fmt.Sprintf(util.LocalN("%d stream imported", "%d streams imported", added), added)
+		util.InfoDialog(w.AppWindow, fmt.Sprintf(util.LocalN("%s, %d skipped as already present.", "%s, %d skipped as already present.", skipped), imported, skipped))
+	}
+}
+
+func (w *MainWindow) onStreamExport() {
+	fileName, ok := util.SaveFileDialog(w.AppWindow, glib.Local("Export streams"), "streams.m3u", "*.m3u", "*.m3u8")
+	if !ok {
+		return
+	}
+
+	// Write the stations out
+	file, err := os.Create(fileName)
+	if w.errCheckDialog(err, glib.Local("Failed to export streams")) {
+		return
+	}
+	err = WriteStreamList(file, config.GetConfig().Streams)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	w.errCheckDialog(err, glib.Local("Failed to export streams"))
+}
+
 func (w *MainWindow) onStreamPropsChanged() {
 	// Validate the popover
 	w.aStreamPropsApply.SetEnabled(
@@ -748,6 +1631,27 @@ func (w *MainWindow) onVolumeValueChanged() {
 	}
 }
 
+// ToggleWindow hides the main window if it's visible and focused, and presents it otherwise. In the daemon mode the
+// mini player is toggled instead, unless the main window is shown
+func (w *MainWindow) ToggleWindow() {
+	if w.miniPlayer != nil && !w.AppWindow.IsVisible() {
+		w.miniPlayer.Toggle()
+		return
+	}
+	if w.AppWindow.IsVisible() && w.AppWindow.IsActive() {
+		w.AppWindow.Hide()
+	} else {
+		w.AppWindow.Present()
+	}
+}
+
+// onTrayVolumeChange changes the volume by the given number of percent on scrolling over the tray icon
+func (w *MainWindow) onTrayVolumeChange(delta int) {
+	if w.VolumeButton.GetSensitive() {
+		w.VolumeAdjustment.SetValue(w.VolumeAdjustment.GetValue() + float64(delta))
+	}
+}
+
 // about shows the application's about dialog
 func (w *MainWindow) about() {
 	dlg, err := gtk.AboutDialogNew()
@@ -768,6 +1672,11 @@ func (w *MainWindow) about() {
 	dlg.Run()
 }
 
+// activateAction activates the application action with the given name, without the "app." prefix
+func (w *MainWindow) activateAction(name string) {
+	w.app.IActionGroup.Activate(name, nil)
+}
+
 // addAction add a new application action, with an optional keyboard shortcut
 func (w *MainWindow) addAction(name, shortcut string, onActivate interface{}) *glib.SimpleAction {
 	action := glib.SimpleActionNew(name, nil)
@@ -777,33 +1686,53 @@ func (w *MainWindow) addAction(name, shortcut string, onActivate interface{}) *g
 	w.app.AddAction(action)
 	if shortcut != "" {
 		w.app.SetAccelsForAction("app."+name, []string{shortcut})
+		w.shortcutActions = append(w.shortcutActions, name)
 	}
 	return action
 }
 
-// applyLibrarySelection navigates into the folder or adds or replaces the content of the queue with the currently
-// selected items in the library
-func (w *MainWindow) applyLibrarySelection(replace triBool) {
-	// Get selected element
-	e := w.getSelectedLibraryElement()
-	if e == nil {
+// applyLibrarySelection navigates into the folder or queues the currently selected items in the library up according
+// to the given activation
+func (w *MainWindow) applyLibrarySelection(act activation) {
+	// Get selected elements
+	elements := w.getSelectedLibraryElements()
+	switch len(elements) {
+	case 0:
 		return
-	}
 
-	// Level-up element
-	if _, ok := e.(*LevelUpLibElement); ok {
-		w.libraryLevelUp()
+	case 1:
+		e := elements[0]
+		// Level-up element
+		if _, ok := e.(*LevelUpLibElement); ok {
+			w.libraryLevelUp()
 
-	} else if replace == tbNone && e.IsFolder() {
-		// Default for folders is entering into
-		w.libPath.Append(e)
+		} else if act == actOpen && e.IsFolder() {
+			// Default for folders is entering into
+			w.libPath.Append(e)
 
-	} else {
-		// Queue the element up otherwise
-		w.queueLibraryElement(replace, e)
+		} else {
+			// Queue the element up otherwise
+			w.queueLibraryElementsAs(libraryClickAction(act, elements), e)
+		}
+
+	default:
+		// Multiple elements selected: queue them all up
+		w.queueLibraryElementsAs(libraryClickAction(act, elements), elements...)
 	}
 }
 
+// libraryClickAction returns the queue action for activating the given library elements: the one configured for
+// playlists if they're all playlists, the one for tracks otherwise
+func libraryClickAction(act activation, elements []LibraryPathElement) string {
+	cfg := config.GetConfig()
+	for _, e := range elements {
+		if _, ok := e.(PlaylistHolder); !ok {
+			return act.clickAction(cfg.TrackDoubleClick, cfg.TrackMiddleClick)
+		}
+	}
+	return act.clickAction(cfg.PlaylistDoubleClick, cfg.PlaylistMiddleClick)
+}
+
 // applyPlayerSettings compiles the player title template and updates the player
 func (w *MainWindow) applyPlayerSettings() {
 	// Apply toolbar setting
@@ -825,32 +1754,85 @@ func (w *MainWindow) applyPlayerSettings() {
 		w.playerTitleTemplate = tmpl
 	}
 
-	// Update the displayed title/artwork if the connector is initialised
+	// Update the displayed title/artwork if the connector is initialised. Album art is reloaded, as its sources may
+	// have changed
 	if w.connector != nil {
+		w.playerCurrentAlbumArtUri = ""
 		w.updatePlayer()
 	}
 }
 
 // applyQueueSelection starts playing from the currently selected track
 func (w *MainWindow) applyQueueSelection() {
-	// Get the tree's selection
-	var err error
+	// Start playback from the first selected index
 	if indices := w.getQueueSelectedIndices(); len(indices) > 0 {
-		// Start playback from the first selected index
-		w.connector.IfConnected(func(client *mpd.Client) {
-			err = client.Play(indices[0])
-		})
+		w.playQueueIndex(indices[0])
 	}
+}
+
+// playQueueIndex starts playing from the queue track with the given index
+func (w *MainWindow) playQueueIndex(index int) {
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.Play(index)
+	})
 
 	// Check for error
 	w.errCheckDialog(err, glib.Local("Failed to play the selected track"))
 }
 
-// applyStreamSelection adds or replaces the content of the queue with the currently selected stream
-func (w *MainWindow) applyStreamSelection(replace triBool) {
+// applyPodcastSelection opens the currently selected podcast, or queues the selected episode up according to the given
+// activation
+func (w *MainWindow) applyPodcastSelection(act activation) {
+	// Podcast list: open the selected podcast, loading its episodes if needed
+	if w.podcastURL == "" {
+		if url := w.getPodcastTargetURL(); url != "" {
+			w.podcastURL = url
+			if w.podcastFeeds[url] == nil {
+				w.podcastLoad(url, nil)
+			}
+			w.updatePodcasts()
+			w.focusMainList()
+		}
+		return
+	}
+
+	// Episode list: queue the selected episode
+	if e := w.getSelectedPodcastEpisode(); e != nil {
+		cfg := config.GetConfig()
+		w.queueStreamAs(act.clickAction(cfg.StreamDoubleClick, cfg.StreamMiddleClick), e.URI)
+	}
+}
+
+// applyStreamSelection queues the currently selected stream up according to the given activation
+func (w *MainWindow) applyStreamSelection(act activation) {
 	if idx := w.getSelectedStreamIndex(); idx >= 0 {
-		w.queueStream(replace, config.GetConfig().Streams[idx].URI)
+		cfg := config.GetConfig()
+		w.queueStreamAs(act.clickAction(cfg.StreamDoubleClick, cfg.StreamMiddleClick), cfg.Streams[idx].URI)
+	}
+}
+
+// checkPlaylistExists returns whether a stored playlist with the given name exists, and shows an error if it does
+func (w *MainWindow) checkPlaylistExists(name string) bool {
+	for _, n := range w.connector.GetPlaylists() {
+		if n == name {
+			util.ErrorDialog(w.AppWindow, fmt.Sprintf(glib.Local("Playlist \"%s\" already exists."), name))
+			return true
+		}
+	}
+	return false
+}
+
+// checkSmartPlaylistExists returns whether a smart playlist with the given name, other than the one at the given index,
+// exists, and shows an error if it does
+func (w *MainWindow) checkSmartPlaylistExists(name string, index int) bool {
+	for i, sp := range config.GetConfig().SmartPlaylists {
+		if i != index && sp.Name == name {
+			util.ErrorDialog(w.AppWindow, fmt.Sprintf(glib.Local("Smart playlist \"%s\" already exists."), name))
+			return true
+		}
 	}
+	return false
 }
 
 // connect starts connecting to MPD
@@ -869,6 +1851,31 @@ func (w *MainWindow) disconnect() {
 	w.connector.Stop()
 }
 
+// editSmartPlaylistQuery asks for a smart playlist's search expression, repeating until MPD accepts it or the user
+// cancels. Returns the expression and whether it's been confirmed
+func (w *MainWindow) editSmartPlaylistQuery(query string) (string, bool) {
+	for {
+		var ok bool
+		query, ok = util.EditDialog(
+			w.AppWindow,
+			glib.Local("Search expression, eg. (Genre == \"Jazz\") or (modified-since '2020-01-01')"),
+			query,
+			glib.Local("OK"))
+		if !ok {
+			return "", false
+		}
+
+		// Validate the expression by running it
+		err := errors.New(glib.Local("Not connected to MPD"))
+		w.connector.IfConnected(func(client *mpd.Client) {
+			_, err = client.Find(query)
+		})
+		if !w.errCheckDialog(err, glib.Local("Invalid search expression")) {
+			return query, true
+		}
+	}
+}
+
 // errCheckDialog checks for error, and if it isn't nil, shows an error dialog ti the given text and the error info
 func (w *MainWindow) errCheckDialog(err error, message string) bool {
 	if err != nil {
@@ -880,6 +1887,81 @@ func (w *MainWindow) errCheckDialog(err error, message string) bool {
 	return false
 }
 
+// copyURIs puts the given URIs onto the clipboard, one per line. If localPaths is true, the URIs are translated into
+// local file paths within the music directory; URIs that have no local path, such as streams, are copied as is
+func (w *MainWindow) copyURIs(uris []string, localPaths bool) {
+	if len(uris) == 0 {
+		return
+	}
+	lines := make([]string, len(uris))
+	musicDir := config.GetConfig().MpdMusicDir
+	for i, uri := range uris {
+		lines[i] = uri
+		if localPaths {
+			if p, ok := util.URIToLocalPath(musicDir, uri); ok {
+				lines[i] = p
+			}
+		}
+	}
+	if clip, err := gtk.ClipboardGet(gdk.SELECTION_CLIPBOARD); !errCheck(err, "ClipboardGet() failed") {
+		clip.SetText(strings.Join(lines, "\n"))
+	}
+}
+
+// openContainingFolder opens the local folder containing the track with the given URI in the file manager, provided
+// the music directory is configured
+func (w *MainWindow) openContainingFolder(uri string) {
+	localPath, ok := util.URIToLocalPath(config.GetConfig().MpdMusicDir, uri)
+	if !ok {
+		return
+	}
+
+	// Make sure the folder exists locally, which is only the case if the music directory matches MPD's one
+	dir := path.Dir(localPath)
+	if _, err := os.Stat(dir); w.errCheckDialog(err, glib.Local("Folder not found in the music directory")) {
+		return
+	}
+	w.errCheckDialog(util.ShowURI(util.LocalPathToFileURL(dir)), glib.Local("Failed to open folder"))
+}
+
+// OpenURIs appends the given URIs to the queue and starts playing the first of them. Local files within the music
+// directory are translated into MPD URIs. If not connected to MPD yet, the URIs are played once the connection is
+// established
+func (w *MainWindow) OpenURIs(uris []string) {
+	if len(uris) == 0 {
+		return
+	}
+	if connected, _ := w.connector.ConnectStatus(); !connected {
+		w.pendingURIs = append(w.pendingURIs, uris...)
+		return
+	}
+
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		firstID := -1
+		for _, uri := range uris {
+			var id int
+			if id, err = client.AddID(mprisURIToMpd(uri), -1); err != nil {
+				return
+			}
+			if firstID < 0 {
+				firstID = id
+			}
+		}
+		err = client.PlayID(firstID)
+	})
+	w.errCheckDialog(err, glib.Local("Failed to add track(s) to the queue"))
+}
+
+// openPendingURIs plays the URIs requested before the connection to MPD was established, if connected now
+func (w *MainWindow) openPendingURIs() {
+	if connected, _ := w.connector.ConnectStatus(); connected && len(w.pendingURIs) > 0 {
+		uris := w.pendingURIs
+		w.pendingURIs = nil
+		w.OpenURIs(uris)
+	}
+}
+
 // focusMainList transfers the focus to the main list on the currently visible page
 func (w *MainWindow) focusMainList() {
 	var widget *gtk.Widget
@@ -887,9 +1969,15 @@ func (w *MainWindow) focusMainList() {
 	case "queue":
 		widget = &w.QueueTreeView.Widget
 
-	// Library: move focus to the selected row, if any
+	// Library: move focus to the selected row or tile, if any
 	case "library":
-		if row := w.LibraryListBox.GetSelectedRow(); row != nil {
+		if w.libGrid {
+			if children := w.LibraryFlowBox.GetSelectedChildren(); len(children) > 0 {
+				widget = &children[0].Widget
+			} else {
+				widget = &w.LibraryFlowBox.Widget
+			}
+		} else if row := util.ListBoxSelectedRow(w.LibraryListBox); row != nil {
 			widget = &row.Widget
 		} else {
 			widget = &w.LibraryListBox.Widget
@@ -902,6 +1990,14 @@ func (w *MainWindow) focusMainList() {
 		} else {
 			widget = &w.StreamsListBox.Widget
 		}
+
+	// Podcasts: move focus to the selected row, if any
+	case "podcasts":
+		if row := w.PodcastsListBox.GetSelectedRow(); row != nil {
+			widget = &row.Widget
+		} else {
+			widget = &w.PodcastsListBox.Widget
+		}
 	}
 
 	// Move focus
@@ -918,6 +2014,67 @@ func (w *MainWindow) getQueueSelectedCount() int {
 	return 0
 }
 
+// localPathsToElements converts the given local file paths into library elements, based on the configured MPD music
+// directory. Shows an error if any of the paths cannot be converted
+func (w *MainWindow) localPathsToElements(paths []string) []LibraryPathElement {
+	musicDir := config.GetConfig().MpdMusicDir
+	if musicDir == "" {
+		util.ErrorDialog(w.AppWindow, glib.Local("Music directory is not configured. Specify it in Preferences to be able to add local files."))
+		return nil
+	}
+
+	// Translate the paths into URIs
+	var elements []LibraryPathElement
+	var outside []string
+	for _, p := range paths {
+		uri, ok := util.LocalPathToURI(musicDir, p)
+		if !ok {
+			outside = append(outside, p)
+			continue
+		}
+
+		// Directories are added recursively
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			elements = append(elements, &DirLibElement{uri: uri})
+		} else {
+			elements = append(elements, &FileLibElement{uri: uri})
+		}
+	}
+
+	// Report paths outside the music directory
+	if len(outside) > 0 {
+		util.ErrorDialog(
+			w.AppWindow,
+			fmt.Sprintf(glib.Local("The following items are outside the music directory %s and cannot be added:\n%s"), musicDir, strings.Join(outside, "\n")))
+	}
+	return elements
+}
+
+// getQueueDropPosition returns the queue position corresponding to the given drop coordinates in the queue tree view.
+// If the coordinates aren't over any row, returns the queue size, which means append
+func (w *MainWindow) getQueueDropPosition(x, y int) int {
+	path, dropPos, ok := w.QueueTreeView.GetDestRowAtPos(x, y)
+	if !ok || path == nil {
+		return w.currentQueueSize
+	}
+
+	// Convert the provided tree (filtered) path into unfiltered one
+	queuePath := w.QueueTreeModelFilter.ConvertPathToChildPath(path)
+	if queuePath == nil {
+		return w.currentQueueSize
+	}
+	ix := queuePath.GetIndices()
+	if len(ix) == 0 {
+		return w.currentQueueSize
+	}
+
+	// Insert after the row if dropped onto its lower half
+	if dropPos == gtk.TREE_VIEW_DROP_AFTER || dropPos == gtk.TREE_VIEW_DROP_INTO_OR_AFTER {
+		return ix[0] + 1
+	}
+	return ix[0]
+}
+
 // getQueueSelectedIndices returns indices of the currently selected rows in the queue
 func (w *MainWindow) getQueueSelectedIndices() []int {
 	// Get the tree's selection
@@ -939,6 +2096,33 @@ func (w *MainWindow) getQueueSelectedIndices() []int {
 	return indices
 }
 
+// getQueueSelectedURIs returns URIs of the currently selected tracks in the queue
+func (w *MainWindow) getQueueSelectedURIs() ([]string, error) {
+	indices := w.getQueueSelectedIndices()
+	if len(indices) == 0 {
+		return nil, nil
+	}
+
+	// Fetch the queue
+	var attrs []mpd.Attrs
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.PlaylistInfo(-1, -1)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the selected tracks
+	uris := make([]string, 0, len(indices))
+	for _, idx := range indices {
+		if idx < len(attrs) {
+			uris = append(uris, attrs[idx]["file"])
+		}
+	}
+	return uris, nil
+}
+
 // getQueueSelectedTrackAttrs returns attributes of the first currently selected row in the queue
 func (w *MainWindow) getQueueSelectedTrackAttrs() (mpd.Attrs, error) {
 	// Get the tree's selection
@@ -966,17 +2150,49 @@ func (w *MainWindow) getQueueSelectedTrackAttrs() (mpd.Attrs, error) {
 	return nil, errors.New("No selection in the queue")
 }
 
-// getSelectedLibraryElement returns the path element of the currently selected library item or nil if there's an error
+// getSelectedLibraryElement returns the path element of the (first) currently selected library item or nil if there's
+// an error
 func (w *MainWindow) getSelectedLibraryElement() LibraryPathElement {
-	// If there's selection
-	row := w.LibraryListBox.GetSelectedRow()
-	if row == nil {
-		return nil
+	if elements := w.getSelectedLibraryElements(); len(elements) > 0 {
+		return elements[0]
 	}
+	return nil
+}
+
+// getSelectedLibraryElements returns the path elements of all currently selected library items
+func (w *MainWindow) getSelectedLibraryElements() []LibraryPathElement {
+	var elements []LibraryPathElement
+
+	// Grid mode: iterate selected tiles
+	if w.libGrid {
+		for _, child := range w.LibraryFlowBox.GetSelectedChildren() {
+			if element := w.getLibraryWidgetElement(&child.Widget); element != nil {
+				elements = append(elements, element)
+			}
+		}
+		return elements
+	}
+
+	// List mode: iterate selected rows
+	w.LibraryListBox.GetSelectedRows().Foreach(func(item interface{}) {
+		if element := w.getLibraryRowElement(item.(*gtk.ListBoxRow)); element != nil {
+			elements = append(elements, element)
+		}
+	})
+	return elements
+}
+
+// getLibraryRowElement returns the path element stored in the given library list row, or nil if there's an error
+func (w *MainWindow) getLibraryRowElement(row *gtk.ListBoxRow) LibraryPathElement {
+	return w.getLibraryWidgetElement(&row.Widget)
+}
 
-	// Extract path, which is stored in the row's name
-	name, err := row.GetName()
-	if errCheck(err, "getSelectedLibraryPath(): row.GetName() failed") {
+// getLibraryWidgetElement returns the path element stored in the given library row or tile widget, or nil if there's
+// an error
+func (w *MainWindow) getLibraryWidgetElement(widget *gtk.Widget) LibraryPathElement {
+	// Extract path, which is stored in the widget's name
+	name, err := widget.GetName()
+	if errCheck(err, "getLibraryWidgetElement(): GetName() failed") {
 		return nil
 	}
 
@@ -987,6 +2203,50 @@ func (w *MainWindow) getSelectedLibraryElement() LibraryPathElement {
 	return nil
 }
 
+// getSelectedSmartPlaylistIndex returns the index of the currently selected smart playlist in the config, or -1 if
+// there's none
+func (w *MainWindow) getSelectedSmartPlaylistIndex() int {
+	if e, ok := w.getSelectedLibraryElement().(*SmartPlaylistLibElement); ok {
+		for i, sp := range config.GetConfig().SmartPlaylists {
+			if sp.Name == e.Label() {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// getSelectedPodcastIndex returns the index of the currently selected podcast or episode row, or -1 if there's none
+func (w *MainWindow) getSelectedPodcastIndex() int {
+	// If there's selection
+	row := w.PodcastsListBox.GetSelectedRow()
+	if row == nil {
+		return -1
+	}
+	return row.GetIndex()
+}
+
+// getSelectedPodcastEpisode returns the currently selected podcast episode, or nil if there's none
+func (w *MainWindow) getSelectedPodcastEpisode() *PodcastEpisode {
+	feed := w.podcastFeeds[w.podcastURL]
+	if idx := w.getSelectedPodcastIndex(); feed != nil && idx >= 0 && idx < len(feed.Episodes) {
+		return &feed.Episodes[idx]
+	}
+	return nil
+}
+
+// getPodcastTargetURL returns the feed URL of the podcast actions apply to: the open podcast, if any, otherwise the
+// selected one. Returns an empty string if there's no such podcast
+func (w *MainWindow) getPodcastTargetURL() string {
+	if w.podcastURL != "" {
+		return w.podcastURL
+	}
+	if idx := w.getSelectedPodcastIndex(); idx >= 0 && idx < len(config.GetConfig().Podcasts) {
+		return config.GetConfig().Podcasts[idx].URL
+	}
+	return ""
+}
+
 // getSelectedStreamIndex returns the index of the currently selected stream, or -1 if there's an error
 func (w *MainWindow) getSelectedStreamIndex() int {
 	// If there's selection
@@ -1028,6 +2288,11 @@ func (w *MainWindow) information() {
 		updateTime = time.Unix(i, 0).Format("2006-01-02 15:04:05")
 	}
 
+	// Indicate an ongoing database update, if any
+	if _, ok := w.connector.Status()["updating_db"]; ok {
+		updateTime += " — " + glib.Local("updating database…")
+	}
+
 	// Load widgets from Glade file
 	var dlg struct {
 		MPDInfoDialog           *gtk.MessageDialog
@@ -1079,22 +2344,70 @@ func (w *MainWindow) information() {
 	dlg.MPDInfoDialog.Run()
 }
 
-// initLibraryWidgets initialises library widgets and actions
-func (w *MainWindow) initLibraryWidgets() {
-	// Create actions
-	w.aLibraryUpdate = w.addAction("library.update", "", w.LibraryUpdatePopoverMenu.Popup)
+// dndTargets returns a list of drag-and-drop targets supported by the application, or nil on error. If withURIList is
+// true, the list also includes a target for files dragged from other applications
+func (w *MainWindow) dndTargets(withURIList bool) []gtk.TargetEntry {
+	te, err := gtk.TargetEntryNew(dndTargetLibraryElements, gtk.TARGET_SAME_APP, dndInfoLibraryElements)
+	if errCheck(err, "TargetEntryNew() failed") {
+		return nil
+	}
+	targets := []gtk.TargetEntry{*te}
+
+	// Add a URI list target, if needed
+	if withURIList {
+		if te, err = gtk.TargetEntryNew(dndTargetURIList, gtk.TARGET_OTHER_APP, dndInfoURIList); errCheck(err, "TargetEntryNew() failed") {
+			return nil
+		}
+		targets = append(targets, *te)
+	}
+	return targets
+}
+
+// queueDndTargets returns a list with the drag-and-drop target for queue tracks, or nil on error
+func (w *MainWindow) queueDndTargets() []gtk.TargetEntry {
+	te, err := gtk.TargetEntryNew(dndTargetQueueTracks, gtk.TARGET_SAME_APP, dndInfoQueueTracks)
+	if errCheck(err, "TargetEntryNew() failed") {
+		return nil
+	}
+	return []gtk.TargetEntry{*te}
+}
+
+// initLibraryWidgets initialises library widgets and actions
+func (w *MainWindow) initLibraryWidgets() {
+	// Create actions
+	w.aLibraryUpdate = w.addAction("library.update", "", w.LibraryUpdatePopoverMenu.Popup)
+	w.addAction("library.sort", "", w.LibrarySortPopoverMenu.Popup)
+	w.aLibraryBookmark = w.addAction("library.bookmark", "", w.libraryToggleBookmark)
 	w.aLibraryUpdateAll = w.addAction("library.update.all", "", func() { w.libraryUpdate(false, false) })
 	w.aLibraryUpdateSel = w.addAction("library.update.selected", "", func() { w.libraryUpdate(false, true) })
+	w.aLibraryUpdateCur = w.addAction("library.update.current", "", w.libraryUpdateCurrent)
 	w.aLibraryRescanAll = w.addAction("library.rescan.all", "", func() { w.libraryUpdate(true, false) })
 	w.aLibraryRescanSel = w.addAction("library.rescan.selected", "", func() { w.libraryUpdate(true, true) })
 	w.aLibraryRename = w.addAction("library.rename", "", w.libraryRename)
+	w.aLibraryDuplicate = w.addAction("library.duplicate", "", w.libraryDuplicate)
 	w.aLibraryDelete = w.addAction("library.delete", "", w.libraryDelete)
+	w.aLibraryRemoveDups = w.addAction("library.remove-duplicates", "", w.libraryRemoveDuplicates)
 	w.aLibraryAddToPlaylist = w.addAction("library.add-to-playlist", "", w.libraryAddToPlaylist)
 	w.addAction("library.search.toggle", "", w.onLibrarySearchToggle)
+	w.addAction("library.grid.toggle", "", w.onLibraryGridToggle)
+	w.addAction("library.fuzzy.toggle", "", w.onLibraryFuzzyToggle)
+	w.addAction("library.regex.toggle", "", w.onLibraryRegexToggle)
+	w.aLibraryAddFolder = w.addAction("library.add-folder", "", func() { w.libraryAddFolder(tbFalse) })
+	w.aLibraryAddFiltered = w.addAction("library.add-folder-filtered", "", w.libraryAddFolderFiltered)
+	w.aLibrarySearchAddAll = w.addAction("library.search.add-all", "", func() { w.queueURIs(tbFalse, w.libSearchURIs...) })
 
 	// Create a library path instance
 	w.libPath = NewLibraryPath(w.onLibraryPathChanged)
 
+	// Set up filtering of the library list
+	w.LibraryListBox.SetFilterFunc(w.libraryFilterRow)
+
+	// Allow dragging library items
+	if targets := w.dndTargets(false); targets != nil {
+		w.LibraryListBox.DragSourceSet(gdk.BUTTON1_MASK, targets, gdk.ACTION_COPY)
+		w.LibraryFlowBox.DragSourceSet(gdk.BUTTON1_MASK, targets, gdk.ACTION_COPY)
+	}
+
 	// Populate search attribute combo box
 	w.LibrarySearchAttrComboBox.Append(librarySearchAllAttrID, glib.Local("Everywhere"))
 	for _, id := range config.MpdTrackAttributeIds {
@@ -1103,19 +2416,44 @@ func (w *MainWindow) initLibraryWidgets() {
 		}
 	}
 	w.LibrarySearchAttrComboBox.SetActiveID(librarySearchAllAttrID)
+
+	// Initialise library sort widgets
+	cfg := config.GetConfig()
+	w.libSortUpdating = true
+	w.LibrarySortByComboBox.SetActiveID(cfg.LibrarySortBy)
+	w.LibrarySortFoldersFirstCheckButton.SetActive(cfg.LibraryFoldersFirst)
+	w.libSortUpdating = false
+
+	// Initialise library matching mode widgets
+	w.libMatchUpdating = true
+	w.LibraryFuzzyToolButton.SetActive(cfg.LibraryFuzzySearch)
+	w.LibraryRegexToolButton.SetActive(cfg.LibraryRegexSearch)
+	w.libMatchUpdating = false
 }
 
 // initPlayerWidgets initialises player widgets and actions
 func (w *MainWindow) initPlayerWidgets() {
-	// Create actions
-	w.aPlayerPrevious = w.addAction("player.previous", "<Ctrl>Left", w.playerPrevious)
+	// Create actions. The previous and next track shortcuts follow the order of the player buttons, which is mirrored
+	// in a right-to-left layout
+	prevAccel, nextAccel := "<Ctrl>Left", "<Ctrl>Right"
+	if isRTL() {
+		prevAccel, nextAccel = nextAccel, prevAccel
+	}
+	w.aPlayerPrevious = w.addAction("player.previous", prevAccel, w.playerPrevious)
 	w.aPlayerStop = w.addAction("player.stop", "<Ctrl>S", w.playerStop)
 	w.aPlayerPlayPause = w.addAction("player.play-pause", "<Ctrl>P", w.playerPlayPause)
-	w.aPlayerNext = w.addAction("player.next", "<Ctrl>Right", w.playerNext)
+	w.aPlayerNext = w.addAction("player.next", nextAccel, w.playerNext)
 	// NB convert to stateful actions once Gotk3 supporting GVariant is released
 	w.aPlayerRandom = w.addAction("player.toggle.random", "<Ctrl>U", w.playerToggleRandom)
 	w.aPlayerRepeat = w.addAction("player.toggle.repeat", "<Ctrl>R", w.playerToggleRepeat)
 	w.aPlayerConsume = w.addAction("player.toggle.consume", "<Ctrl>N", w.playerToggleConsume)
+	w.aPlayerListen = w.addAction("player.toggle.listen", "", w.playerToggleListen)
+	w.ListenButton.SetVisible(streamListenerAvailable)
+	w.aPlayerStar = w.addAction("player.star", "<Ctrl>D", w.playerStar)
+	w.aPlayerRadioRemember = w.addAction("player.radio.remember", "<Ctrl>H", w.playerRadioRemember)
+	w.aPlayerResume = w.addAction("player.resume", "", w.playerResume)
+	w.aPlayerLove = w.addAction("player.love", "<Ctrl><Shift>L", w.playerLove)
+	w.aPlayerSimilar = w.addAction("player.similar", "<Ctrl><Shift>M", w.playerSimilar)
 }
 
 // initQueueWidgets initialises queue widgets and actions
@@ -1123,10 +2461,25 @@ func (w *MainWindow) initQueueWidgets() {
 	// Configure the search bar
 	glib.BindProperty(w.QueueSearchBar.Object, "search-mode-enabled", w.QueueFilterToolButton.Object, "active", glib.BINDING_BIDIRECTIONAL)
 	glib.BindProperty(w.QueueSearchBar.Object, "search-mode-enabled", w.QueueFilterLabel.Object, "visible", glib.BINDING_DEFAULT)
+	w.QueueSearchBar.ConnectEntry(w.QueueSearchEntry)
+
+	// Restore the filter matching mode
+	w.QueueRegexToggleButton.SetActive(config.GetConfig().QueueRegexFilter)
 
 	// Forcefully disable tree search popup on Ctrl+F
 	w.QueueTreeView.SetSearchColumn(-1)
 
+	// Accept items dropped onto the queue
+	if targets := w.dndTargets(true); targets != nil {
+		w.QueueTreeView.DragDestSet(gtk.DEST_DEFAULT_ALL, targets, gdk.ACTION_COPY)
+	}
+
+	// Allow dragging queue tracks onto stored playlists. Unlike a plain drag source, the tree view's own one keeps
+	// multiple selected rows selected when the drag starts, and doesn't interfere with rubber band selection
+	if targets := w.queueDndTargets(); targets != nil {
+		w.QueueTreeView.EnableModelDragSource(gdk.BUTTON1_MASK, targets, gdk.ACTION_COPY)
+	}
+
 	// Create actions
 	w.aQueueNowPlaying = w.addAction("queue.now-playing", "<Ctrl>J", w.updateQueueNowPlaying)
 	w.aQueueClear = w.addAction("queue.clear", "", w.queueClear)
@@ -1138,6 +2491,7 @@ func (w *MainWindow) initQueueWidgets() {
 	w.aQueueSave = w.addAction("queue.save", "", w.queueSave)
 	w.aQueueSaveReplace = w.addAction("queue.save.replace", "", func() { w.queueSaveApply(true) })
 	w.aQueueSaveAppend = w.addAction("queue.save.append", "", func() { w.queueSaveApply(false) })
+	w.aQueueSaveBack = w.addAction("queue.save-back", "<Ctrl><Shift>S", w.queueSaveBack)
 
 	// Populate "Queue sort by" combo box
 	for _, id := range config.MpdTrackAttributeIds {
@@ -1156,6 +2510,72 @@ func (w *MainWindow) initStreamsWidgets() {
 	w.aStreamEdit = w.addAction("stream.edit", "", w.onStreamEdit)
 	w.aStreamDelete = w.addAction("stream.delete", "", w.onStreamDelete)
 	w.aStreamPropsApply = w.addAction("stream.props.apply", "", w.onStreamPropsApply)
+	w.aStreamFind = w.addAction("stream.find", "", w.onStreamFind)
+	w.aStreamImport = w.addAction("stream.import", "", w.onStreamImport)
+	w.aStreamExport = w.addAction("stream.export", "", w.onStreamExport)
+}
+
+// initPodcastsWidgets initialises podcasts widgets and actions
+func (w *MainWindow) initPodcastsWidgets() {
+	// Create actions
+	w.aPodcastBack = w.addAction("podcast.back", "", w.onPodcastBack)
+	w.aPodcastSubscribe = w.addAction("podcast.subscribe", "", w.onPodcastSubscribe)
+	w.aPodcastUnsubscribe = w.addAction("podcast.unsubscribe", "", w.onPodcastUnsubscribe)
+	w.aPodcastRefresh = w.addAction("podcast.refresh", "", w.onPodcastRefresh)
+}
+
+// initMediaKeys grabs or releases the hardware media keys, depending on whether it's enabled in the config
+func (w *MainWindow) initMediaKeys() {
+	enabled := config.GetConfig().MediaKeys
+	switch {
+	case enabled && w.mediaKeys == nil:
+		keys, err := NewMediaKeys(w.activateAction)
+		if err != nil {
+			log.Warningf("Failed to grab media keys: %v", err)
+			return
+		}
+		w.mediaKeys = keys
+	case !enabled && w.mediaKeys != nil:
+		w.mediaKeys.Close()
+		w.mediaKeys = nil
+	}
+}
+
+// initSessionMonitor starts or stops watching screen lock and suspend, depending on whether pausing on either is
+// enabled in the config
+func (w *MainWindow) initSessionMonitor() {
+	cfg := config.GetConfig()
+	enabled := cfg.PauseOnLock || cfg.PauseOnSuspend
+	switch {
+	case enabled && w.sessionMonitor == nil:
+		monitor, err := NewSessionMonitor(w.connector)
+		if err != nil {
+			log.Warningf("Failed to monitor the session: %v", err)
+			return
+		}
+		w.sessionMonitor = monitor
+	case !enabled && w.sessionMonitor != nil:
+		w.sessionMonitor.Close()
+		w.sessionMonitor = nil
+	}
+}
+
+// initTrayIcon creates or removes the tray icon, depending on whether it's enabled in the config
+func (w *MainWindow) initTrayIcon() {
+	enabled := config.GetConfig().TrayIcon
+	switch {
+	case enabled && w.trayIcon == nil:
+		icon, err := NewTrayIcon(w.ToggleWindow, w.activateAction, w.onTrayVolumeChange)
+		if err != nil {
+			log.Warningf("Failed to create tray icon: %v", err)
+			return
+		}
+		w.trayIcon = icon
+		w.updateTrayIcon()
+	case !enabled && w.trayIcon != nil:
+		w.trayIcon.Close()
+		w.trayIcon = nil
+	}
 }
 
 // initWidgets initialises all widgets and actions
@@ -1167,18 +2587,32 @@ func (w *MainWindow) initWidgets() {
 	w.addAction("mpd.connect", "<Ctrl><Shift>C", w.connect)
 	w.aMPDDisconnect = w.addAction("mpd.disconnect", "<Ctrl><Shift>D", w.disconnect)
 	w.aMPDInfo = w.addAction("mpd.info", "<Ctrl><Shift>I", w.information)
+	w.addAction("listening-stats", "", func() { ListeningStatsDialog(w.AppWindow, w.history) })
+	w.addAction("heard-on-radio", "", func() {
+		HeardOnRadioDialog(w.AppWindow, w.radioLog)
+		w.updatePlayerRadioRemember()
+	})
+	w.aPlaylistImport = w.addAction("playlist.import", "", w.playlistImport)
+	w.aPlaylistRestore = w.addAction("playlist.restore", "", w.playlistRestore)
+	w.aPlaylistAppendRecent = w.addAction("playlist.append-recent", "<Ctrl>L", w.appendSelectionToRecentPlaylist)
 	w.addAction("prefs", "<Ctrl>comma", w.preferences)
+	w.addAction("config.reload", "", func() { w.reloadConfig(true) })
 	w.addAction("about", "F1", w.about)
 	w.addAction("shortcuts", "<Ctrl><Shift>question", w.shortcutInfo)
-	w.addAction("quit", "<Ctrl>Q", w.AppWindow.Close)
+	w.addAction("quit", "<Ctrl>Q", w.quit)
+	w.addAction("show", "", w.AppWindow.Present)
+	w.addAction("toggle", "", w.ToggleWindow)
 	w.addAction("page.queue", "<Ctrl>1", func() { w.MainStack.SetVisibleChild(w.QueueBox) })
 	w.addAction("page.library", "<Ctrl>2", func() { w.MainStack.SetVisibleChild(w.LibraryBox) })
 	w.addAction("page.streams", "<Ctrl>3", func() { w.MainStack.SetVisibleChild(w.StreamsBox) })
+	w.addAction("page.podcasts", "<Ctrl>4", func() { w.MainStack.SetVisibleChild(w.PodcastsBox) })
+	w.addAction("view.artist-pane", "", w.onArtistPaneToggle)
 
 	// Init other widgets and actions
 	w.initQueueWidgets()
 	w.initLibraryWidgets()
 	w.initStreamsWidgets()
+	w.initPodcastsWidgets()
 	w.initPlayerWidgets()
 }
 
@@ -1210,6 +2644,120 @@ func (w *MainWindow) libraryAddToPlaylist() {
 	w.LibraryAddToPlaylistPopoverMenu.Popup()
 }
 
+// libraryAddSelectionToPlaylist appends the selected library elements to the playlist with the given name
+func (w *MainWindow) libraryAddSelectionToPlaylist(name string) {
+	// Resolve all selected playable elements into URIs and append them to the playlist
+	if uris, ok := w.resolveLibraryElements(w.getSelectedLibraryElements(), glib.Local("Failed to add item to the playlist")); ok && len(uris) > 0 {
+		w.libraryAppendPlaylist(name, uris...)
+	}
+}
+
+// updateAddToRecentMenuItem updates the label and visibility of the given menu item adding the selection to the most
+// recently used playlist
+func (w *MainWindow) updateAddToRecentMenuItem(item *gtk.MenuItem) {
+	name := w.recentTargetPlaylist()
+	item.SetLabel(fmt.Sprintf(glib.Local("Add to \"%s\""), name))
+	item.SetVisible(name != "")
+}
+
+// recentTargetPlaylist returns the name of the playlist tracks have most recently been added to, or an empty string
+// if none
+func (w *MainWindow) recentTargetPlaylist() string {
+	if recent := config.GetConfig().PlaylistRecentTargets; len(recent) > 0 {
+		return recent[0]
+	}
+	return ""
+}
+
+// appendSelectionToRecentPlaylist adds the selection on the current page to the most recently used playlist
+func (w *MainWindow) appendSelectionToRecentPlaylist() {
+	name := w.recentTargetPlaylist()
+	if name == "" {
+		return
+	}
+	switch w.MainStack.GetVisibleChildName() {
+	case "queue":
+		w.queueAddSelectionToPlaylist(name)
+	case "library":
+		w.libraryAddSelectionToPlaylist(name)
+	}
+}
+
+// updateAddToPlaylistMenu repopulates the given "Add to playlist" submenu with an item per stored playlist, followed by
+// an item for a new playlist. onSelect is called with the name of the chosen playlist
+func (w *MainWindow) updateAddToPlaylistMenu(menu *gtk.Menu, onSelect func(name string)) {
+	util.ClearChildren(menu.Container)
+
+	// addItem adds a new menu item with the given label
+	addItem := func(label string, onActivate func()) {
+		item, err := gtk.MenuItemNewWithLabel(label)
+		if errCheck(err, "MenuItemNewWithLabel() failed") {
+			return
+		}
+		if _, err := item.Connect("activate", onActivate); errCheck(err, "Connect() failed") {
+			return
+		}
+		menu.Append(item)
+	}
+
+	// Add existing playlists, the recently used ones first
+	names := w.connector.GetPlaylists()
+	recent := config.GetConfig().PlaylistRecentTargets
+	recentIndex := func(name string) int {
+		for i, s := range recent {
+			if s == name {
+				return i
+			}
+		}
+		return len(recent)
+	}
+	sort.SliceStable(names, func(i, j int) bool { return recentIndex(names[i]) < recentIndex(names[j]) })
+	for _, name := range names {
+		name := name // Make an in-loop copy of name
+		addItem(name, func() { onSelect(name) })
+	}
+	if sep, err := gtk.SeparatorMenuItemNew(); !errCheck(err, "SeparatorMenuItemNew() failed") {
+		menu.Append(sep)
+	}
+
+	// Add an item for creating a new playlist
+	addItem(glib.Local("New playlist…"), func() {
+		if name, ok := util.EditDialog(w.AppWindow, glib.Local("New playlist"), "", glib.Local("Create")); ok && !w.checkPlaylistExists(name) {
+			onSelect(name)
+		}
+	})
+	menu.ShowAll()
+}
+
+// libraryAddFolder recursively adds the tracks from the selected library folder to the queue
+func (w *MainWindow) libraryAddFolder(replace triBool) {
+	if e, ok := w.getSelectedLibraryElement().(*DirLibElement); ok {
+		w.queueFolder(replace, e.URI(), nil)
+	}
+}
+
+// libraryAddFolderFiltered asks for file types and recursively adds the tracks of these types from the selected library
+// folder to the queue
+func (w *MainWindow) libraryAddFolderFiltered() {
+	e, ok := w.getSelectedLibraryElement().(*DirLibElement)
+	if !ok {
+		return
+	}
+
+	// Ask for file types, defaulting to the last used ones
+	cfg := config.GetConfig()
+	types, ok := util.EditDialog(
+		w.AppWindow,
+		glib.Local("File types to add, separated by semicolons (eg. flac; 24/96)"),
+		cfg.LibraryAddFileTypes,
+		glib.Local("Add"))
+	if !ok {
+		return
+	}
+	cfg.LibraryAddFileTypes = types
+	w.queueFolder(tbFalse, e.URI(), parsePatternList(types))
+}
+
 // libraryAppendPlaylist appends the provided URIs to a playlist with the given name
 func (w *MainWindow) libraryAppendPlaylist(name string, uris ...string) {
 	err := errors.New(glib.Local("Not connected to MPD"))
@@ -1222,147 +2770,908 @@ func (w *MainWindow) libraryAppendPlaylist(name string, uris ...string) {
 	})
 
 	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to add item to the playlist"))
+	if !w.errCheckDialog(err, glib.Local("Failed to add item to the playlist")) {
+		// Remember the playlist as the most recent target
+		cfg := config.GetConfig()
+		cfg.PlaylistRecentTargets = util.PrependRecent(cfg.PlaylistRecentTargets, name, playlistRecentTargetsMax)
+		w.aPlaylistAppendRecent.SetEnabled(true)
+	}
 }
 
-// libraryDelete allows to delete the selected library element
-func (w *MainWindow) libraryDelete() {
-	element := w.getSelectedLibraryElement()
-	if ph, ok := element.(PlaylistHolder); ok {
-		if util.ConfirmDialog(w.AppWindow, glib.Local("Delete playlist"), fmt.Sprintf(glib.Local("Are you sure you want to delete playlist \"%s\"?"), ph.PlaylistName())) {
-			var err error
-			w.connector.IfConnected(func(client *mpd.Client) {
-				err = client.PlaylistRemove(ph.PlaylistName())
-			})
-			// Check for error (outside IfConnected() because it would keep the client locked)
-			w.errCheckDialog(err, glib.Local("Failed to delete the playlist"))
+// libraryCopy copies the URIs or local file paths of the selected library elements to the clipboard
+func (w *MainWindow) libraryCopy(localPaths bool) {
+	// Collect element URIs
+	var uris []string
+	for _, element := range w.getSelectedLibraryElements() {
+		if uh, ok := element.(URIHolder); ok {
+			uris = append(uris, uh.URI())
+		} else if ph, ok := element.(PlaylistHolder); ok {
+			uris = append(uris, ph.PlaylistName())
 		}
 	}
+	w.copyURIs(uris, localPaths)
 }
 
-// libraryLevelUp navigates to the library element at the upper level
-func (w *MainWindow) libraryLevelUp() {
-	if e := w.libPath.Last(); e != nil {
-		// Save the currently active path element for subsequent selection
-		w.libPathElementToSelect = e.Marshal()
-		// Move up a level
-		w.libPath.LevelUp()
+// libraryOpenFolder opens the local folder containing the selected library file in the file manager
+func (w *MainWindow) libraryOpenFolder() {
+	if e, ok := w.getSelectedLibraryElement().(*FileLibElement); ok {
+		w.openContainingFolder(e.URI())
 	}
 }
 
-// libraryRename allows to rename the selected library element
-func (w *MainWindow) libraryRename() {
-	element := w.getSelectedLibraryElement()
-	if ph, ok := element.(PlaylistHolder); ok {
-		if newName, ok := util.EditDialog(w.AppWindow, glib.Local("Rename playlist"), ph.PlaylistName(), glib.Local("Rename")); ok {
-			var err error
-			w.connector.IfConnected(func(client *mpd.Client) {
-				err = client.PlaylistRename(ph.PlaylistName(), newName)
-			})
-			// Check for error (outside IfConnected() because it would keep the client locked)
-			w.errCheckDialog(err, glib.Local("Failed to rename the playlist"))
+// libraryDelete allows to delete the selected library elements
+func (w *MainWindow) libraryDelete() {
+	// Collect selected playlist names
+	names := w.getSelectedPlaylistNames()
+
+	// Compose a confirmation question
+	var question string
+	switch len(names) {
+	case 0:
+		return
+	case 1:
+		question = fmt.Sprintf(glib.Local("Are you sure you want to delete playlist \"%s\"?"), names[0])
+	default:
+		question = fmt.Sprintf(util.LocalN("Are you sure you want to delete %d playlist?", "Are you sure you want to delete %d playlists?", len(names)), len(names))
+	}
+
+	// Ask for a confirmation
+	if util.ConfirmDialog(w.AppWindow, glib.Local("Delete playlist"), question) {
+		var err error
+		w.connector.IfConnected(func(client *mpd.Client) {
+			// Back the playlists up first
+			if err = w.backupPlaylists(client, names...); err != nil {
+				return
+			}
+			commands := client.BeginCommandList()
+			for _, name := range names {
+				commands.PlaylistRemove(name)
+			}
+			err = commands.End()
+		})
+		// Check for error (outside IfConnected() because it would keep the client locked)
+		if !w.errCheckDialog(err, glib.Local("Failed to delete the playlist")) {
+			// Forget the folders of the deleted playlists, and drop them from the recent targets
+			cfg := config.GetConfig()
+			deleted := make(map[string]bool, len(names))
+			for _, name := range names {
+				delete(cfg.PlaylistFolders, name)
+				deleted[name] = true
+			}
+			var recent []string
+			for _, name := range cfg.PlaylistRecentTargets {
+				if !deleted[name] {
+					recent = append(recent, name)
+				}
+			}
+			cfg.PlaylistRecentTargets = recent
+			w.aPlaylistAppendRecent.SetEnabled(len(recent) > 0)
+
+			// Forget the playlist the queue has been loaded from if it's gone
+			if w.queueSource != nil && deleted[w.queueSource.Name] {
+				w.queueSource = nil
+				w.updateQueueInfo()
+			}
 		}
 	}
 }
 
-// libraryShowAlbumFromQueue opens the currently selected queue album in the library
-func (w *MainWindow) libraryShowAlbumFromQueue() {
-	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get album information")) {
-		// Update the current library path
-		w.libPath.SetElements([]LibraryPathElement{
-			NewArtistsLibElement(),
-			NewArtistLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrArtist].AttrName]),
-			NewAlbumLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrAlbum].AttrName]),
-		})
+// getSelectedPlaylistNames returns the names of all currently selected playlists in the library
+func (w *MainWindow) getSelectedPlaylistNames() []string {
+	var names []string
+	for _, element := range w.getSelectedLibraryElements() {
+		if ph, ok := element.(PlaylistHolder); ok {
+			names = append(names, ph.PlaylistName())
+		}
+	}
+	return names
+}
 
-		// Switch to the library tab
-		w.MainStack.SetVisibleChild(w.LibraryBox)
+// libraryMoveToFolder allows to put the selected playlists into a virtual folder
+func (w *MainWindow) libraryMoveToFolder() {
+	names := w.getSelectedPlaylistNames()
+	if len(names) == 0 {
+		return
+	}
+	folder := config.GetConfig().PlaylistFolders[names[0]]
+	if folder, ok := util.EditDialog(w.AppWindow, glib.Local("Move to folder"), folder, glib.Local("Move")); ok {
+		w.setPlaylistFolder(folder, names...)
 	}
 }
 
-// libraryShowArtistFromQueue opens the currently selected queue artist in the library
-func (w *MainWindow) libraryShowArtistFromQueue() {
-	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get artist information")) {
-		// Update the current library path
-		w.libPath.SetElements([]LibraryPathElement{
-			NewArtistsLibElement(),
-			NewArtistLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrArtist].AttrName]),
-		})
+// libraryRemoveFromFolder takes the selected playlists out of their virtual folders
+func (w *MainWindow) libraryRemoveFromFolder() {
+	if names := w.getSelectedPlaylistNames(); len(names) > 0 {
+		w.setPlaylistFolder("", names...)
+	}
+}
 
-		// Switch to the library tab
-		w.MainStack.SetVisibleChild(w.LibraryBox)
+// setPlaylistFolder puts the given playlists into the virtual folder with the provided name, or out of any folder if
+// it's empty, and reloads the library
+func (w *MainWindow) setPlaylistFolder(folder string, names ...string) {
+	cfg := config.GetConfig()
+	if cfg.PlaylistFolders == nil {
+		cfg.PlaylistFolders = make(map[string]string)
+	}
+	for _, name := range names {
+		if folder == "" {
+			delete(cfg.PlaylistFolders, name)
+		} else {
+			cfg.PlaylistFolders[name] = folder
+		}
 	}
+	w.updateLibrary()
 }
 
-// libraryShowGenreFromQueue opens the currently selected queue genre in the library
-func (w *MainWindow) libraryShowGenreFromQueue() {
-	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get genre information")) {
-		// Update the current library path
-		w.libPath.SetElements([]LibraryPathElement{
-			NewGenresLibElement(),
-			NewGenreLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrGenre].AttrName]),
-		})
+// onPlaylistFolderToggle collapses or expands the virtual playlist folder with the given name
+func (w *MainWindow) onPlaylistFolderToggle(folder string, expanded bool) {
+	cfg := config.GetConfig()
+	if cfg.PlaylistFoldersFolded == nil {
+		cfg.PlaylistFoldersFolded = make(map[string]bool)
+	}
+	if expanded {
+		delete(cfg.PlaylistFoldersFolded, folder)
+	} else {
+		cfg.PlaylistFoldersFolded[folder] = true
+	}
+	w.LibraryListBox.InvalidateFilter()
 
-		// Switch to the library tab
-		w.MainStack.SetVisibleChild(w.LibraryBox)
+	// Drop the selection if it's been hidden
+	if row := util.ListBoxSelectedRow(w.LibraryListBox); row != nil && !w.libraryFilterRow(row) {
+		w.LibraryListBox.UnselectAll()
 	}
 }
 
-// libraryUpdate updates or rescans the library
-func (w *MainWindow) libraryUpdate(rescan, selectedOnly bool) {
-	// Determine the update path
-	libPath := ""
-	if selectedOnly {
-		// We only support updating file-based items
-		uh, ok := w.getSelectedLibraryElement().(URIHolder)
-		if !ok {
-			return
+// getPlaylistDropPosition returns the position in the open stored playlist tracks dropped onto the given library row are
+// to be inserted at: that of the row's track, 0 for the level-up row, or -1 (append) for the totals header and for no row
+func (w *MainWindow) getPlaylistDropPosition(row *gtk.ListBoxRow) int {
+	if row == nil {
+		return -1
+	}
+	switch w.getLibraryRowElement(row).(type) {
+	case *LevelUpLibElement:
+		return 0
+	case *FileLibElement:
+		// Tracks are listed in the playlist order, so the track's position is the number of track rows above it
+		pos := 0
+		for i := 0; i < row.GetIndex(); i++ {
+			if _, ok := w.getLibraryRowElement(w.LibraryListBox.GetRowAtIndex(i)).(*FileLibElement); ok {
+				pos++
+			}
 		}
-		libPath = uh.URI()
+		return pos
 	}
+	return -1
+}
 
-	// Run the update
-	var err error
-	w.connector.IfConnected(func(client *mpd.Client) {
-		if rescan {
-			_, err = client.Rescan(libPath)
-		} else {
-			_, err = client.Update(libPath)
+// libraryElementURIs resolves the given playable library element into a list of track URIs
+func (w *MainWindow) libraryElementURIs(element LibraryPathElement) ([]string, error) {
+	// If it's a URI-enabled file element
+	uh, isURIHolder := element.(URIHolder)
+	if isURIHolder && !element.IsFolder() {
+		return []string{uh.URI()}, nil
+	}
+
+	var attrs []mpd.Attrs
+	err := errors.New(glib.Local("Not connected to MPD"))
+	if isURIHolder {
+		// URI-enabled folder element: fetch all the files in it, recursively
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.ListAllInfo(uh.URI())
+		})
+		if err == nil {
+			attrs = filterFileAttrs(attrs)
 		}
-	})
 
-	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to update the library"))
-}
+	} else if ph, ok := element.(PlaylistHolder); ok {
+		// Playlist-enabled element: fetch the playlist's tracks
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.PlaylistContents(ph.PlaylistName())
+		})
 
-// playerPrevious rewinds the player to the previous track
-func (w *MainWindow) playerPrevious() {
-	var err error
-	w.connector.IfConnected(func(client *mpd.Client) {
-		err = client.Previous()
-	})
+	} else if qh, ok := element.(QueryHolder); ok {
+		// Query-enabled element: run the element's query
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.Find(qh.Query())
+		})
+
+	} else if filter := w.libPath.AsFilter(element); len(filter) > 0 {
+		// Attribute-enabled path: extend the current path filter with the element and query the tracks
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.Find(filter...)
+		})
+		// Make sure album tracks are queued in the right order
+		SortAlbumTracks(attrs)
+
+	} else {
+		return nil, fmt.Errorf("element %T cannot be resolved into URIs", element)
+	}
 
 	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to skip to previous track"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract the URIs
+	return util.MapAttrsToSlice(attrs, "file"), nil
 }
 
-// playerStop stops the playback
-func (w *MainWindow) playerStop() {
-	var err error
-	w.connector.IfConnected(func(client *mpd.Client) {
-		err = client.Stop()
-	})
+// libraryFilterRow is a filter function for the library list box, returning whether the given row is to be shown
+func (w *MainWindow) libraryFilterRow(row *gtk.ListBoxRow, _ ...interface{}) bool {
+	return w.libraryFilterElement(w.getLibraryRowElement(row))
+}
 
-	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to stop playback"))
+// libraryFilterGrid applies the filter pattern to the library grid by hiding the tiles that don't match it
+func (w *MainWindow) libraryFilterGrid() {
+	for i := 0; ; i++ {
+		child := w.LibraryFlowBox.GetChildAtIndex(i)
+		if child == nil {
+			break
+		}
+		child.SetVisible(w.libraryFilterElement(w.getLibraryWidgetElement(&child.Widget)))
+	}
 }
 
-// playerPlayPause pauses or resumes the playback
-func (w *MainWindow) playerPlayPause() {
-	var err error
-	w.connector.IfConnected(func(client *mpd.Client) {
-		switch w.connector.Status()["state"] {
-		case "pause":
+// libraryFilterElement returns whether the given library element is to be shown given the current filter pattern
+func (w *MainWindow) libraryFilterElement(element LibraryPathElement) bool {
+	// Show any element that can't be deciphered
+	if element == nil {
+		return true
+	}
+
+	// Playlists in collapsed folders are hidden in the list, unless filtering
+	if pl, ok := element.(*PlaylistLibElement); ok && w.libFilterPattern == "" && !w.libGrid {
+		if _, inPlaylists := w.libPath.Last().(*PlaylistsLibElement); inPlaylists {
+			cfg := config.GetConfig()
+			if folder := cfg.PlaylistFolders[pl.PlaylistName()]; folder != "" && cfg.PlaylistFoldersFolded[folder] {
+				return false
+			}
+		}
+	}
+
+	// Show everything else if there's no filter pattern
+	if w.libFilterPattern == "" {
+		return true
+	}
+
+	// Level-up element is always shown
+	if _, ok := element.(*LevelUpLibElement); ok {
+		return true
+	}
+
+	// Headers and playlist folders are hidden while filtering
+	switch element.(type) {
+	case *HeaderLibElement, *PlaylistFolderLibElement:
+		return false
+	}
+
+	// Match the label against the pattern
+	return w.libFilterMatch(element.Label())
+}
+
+// libraryLevelUp navigates to the library element at the upper level
+func (w *MainWindow) libraryLevelUp() {
+	if e := w.libPath.Last(); e != nil {
+		// Save the currently active path element for subsequent selection
+		w.libPathElementToSelect = e.Marshal()
+		// Move up a level
+		w.libPath.LevelUp()
+	}
+}
+
+// libraryRename allows to rename the selected library element
+func (w *MainWindow) libraryRename() {
+	element := w.getSelectedLibraryElement()
+	if ph, ok := element.(PlaylistHolder); ok {
+		if newName, ok := util.EditDialog(w.AppWindow, glib.Local("Rename playlist"), ph.PlaylistName(), glib.Local("Rename")); ok {
+			w.renamePlaylist(ph.PlaylistName(), newName)
+		}
+	}
+}
+
+// libraryRenameInPlace allows to rename the selected playlist by editing its name directly in the library list. Falls
+// back to the rename dialog in grid mode
+func (w *MainWindow) libraryRenameInPlace() {
+	if !w.aLibraryRename.GetEnabled() {
+		return
+	}
+	if w.libGrid {
+		w.libraryRename()
+		return
+	}
+	ph, ok := w.getSelectedLibraryElement().(PlaylistHolder)
+	row := util.ListBoxSelectedRow(w.LibraryListBox)
+	if !ok || row == nil {
+		return
+	}
+	name := ph.PlaylistName()
+	util.EditListBoxRowLabel(row, name, func(newName string) {
+		w.renamePlaylist(name, newName)
+		w.focusMainList()
+	})
+}
+
+// cancelLibraryRenameTimer cancels the pending in-place rename, if any
+func (w *MainWindow) cancelLibraryRenameTimer() {
+	if w.libRenameTimer != 0 {
+		glib.SourceRemove(w.libRenameTimer)
+		w.libRenameTimer = 0
+	}
+}
+
+// renamePlaylist renames a stored playlist
+func (w *MainWindow) renamePlaylist(name, newName string) {
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.PlaylistRename(name, newName)
+	})
+	// Check for error (outside IfConnected() because it would keep the client locked)
+	if !w.errCheckDialog(err, glib.Local("Failed to rename the playlist")) {
+		// Keep the playlist in its folder and among the recent targets
+		cfg := config.GetConfig()
+		if folder := cfg.PlaylistFolders[name]; folder != "" {
+			delete(cfg.PlaylistFolders, name)
+			cfg.PlaylistFolders[newName] = folder
+		}
+		for i, s := range cfg.PlaylistRecentTargets {
+			if s == name {
+				cfg.PlaylistRecentTargets[i] = newName
+			}
+		}
+
+		// Follow the rename if the queue has been loaded from this playlist
+		if w.queueSource != nil && w.queueSource.Name == name {
+			w.queueSource.Name = newName
+			w.updateQueueInfo()
+		}
+	}
+}
+
+// libraryDuplicate allows to copy the selected playlist under a new name
+func (w *MainWindow) libraryDuplicate() {
+	ph, ok := w.getSelectedLibraryElement().(PlaylistHolder)
+	if !ok {
+		return
+	}
+	name := ph.PlaylistName()
+	newName, ok := util.EditDialog(w.AppWindow, glib.Local("Duplicate playlist"), fmt.Sprintf(glib.Local("%s (copy)"), name), glib.Local("Duplicate"))
+	if !ok {
+		return
+	}
+
+	// Don't overwrite an existing playlist
+	if w.checkPlaylistExists(newName) {
+		return
+	}
+
+	// Copy the tracks over to the new playlist
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		var attrs []mpd.Attrs
+		if attrs, err = client.PlaylistContents(name); err != nil {
+			return
+		}
+		commands := client.BeginCommandList()
+		for _, a := range attrs {
+			commands.PlaylistAdd(newName, a["file"])
+		}
+		err = commands.End()
+	})
+	// Check for error (outside IfConnected() because it would keep the client locked)
+	w.errCheckDialog(err, glib.Local("Failed to duplicate the playlist"))
+}
+
+// libraryRemoveDuplicates removes repeated tracks from the selected playlists, keeping the first occurrence of each
+func (w *MainWindow) libraryRemoveDuplicates() {
+	names := w.getSelectedPlaylistNames()
+	if len(names) == 0 {
+		return
+	}
+
+	removed := 0
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		for _, name := range names {
+			// Find the repeated entries
+			var attrs []mpd.Attrs
+			if attrs, err = client.PlaylistContents(name); err != nil {
+				return
+			}
+			indices := util.DuplicateIndices(util.MapAttrsToSlice(attrs, "file"))
+			if len(indices) == 0 {
+				continue
+			}
+
+			// Back the playlist up and delete the entries, last ones first
+			if err = w.backupPlaylists(client, name); err != nil {
+				return
+			}
+			commands := client.BeginCommandList()
+			for _, idx := range indices {
+				commands.PlaylistDelete(name, idx)
+			}
+			if err = commands.End(); err != nil {
+				return
+			}
+			removed += len(indices)
+		}
+	})
+	// Check for error (outside IfConnected() because it would keep the client locked)
+	if w.errCheckDialog(err, glib.Local("Failed to remove duplicates")) {
+		return
+	}
+
+	// Report the outcome
+	switch removed {
+	case 0:
+		util.InfoDialog(w.AppWindow, glib.Local("No duplicate tracks found."))
+	case 1:
+		util.InfoDialog(w.AppWindow, glib.Local("One duplicate track removed."))
+	default:
+		util.InfoDialog(w.AppWindow, fmt.Sprintf(util.LocalN("%d duplicate track removed.", "%d duplicate tracks removed.", removed), removed))
+	}
+}
+
+// libraryExport allows to save the selected playlist into a local M3U file
+func (w *MainWindow) libraryExport() {
+	ph, ok := w.getSelectedLibraryElement().(PlaylistHolder)
+	if !ok {
+		return
+	}
+	name := ph.PlaylistName()
+	fileName, ok := util.SaveFileDialog(w.AppWindow, glib.Local("Export playlist"), name+".m3u8", "*.m3u8", "*.m3u")
+	if !ok {
+		return
+	}
+
+	// Fetch the playlist's tracks
+	var attrs []mpd.Attrs
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.PlaylistContents(name)
+	})
+	if w.errCheckDialog(err, glib.Local("Failed to export the playlist")) {
+		return
+	}
+
+	// Write them out
+	file, err := os.Create(fileName)
+	if w.errCheckDialog(err, glib.Local("Failed to export the playlist")) {
+		return
+	}
+	err = WriteM3U(file, attrs, config.GetConfig().PlaylistExportPrefix)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	w.errCheckDialog(err, glib.Local("Failed to export the playlist"))
+}
+
+// librarySmartNew allows to define a new smart playlist
+func (w *MainWindow) librarySmartNew() {
+	name, ok := util.EditDialog(w.AppWindow, glib.Local("New smart playlist"), glib.Local("Smart playlist"), glib.Local("Next"))
+	if !ok || w.checkSmartPlaylistExists(name, -1) {
+		return
+	}
+	if query, ok := w.editSmartPlaylistQuery(`(Genre == "Jazz")`); ok {
+		cfg := config.GetConfig()
+		cfg.SmartPlaylists = append(cfg.SmartPlaylists, config.SmartPlaylistSpec{Name: name, Query: query})
+		w.libPathElementToSelect = NewSmartPlaylistLibElementVal(name, query).Marshal()
+		w.updateLibrary()
+	}
+}
+
+// librarySmartEdit allows to change the name and the search expression of the selected smart playlist
+func (w *MainWindow) librarySmartEdit() {
+	idx := w.getSelectedSmartPlaylistIndex()
+	if idx < 0 {
+		return
+	}
+	cfg := config.GetConfig()
+	name, ok := util.EditDialog(w.AppWindow, glib.Local("Edit smart playlist"), cfg.SmartPlaylists[idx].Name, glib.Local("Next"))
+	if !ok || w.checkSmartPlaylistExists(name, idx) {
+		return
+	}
+	if query, ok := w.editSmartPlaylistQuery(cfg.SmartPlaylists[idx].Query); ok {
+		cfg.SmartPlaylists[idx] = config.SmartPlaylistSpec{Name: name, Query: query}
+		w.libPathElementToSelect = NewSmartPlaylistLibElementVal(name, query).Marshal()
+		w.updateLibrary()
+	}
+}
+
+// librarySmartSave saves the tracks of the selected smart playlist into a stored playlist with the same name,
+// replacing its content if it exists
+func (w *MainWindow) librarySmartSave() {
+	idx := w.getSelectedSmartPlaylistIndex()
+	if idx < 0 {
+		return
+	}
+	sp := config.GetConfig().SmartPlaylists[idx]
+
+	// Check whether the stored playlist exists
+	exists := false
+	for _, n := range w.connector.GetPlaylists() {
+		exists = exists || n == sp.Name
+	}
+
+	// Find the tracks and refresh the playlist with them
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		var attrs []mpd.Attrs
+		if attrs, err = client.Find(sp.Query); err != nil {
+			return
+		}
+		commands := client.BeginCommandList()
+		if exists {
+			commands.PlaylistClear(sp.Name)
+		}
+		for _, a := range filterFileAttrs(attrs) {
+			commands.PlaylistAdd(sp.Name, a["file"])
+		}
+		err = commands.End()
+	})
+	// Check for error (outside IfConnected() because it would keep the client locked)
+	w.errCheckDialog(err, glib.Local("Failed to save the playlist"))
+}
+
+// librarySmartDelete allows to delete the selected smart playlist
+func (w *MainWindow) librarySmartDelete() {
+	idx := w.getSelectedSmartPlaylistIndex()
+	if idx < 0 {
+		return
+	}
+	cfg := config.GetConfig()
+	question := fmt.Sprintf(glib.Local("Are you sure you want to delete smart playlist \"%s\"?"), cfg.SmartPlaylists[idx].Name)
+	if util.ConfirmDialog(w.AppWindow, glib.Local("Delete smart playlist"), question) {
+		cfg.SmartPlaylists = append(cfg.SmartPlaylists[:idx], cfg.SmartPlaylists[idx+1:]...)
+		w.updateLibrary()
+	}
+}
+
+// libraryShowFolderFromQueue opens the folder of the currently selected queue track in the library, selecting the track
+func (w *MainWindow) libraryShowFolderFromQueue() {
+	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get track information")) {
+		// Streams have no folder
+		uri := attrs["file"]
+		if util.IsStreamURI(uri) {
+			return
+		}
+
+		// Leave the search mode, if needed, then update the current library path and select the track
+		w.LibrarySearchToolButton.SetActive(false)
+		w.libPathElementToSelect = (&FileLibElement{uri: uri, title: path.Base(uri)}).Marshal()
+		w.libPath.SetElements(FolderPathElements(uri))
+
+		// Switch to the library tab
+		w.MainStack.SetVisibleChild(w.LibraryBox)
+	}
+}
+
+// queueCopy copies the URIs or local file paths of the selected queue tracks to the clipboard
+func (w *MainWindow) queueCopy(localPaths bool) {
+	if uris, err := w.getQueueSelectedURIs(); !w.errCheckDialog(err, glib.Local("Failed to get track information")) {
+		w.copyURIs(uris, localPaths)
+	}
+}
+
+// queueOpenFolder opens the local folder containing the currently selected queue track in the file manager
+func (w *MainWindow) queueOpenFolder() {
+	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get track information")) {
+		w.openContainingFolder(attrs["file"])
+	}
+}
+
+// libraryShowAlbumFromQueue opens the currently selected queue album in the library
+func (w *MainWindow) libraryShowAlbumFromQueue() {
+	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get album information")) {
+		// Leave the search mode, if needed, and update the current library path
+		w.LibrarySearchToolButton.SetActive(false)
+		w.libPath.SetElements([]LibraryPathElement{
+			NewArtistsLibElement(),
+			NewArtistLibElementVal(trackAlbumArtist(attrs)),
+			NewAlbumLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrAlbum].AttrName]),
+		})
+
+		// Switch to the library tab
+		w.MainStack.SetVisibleChild(w.LibraryBox)
+	}
+}
+
+// libraryShowArtistFromQueue opens the currently selected queue artist in the library
+func (w *MainWindow) libraryShowArtistFromQueue() {
+	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get artist information")) {
+		// Leave the search mode, if needed, and update the current library path
+		w.LibrarySearchToolButton.SetActive(false)
+		w.libPath.SetElements([]LibraryPathElement{
+			NewArtistsLibElement(),
+			NewArtistLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrArtist].AttrName]),
+		})
+
+		// Switch to the library tab
+		w.MainStack.SetVisibleChild(w.LibraryBox)
+	}
+}
+
+// libraryShowGenreFromQueue opens the currently selected queue genre in the library
+func (w *MainWindow) libraryShowGenreFromQueue() {
+	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get genre information")) {
+		// Leave the search mode, if needed, and update the current library path
+		w.LibrarySearchToolButton.SetActive(false)
+		w.libPath.SetElements([]LibraryPathElement{
+			NewGenresLibElement(),
+			NewGenreLibElementVal(trackGenre(attrs)),
+		})
+
+		// Switch to the library tab
+		w.MainStack.SetVisibleChild(w.LibraryBox)
+	}
+}
+
+// librarySongInfo shows information about the selected library track
+func (w *MainWindow) librarySongInfo() {
+	// Only files are supported
+	e, ok := w.getSelectedLibraryElement().(*FileLibElement)
+	if !ok {
+		return
+	}
+
+	// Fetch the track's attributes
+	var attrs []mpd.Attrs
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.ListInfo(e.URI())
+	})
+	if w.errCheckDialog(err, glib.Local("Failed to get track information")) {
+		return
+	}
+	if len(attrs) == 0 {
+		w.errCheckDialog(errors.New(glib.Local("No data returned by MPD")), glib.Local("Failed to get track information"))
+		return
+	}
+
+	// Show the dialog
+	w.songInfo(attrs[0])
+}
+
+// libraryUpdate updates or rescans the library
+func (w *MainWindow) libraryUpdate(rescan, selectedOnly bool) {
+	// Determine the update path
+	libPath := ""
+	if selectedOnly {
+		// We only support updating file-based items
+		uh, ok := w.getSelectedLibraryElement().(URIHolder)
+		if !ok {
+			return
+		}
+		libPath = uh.URI()
+	}
+	w.libraryUpdatePath(rescan, libPath)
+}
+
+// libraryBookmarkIndex returns the index of the bookmark for the current library path, or -1 if there's none
+func (w *MainWindow) libraryBookmarkIndex() int {
+	current := w.libPath.Marshal()
+	for i, bm := range config.GetConfig().LibraryBookmarks {
+		if bm.Path == current {
+			return i
+		}
+	}
+	return -1
+}
+
+// libraryOpenBookmark navigates the library to the given serialised path
+func (w *MainWindow) libraryOpenBookmark(path string) {
+	// Leave the search mode, if needed
+	w.LibrarySearchToolButton.SetActive(false)
+	errCheck(w.libPath.Unmarshal(path), "Failed to open bookmarked library path")
+}
+
+// libraryToggleBookmark adds a bookmark for the current library path, or removes it if it's already bookmarked
+func (w *MainWindow) libraryToggleBookmark() {
+	if w.libPath.IsRoot() {
+		return
+	}
+	cfg := config.GetConfig()
+	if i := w.libraryBookmarkIndex(); i >= 0 {
+		cfg.LibraryBookmarks = append(cfg.LibraryBookmarks[:i], cfg.LibraryBookmarks[i+1:]...)
+	} else {
+		cfg.LibraryBookmarks = append(cfg.LibraryBookmarks, config.BookmarkSpec{
+			Name: w.libPath.Last().Label(),
+			Path: w.libPath.Marshal(),
+		})
+	}
+	w.updateLibraryBookmarks()
+}
+
+// libraryUpdateCurrent runs a library update for the currently open folder
+func (w *MainWindow) libraryUpdateCurrent() {
+	// We only support updating file-based items
+	if uh, ok := w.libPath.Last().(URIHolder); ok {
+		w.libraryUpdatePath(false, uh.URI())
+	}
+}
+
+// libraryUpdatePath runs a library update or rescan for the given path (an empty string means the entire library)
+func (w *MainWindow) libraryUpdatePath(rescan bool, libPath string) {
+	// Rescan re-reads every file, so ask for a confirmation
+	if rescan {
+		title := glib.Local("Rescan library")
+		msg := glib.Local("Rescan re-reads all files in the library, including those that haven't been modified, which may take a long time on a large library. Use it only if tags have changed without the file modification time being updated.\n\nContinue?")
+		if libPath != "" {
+			title = glib.Local("Rescan folder")
+			msg = fmt.Sprintf(glib.Local("Rescan re-reads all files in the folder \"%s\", including those that haven't been modified, which may take a long time on a large folder. Use it only if tags have changed without the file modification time being updated.\n\nContinue?"), libPath)
+		}
+		if !util.ConfirmDialog(w.AppWindow, title, msg) {
+			return
+		}
+	}
+
+	// Run the update
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		if rescan {
+			_, err = client.Rescan(libPath)
+		} else {
+			_, err = client.Update(libPath)
+		}
+	})
+
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to update the library"))
+}
+
+// playerPrevious rewinds the player to the previous track
+func (w *MainWindow) playerPrevious() {
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.Previous()
+	})
+
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to skip to previous track"))
+}
+
+// playerSongInfo shows information about the currently played track
+func (w *MainWindow) playerSongInfo() {
+	var attrs mpd.Attrs
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.CurrentSong()
+	})
+	if !w.errCheckDialog(err, glib.Local("Failed to get track information")) && len(attrs) > 0 {
+		w.songInfo(attrs)
+	}
+}
+
+// playerStar toggles the star of the tracks selected in the queue, if it's displayed and there's a selection, or of the
+// currently played track otherwise
+func (w *MainWindow) playerStar() {
+	if w.MainStack.GetVisibleChildName() == "queue" && w.getQueueSelectedCount() > 0 {
+		w.queueStar()
+		return
+	}
+	var attrs mpd.Attrs
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.CurrentSong()
+	})
+	if !w.errCheckDialog(err, glib.Local("Failed to get track information")) && attrs["file"] != "" {
+		w.toggleFavorites([]string{attrs["file"]})
+	}
+}
+
+// playerRadioRemember logs the song announced by the stream being played into the "heard on radio" list
+func (w *MainWindow) playerRadioRemember() {
+	if w.radioSong == nil {
+		return
+	}
+	err := w.radioLog.Add(w.radioSong["Name"], w.radioSong["file"], w.radioSong["Title"])
+	w.errCheckDialog(err, glib.Local("Failed to remember the song"))
+	w.updatePlayerRadioRemember()
+}
+
+// playerLove loves the current track on Last.fm, or unloves it if it's already loved
+func (w *MainWindow) playerLove() {
+	lastFm := NewLastFmFromConfig()
+	track, loved := w.lastFmTrack, w.loveState != tbTrue
+	if !lastFm.IsLoggedIn() || track == (LastFmTrack{}) || w.loveUpdating {
+		return
+	}
+	w.loveUpdating = true
+	w.updatePlayerLoveButton()
+	go func() {
+		err := lastFm.SetLoved(track, loved)
+		util.WhenIdle("playerLove()", func() {
+			w.loveUpdating = false
+			if !w.errCheckDialog(err, glib.Local("Failed to update the track on Last.fm")) && track == w.lastFmTrack {
+				w.loveState = tbFalse
+				if loved {
+					w.loveState = tbTrue
+				}
+			}
+			w.updatePlayerLoveButton()
+		})
+	}()
+}
+
+// playerSimilar looks up tracks similar to the current one on Last.fm and appends those found in the library to the
+// queue
+func (w *MainWindow) playerSimilar() {
+	lastFm := NewLastFmFromConfig()
+	track := w.lastFmTrack
+	if !lastFm.HasAPIKey() || track == (LastFmTrack{}) || w.similarLoading {
+		return
+	}
+	w.similarLoading = true
+	w.updatePlayerSimilar()
+	go func() {
+		// Similar artists also cover tracks Last.fm knows nothing about
+		similar, errTracks := lastFm.SimilarTracks(track)
+		artists, err := lastFm.SimilarArtists(track.Artist)
+		var tracks []mpd.Attrs
+		if err == nil || errTracks == nil {
+			tracks, err = w.libIndex.Tracks()
+		} else {
+			err = errTracks
+		}
+		util.WhenIdle("playerSimilar()", func() {
+			w.similarLoading = false
+			w.updatePlayerSimilar()
+			if w.errCheckDialog(err, glib.Local("Failed to find similar tracks")) {
+				return
+			}
+
+			// Skip the tracks already queued
+			exclude := make(map[string]bool, len(w.queueTrackURIs))
+			for _, uri := range w.queueTrackURIs {
+				exclude[uri] = true
+			}
+			uris := MatchSimilarTracks(tracks, similar, artists, exclude, playerSimilarMax)
+			if len(uris) == 0 {
+				util.InfoDialog(w.AppWindow, glib.Local("No similar tracks found in the library."))
+				return
+			}
+			w.queueURIs(tbFalse, uris...)
+		})
+	}()
+}
+
+// playerResume seeks to the resume position offered for the current track
+func (w *MainWindow) playerResume() {
+	if w.resumeOfferPos > 0 {
+		w.playerSeek(w.resumeOfferPos)
+		w.resumeOfferPos = 0
+		w.updatePlayerResumeButton()
+	}
+}
+
+// playerSeek moves the play position in the current track to the given number of seconds
+func (w *MainWindow) playerSeek(pos float64) {
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.SeekCur(time.Duration(pos*float64(time.Second)), false)
+	})
+
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to seek"))
+}
+
+// playerStop stops the playback
+func (w *MainWindow) playerStop() {
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.Stop()
+	})
+
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to stop playback"))
+}
+
+// playerPlayPause pauses or resumes the playback
+func (w *MainWindow) playerPlayPause() {
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		switch w.connector.Status()["state"] {
+		case "pause":
 			err = client.Pause(false)
 		case "play":
 			err = client.Pause(true)
@@ -1402,6 +3711,61 @@ func (w *MainWindow) playerToggleConsume() {
 	w.errCheckDialog(err, glib.Local("Failed to toggle consume mode"))
 }
 
+// playerToggleListen starts playing MPD's HTTP stream on the local machine, enabling MPD's httpd output, or stops
+// playing it
+func (w *MainWindow) playerToggleListen() {
+	// Ignore if the state of the button is being updated programmatically
+	if w.optionsUpdating {
+		return
+	}
+
+	if w.listener != nil && w.listener.IsPlaying() {
+		w.listener.Stop()
+	} else {
+		w.errCheckDialog(w.startListening(), glib.Local("Failed to start listening"))
+	}
+	w.updateOptions()
+}
+
+// startListening enables MPD's httpd output and starts playing its stream
+func (w *MainWindow) startListening() error {
+	if w.listener == nil {
+		l, err := NewStreamListener(w.onListenError)
+		if err != nil {
+			return err
+		}
+		w.listener = l
+	}
+
+	// Make sure MPD streams
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		var outputs []mpd.Attrs
+		if outputs, err = client.ListOutputs(); err != nil {
+			return
+		}
+		id := httpdOutputID(outputs)
+		if id < 0 {
+			err = errors.New(glib.Local("MPD has no HTTP streaming output"))
+			return
+		}
+		err = client.EnableOutput(id)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Play the stream
+	w.listener.Play(config.GetConfig().HTTPStreamURL())
+	return nil
+}
+
+// onListenError reports the failure to play MPD's HTTP stream
+func (w *MainWindow) onListenError(err error) {
+	w.errCheckDialog(err, glib.Local("Failed to play the HTTP stream"))
+	w.updateOptions()
+}
+
 // playerToggleRandom toggles player's random mode
 func (w *MainWindow) playerToggleRandom() {
 	// Ignore if the state of the button is being updated programmatically
@@ -1434,9 +3798,274 @@ func (w *MainWindow) playerToggleRepeat() {
 	w.errCheckDialog(err, glib.Local("Failed to toggle repeat mode"))
 }
 
+// playlistImport allows to create a stored playlist from a local M3U, PLS or XSPF file, mapping its entries onto the
+// tracks in the MPD database
+func (w *MainWindow) playlistImport() {
+	fileName, ok := util.OpenFileDialog(w.AppWindow, glib.Local("Import playlist"), "*.m3u", "*.m3u8", "*.pls", "*.xspf")
+	if !ok {
+		return
+	}
+
+	// Read the playlist file
+	file, err := os.Open(fileName)
+	if w.errCheckDialog(err, glib.Local("Failed to import the playlist")) {
+		return
+	}
+	entries, err := ParsePlaylist(fileName, file)
+	_ = file.Close()
+	if w.errCheckDialog(err, glib.Local("Failed to import the playlist")) {
+		return
+	}
+
+	// Map the entries onto known tracks
+	tracks, err := w.libIndex.Tracks()
+	if w.errCheckDialog(err, glib.Local("Failed to import the playlist")) {
+		return
+	}
+	known := make(map[string]bool, len(tracks))
+	for _, a := range tracks {
+		known[a["file"]] = true
+	}
+	uris, unmatched := MapPlaylistEntries(entries, path.Dir(fileName), config.GetConfig().MpdMusicDir, known)
+	if len(uris) == 0 {
+		util.ErrorDialog(w.AppWindow, glib.Local("None of the playlist entries could be found in the MPD database."))
+		return
+	}
+
+	// Ask for the new playlist's name
+	base := path.Base(fileName)
+	name, ok := util.EditDialog(w.AppWindow, glib.Local("Import playlist"), strings.TrimSuffix(base, path.Ext(base)), glib.Local("Import"))
+	if !ok || w.checkPlaylistExists(name) {
+		return
+	}
+
+	// Create the playlist
+	err = errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		commands := client.BeginCommandList()
+		for _, uri := range uris {
+			commands.PlaylistAdd(name, uri)
+		}
+		err = commands.End()
+	})
+	if w.errCheckDialog(err, glib.Local("Failed to import the playlist")) {
+		return
+	}
+
+	// Report the entries that have been left out, if any
+	if len(unmatched) > 0 {
+		if len(unmatched) > playlistImportMaxReported {
+			more := len(unmatched) - playlistImportMaxReported
+			unmatched = append(unmatched[:playlistImportMaxReported], fmt.Sprintf(util.LocalN("…and %d more", "…and %d more", more), more))
+		}
+		util.WarningDialog(
+			w.AppWindow,
+			fmt.Sprintf(glib.Local("The following entries could not be found in the MPD database and have been skipped:\n%s"), strings.Join(unmatched, "\n")))
+	}
+}
+
+// playlistRestore shows a menu of available playlist backups and restores the one selected
+func (w *MainWindow) playlistRestore() {
+	backups, err := w.playlistBackups.List()
+	if w.errCheckDialog(err, glib.Local("Failed to read playlist backups")) {
+		return
+	}
+	if len(backups) == 0 {
+		util.ErrorDialog(w.AppWindow, glib.Local("There are no playlist backups."))
+		return
+	}
+
+	// Populate the menu, newest backups first
+	util.ClearChildren(w.PlaylistRestoreMenu.Container)
+	for _, b := range backups {
+		b := b // Make an in-loop copy of b
+		item, err := gtk.MenuItemNewWithLabel(fmt.Sprintf("%s — %s", b.Name, b.Time.Format("2006-01-02 15:04:05")))
+		if errCheck(err, "MenuItemNewWithLabel() failed") {
+			return
+		}
+		if _, err := item.Connect("activate", func() { w.restorePlaylistBackup(&b) }); errCheck(err, "Connect() failed") {
+			return
+		}
+		w.PlaylistRestoreMenu.Append(item)
+	}
+	w.PlaylistRestoreMenu.ShowAll()
+	w.PlaylistRestoreMenu.PopupAtWidget(w.AppMenuButton, gdk.GDK_GRAVITY_SOUTH_EAST, gdk.GDK_GRAVITY_NORTH_EAST, nil)
+}
+
+// podcastLoad asynchronously fetches the feed of the podcast with the given URL, then calls onLoaded (if any) on
+// success
+func (w *MainWindow) podcastLoad(url string, onLoaded func(feed *PodcastFeed)) {
+	// Don't load the same feed twice at a time
+	if w.podcastLoading[url] {
+		return
+	}
+	w.podcastLoading[url] = true
+	w.updatePodcastsInfo()
+	w.updatePodcastsActions()
+
+	go func() {
+		feed, err := FetchPodcastFeed(url)
+		util.WhenIdle("podcastLoad()", func() {
+			delete(w.podcastLoading, url)
+			if !w.errCheckDialog(err, glib.Local("Failed to load podcast")) {
+				w.podcastFeeds[url] = feed
+				for _, e := range feed.Episodes {
+					w.podcastEpisodeURIs[e.URI] = true
+				}
+				if onLoaded != nil {
+					onLoaded(feed)
+				}
+			}
+			w.updatePodcasts()
+		})
+	}()
+}
+
+// podcastMarkPlayed marks the selected podcast episode as played or unplayed
+func (w *MainWindow) podcastMarkPlayed(played bool) {
+	if e := w.getSelectedPodcastEpisode(); e != nil {
+		w.errCheckDialog(w.podcastState.SetPlayed(played, e.URI), glib.Local("Failed to save podcast state"))
+		w.updatePodcasts()
+	}
+}
+
+// podcastUnplayedCount returns the number of unplayed episodes in the given podcast feed
+func (w *MainWindow) podcastUnplayedCount(feed *PodcastFeed) int {
+	cnt := 0
+	for _, e := range feed.Episodes {
+		if !w.podcastState.IsPlayed(e.URI) {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// restorePlaylistBackup recreates the stored playlist from the given backup, replacing it if it exists
+func (w *MainWindow) restorePlaylistBackup(b *PlaylistBackup) {
+	uris, err := b.URIs()
+	if w.errCheckDialog(err, glib.Local("Failed to restore the playlist")) {
+		return
+	}
+
+	// Ask for a confirmation if the playlist is to be replaced
+	exists := false
+	for _, name := range w.connector.GetPlaylists() {
+		exists = exists || name == b.Name
+	}
+	if exists && !util.ConfirmDialog(
+		w.AppWindow,
+		glib.Local("Restore playlist"),
+		fmt.Sprintf(glib.Local("Are you sure you want to replace playlist \"%s\" with its backup?"), b.Name)) {
+		return
+	}
+
+	// Recreate the playlist, backing up its current contents first
+	err = errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		if exists {
+			if err = w.backupPlaylists(client, b.Name); err != nil {
+				return
+			}
+		}
+		commands := client.BeginCommandList()
+		if exists {
+			commands.PlaylistRemove(b.Name)
+		}
+		for _, uri := range uris {
+			commands.PlaylistAdd(b.Name, uri)
+		}
+		err = commands.End()
+	})
+	// Check for error (outside IfConnected() because it would keep the client locked)
+	w.errCheckDialog(err, glib.Local("Failed to restore the playlist"))
+}
+
+// backupPlaylists snapshots the contents of the given stored playlists before they get replaced or deleted
+func (w *MainWindow) backupPlaylists(client *mpd.Client, names ...string) error {
+	for _, name := range names {
+		attrs, err := client.PlaylistContents(name)
+		if err != nil {
+			return err
+		}
+		if err := w.playlistBackups.Backup(name, attrs); err != nil {
+			return fmt.Errorf(glib.Local("failed to back up playlist \"%s\": %v"), name, err)
+		}
+	}
+	return nil
+}
+
 // preferences shows the preferences dialog
 func (w *MainWindow) preferences() {
-	PreferencesDialog(w.AppWindow, w.connect, w.updateQueueColumns, w.applyPlayerSettings)
+	PreferencesDialog(w.AppWindow, w.connect, w.updateQueueColumns, w.updateLibrary, w.applyPlayerSettings, w.applyIntegrationSettings, w.applyAppearance)
+
+	// Write out the changed settings so that the config file doesn't lag behind them
+	config.GetConfig().Save()
+
+	// The user token may have been entered or changed
+	w.listenBrainzFlush()
+}
+
+// applyIntegrationSettings applies the desktop and online service settings changed in the preferences
+func (w *MainWindow) applyIntegrationSettings() {
+	// The tray icon, the media keys and the session related options may have been enabled or disabled
+	w.initTrayIcon()
+	w.initMediaKeys()
+	w.initSessionMonitor()
+	w.updateInhibit()
+
+	// The user may have logged in to or out of Last.fm
+	w.updatePlayerLoved()
+}
+
+// applyAppearance applies the theme variant and the user style sheet selected in the preferences
+func (w *MainWindow) applyAppearance() {
+	if w.appearance == nil {
+		a, err := NewAppearance()
+		if errCheck(err, "NewAppearance() failed") {
+			return
+		}
+		w.appearance = a
+	}
+	cfg := config.GetConfig()
+	errCheck(w.appearance.Apply(cfg.Theme, cfg.CustomCSSFile), "Failed to apply appearance settings")
+}
+
+// reloadConfig re-reads the config file and applies the settings changed in it, keeping those changed in the application
+// only. Unless force is true, it's only done if the file's content differs from what has last been loaded or saved
+func (w *MainWindow) reloadConfig(force bool) {
+	cfg := config.GetConfig()
+	network, addr := cfg.MpdNetworkAddress()
+	password := cfg.MpdPassword
+	if force {
+		if w.errCheckDialog(cfg.Reload(), glib.Local("Failed to reload configuration")) {
+			return
+		}
+	} else if changed, err := cfg.ReloadIfChanged(); errCheck(err, "Failed to reload configuration") || !changed {
+		return
+	}
+	log.Debug("Configuration reloaded")
+
+	// Reconnect if connected and the connection settings have changed
+	if connected, _ := w.connector.ConnectStatus(); connected {
+		if n, a := cfg.MpdNetworkAddress(); n != network || a != addr || cfg.MpdPassword != password {
+			w.connect()
+		}
+	}
+
+	// Apply the settings
+	w.updateQueueColumns()
+	w.updateLibrary()
+	w.updateStreams()
+	w.updatePodcasts()
+	w.applyAppearance()
+	w.applyPlayerSettings()
+	w.applyIntegrationSettings()
+}
+
+// quit closes the main window for good, even if it would otherwise be hidden to the tray
+func (w *MainWindow) quit() {
+	w.quitting = true
+	w.AppWindow.Close()
 }
 
 // queueClear empties MPD's play queue
@@ -1475,6 +4104,20 @@ func (w *MainWindow) queueDelete() {
 	w.errCheckDialog(err, glib.Local("Failed to delete tracks from the queue"))
 }
 
+// queueCut deletes the selected tracks from the queue, remembering them for queuePaste()
+func (w *MainWindow) queueCut() {
+	var uris []string
+	for _, idx := range w.getQueueSelectedIndices() {
+		if idx < len(w.queueTrackURIs) {
+			uris = append(uris, w.queueTrackURIs[idx])
+		}
+	}
+	if len(uris) > 0 {
+		w.queueCutURIs = uris
+		w.queueDelete()
+	}
+}
+
 // queueFilter applies the currently entered filter substring to the queue
 func (w *MainWindow) queueFilter() {
 	substr := ""
@@ -1484,15 +4127,20 @@ func (w *MainWindow) queueFilter() {
 		substr = util.EntryText(&w.QueueSearchEntry.Entry, "")
 	}
 
+	// Make a matcher for the pattern. An invalid regex matches nothing
+	cfg := config.GetConfig()
+	match, err := util.NewPatternMatcher(substr, false, cfg.QueueRegexFilter, cfg.SearchIgnoreDiacritics)
+	util.SetEntryError(&w.QueueSearchEntry.Entry, err != nil)
+	if err != nil {
+		match = func(string) bool { return false }
+	}
+
 	// Iterate all rows in the list store
 	count := 0
 	w.QueueListStore.ForEach(func(model *gtk.TreeModel, path *gtk.TreePath, iter *gtk.TreeIter, userData ...interface{}) bool {
 		// Show all rows if no search pattern given
 		visible := substr == ""
 		if !visible {
-			// We're going to compare case-insensitively
-			substr := strings.ToLower(substr)
-
 			// Scan all known columns in the row
 			for _, id := range config.MpdTrackAttributeIds {
 				// Get column's value
@@ -1505,7 +4153,7 @@ func (w *MainWindow) queueFilter() {
 				s, _ := v.GetString()
 
 				// Check for a match and stop checking if match has already been found
-				visible = s != "" && strings.Contains(strings.ToLower(s), substr)
+				visible = s != "" && match(s)
 				if visible {
 					break
 				}
@@ -1523,7 +4171,74 @@ func (w *MainWindow) queueFilter() {
 		// Proceed to the next row
 		return false
 	})
-	w.QueueFilterLabel.SetText(fmt.Sprintf(glib.Local("%d track(s) displayed"), count))
+	w.QueueFilterLabel.SetText(fmt.Sprintf(util.LocalN("%d track displayed", "%d tracks displayed", count), count))
+}
+
+// onQueueRegexToggle switches regular expression matching in the queue filter on or off
+func (w *MainWindow) onQueueRegexToggle() {
+	config.GetConfig().QueueRegexFilter = w.QueueRegexToggleButton.GetActive()
+	w.queueFilter()
+}
+
+// queueFolder recursively adds or replaces the content of the queue with all tracks in the given folder, optionally
+// only those of the given file types (see FilterAttrsByFormat()). The tracks are added in batches in the background,
+// with the progress displayed in the library's progress bar
+func (w *MainWindow) queueFolder(replace triBool, uri string, types []string) {
+	// Only one folder can be added at a time
+	if w.libQueueingFolder {
+		return
+	}
+	w.libQueueingFolder = true
+	w.updateLibraryActions()
+	clearQueue := replace == tbTrue
+
+	// Show the progress bar
+	w.LibraryProgressBar.SetFraction(0)
+	w.LibraryProgressBar.SetText(fmt.Sprintf(glib.Local("Adding %s…"), path.Base(uri)))
+	w.LibraryProgressBar.Show()
+
+	go func() {
+		// Fetch the list of all files in the folder
+		var attrs []mpd.Attrs
+		err := errors.New(glib.Local("Not connected to MPD"))
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.ListAllInfo(uri)
+		})
+
+		// Collect file URIs
+		uris := util.MapAttrsToSlice(FilterAttrsByFormat(attrs, types), "file")
+
+		// Add the files in batches
+		for start := 0; err == nil && start < len(uris); start += libraryQueueBatchSize {
+			end := start + libraryQueueBatchSize
+			if end > len(uris) {
+				end = len(uris)
+			}
+			err = errors.New(glib.Local("Not connected to MPD"))
+			w.connector.IfConnected(func(client *mpd.Client) {
+				commands := client.BeginCommandList()
+				if clearQueue && start == 0 {
+					commands.Clear()
+				}
+				for _, u := range uris[start:end] {
+					commands.Add(u)
+				}
+				err = commands.End()
+			})
+
+			// Report the progress
+			fraction := float64(end) / float64(len(uris))
+			util.WhenIdle("LibraryProgressBar.SetFraction()", w.LibraryProgressBar.SetFraction, fraction)
+		}
+
+		// Finish up on the GLib's thread
+		util.WhenIdle("queueFolder()", func() {
+			w.libQueueingFolder = false
+			w.LibraryProgressBar.Hide()
+			w.updateLibraryActions()
+			w.errCheckDialog(err, glib.Local("Failed to add track(s) to the queue"))
+		})
+	}()
 }
 
 // queueLibraryElement adds or replaces the content of the queue with the specified library path element
@@ -1535,7 +4250,12 @@ func (w *MainWindow) queueLibraryElement(replace triBool, element LibraryPathEle
 
 	// If it's a URI-enabled element
 	if uh, ok := element.(URIHolder); ok {
-		w.queueURIs(replace, uh.URI())
+		// Folders are added in the background as they may contain lots of files
+		if element.IsFolder() {
+			w.queueFolder(replace, uh.URI(), nil)
+		} else {
+			w.queueURIs(replace, uh.URI())
+		}
 		return
 	}
 
@@ -1545,51 +4265,154 @@ func (w *MainWindow) queueLibraryElement(replace triBool, element LibraryPathEle
 		return
 	}
 
-	// Attribute-enabled path: extend the current path filter with the element
-	if filter := w.libPath.AsFilter(element); len(filter) > 0 {
-		var attrs []mpd.Attrs
-		var err error
-		w.connector.IfConnected(func(client *mpd.Client) {
-			// For the lack of FindAdd() command in gompd, we need to query tracks first
-			attrs, err = client.Find(filter...)
-		})
+	// Attribute-enabled path. For the lack of FindAdd() command in gompd, we need to query tracks first
+	uris, err := w.libraryElementURIs(element)
+	if w.errCheckDialog(err, glib.Local("Failed to add item to the queue")) {
+		return
+	}
+	w.queueURIs(replace, uris...)
+}
 
-		// Check for error
-		if w.errCheckDialog(err, glib.Local("Failed to add item to the queue")) {
-			return
-		}
+// queueLibraryElementsNext inserts the tracks of the specified library path elements right after the currently playing
+// track, and optionally starts playing them
+func (w *MainWindow) queueLibraryElementsNext(play bool, elements ...LibraryPathElement) {
+	if uris, ok := w.resolveLibraryElements(elements, glib.Local("Failed to add item to the queue")); ok {
+		w.queueURIsNext(play, uris...)
+	}
+}
+
+// queueLibraryElementsAs queues the specified library path elements up according to the given action, one of the
+// config.ClickAction* constants
+func (w *MainWindow) queueLibraryElementsAs(action string, elements ...LibraryPathElement) {
+	switch action {
+	case config.ClickActionPlay, config.ClickActionNext:
+		w.queueLibraryElementsNext(action == config.ClickActionPlay, elements...)
+	case config.ClickActionReplace:
+		w.queueLibraryElements(tbTrue, elements...)
+	default:
+		w.queueLibraryElements(tbFalse, elements...)
+	}
+}
 
-		// Convert attrs to list of URIs and queue them
-		w.queueURIs(replace, util.MapAttrsToSlice(attrs, "file")...)
+// queueLibraryElements adds or replaces the content of the queue with the specified library path elements
+func (w *MainWindow) queueLibraryElements(replace triBool, elements ...LibraryPathElement) {
+	// A single element is queued as is
+	if len(elements) == 1 {
+		w.queueLibraryElement(replace, elements[0])
 		return
 	}
 
-	// Oops
-	log.Errorf("Element %T cannot be queued", element)
+	// Multiple elements are resolved into URIs first
+	if uris, ok := w.resolveLibraryElements(elements, glib.Local("Failed to add item to the queue")); ok {
+		w.queueURIs(replace, uris...)
+	}
+}
+
+// queueMoveCursor moves the cursor in the queue according to the given Vim-style cursor movement command
+func (w *MainWindow) queueMoveCursor(cmd vimCommand) {
+	// The cursor's path is one in the filtered list, whose rows are all shown
+	cur := -1
+	if path, _ := w.QueueTreeView.GetCursor(); path != nil {
+		if ix := path.GetIndices(); len(ix) > 0 {
+			cur = ix[0]
+		}
+	}
+	count := w.QueueTreeModelFilter.IterNChildren(nil)
+	if idx := vimCursorIndex(cmd, cur, count, func(int) bool { return true }); idx >= 0 {
+		if path, err := gtk.TreePathNewFromIndicesv([]int{idx}); !errCheck(err, "queueMoveCursor(): TreePathNewFromIndicesv() failed") {
+			w.QueueTreeView.SetCursor(path, nil, false)
+		}
+	}
+}
+
+// queuePaste inserts the tracks last removed with queueCut() after the last selected track, or after the currently
+// playing one if there's no selection
+func (w *MainWindow) queuePaste() {
+	if len(w.queueCutURIs) == 0 {
+		return
+	}
+	if indices := w.getQueueSelectedIndices(); len(indices) > 0 {
+		w.queueURIsAt(indices[len(indices)-1]+1, false, w.queueCutURIs...)
+	} else {
+		w.queueURIsNext(false, w.queueCutURIs...)
+	}
 }
 
 // queuePlaylist adds or replaces the content of the queue with the specified playlist
 func (w *MainWindow) queuePlaylist(replace triBool, uri string) {
 	log.Debugf("queuePlaylist(%v, %v)", replace, uri)
+	// NB: extract only playlist name from the URI for now
+	name := strings.TrimSuffix(path.Base(uri), ".m3u")
+	doReplace := replace == tbTrue
+	var attrs []mpd.Attrs
 	var err error
 	w.connector.IfConnected(func(client *mpd.Client) {
 		commands := client.BeginCommandList()
 
 		// Clear the queue, if needed
-		if replace == tbTrue || replace == tbNone && config.GetConfig().PlaylistDefaultReplace {
+		if doReplace {
 			commands.Clear()
 		}
 
 		// Add the content of the playlist
-		// NB: extract only playlist name from the URI for now
-		commands.PlaylistLoad(strings.TrimSuffix(path.Base(uri), ".m3u"), -1, -1)
+		commands.PlaylistLoad(name, -1, -1)
 
 		// Run the commands
+		if err = commands.End(); err != nil || !doReplace {
+			return
+		}
+
+		// Fetch the playlist's tracks to be able to tell when the queue diverges from it
+		attrs, err = client.PlaylistContents(name)
+	})
+
+	// Check for error
+	if !w.errCheckDialog(err, glib.Local("Failed to add playlist to the queue")) && doReplace {
+		// Remember the playlist the queue has been loaded from
+		w.queueSource = &QueueSource{Name: name, URIs: util.MapAttrsToSlice(attrs, "file")}
+	}
+}
+
+// playPlaylist replaces the queue with the given stored playlist and starts playing it from the beginning
+func (w *MainWindow) playPlaylist(name string) {
+	w.queuePlaylist(tbTrue, name)
+
+	// Bail out if loading the playlist has failed
+	if w.queueSource == nil || w.queueSource.Name != name {
+		return
+	}
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.Play(0)
+	})
+
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to start playback"))
+}
+
+// queueSaveBack writes the play queue back into the playlist it's been loaded from
+func (w *MainWindow) queueSaveBack() {
+	if w.queueSource == nil {
+		return
+	}
+	name := w.queueSource.Name
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		// Back the playlist up first
+		if err = w.backupPlaylists(client, name); err != nil {
+			return
+		}
+		commands := client.BeginCommandList()
+		commands.PlaylistRemove(name)
+		commands.PlaylistSave(name)
 		err = commands.End()
 	})
-
-	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to add playlist to the queue"))
+	// Check for error (outside IfConnected() because it would keep the client locked)
+	if !w.errCheckDialog(err, glib.Local("Failed to save the playlist")) {
+		// The playlist now matches the queue
+		w.queueSource.URIs = w.queueTrackURIs
+		w.updateQueueInfo()
+	}
 }
 
 // queueSave shows a dialog for saving the play queue into a playlist and performs the operation if confirmed
@@ -1632,6 +4455,13 @@ func (w *MainWindow) queueSaveApply(replace bool) {
 			return
 		}
 
+		// If replacing an existing playlist, back it up first
+		if !isNew && replace {
+			if err = w.backupPlaylists(client, name); err != nil {
+				return
+			}
+		}
+
 		// Begin a command list
 		commands := client.BeginCommandList()
 
@@ -1662,7 +4492,13 @@ func (w *MainWindow) queueSaveApply(replace bool) {
 	})
 
 	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to create a playlist"))
+	if !w.errCheckDialog(err, glib.Local("Failed to create a playlist")) {
+		// If the queue has replaced the playlist it's been loaded from, they're in sync again
+		if w.queueSource != nil && w.queueSource.Name == name && replace && !selOnly {
+			w.queueSource.URIs = w.queueTrackURIs
+			w.updateQueueInfo()
+		}
+	}
 }
 
 // queueShuffle randomises MPD's play queue
@@ -1686,9 +4522,16 @@ func (w *MainWindow) queueSort(attr *config.MpdTrackAttribute, descending bool)
 			return
 		}
 
-		// Sort the list
+		// Sort the list, disregarding "The", "A" etc. in artist names, if needed
+		ignoreArticles := config.GetConfig().SortIgnoreArticles && isArtistAttrName(attr.AttrName)
+		value := func(a mpd.Attrs) string {
+			if ignoreArticles {
+				return strings.ToLower(util.StripLeadingArticle(a[attr.AttrName]))
+			}
+			return a[attr.AttrName]
+		}
 		sort.SliceStable(attrs, func(i, j int) bool {
-			a, b := attrs[i][attr.AttrName], attrs[j][attr.AttrName]
+			a, b := value(attrs[i]), value(attrs[j])
 			if attr.Numeric {
 				an, bn := util.ParseFloatDef(a, 0), util.ParseFloatDef(b, 0)
 				if descending {
@@ -1727,6 +4570,55 @@ func (w *MainWindow) queueSortApply(descending bool) {
 	}
 }
 
+// queueSongInfo shows information about the selected queue track
+func (w *MainWindow) queueSongInfo() {
+	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get track information")) {
+		w.songInfo(attrs)
+	}
+}
+
+// queueStar toggles the star of the tracks selected in the queue
+func (w *MainWindow) queueStar() {
+	uris, err := w.getQueueSelectedURIs()
+	if !w.errCheckDialog(err, glib.Local("Failed to get selected tracks")) {
+		w.toggleFavorites(uris)
+	}
+}
+
+// queueAddSelectionToPlaylist appends the tracks selected in the queue to the playlist with the given name
+func (w *MainWindow) queueAddSelectionToPlaylist(name string) {
+	uris, err := w.getQueueSelectedURIs()
+	if !w.errCheckDialog(err, glib.Local("Failed to get selected tracks")) && len(uris) > 0 {
+		w.libraryAppendPlaylist(name, uris...)
+	}
+}
+
+// playlistInsertURIs inserts the given tracks into the stored playlist at the given position, or appends them if the
+// position is negative or beyond the end of the playlist
+func (w *MainWindow) playlistInsertURIs(name string, pos int, uris []string) {
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		// Fetch the current playlist length
+		var attrs []mpd.Attrs
+		if attrs, err = client.PlaylistContents(name); err != nil {
+			return
+		}
+		n := len(attrs)
+
+		// Append each track and move it into place
+		commands := client.BeginCommandList()
+		for i, uri := range uris {
+			commands.PlaylistAdd(name, uri)
+			if pos >= 0 && pos < n {
+				commands.PlaylistMove(name, n+i, pos+i)
+			}
+		}
+		err = commands.End()
+	})
+	// Check for error (outside IfConnected() because it would keep the client locked)
+	w.errCheckDialog(err, glib.Local("Failed to add track(s) to the playlist"))
+}
+
 // queueStream adds or replaces the content of the queue with the specified stream
 func (w *MainWindow) queueStream(replace triBool, uri string) {
 	log.Debugf("queueStream(%v, %v)", replace, uri)
@@ -1735,7 +4627,7 @@ func (w *MainWindow) queueStream(replace triBool, uri string) {
 		commands := client.BeginCommandList()
 
 		// Clear the queue, if needed
-		if replace == tbTrue || replace == tbNone && config.GetConfig().StreamDefaultReplace {
+		if replace == tbTrue {
 			commands.Clear()
 		}
 
@@ -1750,6 +4642,54 @@ func (w *MainWindow) queueStream(replace triBool, uri string) {
 	w.errCheckDialog(err, glib.Local("Failed to add stream to the queue"))
 }
 
+// queueStreamAs queues the specified stream up according to the given action, one of the config.ClickAction* constants
+func (w *MainWindow) queueStreamAs(action string, uri string) {
+	switch action {
+	case config.ClickActionPlay, config.ClickActionNext:
+		w.queueURIsNext(action == config.ClickActionPlay, uri)
+	case config.ClickActionReplace:
+		w.queueStream(tbTrue, uri)
+	default:
+		w.queueStream(tbFalse, uri)
+	}
+}
+
+// queueURIsAt inserts the specified URIs into the queue at the given position. If play is true, also starts playing
+// the first inserted track
+func (w *MainWindow) queueURIsAt(pos int, play bool, uris ...string) {
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		// Insert the URIs
+		commands := client.BeginCommandList()
+		for i, uri := range uris {
+			commands.AddID(uri, pos+i)
+		}
+
+		// Start playback, if needed
+		if play {
+			commands.Play(pos)
+		}
+
+		// Run the commands
+		err = commands.End()
+	})
+
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to add track(s) to the queue"))
+}
+
+// queueURIsNext inserts the specified URIs into the queue right after the currently playing track, or appends them if
+// nothing is being played. If play is true, also starts playing the first inserted track
+func (w *MainWindow) queueURIsNext(play bool, uris ...string) {
+	// Determine the insert position
+	status := w.connector.Status()
+	pos := util.AtoiDef(status["song"], -1) + 1
+	if pos == 0 {
+		pos = util.AtoiDef(status["playlistlength"], 0)
+	}
+	w.queueURIsAt(pos, play, uris...)
+}
+
 // queueURIs adds or replaces the content of the queue with the specified URIs
 func (w *MainWindow) queueURIs(replace triBool, uris ...string) {
 	var err error
@@ -1757,7 +4697,7 @@ func (w *MainWindow) queueURIs(replace triBool, uris ...string) {
 		commands := client.BeginCommandList()
 
 		// Clear the queue, if needed
-		if replace == tbTrue || replace == tbNone && config.GetConfig().TrackDefaultReplace {
+		if replace == tbTrue {
 			commands.Clear()
 		}
 
@@ -1776,8 +4716,20 @@ func (w *MainWindow) queueURIs(replace triBool, uris ...string) {
 
 // shortcutInfo displays a shortcut info window
 func (w *MainWindow) shortcutInfo() {
-	// Construct a window from the Glade resource
-	builder, err := NewBuilder(generated.GetShortcutsGlade())
+	// List the shortcuts currently assigned to actions, followed by those handled by widgets
+	var shortcuts []shortcut
+	for _, name := range w.shortcutActions {
+		if accels := w.app.GetAccelsForAction("app." + name); len(accels) > 0 {
+			shortcuts = append(shortcuts, actionShortcut(name, accels))
+		}
+	}
+	shortcuts = append(shortcuts, keyShortcuts...)
+	if vimKeymapEnabled() {
+		shortcuts = append(shortcuts, vimKeyShortcuts...)
+	}
+
+	// Construct a window from the generated definition
+	builder, err := NewBuilder(shortcutsWindowXML(shortcuts))
 
 	// Map the window's widgets
 	win := struct {
@@ -1788,7 +4740,7 @@ func (w *MainWindow) shortcutInfo() {
 	}
 
 	// Check for errors
-	if w.errCheckDialog(err, "Failed to open the Shortcuts Window") {
+	if w.errCheckDialog(err, glib.Local("Failed to open the Shortcuts Window")) {
 		return
 	}
 
@@ -1805,11 +4757,112 @@ func (w *MainWindow) shortcutInfo() {
 	errCheck(sw.SetProperty("section-name", "shortcuts"), "Failed to set shortcut window's section name")
 }
 
+// resolveLibraryElements resolves all playable elements in the given slice into track URIs. Returns false if there was
+// an error (which is reported to the user with the given message) or nothing to resolve
+func (w *MainWindow) resolveLibraryElements(elements []LibraryPathElement, errMessage string) ([]string, bool) {
+	var uris []string
+	for _, element := range elements {
+		if !element.IsPlayable() {
+			continue
+		}
+		elURIs, err := w.libraryElementURIs(element)
+		if w.errCheckDialog(err, errMessage) {
+			return nil, false
+		}
+		uris = append(uris, elURIs...)
+	}
+	return uris, len(uris) > 0
+}
+
 // Show displays the window and all its child widgets
 func (w *MainWindow) Show() {
 	w.AppWindow.Show()
 }
 
+// ShowHidden initialises the main window without displaying it, so that it can be brought up later from the tray icon.
+// The window is shown as usual if there's no tray icon
+func (w *MainWindow) ShowHidden() {
+	if w.trayIcon == nil {
+		w.Show()
+		return
+	}
+
+	// Do the same as on mapping, which is skipped once the window eventually shows up
+	w.onMap()
+}
+
+// RunDaemon initialises the main window without displaying it and keeps the application running in the background,
+// relying on the tray icon, MPRIS and notifications. Toggling the window summons the mini player instead
+func (w *MainWindow) RunDaemon() {
+	w.daemon = true
+	if m, err := NewMiniPlayer(w.QueueListStore, w.playQueueIndex); err != nil {
+		log.Warningf("Failed to create mini player: %v", err)
+	} else {
+		w.miniPlayer = m
+	}
+
+	// Do the same as on mapping, which is skipped once the window eventually shows up
+	w.onMap()
+}
+
+// Present shows the main window to the user, raising and focusing it
+func (w *MainWindow) Present() {
+	w.AppWindow.Present()
+}
+
+// toggleFavorites stars the given tracks by adding them to the favourites playlist, or unstars them if they're all
+// starred already
+func (w *MainWindow) toggleFavorites(uris []string) {
+	add, removePos := w.favorites.Toggle(uris)
+	if len(add) == 0 && len(removePos) == 0 {
+		return
+	}
+	name := config.GetConfig().FavoritesPlaylist
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		commands := client.BeginCommandList()
+		for _, pos := range removePos {
+			commands.PlaylistDelete(name, pos)
+		}
+		for _, uri := range add {
+			commands.PlaylistAdd(name, uri)
+		}
+		err = commands.End()
+	})
+	// Check for error (outside IfConnected() because it would keep the client locked). The stars get updated once
+	// MPD notifies of the playlist change
+	w.errCheckDialog(err, glib.Local("Failed to update favorites"))
+}
+
+// updateFavorites reloads the starred tracks and updates their indicators in the queue
+func (w *MainWindow) updateFavorites() {
+	var attrs []mpd.Attrs
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.PlaylistContents(config.GetConfig().FavoritesPlaylist)
+	})
+	// The playlist doesn't exist until a track is starred, so don't bother the user
+	if err != nil {
+		log.Debugf("Failed to load favorites: %v", err)
+	}
+	w.favorites = NewFavorites(attrs)
+
+	// Update the stars in the queue
+	w.QueueListStore.ForEach(func(model *gtk.TreeModel, path *gtk.TreePath, iter *gtk.TreeIter, userData ...interface{}) bool {
+		v, err := model.GetValue(iter, config.MTAttrPath)
+		if errCheck(err, "updateFavorites(): QueueListStore.GetValue() failed") {
+			return false
+		}
+		uri, _ := v.GetString()
+		icon := ""
+		if w.favorites.IsStarred(uri) {
+			icon = queueStarIcon
+		}
+		errCheck(w.QueueListStore.SetValue(iter, config.QueueColumnStarIcon, icon), "updateFavorites(): QueueListStore.SetValue() failed")
+		return false
+	})
+}
+
 // setQueueHighlight selects or deselects an item in the Queue tree view at the given index
 func (w *MainWindow) setQueueHighlight(index int, selected bool) {
 	if index >= 0 {
@@ -1830,16 +4883,39 @@ func (w *MainWindow) setQueueHighlight(index int, selected bool) {
 	}
 }
 
+// songInfo fetches the comments for and shows information about a track with the given attributes
+func (w *MainWindow) songInfo(attrs mpd.Attrs) {
+	// Fetch track comments. Not all tracks (eg. streams) support them, so just log any error
+	var comments mpd.Attrs
+	if uri := attrs["file"]; uri != "" {
+		w.connector.IfConnected(func(client *mpd.Client) {
+			var err error
+			if comments, err = client.ReadComments(uri); err != nil {
+				log.Debugf("Failed to read comments for %s: %v", uri, err)
+			}
+		})
+	}
+
+	// Show the dialog
+	SongInfoDialog(w.AppWindow, attrs, comments)
+}
+
 // updateAll updates all window's widgets and lists
 func (w *MainWindow) updateAll() {
 	// Update global actions
 	connected, connecting := w.connector.ConnectStatus()
 	w.aMPDDisconnect.SetEnabled(connected || connecting)
 	w.aMPDInfo.SetEnabled(connected)
+	w.aPlaylistImport.SetEnabled(connected)
+	w.aPlaylistRestore.SetEnabled(connected)
+	w.aPlaylistAppendRecent.SetEnabled(connected && w.recentTargetPlaylist() != "")
 
 	// Update other widgets
+	w.updateFavorites()
+	w.updateMprisPlaylists()
 	w.updateQueue()
 	w.updateLibraryPath()
+	w.updateLibraryBookmarks()
 	w.updateLibrary()
 	w.updateLibraryActions()
 	w.updateOptions()
@@ -1847,52 +4923,208 @@ func (w *MainWindow) updateAll() {
 	w.updateVolume()
 }
 
-// updateLibrary updates the current library list contents
+// libraryContent represents the library list contents loaded from MPD
+type libraryContent struct {
+	elements   []LibraryPathElement // Elements to display
+	searchURIs []string             // URIs of all tracks found, in search mode
+	totalSecs  float64              // Total playing time of the tracks
+	maxRows    int                  // Maximum number of rows to display, -1 for unlimited
+}
+
+// libraryViewType returns the type of the current library view (one of the config.LibraryView* constants)
+func (w *MainWindow) libraryViewType() string {
+	if !w.LibrarySearchToolButton.GetActive() {
+		switch w.libPath.Last().(type) {
+		case AttributeHolderParent, *AlbumLibElement:
+			return config.LibraryViewAlbums
+		}
+	}
+	return config.LibraryViewFolders
+}
+
+// libraryLoadParams represents the parameters of a library contents load
+type libraryLoadParams struct {
+	lastElement     LibraryPathElement         // Last element of the current library path
+	filter          []string                   // Current library path as a filter
+	pattern         string                     // Search pattern, empty if not in the search mode
+	attrName        string                     // Name of the attribute to search by
+	sortBy          string                     // Library sort order
+	foldersFirst    bool                       // Whether to put folders first
+	maxResults      int                        // Maximum number of search results to display
+	match           func(s string) bool        // Function matching tracks against the search pattern locally, nil to leave it to MPD
+	excludePatterns []string                   // Patterns of paths to hide from the listing
+	ignoreArticles  bool                       // Whether to ignore leading articles when sorting artists
+	smartPlaylists  []config.SmartPlaylistSpec // Defined smart playlists
+	playlistFolders map[string]string          // Virtual folders of stored playlists, keyed by playlist name
+}
+
+// updateLibrary updates the current library list contents. The contents is loaded in the background, and any load still
+// in progress is cancelled
 func (w *MainWindow) updateLibrary() {
-	// Clear the library list
+	// Invalidate any ongoing load
+	w.libLoadGen++
+	gen := w.libLoadGen
+
+	// Clear the library list and grid
 	util.ClearChildren(w.LibraryListBox.Container)
+	util.ClearChildren(w.LibraryFlowBox.Container)
+	w.libPreviewRow, w.libPreviewPlaylist = nil, ""
+	w.libSearchURIs = nil
+
+	// Queue tracks can only be dropped into an open stored playlist
+	if _, ok := w.libPath.Last().(*PlaylistLibElement); ok {
+		if targets := w.queueDndTargets(); targets != nil {
+			w.LibraryListBox.DragDestSet(gtk.DEST_DEFAULT_ALL, targets, gdk.ACTION_COPY)
+		}
+	} else {
+		util.DragDestUnset(w.LibraryListBox)
+	}
 
-	var (
-		elements []LibraryPathElement
-		err      error
-		pattern  string
-	)
-	maxResultRows := -1
-	lastElement := w.libPath.Last()
+	// Collect the load parameters while on the GLib's thread
+	cfg := config.GetConfig()
+	params := libraryLoadParams{
+		lastElement:     w.libPath.Last(),
+		filter:          w.libPath.AsFilter(),
+		sortBy:          cfg.LibrarySortBy,
+		foldersFirst:    cfg.LibraryFoldersFirst,
+		maxResults:      cfg.MaxSearchResults,
+		excludePatterns: cfg.LibraryExcludePatterns,
+		ignoreArticles:  cfg.SortIgnoreArticles,
+		smartPlaylists:  append([]config.SmartPlaylistSpec(nil), cfg.SmartPlaylists...),
+		playlistFolders: make(map[string]string, len(cfg.PlaylistFolders)),
+	}
+	for name, folder := range cfg.PlaylistFolders {
+		params.playlistFolders[name] = folder
+	}
 
 	// If search mode activated
 	if w.LibrarySearchToolButton.GetActive() {
-		pattern = util.EntryText(&w.LibrarySearchEntry.Entry, "")
-	}
-
-	// Search mode: fetch selected attribute
-	if pattern != "" {
-		attrName := "any"
+		params.pattern = util.EntryText(&w.LibrarySearchEntry.Entry, "")
+		params.attrName = "any"
 		if attr, ok := config.MpdTrackAttributes[util.AtoiDef(w.LibrarySearchAttrComboBox.GetActiveID(), -1)]; ok {
-			attrName = attr.AttrName
+			params.attrName = attr.AttrName
 		}
 
-		// Run search
-		var attrs []mpd.Attrs
+		// Fuzzy, regex and diacritic-insensitive matching is done locally: MPD can't do the first and the last, and its
+		// regex syntax differs from Go's
+		util.SetEntryError(&w.LibrarySearchEntry.Entry, false)
+		if cfg.LibraryFuzzySearch || cfg.LibraryRegexSearch || cfg.SearchIgnoreDiacritics {
+			params.match = w.libraryMatcher(&w.LibrarySearchEntry.Entry, params.pattern)
+		}
+	}
+
+	// Choose between list and grid presentation, depending on the view type
+	w.libGrid = cfg.LibraryGridViews[w.libraryViewType()]
+	w.libViewUpdating = true
+	w.LibraryGridToolButton.SetActive(w.libGrid)
+	w.libViewUpdating = false
+	w.LibraryListBox.SetVisible(!w.libGrid)
+	w.LibraryFlowBox.SetVisible(w.libGrid)
+
+	// Show the spinner
+	w.LibrarySpinner.Show()
+	w.LibrarySpinner.Start()
+
+	go func() {
+		content, err := w.loadLibraryContent(&params)
+
+		// Display the content on the GLib's thread, unless the load has gone stale in the meantime
+		util.WhenIdle("updateLibrary()", func() {
+			if gen != w.libLoadGen {
+				return
+			}
+			if err != nil {
+				w.stopLibrarySpinner()
+				return
+			}
+			w.libSearchURIs = content.searchURIs
+			w.populateLibrary(gen, content)
+		})
+	}()
+}
+
+// countLibraryElements sets the details of the given children of the parent element to the counts of the tracks under
+// them. The tracks are taken from the track index at the top level, and fetched from MPD using the filter otherwise
+func (w *MainWindow) countLibraryElements(parent AttributeHolderParent, filter []string, elements []LibraryPathElement) {
+	var tracks []mpd.Attrs
+	var err error
+	if len(filter) == 0 {
+		tracks, err = w.libIndex.Tracks()
+	} else {
 		w.connector.IfConnected(func(client *mpd.Client) {
-			attrs, err = client.Search(fmt.Sprintf("(%s contains \"%s\")", attrName, pattern))
+			tracks, err = client.Find(filter...)
 		})
-		if errCheck(err, "updateLibrary(): Search() failed") {
-			return
+	}
+
+	// Counts are optional, so don't fail on error
+	if !errCheck(err, "countLibraryElements(): failed to fetch tracks") {
+		SetElementCountDetails(elements, CountChildTracks(parent, tracks))
+	}
+}
+
+// loadLibraryContent loads the library list contents from MPD. Doesn't touch any widgets, so it can be run in the
+// background
+func (w *MainWindow) loadLibraryContent(params *libraryLoadParams) (*libraryContent, error) {
+	content := &libraryContent{maxRows: -1}
+	lastElement := params.lastElement
+	var err error
+
+	// addTotalTime counts the total playing time of the given tracks
+	addTotalTime := func(attrs []mpd.Attrs) {
+		for _, a := range attrs {
+			content.totalSecs += util.ParseFloatDef(a["duration"], 0)
 		}
-		maxResultRows = config.GetConfig().MaxSearchResults
+	}
+
+	// Search mode: fetch selected attribute
+	if params.pattern != "" {
+		// Run search, either against the local track index or by MPD. Oversized databases aren't indexed, so fall back
+		// to MPD's plain search for them
+		var attrs []mpd.Attrs
+		var tracks []mpd.Attrs
+		match := params.match
+		if match != nil {
+			if tracks, err = w.libIndex.Tracks(); errors.Is(err, errTrackIndexTooLarge) {
+				log.Warningf("Falling back to plain search: %v", err)
+				match = nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if match != nil {
+			attrs = MatchTrackAttrs(tracks, trackSearchAttrNames(params.attrName), match)
+		} else {
+			w.connector.IfConnected(func(client *mpd.Client) {
+				attrs, err = client.Search(fmt.Sprintf("(%s contains \"%s\")", params.attrName, params.pattern))
+			})
+			if errCheck(err, "loadLibraryContent(): Search() failed") {
+				return nil, err
+			}
+		}
+		attrs = ExcludeLibraryAttrs(attrs, params.excludePatterns)
+		content.maxRows = params.maxResults
+
+		// Sort and convert the list into elements
+		SortLibraryAttrs(attrs, params.sortBy, params.foldersFirst)
+		content.elements = AttrsToElements(attrs, "")
 
-		// Convert the list into elements
-		elements = AttrsToElements(attrs, "")
+		// Collect all found URIs and their total duration
+		content.searchURIs = util.MapAttrsToSlice(attrs, "file")
+		addTotalTime(attrs)
 
 	} else if lastElement == nil {
 		// Root
-		elements = []LibraryPathElement{
+		content.elements = []LibraryPathElement{
 			NewFilesystemLibElement(),
 			NewGenresLibElement(),
 			NewArtistsLibElement(),
+			NewComposersLibElement(),
+			NewPerformersLibElement(),
 			NewAlbumsLibElement(),
+			NewYearsLibElement(),
 			NewPlaylistsLibElement(),
+			NewSmartPlaylistsLibElement(),
+			NewRecentLibElement(),
 		}
 
 	} else if uh, ok := lastElement.(URIHolder); ok {
@@ -1901,12 +5133,49 @@ func (w *MainWindow) updateLibrary() {
 		w.connector.IfConnected(func(client *mpd.Client) {
 			attrs, err = client.ListInfo(uh.URI())
 		})
-		if errCheck(err, "updateLibrary(): ListInfo() failed") {
-			return
+		if errCheck(err, "loadLibraryContent(): ListInfo() failed") {
+			return nil, err
+		}
+		attrs = ExcludeLibraryAttrs(attrs, params.excludePatterns)
+
+		// Sort and convert the list into elements
+		SortLibraryAttrs(attrs, params.sortBy, params.foldersFirst)
+		content.elements = AttrsToElements(attrs, uh.URI()+"/")
+
+	} else if qh, ok := lastElement.(QueryHolder); ok {
+		// Query-enabled element: load the tracks matching the query. Recently added tracks are listed newest first
+		var attrs []mpd.Attrs
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.Find(qh.Query())
+		})
+		if errCheck(err, "loadLibraryContent(): Find() failed") {
+			return nil, err
+		}
+		attrs = ExcludeLibraryAttrs(attrs, params.excludePatterns)
+
+		// Sort and convert the list into elements
+		if _, ok := lastElement.(*RecentLibElement); ok {
+			SortLibraryAttrs(attrs, config.LibrarySortByModified, false)
+		} else {
+			SortLibraryAttrs(attrs, params.sortBy, params.foldersFirst)
 		}
+		content.elements = AttrsToElements(attrs, "")
+		addTotalTime(attrs)
 
-		// Convert the list into elements
-		elements = AttrsToElements(attrs, uh.URI()+"/")
+	} else if _, ok := lastElement.(*AlbumLibElement); ok {
+		// Album element: load the album's tracks along with their attributes
+		var attrs []mpd.Attrs
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.Find(params.filter...)
+		})
+		if errCheck(err, "loadLibraryContent(): Find() failed") {
+			return nil, err
+		}
+		attrs = ExcludeLibraryAttrs(attrs, params.excludePatterns)
+
+		// Convert the tracks into elements and count their total duration
+		content.elements = AlbumTracksToElements(attrs)
+		addTotalTime(attrs)
 
 	} else if browseBy, ok := lastElement.(AttributeHolderParent); ok {
 		// Attribute-enabled path: determine the attribute we're browsing by
@@ -1914,98 +5183,427 @@ func (w *MainWindow) updateLibrary() {
 			// First element is the attribute we're browsing by
 			[]string{config.MpdTrackAttributes[browseBy.ChildAttributeID()].AttrName},
 			// Then the filter arguments follow
-			w.libPath.AsFilter()...)
+			params.filter...)
 
 		// Load the list of tags
 		var list []string
 		w.connector.IfConnected(func(client *mpd.Client) {
 			list, err = client.List(args...)
 		})
-		if errCheck(err, "updateLibrary(): List() failed") {
-			return
+		if errCheck(err, "loadLibraryContent(): List() failed") {
+			return nil, err
+		}
+
+		// Genres may hold multiple values in a single tag: split them up
+		if browseBy.ChildAttributeID() == config.MTAttrGenre {
+			list = UniqueTagValues(list)
+		}
+
+		// Convert the string list into a list of elements
+		elements := make([]LibraryPathElement, 0, len(list))
+		for _, s := range list {
+			if c := browseBy.NewChild(s); c != nil {
+				elements = append(elements, c)
+			}
+		}
+
+		// Drop duplicates, which appear when values are rolled up (eg. dates into years)
+		elements = UniqueElements(elements)
+
+		// Sort artists disregarding "The", "A" etc., if needed
+		if params.ignoreArticles && isArtistAttrID(browseBy.ChildAttributeID()) {
+			SortElementsIgnoringArticles(elements)
+		}
+
+		// Show the number of albums or tracks under each element
+		w.countLibraryElements(browseBy, params.filter, elements)
+
+		// Keep compilations ("Various Artists") on top
+		PinCompilationElements(elements)
+		content.elements = elements
+
+	} else if _, ok := lastElement.(*SmartPlaylistsLibElement); ok {
+		// Smart playlists list element: list the defined smart playlists
+		for _, sp := range params.smartPlaylists {
+			content.elements = append(content.elements, NewSmartPlaylistLibElementVal(sp.Name, sp.Query))
+		}
+
+	} else if pl, ok := lastElement.(*PlaylistLibElement); ok {
+		// Playlist element: load the playlist's tracks, in their order
+		var attrs []mpd.Attrs
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.PlaylistContents(pl.PlaylistName())
+		})
+		if errCheck(err, "loadLibraryContent(): PlaylistContents() failed") {
+			return nil, err
+		}
+		content.elements = PlaylistTracksToElements(attrs)
+		addTotalTime(attrs)
+
+	} else if _, ok := lastElement.(*PlaylistsLibElement); ok {
+		// Playlists list element: load list of playlists
+		var attrs []mpd.Attrs
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.ListPlaylists()
+		})
+		if errCheck(err, "loadLibraryContent(): ListPlaylists() failed") {
+			return nil, err
+		}
+
+		// Sort the playlists and add the number of tracks and total duration to every one of them
+		SortLibraryAttrs(attrs, params.sortBy, false)
+		for _, a := range attrs {
+			e := &PlaylistLibElement{name: a["playlist"]}
+			e.modified, _ = time.Parse(time.RFC3339, a["Last-Modified"])
+			if stats, ok := w.libPlaylistStats.Stats(e.name, a["Last-Modified"]); ok {
+				e.details = stats.Details()
+			}
+			content.elements = append(content.elements, e)
+		}
+
+		// Arrange the playlists into folders
+		content.elements = GroupPlaylistElements(content.elements, params.playlistFolders)
+
+	} else {
+		err = fmt.Errorf("unknown library path kind (last element is %T)", lastElement)
+		log.Error(err)
+		return nil, err
+	}
+
+	// If no search mode and not root, insert a "level up" element
+	if params.pattern == "" && lastElement != nil {
+		content.elements = append([]LibraryPathElement{NewLevelUpLibElement()}, content.elements...)
+	}
+	return content, nil
+}
+
+// populateLibrary fills the library list with the given content. Rows are added in batches, one batch per idle
+// callback, so that large lists don't block the UI. Populating stops as soon as a newer load starts
+func (w *MainWindow) populateLibrary(gen int, content *libraryContent) {
+	cfg := config.GetConfig()
+	showModified := cfg.LibraryShowModified
+	if _, ok := w.libPath.Last().(*PlaylistsLibElement); ok && cfg.PlaylistShowModified {
+		showModified = true
+	}
+	var rowToSelect *gtk.ListBoxRow
+	var tileToSelect *gtk.FlowBoxChild
+	index, countItems, limited := 0, 0, false
+
+	// addBatch adds the next batch of rows, and returns whether there are more batches to add
+	addBatch := func() bool {
+		// Stop if the load has gone stale
+		if gen != w.libLoadGen {
+			return false
+		}
+
+		for end := index + libraryPopulateBatchSize; index < len(content.elements) && index < end && !limited; index++ {
+			element := content.elements[index]
+
+			// Headers and playlist folders are only displayed in the list, and aren't counted
+			switch element.(type) {
+			case *HeaderLibElement, *PlaylistFolderLibElement:
+				if !w.libGrid {
+					w.addLibraryRow(element, showModified)
+				}
+				continue
+			}
+
+			// If no specific item to select, pick the first one. Otherwise check for a matching marshalled form
+			toSelect := rowToSelect == nil && tileToSelect == nil &&
+				(w.libPathElementToSelect == "" || w.libPathElementToSelect == element.Marshal())
+
+			// Add a tile or a row
+			if w.libGrid {
+				tile := w.addLibraryTile(element)
+				if tile == nil {
+					continue
+				}
+				if toSelect {
+					tileToSelect = tile
+				}
+			} else {
+				row := w.addLibraryRow(element, showModified)
+				if row == nil {
+					continue
+				}
+				if toSelect {
+					rowToSelect = row
+				}
+			}
+			countItems++
+			limited = content.maxRows >= 0 && countItems >= content.maxRows
+		}
+
+		// Show the added rows or tiles
+		if w.libGrid {
+			w.LibraryFlowBox.ShowAll()
+			w.libraryFilterGrid()
+		} else {
+			w.LibraryListBox.ShowAll()
 		}
 
-		// Convert the string list into a list of elements
-		elements = make([]LibraryPathElement, 0, len(list))
-		for _, s := range list {
-			if c := browseBy.NewChild(s); c != nil {
-				elements = append(elements, c)
-			}
+		// Carry on if there are more elements to add
+		if index < len(content.elements) && !limited {
+			return true
 		}
+		w.finishLibraryLoad(content, rowToSelect, tileToSelect, countItems, limited)
+		return false
+	}
 
-	} else if pl, ok := lastElement.(*PlaylistsLibElement); ok {
-		// Playlists list element: load list of playlists
-		for _, name := range w.connector.GetPlaylists() {
-			elements = append(elements, pl.NewChild(name))
+	util.WhenIdle("populateLibrary()", addBatch)
+}
+
+// addLibraryRow adds a new row for the given element to the library list. Returns nil on error
+func (w *MainWindow) addLibraryRow(element LibraryPathElement, showModified bool) *gtk.ListBoxRow {
+	// Playlist folders are displayed as expanders
+	if fe, ok := element.(*PlaylistFolderLibElement); ok {
+		return w.addLibraryFolderRow(fe)
+	}
+
+	label := element.Label()
+	markup := false
+
+	// Add replace/append buttons if needed
+	var buttons []gtk.IWidget
+	if element.IsPlayable() {
+		buttons = []gtk.IWidget{
+			util.NewButton("", glib.Local("Append to the queue"), "", "ymuse-add-symbolic", func() { w.queueLibraryElement(tbFalse, element) }),
+			util.NewButton("", glib.Local("Replace the queue"), "", "ymuse-replace-queue-symbolic", func() { w.queueLibraryElement(tbTrue, element) }),
+			util.NewButton("", glib.Local("Play next"), "", "ymuse-next-symbolic", func() { w.queueLibraryElementsNext(false, element) }),
 		}
 
+		// Add a right-aligned track number column, if there's a number
+		if nh, ok := element.(NumberHolder); ok && nh.Number() != "" {
+			buttons = append(buttons, newLibraryColumnLabel(nh.Number(), libraryNumberWidthChars, true))
+		}
 	} else {
-		log.Errorf("Unknown library path kind (last element is %T)", lastElement)
-		return
+		// Make non-playable (root) elements and headers bold
+		label = "<b>" + html.EscapeString(label) + "</b>"
+		markup = true
 	}
 
-	// If no search mode and not root, insert a "level up" element
-	if pattern == "" && lastElement != nil {
-		elements = append([]LibraryPathElement{NewLevelUpLibElement()}, elements...)
+	// Add a new list box row
+	row, hbx, err := util.NewListBoxRow(w.LibraryListBox, markup, label, MarshalLibPathElement(element), element.Icon(), buttons...)
+	if errCheck(err, "NewListBoxRow() failed") {
+		return nil
 	}
 
-	// Repopulate the library list
-	var rowToSelect *gtk.ListBoxRow
-	countItems, limited := 0, false
-	for _, element := range elements {
-		element := element // Make an in-loop copy for closures
-		label := element.Label()
-		markup := false
+	// Add a label with details [track length], if any, aligned in a column
+	if dh, ok := element.(DetailsHolder); ok {
+		if details := dh.Details(); details != "" {
+			if lbl := newLibraryColumnLabel(details, libraryDetailsWidthChars, false); lbl != nil {
+				hbx.PackEnd(lbl, false, false, 0)
+			}
+		}
+	}
+
+	// Add a badge with the audio format, if known
+	if fh, ok := element.(FormatHolder); ok && fh.AudioFormat() != "" {
+		if lbl := newLibraryColumnLabel(fh.AudioFormat(), libraryFormatWidthChars, true); lbl != nil {
+			hbx.PackEnd(lbl, false, false, 0)
+		}
+	}
 
-		// Add replace/append buttons if needed
-		var buttons []gtk.IWidget
-		if element.IsPlayable() {
-			buttons = []gtk.IWidget{
-				util.NewButton("", glib.Local("Append to the queue"), "", "ymuse-add-symbolic", func() { w.queueLibraryElement(tbFalse, element) }),
-				util.NewButton("", glib.Local("Replace the queue"), "", "ymuse-replace-queue-symbolic", func() { w.queueLibraryElement(tbTrue, element) }),
+	// Headers can't be selected or activated
+	if _, ok := element.(*HeaderLibElement); ok {
+		row.SetSelectable(false)
+		row.SetActivatable(false)
+		hbx.SetMarginTop(6)
+		return row
+	}
+
+	// Add a label with last modification time, if needed
+	if mh, ok := element.(ModifiedHolder); ok && showModified && !mh.LastModified().IsZero() {
+		lbl, err := gtk.LabelNew(mh.LastModified().Local().Format("2006-01-02 15:04"))
+		// Just ignore the error and proceed
+		if !errCheck(err, "LabelNew() failed") {
+			if ctx, err := lbl.GetStyleContext(); err == nil {
+				ctx.AddClass("dim-label")
 			}
-		} else {
-			// Make non-playable (root) elements bold
-			label = "<b>" + label + "</b>"
-			markup = true
+			hbx.PackEnd(lbl, false, false, 0)
 		}
+	}
+	return row
+}
 
-		// Add a new list box row
-		row, hbx, err := util.NewListBoxRow(w.LibraryListBox, markup, label, MarshalLibPathElement(element), element.Icon(), buttons...)
-		if errCheck(err, "NewListBoxRow() failed") {
-			return
+// addLibraryFolderRow adds a new non-selectable row for the given virtual playlist folder to the library list, allowing
+// to collapse and expand the folder. Returns nil on error
+func (w *MainWindow) addLibraryFolderRow(element *PlaylistFolderLibElement) *gtk.ListBoxRow {
+	row, err := gtk.ListBoxRowNew()
+	if errCheck(err, "ListBoxRowNew() failed") {
+		return nil
+	}
+	row.SetName(MarshalLibPathElement(element))
+	row.SetSelectable(false)
+	row.SetActivatable(false)
+	hbx, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 6)
+	if errCheck(err, "BoxNew() failed") {
+		return nil
+	}
+	hbx.SetMarginStart(6)
+	hbx.SetMarginEnd(6)
+	hbx.SetMarginTop(6)
+	row.Add(hbx)
+
+	// Add an expander with the folder name in bold
+	expander, err := gtk.ExpanderNew("")
+	if errCheck(err, "ExpanderNew() failed") {
+		return nil
+	}
+	lbl := util.NewLabel("")
+	if lbl == nil {
+		return nil
+	}
+	lbl.SetMarkup("<b>" + html.EscapeString(element.Label()) + "</b>")
+	expander.SetLabelWidget(lbl)
+	name := element.Label()
+	expander.SetExpanded(!config.GetConfig().PlaylistFoldersFolded[name])
+	_, _ = expander.Connect("notify::expanded", func() {
+		w.onPlaylistFolderToggle(name, expander.GetExpanded())
+	})
+	hbx.PackStart(expander, true, true, 0)
+
+	// Add the number of playlists
+	if details := element.Details(); details != "" {
+		if lbl := newLibraryColumnLabel(details, libraryDetailsWidthChars, false); lbl != nil {
+			hbx.PackEnd(lbl, false, false, 0)
 		}
+	}
+	w.LibraryListBox.Add(row)
+	return row
+}
 
-		// If no specific row to select, pick the first one. Otherwise check for a matching marshalled form
-		if rowToSelect == nil && (w.libPathElementToSelect == "" || w.libPathElementToSelect == element.Marshal()) {
-			rowToSelect = row
+// newLibraryColumnLabel creates and returns a right-aligned label at least widthChars wide, used for displaying aligned
+// columns of values in the library list. Returns nil on error
+func newLibraryColumnLabel(text string, widthChars int, dim bool) *gtk.Label {
+	lbl, err := gtk.LabelNew(text)
+	if errCheck(err, "LabelNew() failed") {
+		return nil
+	}
+	lbl.SetWidthChars(widthChars)
+	lbl.SetXAlign(1)
+	if dim {
+		if ctx, err := lbl.GetStyleContext(); err == nil {
+			ctx.AddClass("dim-label")
 		}
+	}
+	return lbl
+}
 
-		// Add a label with details [track length], if any
-		if dh, ok := element.(DetailsHolder); ok {
-			if details := dh.Details(); details != "" {
-				lbl, err := gtk.LabelNew(details)
-				// Just ignore the error and proceed
-				if !errCheck(err, "LabelNew() failed") {
-					hbx.PackEnd(lbl, false, false, 0)
-				}
+// addLibraryTile adds a new tile for the given element to the library grid. Returns nil on error
+func (w *MainWindow) addLibraryTile(element LibraryPathElement) *gtk.FlowBoxChild {
+	tile, err := gtk.FlowBoxChildNew()
+	if errCheck(err, "FlowBoxChildNew() failed") {
+		return nil
+	}
+	tile.SetName(MarshalLibPathElement(element))
+	tooltip := element.Label()
+	if dh, ok := element.(DetailsHolder); ok && dh.Details() != "" {
+		tooltip += "\n" + dh.Details()
+	}
+	if mh, ok := element.(ModifiedHolder); ok && !mh.LastModified().IsZero() {
+		tooltip += "\n" + fmt.Sprintf(glib.Local("Modified %s"), mh.LastModified().Local().Format("2006-01-02 15:04"))
+	}
+	tile.SetTooltipText(tooltip)
+
+	// Add a vertical box with a large icon and a label
+	vbx, err := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 6)
+	if errCheck(err, "BoxNew() failed") {
+		return nil
+	}
+	vbx.SetMarginTop(6)
+	vbx.SetMarginBottom(6)
+	tile.Add(vbx)
+	if icon := element.Icon(); icon != "" {
+		// Icon is optional, do not fail entirely on an error
+		if img, err := gtk.ImageNewFromIconName(icon, gtk.ICON_SIZE_DIALOG); !errCheck(err, "ImageNewFromIconName() failed") {
+			vbx.PackStart(img, false, false, 0)
+
+			// Album icons serve as placeholders for the album art
+			if _, ok := element.(*AlbumLibElement); ok {
+				img.SetPixelSize(libraryArtworkSize)
+				w.loadLibraryTileArt(img, element)
 			}
 		}
-		countItems++
+	}
+	lbl, err := gtk.LabelNew(element.Label())
+	if errCheck(err, "LabelNew() failed") {
+		return nil
+	}
+	lbl.SetJustify(gtk.JUSTIFY_CENTER)
+	lbl.SetLineWrap(true)
+	lbl.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+	lbl.SetLines(2)
+	lbl.SetEllipsize(pango.ELLIPSIZE_END)
+	lbl.SetMaxWidthChars(16)
+	lbl.SetWidthChars(16)
+	vbx.PackStart(lbl, false, false, 0)
+
+	// Add the tile to the grid
+	w.LibraryFlowBox.Insert(tile, -1)
+	return tile
+}
 
-		if maxResultRows >= 0 && countItems >= maxResultRows {
-			limited = true
-			break
+// loadLibraryTileArt fetches the album art for the given album element in the background, and puts it into the given
+// image once loaded, unless the library has been reloaded in the meantime
+func (w *MainWindow) loadLibraryTileArt(img *gtk.Image, element LibraryPathElement) {
+	filter := w.libPath.AsFilter(element)
+	if len(filter) == 0 {
+		return
+	}
+	gen := w.libLoadGen
+	settings := NewAlbumArtSettings()
+	w.albumArtLoader.Load(
+		"album:"+strings.Join(filter, "\x00"),
+		func() ([]byte, error) {
+			// Fetch the album art for the first track of the album
+			var tracks []mpd.Attrs
+			var err error
+			w.connector.IfConnected(func(client *mpd.Client) {
+				tracks, err = client.Find(append(filter, "window", "0:1")...)
+			})
+			if err != nil || len(tracks) == 0 {
+				return nil, err
+			}
+			return FetchAlbumArt(w.connector, w.albumArtCache, settings, tracks[0])
+		},
+		func(data []byte) {
+			util.WhenIdle("loadLibraryTileArt()", func() {
+				if gen == w.libLoadGen {
+					if px := albumArtPixbuf(data, libraryArtworkSize); px != nil {
+						img.SetFromPixbuf(px)
+					}
+				}
+			})
+		})
+}
+
+// finishLibraryLoad completes populating the library list: selects the required row (in list mode) or tile (in grid
+// mode) and updates the library info
+func (w *MainWindow) finishLibraryLoad(content *libraryContent, rowToSelect *gtk.ListBoxRow, tileToSelect *gtk.FlowBoxChild, countItems int, limited bool) {
+	w.stopLibrarySpinner()
+
+	// Select the required tile. If the grid has focus, move it onto the tile
+	if tileToSelect != nil {
+		w.LibraryFlowBox.SelectChild(tileToSelect)
+		if w.LibraryFlowBox.HasFocus() {
+			tileToSelect.GrabFocus()
 		}
 	}
 
-	// Show all rows
-	w.LibraryListBox.ShowAll()
+	// Select the required row and scroll to it (later). If the list has focus, move it onto the row
+	if !w.libGrid {
+		w.LibraryListBox.SelectRow(rowToSelect)
+		if rowToSelect != nil && w.LibraryListBox.HasFocus() {
+			rowToSelect.GrabFocus()
+		}
+		util.WhenIdle("ListBoxScrollToSelected()", util.ListBoxScrollToSelected, w.LibraryListBox)
+	}
 
-	// Select the required row and scroll to it (later)
-	w.LibraryListBox.SelectRow(rowToSelect)
-	util.WhenIdle("ListBoxScrollToSelected()", util.ListBoxScrollToSelected, w.LibraryListBox)
-	w.libPathElementToSelect = ""
+	// Forget the element to select once the list is loaded from MPD (it isn't while there's no connection)
+	if connected, _ := w.connector.ConnectStatus(); connected {
+		w.libPathElementToSelect = ""
+	}
 
 	// Compose info
 	info := ""
@@ -2013,11 +5611,16 @@ func (w *MainWindow) updateLibrary() {
 		info = glib.Local("No items")
 	} else {
 		// Compose info
-		info += fmt.Sprintf(glib.Local("%d items"), countItems)
+		info += fmt.Sprintf(util.LocalN("%d item", "%d items", countItems), countItems)
 
 		// Add note about limited set, if applicable
 		if limited {
-			info += " " + fmt.Sprintf(glib.Local("(limited selection of %d items)"), len(elements))
+			info += " " + fmt.Sprintf(util.LocalN("(limited selection of %d item)", "(limited selection of %d items)", len(content.elements)), len(content.elements))
+		}
+
+		// Add playing time, if any
+		if content.totalSecs > 0 {
+			info += ", " + fmt.Sprintf(glib.Local("playing time %s"), util.FormatSeconds(content.totalSecs))
 		}
 	}
 
@@ -2025,36 +5628,272 @@ func (w *MainWindow) updateLibrary() {
 		info += " — " + glib.Local("updating database…")
 	}
 
-	// Update info
-	w.LibraryInfoLabel.SetText(info)
+	// Update info and actions
+	w.libInfo = info
+	w.updateLibraryInfo()
+	w.updateLibraryActions()
+}
+
+// stopLibrarySpinner stops and hides the library loading spinner
+func (w *MainWindow) stopLibrarySpinner() {
+	w.LibrarySpinner.Stop()
+	w.LibrarySpinner.Hide()
 }
 
 // updateLibraryActions updates the widgets for library list
 func (w *MainWindow) updateLibraryActions() {
+	elements := w.getSelectedLibraryElements()
 	element := w.getSelectedLibraryElement()
 	connected, _ := w.connector.ConnectStatus()
 	selected := element != nil
 	_, playlist := element.(PlaylistHolder)
 	_, filesystem := element.(URIHolder)
 	editable := playlist && connected && selected
+	renamable := editable && len(elements) == 1
 	updatable := connected && selected && filesystem
 	playable := connected && selected && element.IsPlayable()
 	// Actions
 	w.aLibraryUpdate.SetEnabled(connected)
 	w.aLibraryUpdateAll.SetEnabled(connected)
 	w.aLibraryUpdateSel.SetEnabled(updatable)
+	_, inFilesystem := w.libPath.Last().(URIHolder)
+	w.aLibraryUpdateCur.SetEnabled(connected && inFilesystem)
 	w.aLibraryRescanAll.SetEnabled(connected)
 	w.aLibraryRescanSel.SetEnabled(updatable)
-	w.aLibraryRename.SetEnabled(editable)
+	w.aLibraryRename.SetEnabled(renamable)
+	w.aLibraryDuplicate.SetEnabled(renamable)
 	w.aLibraryDelete.SetEnabled(editable)
+	w.aLibraryRemoveDups.SetEnabled(editable)
 	w.aLibraryAddToPlaylist.SetEnabled(playable)
+	w.aLibrarySearchAddAll.SetEnabled(connected && len(w.libSearchURIs) > 0)
+	_, folder := element.(*DirLibElement)
+	w.aLibraryAddFolder.SetEnabled(connected && folder && !w.libQueueingFolder)
+	w.aLibraryAddFiltered.SetEnabled(connected && folder && !w.libQueueingFolder)
 	// Menu items
 	w.LibraryAppendMenuItem.SetSensitive(playable)
 	w.LibraryReplaceMenuItem.SetSensitive(playable)
-	w.LibraryRenameMenuItem.SetSensitive(editable)
+	w.LibraryPlayNowMenuItem.SetSensitive(playable)
+	w.LibraryPlayNextMenuItem.SetSensitive(playable)
+	_, file := element.(*FileLibElement)
+	w.LibrarySongInfoMenuItem.SetSensitive(connected && file)
+	w.LibraryRenameMenuItem.SetSensitive(renamable)
+	w.LibraryDuplicateMenuItem.SetSensitive(renamable)
+	w.LibraryRemoveDupsMenuItem.SetSensitive(editable)
+	w.LibraryExportMenuItem.SetSensitive(renamable)
+	inFolder := false
+	for _, name := range w.getSelectedPlaylistNames() {
+		inFolder = inFolder || config.GetConfig().PlaylistFolders[name] != ""
+	}
+	w.LibraryMoveToFolderMenuItem.SetSensitive(playlist && selected)
+	w.LibraryRemoveFromFolderMenuItem.SetSensitive(inFolder)
+	_, smart := element.(*SmartPlaylistLibElement)
+	_, inSmart := w.libPath.Last().(*SmartPlaylistsLibElement)
+	w.LibrarySmartNewMenuItem.SetVisible(inSmart)
+	w.LibrarySmartEditMenuItem.SetVisible(smart)
+	w.LibrarySmartSaveMenuItem.SetVisible(smart)
+	w.LibrarySmartSaveMenuItem.SetSensitive(connected && len(elements) == 1)
+	w.LibrarySmartDeleteMenuItem.SetVisible(smart)
 	w.LibraryDeleteMenuItem.SetSensitive(editable)
 	w.LibraryUpdateSelMenuItem.SetSensitive(updatable)
 	w.LibraryAddToPlaylistMenuItem.SetSensitive(playable)
+	w.LibraryCopyURIMenuItem.SetSensitive(filesystem || playlist)
+	w.LibraryCopyFilePathMenuItem.SetVisible(config.GetConfig().MpdMusicDir != "")
+	w.LibraryCopyFilePathMenuItem.SetSensitive(filesystem)
+	w.LibraryOpenFolderMenuItem.SetVisible(config.GetConfig().MpdMusicDir != "")
+	w.LibraryOpenFolderMenuItem.SetSensitive(file)
+}
+
+// updateLibraryPreview shows a collapsible preview of the playlist selected in the library list right under its row,
+// or removes the preview if there's no single playlist selected
+func (w *MainWindow) updateLibraryPreview() {
+	// Find out which playlist is selected, if any
+	name := ""
+	var selRow *gtk.ListBoxRow
+	if !w.libGrid {
+		if rows := w.LibraryListBox.GetSelectedRows(); rows != nil && rows.Length() == 1 {
+			selRow = rows.NthData(0).(*gtk.ListBoxRow)
+			if pl, ok := w.getLibraryRowElement(selRow).(*PlaylistLibElement); ok && w.libraryFilterElement(pl) {
+				name = pl.PlaylistName()
+			}
+		}
+	}
+
+	// Nothing to do if this playlist is already being previewed
+	if name != "" && name == w.libPreviewPlaylist && w.libPreviewRow != nil {
+		return
+	}
+
+	// Remove the current preview, if any
+	if w.libPreviewRow != nil {
+		w.LibraryListBox.Remove(w.libPreviewRow)
+		w.libPreviewRow, w.libPreviewPlaylist = nil, ""
+	}
+	if name == "" {
+		return
+	}
+
+	// Create a non-selectable preview row. It's named as a header so that it's never taken for a library element
+	row, err := gtk.ListBoxRowNew()
+	if errCheck(err, "ListBoxRowNew() failed") {
+		return
+	}
+	row.SetName(MarshalLibPathElement(NewHeaderLibElement()))
+	row.SetSelectable(false)
+	row.SetActivatable(false)
+	expander, err := gtk.ExpanderNew(glib.Local("Loading…"))
+	if errCheck(err, "ExpanderNew() failed") {
+		return
+	}
+	expander.SetMarginStart(48)
+	expander.SetMarginEnd(6)
+	expander.SetMarginBottom(6)
+	expander.SetExpanded(config.GetConfig().LibraryPreviewExpanded)
+	_, _ = expander.Connect("notify::expanded", func() {
+		config.GetConfig().LibraryPreviewExpanded = expander.GetExpanded()
+	})
+	lbl := util.NewLabel("")
+	if lbl == nil {
+		return
+	}
+	if ctx, err := lbl.GetStyleContext(); err == nil {
+		ctx.AddClass("dim-label")
+	}
+	lbl.SetEllipsize(pango.ELLIPSIZE_END)
+	expander.Add(lbl)
+	row.Add(expander)
+	w.LibraryListBox.Insert(row, selRow.GetIndex()+1)
+	row.ShowAll()
+	w.libPreviewRow, w.libPreviewPlaylist = row, name
+
+	// Load the playlist's tracks in the background
+	go func() {
+		var attrs []mpd.Attrs
+		err := errors.New(glib.Local("Not connected to MPD"))
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.PlaylistContents(name)
+		})
+
+		// Fill in the preview on the GLib's thread, unless it's been removed in the meantime
+		util.WhenIdle("updateLibraryPreview()", func() {
+			if w.libPreviewRow != row {
+				return
+			}
+			if errCheck(err, "updateLibraryPreview(): PlaylistContents() failed") {
+				expander.SetLabel(glib.Local("Failed to load the playlist"))
+				return
+			}
+			summary, lines := PlaylistPreview(attrs, libraryPreviewMaxTracks)
+			expander.SetLabel(summary)
+			lbl.SetText(strings.Join(lines, "\n"))
+		})
+	}()
+}
+
+// updateLibraryInfo updates the library info label, adding the number of items matching the folder filter, if any
+func (w *MainWindow) updateLibraryInfo() {
+	info := w.libInfo
+	if w.libFilterPattern != "" {
+		count := 0
+		for i := 0; ; i++ {
+			// Fetch the element of the next row or tile
+			var element LibraryPathElement
+			if w.libGrid {
+				child := w.LibraryFlowBox.GetChildAtIndex(i)
+				if child == nil {
+					break
+				}
+				element = w.getLibraryWidgetElement(&child.Widget)
+			} else {
+				row := w.LibraryListBox.GetRowAtIndex(i)
+				if row == nil {
+					break
+				}
+				element = w.getLibraryRowElement(row)
+			}
+
+			switch element.(type) {
+			case *LevelUpLibElement, *HeaderLibElement, *PlaylistFolderLibElement:
+				// Don't count
+			default:
+				if w.libraryFilterElement(element) {
+					count++
+				}
+			}
+		}
+		info += " — " + fmt.Sprintf(util.LocalN("%d matching", "%d matching", count), count)
+	}
+	w.LibraryInfoLabel.SetText(info)
+}
+
+// updateLibraryBookmarks updates the library bookmarks box and the bookmark action
+func (w *MainWindow) updateLibraryBookmarks() {
+	// Remove all buttons from the box
+	util.ClearChildren(w.LibraryBookmarksBox.Container)
+
+	// Create a button for every bookmark
+	current := w.libPath.Marshal()
+	bookmarks := config.GetConfig().LibraryBookmarks
+	for _, bm := range bookmarks {
+		path := bm.Path // Make an in-loop copy for the closure
+		if btn := util.NewButton(bm.Name, "", "", "user-bookmarks-symbolic", func() { w.libraryOpenBookmark(path) }); btn != nil {
+			btn.SetRelief(gtk.RELIEF_NONE)
+			btn.SetSensitive(path != current)
+			btn.Show()
+			w.LibraryBookmarksBox.PackStart(btn, false, false, 0)
+		}
+	}
+	w.LibraryBookmarksBox.SetVisible(len(bookmarks) > 0)
+
+	// Update the bookmark tool button
+	if w.libraryBookmarkIndex() >= 0 {
+		w.LibraryBookmarkToolButton.SetTooltipText(glib.Local("Remove the bookmark for the current folder"))
+	} else {
+		w.LibraryBookmarkToolButton.SetTooltipText(glib.Local("Bookmark the current folder"))
+	}
+	w.aLibraryBookmark.SetEnabled(!w.libPath.IsRoot())
+}
+
+// addLibraryPathMenuButton adds a menu button for the collapsed path elements with indices in the range [from, to) to
+// the library path box
+func (w *MainWindow) addLibraryPathMenuButton(elements []LibraryPathElement, from, to int) {
+	// Create a menu with an item per collapsed element
+	menu, err := gtk.MenuNew()
+	if errCheck(err, "MenuNew() failed") {
+		return
+	}
+	for i := from; i < to; i++ {
+		item, err := gtk.MenuItemNewWithLabel(elements[i].Label())
+		if errCheck(err, "MenuItemNewWithLabel() failed") {
+			return
+		}
+		i := i // Make an in-loop copy of i
+		if _, err := item.Connect("activate", func() { w.libraryPathNavigateTo(i) }); errCheck(err, "Connect() failed") {
+			return
+		}
+		menu.Append(item)
+	}
+	menu.ShowAll()
+
+	// Create a menu button
+	btn, err := gtk.MenuButtonNew()
+	if errCheck(err, "MenuButtonNew() failed") {
+		return
+	}
+	btn.SetLabel("…")
+	btn.SetTooltipText(glib.Local("Show hidden path elements"))
+	btn.SetPopup(menu)
+	w.LibraryPathBox.PackStart(btn, false, false, 0)
+}
+
+// libraryPathNavigateTo shortens the current library path so that the element with the given index becomes the last
+func (w *MainWindow) libraryPathNavigateTo(index int) {
+	// Save the first path element from the chopped-off tail for subsequent selection
+	if e := w.libPath.ElementAt(index + 1); e != nil {
+		w.libPathElementToSelect = e.Marshal()
+	}
+
+	// Move to the selected level
+	w.libPath.SetLength(index + 1)
 }
 
 // updateLibraryPath updates the current library path selector
@@ -2071,25 +5910,31 @@ func (w *MainWindow) updateLibraryPath() {
 		w.libPath.IsRoot(),
 		func() { w.libPath.SetLength(0) })
 
-	// Create buttons for path elements
-	for i, element := range w.libPath.Elements() {
-		// Create a button. The last button must be depressed
-		i := i // Make an in-loop copy of i
-		util.NewBoxToggleButton(
-			w.LibraryPathBox,
-			element.Label(),
-			"",
-			element.Icon(),
-			element == w.libPath.Last(),
-			func() {
-				// Save the first path element from the chopped-off tail for subsequent selection
-				if e := w.libPath.ElementAt(i + 1); e != nil {
-					w.libPathElementToSelect = e.Marshal()
-				}
+	// Create buttons for path elements. If the path is too long, collapse the middle elements into a menu button
+	elements := w.libPath.Elements()
+	collapseFrom, collapseTo := len(elements), len(elements)
+	if len(elements) > libraryPathMaxButtons {
+		collapseFrom, collapseTo = 1, len(elements)-libraryPathMaxButtons+2
+	}
+	for i, element := range elements {
+		switch {
+		// Collapsed element: add a menu button before the first one
+		case i >= collapseFrom && i < collapseTo:
+			if i == collapseFrom {
+				w.addLibraryPathMenuButton(elements, collapseFrom, collapseTo)
+			}
 
-				// Move to the selected level
-				w.libPath.SetLength(i + 1)
-			})
+		// Regular element: create a button. The last button must be depressed
+		default:
+			i := i // Make an in-loop copy of i
+			util.NewBoxToggleButton(
+				w.LibraryPathBox,
+				element.Label(),
+				"",
+				element.Icon(),
+				element == w.libPath.Last(),
+				func() { w.libraryPathNavigateTo(i) })
+		}
 	}
 
 	// Show all buttons
@@ -2103,7 +5948,9 @@ func (w *MainWindow) updateOptions() {
 	w.RandomButton.SetActive(status["random"] == "1")
 	w.RepeatButton.SetActive(status["repeat"] == "1")
 	w.ConsumeButton.SetActive(status["consume"] == "1")
+	w.ListenButton.SetActive(w.listener != nil && w.listener.IsPlaying())
 	w.optionsUpdating = false
+	w.updateMprisStatus()
 }
 
 // updatePlayer updates player control widgets
@@ -2112,7 +5959,10 @@ func (w *MainWindow) updatePlayer() {
 	status := w.connector.Status()
 	var statusHTML string
 	var err error
-	curURI := ""
+	curURI, curArtist := "", ""
+	var curTrack mpd.Attrs
+	var lastFmTrack LastFmTrack
+	w.radioSong = nil
 
 	switch {
 	// Still connecting
@@ -2136,104 +5986,385 @@ func (w *MainWindow) updatePlayer() {
 			// Dump the current track for debug purposes
 			log.Debugf("Current track: %#v", curSong)
 
-			// Apply track title template
+			// Apply track title template, displaying a stream's current song like a regular track
 			var buffer bytes.Buffer
-			if err := w.playerTitleTemplate.Execute(&buffer, curSong); err != nil {
+			if err := w.playerTitleTemplate.Execute(&buffer, EnrichStreamAttrs(curSong)); err != nil {
 				statusHTML = html.EscapeString(fmt.Sprintf("%s: %v", glib.Local("Template error"), err))
 			} else {
-				statusHTML = buffer.String()
+				statusHTML = buffer.String()
+			}
+
+			// Get the current URI
+			curTrack = curSong
+			curURI = curSong["file"]
+
+			// Remember the current stream if it announces a song
+			if util.IsStreamURI(curURI) && curSong["Title"] != "" {
+				w.radioSong = curSong
+			}
+
+			// Identify the artist and the track for Last.fm, counting in a stream's current song
+			a := EnrichStreamAttrs(curSong)
+			curArtist = a["Artist"]
+			if curArtist != "" && a["Title"] != "" {
+				lastFmTrack = LastFmTrack{Artist: curArtist, Title: a["Title"]}
+			}
+
+			// Keep track of the resume position of long tracks
+			if curURI != w.resume.URI() {
+				w.updatePlayerResume(curURI, util.ParseFloatDef(curSong["duration"], 0), util.ParseFloatDef(status["elapsed"], 0))
+			}
+
+			// Mark podcast episodes played once their playback starts
+			if status["state"] == "play" && w.podcastEpisodeURIs[curURI] && !w.podcastState.IsPlayed(curURI) {
+				errCheck(w.podcastState.SetPlayed(true, curURI), "SetPlayed() failed")
+				w.updatePodcasts()
+			}
+
+			// Keep track of the played tracks
+			if status["state"] == "stop" {
+				w.history.SetTrack(nil)
+			} else {
+				w.history.SetTrack(curSong)
+			}
+		}
+
+		// Update play/pause button's appearance
+		switch status["state"] {
+		case "play":
+			w.PlayPauseButton.SetIconName("ymuse-pause-symbolic")
+		default:
+			w.PlayPauseButton.SetIconName("ymuse-play-symbolic")
+		}
+
+	// Not connected
+	default:
+		statusHTML = fmt.Sprintf("<i>%s</i>", html.EscapeString(glib.Local("Not connected to MPD")))
+	}
+
+	// If there's an error
+	if errMsg, ok := status["error"]; ok {
+		statusHTML += fmt.Sprintf(" — <span foreground=\"red\">%s</span>", html.EscapeString(errMsg))
+	}
+
+	// Update the album art
+	w.updatePlayerAlbumArt(curURI, curTrack)
+	w.playerTrack = curTrack
+	w.updateMprisTrack()
+	w.updateTrackNotification()
+
+	// Update status text
+	w.StatusLabel.SetMarkup(statusHTML)
+	if w.miniPlayer != nil {
+		w.miniPlayer.Update(statusHTML, status["state"] == "play")
+	}
+
+	// Highlight and scroll the tree to the currently played item
+	w.updateQueueNowPlaying()
+
+	// Enable or disable player actions based on the connection status
+	w.aPlayerPrevious.SetEnabled(connected)
+	w.aPlayerStop.SetEnabled(connected)
+	w.aPlayerPlayPause.SetEnabled(connected)
+	w.aPlayerNext.SetEnabled(connected)
+	w.aPlayerRandom.SetEnabled(connected)
+	w.aPlayerRepeat.SetEnabled(connected)
+	w.aPlayerConsume.SetEnabled(connected)
+	w.aPlayerListen.SetEnabled(connected && streamListenerAvailable)
+	w.aPlayerStar.SetEnabled(connected)
+	if !connected && w.listener != nil && w.listener.IsPlaying() {
+		w.listener.Stop()
+		w.updateOptions()
+	}
+	w.updatePlayerRadioRemember()
+	w.updateTrayIcon()
+	w.updateInhibit()
+	if lastFmTrack != w.lastFmTrack {
+		w.lastFmTrack = lastFmTrack
+		w.updatePlayerLoved()
+	} else {
+		w.updatePlayerLoveButton()
+	}
+	w.updatePlayerSimilar()
+	w.curArtist = curArtist
+	w.updateArtistPane()
+
+	// Update the seek bar
+	w.updatePlayerSeekBar()
+}
+
+// updatePlayerLoved fetches the loved state of the current track from Last.fm in the background
+func (w *MainWindow) updatePlayerLoved() {
+	w.loveState = tbNone
+	lastFm := NewLastFmFromConfig()
+	track := w.lastFmTrack
+	if lastFm.IsLoggedIn() && track != (LastFmTrack{}) {
+		user := config.GetConfig().LastFmUser
+		go func() {
+			loved, err := lastFm.IsLoved(user, track)
+			util.WhenIdle("updatePlayerLoved()", func() {
+				// Ignore if the track has changed in the meantime
+				if !errCheck(err, "IsLoved() failed") && track == w.lastFmTrack {
+					w.loveState = tbFalse
+					if loved {
+						w.loveState = tbTrue
+					}
+					w.updatePlayerLoveButton()
+				}
+			})
+		}()
+	}
+	w.updatePlayerLoveButton()
+}
+
+// updatePlayerLoveButton updates the appearance of the button loving the current track on Last.fm
+func (w *MainWindow) updatePlayerLoveButton() {
+	available := w.lastFmTrack != (LastFmTrack{}) && NewLastFmFromConfig().IsLoggedIn()
+	w.aPlayerLove.SetEnabled(available && !w.loveUpdating)
+	w.LoveButton.SetVisible(available)
+	if ctx, err := w.LoveImage.GetStyleContext(); !errCheck(err, "LoveImage.GetStyleContext() failed") {
+		if w.loveState == tbTrue {
+			ctx.RemoveClass("dim-label")
+			w.LoveButton.SetTooltipText(glib.Local("Loved on Last.fm. Click to unlove"))
+		} else {
+			ctx.AddClass("dim-label")
+			w.LoveButton.SetTooltipText(glib.Local("Love the track on Last.fm"))
+		}
+	}
+}
+
+// updatePlayerSimilar enables or disables the action adding tracks similar to the current one
+func (w *MainWindow) updatePlayerSimilar() {
+	w.aPlayerSimilar.SetEnabled(w.lastFmTrack != (LastFmTrack{}) && NewLastFmFromConfig().HasAPIKey() && !w.similarLoading)
+}
+
+// updateArtistPane displays information about the current artist in the artist pane, if it's visible. Information that
+// isn't cached yet is fetched in the background
+func (w *MainWindow) updateArtistPane() {
+	artist := w.curArtist
+	if !w.ArtistPaneScrolledWindow.GetVisible() || artist == w.artistPaneArtist {
+		return
+	}
+	w.artistPaneArtist = artist
+
+	// Nothing to show without an artist
+	if artist == "" {
+		w.setArtistPane("", glib.Local("No information about the artist"), nil, nil)
+		return
+	}
+
+	// Use cached information, if any
+	if info, image, ok := w.artistCache.Get(artist); ok {
+		w.setArtistPane(artist, "", info, image)
+		return
+	}
+
+	// Fetch the information otherwise
+	w.setArtistPane(artist, glib.Local("Loading…"), nil, nil)
+	lastFm := NewLastFmFromConfig()
+	go func() {
+		info, image, err := FetchArtistInfo(lastFm, wikipediaURL, artist)
+		if err == nil {
+			errCheck(w.artistCache.Put(info, image), "Failed to cache artist info")
+		}
+		util.WhenIdle("updateArtistPane()", func() {
+			// Ignore if the artist has changed in the meantime
+			if artist != w.artistPaneArtist {
+				return
+			}
+			if errCheck(err, "FetchArtistInfo() failed") {
+				w.setArtistPane(artist, fmt.Sprintf(glib.Local("Failed to load information about the artist: %v"), err), nil, nil)
+			} else {
+				w.setArtistPane(artist, "", info, image)
 			}
+		})
+	}()
+}
 
-			// Get the current URI
-			curURI = curSong["file"]
-		}
+// setArtistPane populates the artist pane with the given artist name, the message to display in place of the
+// biography (if not empty), the artist information (can be nil) and the image (can be nil)
+func (w *MainWindow) setArtistPane(name, message string, info *ArtistInfo, image []byte) {
+	w.ArtistPaneNameLabel.SetMarkup(fmt.Sprintf("<big><b>%s</b></big>", html.EscapeString(name)))
+	w.ArtistPaneNameLabel.SetVisible(name != "")
 
-		// Update play/pause button's appearance
-		switch status["state"] {
-		case "play":
-			w.PlayPauseButton.SetIconName("ymuse-pause-symbolic")
-		default:
-			w.PlayPauseButton.SetIconName("ymuse-play-symbolic")
+	// Biography and its source
+	source := ""
+	switch {
+	case message != "":
+	case info != nil && info.Bio != "":
+		message = info.Bio
+		if info.PageURL != "" {
+			source = fmt.Sprintf(
+				"<a href=\"%s\">%s</a>",
+				html.EscapeString(info.PageURL),
+				html.EscapeString(fmt.Sprintf(glib.Local("Read more on %s"), info.Source)))
 		}
-
-	// Not connected
 	default:
-		statusHTML = fmt.Sprintf("<i>%s</i>", html.EscapeString(glib.Local("Not connected to MPD")))
+		message = glib.Local("No information about the artist")
 	}
-
-	// If there's an error
-	if errMsg, ok := status["error"]; ok {
-		statusHTML += fmt.Sprintf(" — <span foreground=\"red\">%s</span>", html.EscapeString(errMsg))
+	w.ArtistPaneBioLabel.SetText(message)
+	w.ArtistPaneSourceLabel.SetMarkup(source)
+	w.ArtistPaneSourceLabel.SetVisible(source != "")
+
+	// Image, scaled to the pane width
+	showImage := false
+	if len(image) > 0 {
+		if px, err := gdk.PixbufNewFromBytesOnly(image); !errCheck(err, "PixbufNewFromBytesOnly() failed") && px.GetWidth() > 0 {
+			height := px.GetHeight() * artistPaneImageWidth / px.GetWidth()
+			if px, err = px.ScaleSimple(artistPaneImageWidth, height, gdk.INTERP_BILINEAR); !errCheck(err, "ScaleSimple() failed") {
+				w.ArtistPaneImage.SetFromPixbuf(px)
+				showImage = true
+			}
+		}
+	}
+	if !showImage {
+		w.ArtistPaneImage.Clear()
 	}
+	w.ArtistPaneImage.SetVisible(showImage)
+}
 
-	// Update the album art
-	w.updatePlayerAlbumArt(curURI)
+// updatePlayerRadioRemember updates the appearance of the button remembering the song announced by the current stream
+func (w *MainWindow) updatePlayerRadioRemember() {
+	remembered := w.radioSong != nil && w.radioLog.IsLast(w.radioSong["file"], w.radioSong["Title"])
+	w.aPlayerRadioRemember.SetEnabled(w.radioSong != nil && !remembered)
+	w.RadioRememberButton.SetVisible(w.radioSong != nil)
+	if remembered {
+		w.RadioRememberButton.SetTooltipText(glib.Local("The song is already remembered"))
+	} else {
+		w.RadioRememberButton.SetTooltipText(glib.Local("Remember the song playing on the radio"))
+	}
+}
 
-	// Update status text
-	w.StatusLabel.SetMarkup(statusHTML)
+// updatePlayerResume handles a change of the current track: stores the final resume position of the previous track,
+// if needed, and offers (or seeks to) the resume position stored for the new one, unless it's been played past the start
+func (w *MainWindow) updatePlayerResume(uri string, length, elapsed float64) {
+	// Fetch the stored resume position. MPD only keeps stickers for tracks in its database
+	savedPos := 0.0
+	if uri != "" && !util.IsStreamURI(uri) && IsResumable(length) {
+		w.connector.IfConnected(func(client *mpd.Client) {
+			// A missing sticker is reported as an error, too
+			if sticker, err := client.StickerGet(uri, resumeStickerName); err == nil {
+				savedPos = ParseResumePos(sticker.Value)
+			}
+		})
+	}
+	w.applyResumeUpdate(w.resume.SetTrack(uri, length, savedPos))
 
-	// Highlight and scroll the tree to the currently played item
-	w.updateQueueNowPlaying()
+	// Resume the new track, or offer to do so
+	w.resumeOfferPos = 0
+	if savedPos > 0 && elapsed < resumeMinPos {
+		if config.GetConfig().PlayerAutoResume {
+			w.playerSeek(savedPos)
+		} else {
+			w.resumeOfferPos = savedPos
+		}
+	}
+	w.updatePlayerResumeButton()
+}
 
-	// Enable or disable player actions based on the connection status
-	w.aPlayerPrevious.SetEnabled(connected)
-	w.aPlayerStop.SetEnabled(connected)
-	w.aPlayerPlayPause.SetEnabled(connected)
-	w.aPlayerNext.SetEnabled(connected)
-	w.aPlayerRandom.SetEnabled(connected)
-	w.aPlayerRepeat.SetEnabled(connected)
-	w.aPlayerConsume.SetEnabled(connected)
+// updatePlayerResumeButton updates the visibility and the label of the button resuming the current track
+func (w *MainWindow) updatePlayerResumeButton() {
+	w.aPlayerResume.SetEnabled(w.resumeOfferPos > 0)
+	w.PlayerResumeButton.SetVisible(w.resumeOfferPos > 0)
+	if w.resumeOfferPos > 0 {
+		w.PlayerResumeButton.SetLabel(fmt.Sprintf(glib.Local("Resume at %s"), util.FormatSeconds(w.resumeOfferPos)))
+	}
+}
 
-	// Update the seek bar
-	w.updatePlayerSeekBar()
+// applyResumeUpdate stores or removes the resume position of a track in MPD, as per the given update (if any)
+func (w *MainWindow) applyResumeUpdate(update *ResumeUpdate) {
+	if update == nil {
+		return
+	}
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		if update.Pos > 0 {
+			err = client.StickerSet(update.URI, resumeStickerName, FormatResumePos(update.Pos))
+		} else {
+			err = client.StickerDelete(update.URI, resumeStickerName)
+		}
+	})
+	errCheck(err, "applyResumeUpdate() failed")
 }
 
-// updatePlayerAlbumArt updates player's album art image appearance and visibility
-func (w *MainWindow) updatePlayerAlbumArt(uri string) {
+// updatePlayerAlbumArt updates player's album art image appearance and visibility, given the current track's URI and
+// attributes. The album art is loaded in the background, with a placeholder displayed in the meantime
+func (w *MainWindow) updatePlayerAlbumArt(uri string, attrs mpd.Attrs) {
 	// Check if the album art is to be shown
-	show := false
+	enabled := false
 	if uri != "" {
 		isStream := util.IsStreamURI(uri)
 		cfg := config.GetConfig()
-		if isStream && cfg.PlayerAlbumArtStreams || !isStream && cfg.PlayerAlbumArtTracks {
-			// Avoid updating album art if there's no change in the URI
-			if w.playerCurrentAlbumArtUri == uri {
-				show = true
-			} else {
-				// Try to fetch the album art
-				var albumArt []byte
-				log.Debugf("Fetching album art for %s", uri)
-				w.connector.IfConnected(func(client *mpd.Client) {
-					var err error
-					if albumArt, err = client.AlbumArt(uri); err != nil {
-						log.Debugf("Failed to obtain album art: %v", err)
-						albumArt = nil
-					}
-				})
-
-				// If succeeded
-				if len(albumArt) > 0 {
-					log.Debugf("Fetched album art: %d bytes", len(albumArt))
-					// Make a pixbuf from the data bytes
-					if px, err := gdk.PixbufNewFromBytesOnly(albumArt); !errCheck(err, "PixbufNewFromBytesOnly() failed") {
-						// Downscale the image if needed
-						if px, err = px.ScaleSimple(playerArtworkSize, playerArtworkSize, gdk.INTERP_BILINEAR); !errCheck(err, "ScaleSimple() failed") {
-							w.AlbumArtworkImage.SetFromPixbuf(px)
-							show = true
-							// Save the last used URI
-							w.playerCurrentAlbumArtUri = uri
-						}
-					}
-				}
-			}
-		}
+		enabled = isStream && cfg.PlayerAlbumArtStreams || !isStream && cfg.PlayerAlbumArtTracks
 	}
 
-	// Show or hide the album art
-	if !show {
+	switch {
+	case !enabled:
+		// Forget the album art
 		w.AlbumArtworkImage.Clear()
 		w.playerCurrentAlbumArtUri = ""
+		w.playerAlbumArtMissing = false
+
+	// Avoid updating album art if there's no change in the URI
+	case w.playerCurrentAlbumArtUri != uri:
+		// Display a placeholder until the album art is loaded
+		w.playerCurrentAlbumArtUri = uri
+		w.playerAlbumArtMissing = false
+		w.AlbumArtworkImage.SetFromIconName(albumArtPlaceholder, gtk.ICON_SIZE_DIALOG)
+		w.AlbumArtworkImage.SetPixelSize(playerArtworkSize)
+		w.loadPlayerAlbumArt(uri, attrs)
+	}
+	w.showPlayerAlbumArt(enabled && !w.playerAlbumArtMissing)
+}
+
+// loadPlayerAlbumArt fetches the album art for the given track in the background, and displays it in the player if the
+// track is still current
+func (w *MainWindow) loadPlayerAlbumArt(uri string, attrs mpd.Attrs) {
+	log.Debugf("Fetching album art for %s", uri)
+	settings := NewAlbumArtSettings()
+	w.albumArtLoader.Load(
+		uri,
+		func() ([]byte, error) { return FetchAlbumArt(w.connector, w.albumArtCache, settings, attrs) },
+		func(data []byte) {
+			util.WhenIdle("loadPlayerAlbumArt()", func() {
+				// Make sure the track hasn't changed in the meantime
+				if uri != w.playerCurrentAlbumArtUri {
+					return
+				}
+				if px := albumArtPixbuf(data, playerArtworkSize); px != nil {
+					log.Debugf("Fetched album art: %d bytes", len(data))
+					w.AlbumArtworkImage.SetFromPixbuf(px)
+					// The artwork may have been cached just now
+					w.updateMprisTrack()
+					w.updateTrackNotification()
+				} else {
+					// No album art: hide the placeholder
+					w.playerAlbumArtMissing = true
+					w.AlbumArtworkImage.Clear()
+					w.showPlayerAlbumArt(false)
+				}
+			})
+		})
+}
+
+// albumArtPixbuf makes a pixbuf of the given size from the given image data. Returns nil if there's no data or it
+// isn't a valid image
+func albumArtPixbuf(data []byte, size int) *gdk.Pixbuf {
+	if len(data) == 0 {
+		return nil
+	}
+	px, err := gdk.PixbufNewFromBytesOnly(data)
+	if errCheck(err, "PixbufNewFromBytesOnly() failed") {
+		return nil
+	}
+	if px, err = px.ScaleSimple(size, size, gdk.INTERP_BILINEAR); errCheck(err, "ScaleSimple() failed") {
+		return nil
 	}
+	return px
+}
+
+// showPlayerAlbumArt shows or hides the player's album art image
+func (w *MainWindow) showPlayerAlbumArt(show bool) {
 	w.AlbumArtworkImage.SetVisible(show)
 
 	// If the image isn't visible, center-justify the title. Otherwise use left justification
@@ -2262,6 +6393,10 @@ func (w *MainWindow) updatePlayerSeekBar() {
 			status := w.connector.Status()
 			trackLen = util.ParseFloatDef(status["duration"], -1)
 			trackPos = util.ParseFloatDef(status["elapsed"], -1)
+			if status["state"] == "play" {
+				w.history.Progress(trackPos)
+				w.applyResumeUpdate(w.resume.Progress(trackPos))
+			}
 		}
 
 		// If not seekable, remove the slider
@@ -2284,6 +6419,223 @@ func (w *MainWindow) updatePlayerSeekBar() {
 		}
 	}
 	w.PositionLabel.SetMarkup(seekPos)
+	w.updateMprisStatus()
+
+	// Show the progress on the application's icon
+	if w.launcherEntry != nil {
+		progress, visible := 0.0, trackPos >= 0 && trackLen > 0
+		if visible {
+			progress = trackPos / trackLen
+		}
+		w.launcherEntry.SetProgress(progress, visible)
+	}
+}
+
+// updateMprisStatus publishes the current player status over MPRIS
+func (w *MainWindow) updateMprisStatus() {
+	if w.mpris != nil {
+		w.mpris.SetStatus(w.connector.Status())
+	}
+}
+
+// updateMprisPlaylists publishes the stored playlists over MPRIS
+func (w *MainWindow) updateMprisPlaylists() {
+	if w.mpris != nil {
+		w.mpris.SetPlaylists(w.connector.GetPlaylists())
+	}
+}
+
+// updateMprisTrack publishes the current track over MPRIS
+func (w *MainWindow) updateMprisTrack() {
+	if w.mpris != nil {
+		w.mpris.SetTrack(w.playerTrack)
+	}
+}
+
+// updateTrackNotification shows a desktop notification of the current track if it has changed and is being played
+func (w *MainWindow) updateTrackNotification() {
+	cfg := config.GetConfig()
+	show := cfg.NotifyTrackChange && w.connector.Status()["state"] == "play" &&
+		!(cfg.NotifySuppressFocused && w.AppWindow.IsActive())
+	w.notifier.Update(w.playerTrack, show, AlbumArtFile(w.albumArtCache, w.playerTrack))
+}
+
+// updateInhibit inhibits session idle and suspend while MPD running on the local machine is playing, if enabled, and
+// lifts the inhibition otherwise
+func (w *MainWindow) updateInhibit() {
+	cfg := config.GetConfig()
+	connected, _ := w.connector.ConnectStatus()
+	inhibit := cfg.InhibitSuspend && connected && w.connector.Status()["state"] == "play" && cfg.MpdIsLocal()
+	switch {
+	case inhibit && w.inhibitCookie == 0:
+		w.inhibitCookie = w.app.Inhibited(w.AppWindow, gtk.APPLICATION_INHIBIT_IDLE|gtk.APPLICATION_INHIBIT_SUSPEND, glib.Local("Playing music"))
+		if w.inhibitCookie == 0 {
+			log.Warning("Failed to inhibit suspend")
+		} else {
+			log.Debug("Inhibited session idle and suspend")
+		}
+	case !inhibit && w.inhibitCookie != 0:
+		w.app.Uninhibit(w.inhibitCookie)
+		w.inhibitCookie = 0
+		log.Debug("Lifted session idle and suspend inhibition")
+	}
+}
+
+// updateTrayIcon updates the tooltip and the menu of the tray icon, if any, from the current track and player state
+func (w *MainWindow) updateTrayIcon() {
+	if w.trayIcon == nil {
+		return
+	}
+
+	// Show the current track in the tooltip
+	title, body := config.AppMetadata.Name, ""
+	if w.playerTrack != nil {
+		title, body = trackNotificationText(w.playerTrack)
+	}
+	w.trayIcon.SetToolTip(title, html.EscapeString(body))
+
+	// Update the menu
+	playPause := glib.Local("Play")
+	if w.connector.Status()["state"] == "play" {
+		playPause = glib.Local("Pause")
+	}
+	w.trayIcon.SetMenu([]TrayMenuItem{
+		{Label: glib.Local("Show Ymuse"), Action: "show", Enabled: true},
+		{},
+		{Label: playPause, Action: "player.play-pause", Enabled: w.aPlayerPlayPause.GetEnabled()},
+		{Label: glib.Local("Previous"), Action: "player.previous", Enabled: w.aPlayerPrevious.GetEnabled()},
+		{Label: glib.Local("Next"), Action: "player.next", Enabled: w.aPlayerNext.GetEnabled()},
+		{},
+		{Label: glib.Local("Quit"), Action: "quit", Enabled: true},
+	})
+}
+
+// updatePodcasts updates the podcasts list contents: either the subscribed podcasts or the episodes of the open one
+func (w *MainWindow) updatePodcasts() {
+	// Keep the selection as long as the same list is displayed
+	selIdx := 0
+	if w.podcastListURL == w.podcastURL {
+		selIdx = w.getSelectedPodcastIndex()
+	}
+	w.podcastListURL = w.podcastURL
+
+	// Clear the podcasts list
+	util.ClearChildren(w.PodcastsListBox.Container)
+
+	// Podcast list: make sure the podcasts are sorted by name
+	if w.podcastURL == "" {
+		cfg := config.GetConfig()
+		sort.Slice(cfg.Podcasts, func(i, j int) bool { return cfg.Podcasts[i].Name < cfg.Podcasts[j].Name })
+		for _, p := range cfg.Podcasts {
+			_, hbx, err := util.NewListBoxRow(w.PodcastsListBox, false, p.Name, "", "ymuse-playlist")
+			if errCheck(err, "NewListBoxRow() failed") {
+				return
+			}
+
+			// Add an unplayed episode count column, if the episodes are known
+			if feed := w.podcastFeeds[p.URL]; feed != nil {
+				if cnt := w.podcastUnplayedCount(feed); cnt > 0 {
+					if lbl := newLibraryColumnLabel(fmt.Sprintf(util.LocalN("%d unplayed", "%d unplayed", cnt), cnt), libraryDetailsWidthChars, false); lbl != nil {
+						hbx.PackEnd(lbl, false, false, 0)
+					}
+				}
+			}
+		}
+
+	} else if feed := w.podcastFeeds[w.podcastURL]; feed != nil {
+		// Episode list: unplayed episodes are highlighted
+		for _, e := range feed.Episodes {
+			e := e // Make an in-loop copy of the var
+			label := html.EscapeString(e.Title)
+			if !w.podcastState.IsPlayed(e.URI) {
+				label = "<b>" + label + "</b>"
+			}
+			_, hbx, err := util.NewListBoxRow(
+				w.PodcastsListBox,
+				true,
+				label,
+				"",
+				"ymuse-audio-file",
+				// Add replace/append buttons
+				util.NewButton("", glib.Local("Append to the queue"), "", "ymuse-add-symbolic", func() { w.queueStream(tbFalse, e.URI) }),
+				util.NewButton("", glib.Local("Replace the queue"), "", "ymuse-replace-queue-symbolic", func() { w.queueStream(tbTrue, e.URI) }))
+			if errCheck(err, "NewListBoxRow() failed") {
+				return
+			}
+
+			// Add duration and publication date columns
+			if e.Duration > 0 {
+				if lbl := newLibraryColumnLabel(util.FormatSeconds(e.Duration), libraryDetailsWidthChars, false); lbl != nil {
+					hbx.PackEnd(lbl, false, false, 0)
+				}
+			}
+			if !e.Published.IsZero() {
+				if lbl := newLibraryColumnLabel(e.Published.Local().Format("2006-01-02"), libraryFormatWidthChars, true); lbl != nil {
+					hbx.PackEnd(lbl, false, false, 0)
+				}
+			}
+		}
+	}
+
+	// Show all rows
+	w.PodcastsListBox.ShowAll()
+
+	// Select the required row, falling back to the first one
+	if selIdx < 0 {
+		selIdx = 0
+	}
+	row := w.PodcastsListBox.GetRowAtIndex(selIdx)
+	if row == nil {
+		row = w.PodcastsListBox.GetRowAtIndex(0)
+	}
+	w.PodcastsListBox.SelectRow(row)
+
+	// Update info and actions
+	w.updatePodcastsInfo()
+	w.updatePodcastsActions()
+}
+
+// updatePodcastsInfo updates the podcasts info label
+func (w *MainWindow) updatePodcastsInfo() {
+	var info string
+	if w.podcastURL == "" {
+		if cnt := len(config.GetConfig().Podcasts); cnt > 0 {
+			info = fmt.Sprintf(util.LocalN("%d podcast", "%d podcasts", cnt), cnt)
+		} else {
+			info = glib.Local("No podcasts")
+		}
+	} else if feed := w.podcastFeeds[w.podcastURL]; feed != nil {
+		episodes := fmt.Sprintf(util.LocalN("%d episode", "%d episodes", len(feed.Episodes)), len(feed.Episodes))
+		unplayed := w.podcastUnplayedCount(feed)
+		info = fmt.Sprintf(util.LocalN("%s: %s, %d unplayed", "%s: %s, %d unplayed", unplayed), util.Default(w.podcastURL, feed.Title), episodes, unplayed)
+	}
+
+	// Indicate feeds being loaded
+	if len(w.podcastLoading) > 0 {
+		if info != "" {
+			info += " — "
+		}
+		info += glib.Local("Loading…")
+	}
+	w.PodcastsInfoLabel.SetText(info)
+}
+
+// updatePodcastsActions updates the widgets for podcasts list
+func (w *MainWindow) updatePodcastsActions() {
+	connected, _ := w.connector.ConnectStatus()
+	url := w.getPodcastTargetURL()
+	episode := w.getSelectedPodcastEpisode()
+	played := episode != nil && w.podcastState.IsPlayed(episode.URI)
+	// Actions
+	w.aPodcastBack.SetEnabled(w.podcastURL != "")
+	w.aPodcastSubscribe.SetEnabled(true) // Subscribing is always possible
+	w.aPodcastUnsubscribe.SetEnabled(url != "")
+	w.aPodcastRefresh.SetEnabled(url != "" && !w.podcastLoading[url])
+	// Menu items
+	w.PodcastsAppendMenuItem.SetSensitive(connected && episode != nil)
+	w.PodcastsReplaceMenuItem.SetSensitive(connected && episode != nil)
+	w.PodcastsMarkPlayedMenuItem.SetSensitive(episode != nil && !played)
+	w.PodcastsMarkUnplayedMenuItem.SetSensitive(episode != nil && played)
 }
 
 // updateQueue updates the current play queue contents
@@ -2299,6 +6651,9 @@ func (w *MainWindow) updateQueue() {
 	w.QueueListStore.Clear()
 	w.currentQueueIndex = -1
 	w.currentQueueSize = 0
+	w.queueTrackURIs = nil
+	w.queueAlbums = make(map[string]mpd.Attrs)
+	w.queueAlbumRows = make(map[string][]int)
 
 	// Update the queue if there's a connection
 	var attrs []mpd.Attrs
@@ -2311,8 +6666,10 @@ func (w *MainWindow) updateQueue() {
 	}
 
 	// Repopulate the queue list store
-	totalSecs := 0.0
+	w.queueTotalSecs = 0
 	for _, a := range attrs {
+		// Streams reveal their current song through metadata
+		a = EnrichStreamAttrs(a)
 		rowData := make(map[int]interface{})
 		// Iterate attributes
 		for id, mpdAttr := range config.MpdTrackAttributes {
@@ -2356,6 +6713,9 @@ func (w *MainWindow) updateQueue() {
 		rowData[config.QueueColumnFontWeight] = fontWeightNormal
 		rowData[config.QueueColumnBgColor] = w.colourBgNormal
 		rowData[config.QueueColumnVisible] = true
+		if w.favorites.IsStarred(a["file"]) {
+			rowData[config.QueueColumnStarIcon] = queueStarIcon
+		}
 
 		// Create arrays (indices and values)
 		rowIndices, rowValues := make([]int, len(rowData)), make([]interface{}, len(rowData))
@@ -2371,38 +6731,89 @@ func (w *MainWindow) updateQueue() {
 			w.QueueListStore.InsertWithValues(nil, -1, rowIndices, rowValues),
 			"QueueListStore.SetCols() failed")
 
+		// Group the tracks by album
+		if uri := a["file"]; !util.IsStreamURI(uri) {
+			if key := AlbumArtKey(a); key != "" {
+				if _, ok := w.queueAlbums[key]; !ok {
+					w.queueAlbums[key] = a
+				}
+				w.queueAlbumRows[key] = append(w.queueAlbumRows[key], w.currentQueueSize)
+			}
+		}
+
 		// Accumulate counters
-		totalSecs += util.ParseFloatDef(a["duration"], 0)
+		w.queueTotalSecs += util.ParseFloatDef(a["duration"], 0)
+		w.queueTrackURIs = append(w.queueTrackURIs, a["file"])
 		w.currentQueueSize++
 	}
 
+	// Display album art thumbnails, if enabled
+	w.updateQueueAlbumArt()
+
+	// Publish the queue over MPRIS and its size to docks
+	if w.mpris != nil {
+		w.mpris.SetQueue(attrs)
+	}
+	if w.launcherEntry != nil {
+		w.launcherEntry.SetCount(len(attrs))
+	}
+
+	// Update the queue info and actions
+	w.updateQueueInfo()
+
+	// Restore the tree view model
+	// Restore the tree view model
+	w.QueueTreeView.SetModel(w.QueueTreeModelFilter)
+
+	// Highlight and scroll the tree to the currently played item
+	w.updateQueueNowPlaying()
+}
+
+// updateQueueInfo updates the play queue info text, including the playlist the queue has been loaded from, and the
+// queue actions
+func (w *MainWindow) updateQueueInfo() {
 	// Add number of tracks
 	var status string
-	switch w.currentQueueSize {
-	case 0:
+	if w.currentQueueSize == 0 {
 		status = glib.Local("Queue is empty")
-	case 1:
-		status = glib.Local("One track")
-	default:
-		status = fmt.Sprintf(glib.Local("%d tracks"), len(attrs))
+	} else {
+		status = fmt.Sprintf(util.LocalN("%d track", "%d tracks", w.currentQueueSize), w.currentQueueSize)
 	}
 
 	// Add playing time, if any
-	if totalSecs > 0 {
-		status += ", " + fmt.Sprintf(glib.Local("playing time %s"), util.FormatSeconds(totalSecs))
+	if w.queueTotalSecs > 0 {
+		status += ", " + fmt.Sprintf(glib.Local("playing time %s"), util.FormatSeconds(w.queueTotalSecs))
+	}
+
+	// Add the playlist the queue has been loaded from, forgetting it once the queue has nothing in common with it
+	w.queueDiverged = false
+	if w.queueSource != nil {
+		related, diverged := w.queueSource.Compare(w.queueTrackURIs)
+		switch {
+		case !related:
+			w.queueSource = nil
+		case diverged:
+			w.queueDiverged = true
+			status += ", " + fmt.Sprintf(glib.Local("from playlist \"%s\" (modified)"), w.queueSource.Name)
+		default:
+			status += ", " + fmt.Sprintf(glib.Local("from playlist \"%s\""), w.queueSource.Name)
+		}
 	}
 
 	// Update the queue info
 	w.QueueInfoLabel.SetText(status)
 
+	// Publish the playlist the queue has been loaded from over MPRIS
+	if w.mpris != nil {
+		name := ""
+		if w.queueSource != nil {
+			name = w.queueSource.Name
+		}
+		w.mpris.SetActivePlaylist(name)
+	}
+
 	// Update queue actions
 	w.updateQueueActions()
-
-	// Restore the tree view model
-	w.QueueTreeView.SetModel(w.QueueTreeModelFilter)
-
-	// Highlight and scroll the tree to the currently played item
-	w.updateQueueNowPlaying()
 }
 
 // updateQueueColumns updates the columns in the play queue tree view
@@ -2412,6 +6823,19 @@ func (w *MainWindow) updateQueueColumns() {
 		w.QueueTreeView.RemoveColumn(item.(*gtk.TreeViewColumn))
 	})
 
+	// Add an album art renderer and column, if enabled
+	if config.GetConfig().QueueAlbumArt {
+		if renderer, err := gtk.CellRendererPixbufNew(); !errCheck(err, "CellRendererPixbufNew() failed") {
+			if col, err := gtk.TreeViewColumnNewWithAttribute("", renderer, "pixbuf", config.QueueColumnAlbumArt); !errCheck(err, "TreeViewColumnNewWithAttribute() failed") {
+				col.SetSizing(gtk.TREE_VIEW_COLUMN_FIXED)
+				col.SetFixedWidth(-1)
+				col.AddAttribute(renderer, "cell-background", config.QueueColumnBgColor)
+				w.QueueTreeView.AppendColumn(col)
+			}
+		}
+	}
+	w.updateQueueAlbumArt()
+
 	// Add an icon renderer
 	if renderer, err := gtk.CellRendererPixbufNew(); !errCheck(err, "CellRendererPixbufNew() failed") {
 		// Add an icon column
@@ -2423,6 +6847,16 @@ func (w *MainWindow) updateQueueColumns() {
 		}
 	}
 
+	// Add a star renderer and column for favourite tracks
+	if renderer, err := gtk.CellRendererPixbufNew(); !errCheck(err, "CellRendererPixbufNew() failed") {
+		if col, err := gtk.TreeViewColumnNewWithAttribute("", renderer, "icon-name", config.QueueColumnStarIcon); !errCheck(err, "TreeViewColumnNewWithAttribute() failed") {
+			col.SetSizing(gtk.TREE_VIEW_COLUMN_FIXED)
+			col.SetFixedWidth(-1)
+			col.AddAttribute(renderer, "cell-background", config.QueueColumnBgColor)
+			w.QueueTreeView.AppendColumn(col)
+		}
+	}
+
 	// Add selected columns
 	for index, colSpec := range config.GetConfig().QueueColumns {
 		index := index // Make an in-loop copy of index for the closures below
@@ -2475,6 +6909,61 @@ func (w *MainWindow) updateQueueColumns() {
 	w.QueueTreeView.ShowAll()
 }
 
+// updateQueueAlbumArt displays album art thumbnails in the play queue, if enabled. Thumbnails of the albums that
+// haven't been loaded yet are fetched in the background, once per album
+func (w *MainWindow) updateQueueAlbumArt() {
+	// Drop the thumbnails if they're disabled
+	if !config.GetConfig().QueueAlbumArt {
+		w.queueAlbumArt = make(map[string]*gdk.Pixbuf)
+		return
+	}
+
+	// Forget the thumbnails of the albums no longer in the queue
+	for key := range w.queueAlbumArt {
+		if _, ok := w.queueAlbums[key]; !ok {
+			delete(w.queueAlbumArt, key)
+		}
+	}
+
+	settings := NewAlbumArtSettings()
+	for key, attrs := range w.queueAlbums {
+		// Use the thumbnail if it's already loaded
+		if px, ok := w.queueAlbumArt[key]; ok {
+			w.setQueueAlbumArt(key, px)
+			continue
+		}
+
+		// Load it otherwise
+		key, attrs := key, attrs
+		w.albumArtLoader.Load(
+			"queue:"+key,
+			func() ([]byte, error) { return FetchAlbumArt(w.connector, w.albumArtCache, settings, attrs) },
+			func(data []byte) {
+				util.WhenIdle("updateQueueAlbumArt()", func() {
+					// Skip if the album has left the queue in the meantime
+					if _, ok := w.queueAlbums[key]; !ok || !config.GetConfig().QueueAlbumArt {
+						return
+					}
+					px := albumArtPixbuf(data, queueArtworkSize)
+					w.queueAlbumArt[key] = px
+					w.setQueueAlbumArt(key, px)
+				})
+			})
+	}
+}
+
+// setQueueAlbumArt puts the given album art thumbnail into the play queue rows of the album with the given key
+func (w *MainWindow) setQueueAlbumArt(key string, px *gdk.Pixbuf) {
+	if px == nil {
+		return
+	}
+	for _, index := range w.queueAlbumRows[key] {
+		if iter, err := w.QueueListStore.GetIterFromString(strconv.Itoa(index)); !errCheck(err, "GetIterFromString() failed") {
+			errCheck(w.QueueListStore.SetValue(iter, config.QueueColumnAlbumArt, px), "setQueueAlbumArt(): QueueListStore.SetValue() failed")
+		}
+	}
+}
+
 // updateQueueActions updates the play queue actions
 func (w *MainWindow) updateQueueActions() {
 	connected, _ := w.connector.ConnectStatus()
@@ -2491,8 +6980,22 @@ func (w *MainWindow) updateQueueActions() {
 	w.aQueueSortShuffle.SetEnabled(notEmpty)
 	w.aQueueDelete.SetEnabled(selection)
 	w.aQueueSave.SetEnabled(notEmpty)
+	w.aQueueSaveBack.SetEnabled(connected && w.queueDiverged)
 	// Menu items
 	w.QueueNowPlayingMenuItem.SetSensitive(notEmpty)
+	w.QueueSongInfoMenuItem.SetSensitive(selOne)
+	w.QueueStarMenuItem.SetSensitive(selection)
+	w.QueueAddToPlaylistMenuItem.SetSensitive(selection)
+	if w.queueDiverged {
+		w.QueueSaveBackMenuItem.SetLabel(fmt.Sprintf(glib.Local("Save back to \"%s\""), w.queueSource.Name))
+	}
+	w.QueueSaveBackMenuItem.SetVisible(connected && w.queueDiverged)
+	w.QueueShowFolderInLibraryMenuItem.SetSensitive(selOne)
+	w.QueueOpenFolderMenuItem.SetVisible(config.GetConfig().MpdMusicDir != "")
+	w.QueueOpenFolderMenuItem.SetSensitive(selOne)
+	w.QueueCopyURIMenuItem.SetSensitive(selection)
+	w.QueueCopyFilePathMenuItem.SetVisible(config.GetConfig().MpdMusicDir != "")
+	w.QueueCopyFilePathMenuItem.SetSensitive(selection)
 	w.QueueShowAlbumInLibraryMenuItem.SetSensitive(selOne)
 	w.QueueShowArtistInLibraryMenuItem.SetSensitive(selOne)
 	w.QueueShowGenreInLibraryMenuItem.SetSensitive(selOne)
@@ -2537,7 +7040,7 @@ func (w *MainWindow) updateStreams() {
 	var rowToSelect *gtk.ListBoxRow
 	for _, stream := range config.GetConfig().Streams {
 		stream := stream // Make an in-loop copy of the var
-		row, _, err := util.NewListBoxRow(
+		row, hbx, err := util.NewListBoxRow(
 			w.StreamsListBox,
 			false,
 			stream.Name,
@@ -2550,6 +7053,13 @@ func (w *MainWindow) updateStreams() {
 			return
 		}
 
+		// Add a genre column, if there's one
+		if stream.Genre != "" {
+			if lbl := newLibraryColumnLabel(stream.Genre, libraryDetailsWidthChars, false); lbl != nil {
+				hbx.PackEnd(lbl, false, false, 0)
+			}
+		}
+
 		// Select the first row in the list
 		if rowToSelect == nil {
 			rowToSelect = row
@@ -2565,7 +7075,7 @@ func (w *MainWindow) updateStreams() {
 	// Compose info
 	var info string
 	if cnt := len(config.GetConfig().Streams); cnt > 0 {
-		info = fmt.Sprintf(glib.Local("%d streams"), cnt)
+		info = fmt.Sprintf(util.LocalN("%d stream", "%d streams", cnt), cnt)
 	} else {
 		info = glib.Local("No streams")
 	}
@@ -2574,6 +7084,18 @@ func (w *MainWindow) updateStreams() {
 	w.StreamsInfoLabel.SetText(info)
 }
 
+// addStream adds the given stream to the streams list, unless there's already one with the same URI
+func (w *MainWindow) addStream(stream config.StreamSpec) {
+	cfg := config.GetConfig()
+	for _, s := range cfg.Streams {
+		if s.URI == stream.URI {
+			return
+		}
+	}
+	cfg.Streams = append(cfg.Streams, stream)
+	w.updateStreams()
+}
+
 // updateStreamsActions updates the widgets for streams list
 func (w *MainWindow) updateStreamsActions() {
 	connected, _ := w.connector.ConnectStatus()
@@ -2582,6 +7104,9 @@ func (w *MainWindow) updateStreamsActions() {
 	w.aStreamAdd.SetEnabled(true) // Adding a stream is always possible
 	w.aStreamEdit.SetEnabled(selected)
 	w.aStreamDelete.SetEnabled(selected)
+	w.aStreamFind.SetEnabled(true)   // Finding streams is always possible
+	w.aStreamImport.SetEnabled(true) // Importing streams is always possible
+	w.aStreamExport.SetEnabled(len(config.GetConfig().Streams) > 0)
 	// Menu items
 	w.StreamsAppendMenuItem.SetSensitive(connected && selected)
 	w.StreamsReplaceMenuItem.SetSensitive(connected && selected)
@@ -2589,6 +7114,38 @@ func (w *MainWindow) updateStreamsActions() {
 	w.StreamsDeleteMenuItem.SetSensitive(selected)
 }
 
+// updateLayout reflows the window's content for a narrow (phone-width) or a regular window. In a narrow window the
+// page switcher moves to the bottom, and the artist pane and the player bar are stacked vertically
+func (w *MainWindow) updateLayout(narrow bool) {
+	if w.narrowLayout != tbNone && narrow == (w.narrowLayout == tbTrue) {
+		return
+	}
+	log.Debugf("updateLayout(%v)", narrow)
+	w.narrowLayout = tbFalse
+	orientation := gtk.ORIENTATION_HORIZONTAL
+	if narrow {
+		w.narrowLayout = tbTrue
+		orientation = gtk.ORIENTATION_VERTICAL
+	}
+
+	// Move the page switcher between the header bar and the bottom of the window
+	w.MainStackSwitcher.SetVisible(!narrow)
+	w.BottomStackSwitcher.SetVisible(narrow)
+
+	// Stack the panes and the player controls
+	(&gtk.Orientable{Object: w.MainPaned.Object}).SetOrientation(orientation)
+	w.BottomBarBox.SetOrientation(orientation)
+
+	// Let the user style sheet tell the layouts apart
+	if ctx, err := w.AppWindow.GetStyleContext(); !errCheck(err, "updateLayout(): GetStyleContext() failed") {
+		if narrow {
+			ctx.AddClass("ymuse-narrow")
+		} else {
+			ctx.RemoveClass("ymuse-narrow")
+		}
+	}
+}
+
 // updateStyle updates custom colours based on the current theme
 func (w *MainWindow) updateStyle() {
 	// Fetch window's style context
@@ -2651,4 +7208,5 @@ func (w *MainWindow) updateVolume() {
 		w.VolumeAdjustment.SetValue(float64(vol))
 		w.volumeUpdating = false
 	}
+	w.updateMprisStatus()
 }
@@ -0,0 +1,139 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+)
+
+// mediaKeysDaemon describes a settings daemon offering the media keys API
+type mediaKeysDaemon struct {
+	busName string          // Bus name of the daemon
+	path    dbus.ObjectPath // Path of the media keys object
+	iface   string          // Media keys interface
+}
+
+// mediaKeysDaemons lists the known settings daemons, in the order they're tried
+var mediaKeysDaemons = []mediaKeysDaemon{
+	{"org.gnome.SettingsDaemon.MediaKeys", "/org/gnome/SettingsDaemon/MediaKeys", "org.gnome.SettingsDaemon.MediaKeys"},
+	{"org.gnome.SettingsDaemon", "/org/gnome/SettingsDaemon/MediaKeys", "org.gnome.SettingsDaemon.MediaKeys"},
+	{"org.mate.SettingsDaemon", "/org/mate/SettingsDaemon/MediaKeys", "org.mate.SettingsDaemon.MediaKeys"},
+}
+
+// MediaKeys grabs the hardware media keys (XF86AudioPlay etc.) through the settings daemon of the desktop, for the
+// environments that don't route them to the player over MPRIS
+type MediaKeys struct {
+	conn     *dbus.Conn          // Session bus connection
+	daemon   mediaKeysDaemon     // Daemon the keys are grabbed through
+	onAction func(action string) // Callback for activating an application action on a key press
+}
+
+// NewMediaKeys connects to the session bus and grabs the media keys through the first available settings daemon.
+// onAction is invoked on the GTK thread with the name of the application action to activate whenever a key is pressed
+func NewMediaKeys(onAction func(action string)) (*MediaKeys, error) {
+	conn, err := connectBus(dbus.SessionBusPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find a daemon willing to grab the keys
+	k := &MediaKeys{conn: conn, onAction: onAction}
+	for _, d := range mediaKeysDaemons {
+		k.daemon = d
+		if err = k.grab(); err == nil {
+			break
+		}
+	}
+	if err == nil {
+		err = conn.AddMatchSignal(
+			dbus.WithMatchSender(k.daemon.busName),
+			dbus.WithMatchObjectPath(k.daemon.path),
+			dbus.WithMatchInterface(k.daemon.iface),
+			dbus.WithMatchMember("MediaPlayerKeyPressed"))
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	log.Debugf("Grabbed media keys through %s", k.daemon.busName)
+
+	// Listen to key presses
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	go k.watch(signals)
+	return k, nil
+}
+
+// Close releases the keys and disconnects from the session bus
+func (k *MediaKeys) Close() {
+	errCheck(k.call("ReleaseMediaPlayerKeys", config.AppMetadata.ID), "Failed to release media keys")
+	errCheck(k.conn.Close(), "Failed to close session bus connection")
+}
+
+// Grab asks the daemon in the background to deliver the keys to the application again, rather than to another player
+// that has grabbed them since. Meant to be called when the main window gets focused
+func (k *MediaKeys) Grab() {
+	go func() {
+		errCheck(k.grab(), "Failed to grab media keys")
+	}()
+}
+
+// grab asks the daemon to deliver the keys to the application
+func (k *MediaKeys) grab() error {
+	return k.call("GrabMediaPlayerKeys", config.AppMetadata.ID, uint32(0))
+}
+
+// call invokes the given method of the daemon's media keys object
+func (k *MediaKeys) call(method string, args ...interface{}) error {
+	return k.conn.Object(k.daemon.busName, k.daemon.path).Call(k.daemon.iface+"."+method, 0, args...).Err
+}
+
+// watch translates the key presses destined for the application into actions, until the connection is closed
+func (k *MediaKeys) watch(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if sig.Name != k.daemon.iface+".MediaPlayerKeyPressed" || len(sig.Body) != 2 {
+			continue
+		}
+		app, _ := sig.Body[0].(string)
+		key, _ := sig.Body[1].(string)
+		if action := mediaKeyAction(key); app == config.AppMetadata.ID && action != "" {
+			log.Debugf("Media key pressed: %s", key)
+			util.WhenIdle("MediaKeys.watch()", k.onAction, action)
+		}
+	}
+}
+
+// mediaKeyAction returns the name of the application action corresponding to the given media key, as reported by the
+// settings daemon, or an empty string if the key isn't supported
+func mediaKeyAction(key string) string {
+	switch key {
+	case "Play", "Pause":
+		return "player.play-pause"
+	case "Stop":
+		return "player.stop"
+	case "Next":
+		return "player.next"
+	case "Previous":
+		return "player.previous"
+	case "Repeat":
+		return "player.toggle.repeat"
+	case "Shuffle":
+		return "player.toggle.random"
+	}
+	return ""
+}
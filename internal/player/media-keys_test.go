@@ -0,0 +1,42 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import "testing"
+
+func TestMediaKeyAction(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"Play", "player.play-pause"},
+		{"Pause", "player.play-pause"},
+		{"Stop", "player.stop"},
+		{"Next", "player.next"},
+		{"Previous", "player.previous"},
+		{"Repeat", "player.toggle.repeat"},
+		{"Shuffle", "player.toggle.random"},
+		{"FastForward", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := mediaKeyAction(tt.key); got != tt.want {
+				t.Errorf("mediaKeyAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/generated"
+)
+
+// MiniPlayer is a lightweight popup window with the current track, the playback controls and the play queue, summoned
+// in place of the main window when running in the background
+type MiniPlayer struct {
+	MiniPlayerWindow         *gtk.Window
+	MiniPlayerTitleLabel     *gtk.Label
+	MiniPlayerPlayPauseImage *gtk.Image
+	MiniPlayerQueueTreeView  *gtk.TreeView
+
+	onPlayIndex func(index int) // Callback for playing the queue track with the given index
+}
+
+// NewMiniPlayer creates and returns a new, hidden MiniPlayer instance displaying the given play queue list store.
+// onPlayIndex is invoked with the index of the queue track the user picks
+func NewMiniPlayer(queue *gtk.ListStore, onPlayIndex func(index int)) (*MiniPlayer, error) {
+	// Load the window layout and map the widgets
+	m := &MiniPlayer{onPlayIndex: onPlayIndex}
+	builder, err := NewBuilder(generated.GetMiniPlayerGlade())
+	if err == nil {
+		err = builder.BindWidgets(m)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Map the handlers to callback functions
+	builder.ConnectSignals(map[string]interface{}{
+		"on_MiniPlayerWindow_delete":              m.onDelete,
+		"on_MiniPlayerWindow_keyPress":            m.onKeyPress,
+		"on_MiniPlayerOpenButton_clicked":         m.Hide,
+		"on_MiniPlayerQueueTreeView_rowActivated": m.onQueueRowActivated,
+	})
+
+	// Display the current track marker, the artist and the title of each queue track
+	m.MiniPlayerQueueTreeView.SetModel(queue)
+	if renderer, err := gtk.CellRendererPixbufNew(); !errCheck(err, "CellRendererPixbufNew() failed") {
+		if col, err := gtk.TreeViewColumnNewWithAttribute("", renderer, "icon-name", config.QueueColumnIcon); !errCheck(err, "TreeViewColumnNewWithAttribute() failed") {
+			m.MiniPlayerQueueTreeView.AppendColumn(col)
+		}
+	}
+	for _, attrID := range []int{config.MTAttrTrack, config.MTAttrArtist} {
+		renderer, err := gtk.CellRendererTextNew()
+		if errCheck(err, "CellRendererTextNew() failed") {
+			continue
+		}
+		col, err := gtk.TreeViewColumnNewWithAttribute("", renderer, "text", attrID)
+		if errCheck(err, "TreeViewColumnNewWithAttribute() failed") {
+			continue
+		}
+		col.SetSizing(gtk.TREE_VIEW_COLUMN_FIXED)
+		col.SetExpand(true)
+		col.AddAttribute(renderer, "weight", config.QueueColumnFontWeight)
+		m.MiniPlayerQueueTreeView.AppendColumn(col)
+	}
+	return m, nil
+}
+
+// Toggle shows the window if it's hidden or isn't focused, and hides it otherwise
+func (m *MiniPlayer) Toggle() {
+	if m.MiniPlayerWindow.IsVisible() && m.MiniPlayerWindow.IsActive() {
+		m.Hide()
+	} else {
+		m.MiniPlayerWindow.Present()
+	}
+}
+
+// Hide hides the window
+func (m *MiniPlayer) Hide() {
+	m.MiniPlayerWindow.Hide()
+}
+
+// Update displays the current track's status markup and whether the playback is on
+func (m *MiniPlayer) Update(statusHTML string, playing bool) {
+	m.MiniPlayerTitleLabel.SetMarkup(statusHTML)
+	if playing {
+		m.MiniPlayerPlayPauseImage.SetFromIconName("ymuse-pause-symbolic", gtk.ICON_SIZE_BUTTON)
+	} else {
+		m.MiniPlayerPlayPauseImage.SetFromIconName("ymuse-play-symbolic", gtk.ICON_SIZE_BUTTON)
+	}
+}
+
+// Destroy disposes of the window
+func (m *MiniPlayer) Destroy() {
+	m.MiniPlayerWindow.Destroy()
+}
+
+// onDelete hides the window instead of destroying it
+func (m *MiniPlayer) onDelete() bool {
+	m.Hide()
+	return true
+}
+
+func (m *MiniPlayer) onKeyPress(_ *gtk.Window, event *gdk.Event) {
+	// Esc: hide the window
+	evt := gdk.EventKeyNewFromEvent(event)
+	if evt.KeyVal() == gdk.KEY_Escape && gdk.ModifierType(evt.State())&gtk.AcceleratorGetDefaultModMask() == 0 {
+		m.Hide()
+	}
+}
+
+func (m *MiniPlayer) onQueueRowActivated(_ *gtk.TreeView, path *gtk.TreePath) {
+	if indices := path.GetIndices(); len(indices) > 0 {
+		m.onPlayIndex(indices[0])
+	}
+}
@@ -0,0 +1,750 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mprisBusName          = "org.mpris.MediaPlayer2.ymuse"              // Well-known bus name of the player
+	mprisPath             = "/org/mpris/MediaPlayer2"                   // Path of the MPRIS object
+	mprisRootIface        = "org.mpris.MediaPlayer2"                    // MPRIS root interface
+	mprisPlayerIface      = "org.mpris.MediaPlayer2.Player"             // MPRIS player interface
+	mprisTrackListIface   = "org.mpris.MediaPlayer2.TrackList"          // MPRIS track list interface
+	mprisPlaylistsIface   = "org.mpris.MediaPlayer2.Playlists"          // MPRIS playlists interface
+	mprisTrackPathBase    = "/com/yktoo/ymuse/track/"                   // Prefix of the track IDs, followed by MPD song ID
+	mprisPlaylistPathBase = "/com/yktoo/ymuse/playlist/"                // Prefix of the playlist IDs, followed by hex-encoded playlist name
+	mprisNoTrack          = "/org/mpris/MediaPlayer2/TrackList/NoTrack" // Track ID representing the absence of a track
+	mprisSeekTolerance    = 2 * time.Second                             // Position jumps larger than this are reported as seeks
+	mprisDesktopEntry     = "ymuse"                                     // Base name of the application's .desktop file
+)
+
+// Mpris exposes the player on the D-Bus session bus through the MPRIS2 interfaces, so that desktop media widgets can
+// display and control it. Property updates must happen on the GTK thread, D-Bus calls are served in the background
+type Mpris struct {
+	connector     *Connector       // Connector to control MPD through
+	albumArtCache *AlbumArtCache   // Cache to look album art files up in
+	conn          *dbus.Conn       // Session bus connection
+	props         *prop.Properties // Exported properties
+
+	onRaise            func()            // Callback for raising the main window
+	onQuit             func()            // Callback for quitting the application
+	onActivatePlaylist func(name string) // Callback for replacing the queue with a stored playlist and playing it
+
+	trackID  dbus.ObjectPath // ID of the current track
+	lastPos  time.Duration   // Last reported play position
+	lastTime time.Time       // Moment the play position has last been reported
+	playing  bool            // Whether the player was playing when the position has last been reported
+
+	queue      map[dbus.ObjectPath]mpd.Attrs // Tracks in the MPD queue, keyed by track ID
+	queueIDs   []dbus.ObjectPath             // IDs of the tracks in the MPD queue, in order
+	queueMutex sync.RWMutex                  // Mutex guarding the queue, which is also read by D-Bus calls
+
+	playlists      []string     // Names of the stored playlists, sorted
+	playlistsMutex sync.RWMutex // Mutex guarding the playlists, which are also read by D-Bus calls
+}
+
+// mprisPlayerMethods maps the names of mprisPlayer's methods to the D-Bus method names, where they differ. Seek would
+// clash with io.Seeker otherwise
+var mprisPlayerMethods = map[string]string{"SeekBy": "Seek"}
+
+// mprisRoot implements the org.mpris.MediaPlayer2 interface
+type mprisRoot struct {
+	m *Mpris
+}
+
+// mprisPlayer implements the org.mpris.MediaPlayer2.Player interface
+type mprisPlayer struct {
+	m *Mpris
+}
+
+// mprisTrackList implements the org.mpris.MediaPlayer2.TrackList interface
+type mprisTrackList struct {
+	m *Mpris
+}
+
+// mprisPlaylists implements the org.mpris.MediaPlayer2.Playlists interface
+type mprisPlaylists struct {
+	m *Mpris
+}
+
+// mprisPlaylist describes an MPRIS playlist, (oss) on the bus
+type mprisPlaylist struct {
+	ID   dbus.ObjectPath // Playlist ID
+	Name string          // Playlist name
+	Icon string          // URI of the playlist icon, empty if none
+}
+
+// mprisMaybePlaylist describes an optional MPRIS playlist, (b(oss)) on the bus
+type mprisMaybePlaylist struct {
+	Valid    bool          // Whether there's a playlist
+	Playlist mprisPlaylist // The playlist, if Valid
+}
+
+// NewMpris connects to the session bus and exports the MPRIS2 interfaces controlling MPD through the given connector.
+// Album art files are looked up in the given cache. onRaise, onQuit and onActivatePlaylist are invoked on the GTK
+// thread when a client asks to raise the window, to quit and to play a stored playlist, respectively
+func NewMpris(connector *Connector, albumArtCache *AlbumArtCache, onRaise, onQuit func(), onActivatePlaylist func(name string)) (*Mpris, error) {
+	conn, err := connectBus(dbus.SessionBusPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mpris{
+		connector:          connector,
+		albumArtCache:      albumArtCache,
+		conn:               conn,
+		onRaise:            onRaise,
+		onQuit:             onQuit,
+		onActivatePlaylist: onActivatePlaylist,
+		trackID:            mprisNoTrack,
+		queue:              make(map[dbus.ObjectPath]mpd.Attrs),
+	}
+	if err := m.export(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// Claim the bus name once everything is in place
+	reply, err := conn.RequestName(mprisBusName, dbus.NameFlagDoNotQueue)
+	if err == nil && reply != dbus.RequestNameReplyPrimaryOwner {
+		err = fmt.Errorf("bus name %s is already taken", mprisBusName)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	log.Debugf("Registered on the session bus as %s", mprisBusName)
+	return m, nil
+}
+
+// connectBus opens a private connection to a message bus using the given function and authenticates on it
+func connectBus(open func(opts ...dbus.ConnOption) (*dbus.Conn, error)) (*dbus.Conn, error) {
+	conn, err := open()
+	if err == nil {
+		if err = conn.Auth(nil); err == nil {
+			err = conn.Hello()
+		}
+	}
+	if err != nil {
+		if conn != nil {
+			_ = conn.Close()
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Close releases the bus name and disconnects from the session bus
+func (m *Mpris) Close() {
+	errCheck(m.conn.Close(), "Failed to close session bus connection")
+}
+
+// SetTrack updates the metadata of the current track (nil if none)
+func (m *Mpris) SetTrack(track mpd.Attrs) {
+	id := mprisTrackID(track)
+	if id != m.trackID {
+		m.trackID = id
+		m.lastTime = time.Time{}
+	}
+	m.set(mprisPlayerIface, "Metadata", m.metadata(track))
+}
+
+// SetQueue updates the track list from the given content of the MPD queue. Clients are told the list is replaced
+// whenever it changes
+func (m *Mpris) SetQueue(tracks []mpd.Attrs) {
+	queue := make(map[dbus.ObjectPath]mpd.Attrs, len(tracks))
+	ids := make([]dbus.ObjectPath, 0, len(tracks))
+	for _, track := range tracks {
+		id := mprisTrackID(track)
+		queue[id] = track
+		ids = append(ids, id)
+	}
+
+	// Replace the queue
+	m.queueMutex.Lock()
+	changed := !reflect.DeepEqual(ids, m.queueIDs)
+	m.queue, m.queueIDs = queue, ids
+	m.queueMutex.Unlock()
+
+	// Announce the change
+	if changed {
+		m.props.SetMust(mprisTrackListIface, "Tracks", ids)
+		errCheck(
+			m.conn.Emit(mprisPath, mprisTrackListIface+".TrackListReplaced", ids, m.trackID),
+			"Failed to emit TrackListReplaced signal")
+	}
+}
+
+// SetStatus updates the player state from the given MPD status, and reports the seeks that have happened since the
+// previous update
+func (m *Mpris) SetStatus(status mpd.Attrs) {
+	connected, _ := m.connector.ConnectStatus()
+	hasTrack := connected && status["songid"] != ""
+	m.set(mprisPlayerIface, "PlaybackStatus", mprisPlaybackStatus(status["state"]))
+	m.set(mprisPlayerIface, "LoopStatus", mprisLoopStatus(status))
+	m.set(mprisPlayerIface, "Shuffle", status["random"] == "1")
+	m.set(mprisPlayerIface, "Volume", math.Max(float64(util.AtoiDef(status["volume"], 0)), 0)/100)
+	m.set(mprisPlayerIface, "CanGoNext", hasTrack)
+	m.set(mprisPlayerIface, "CanGoPrevious", hasTrack)
+	m.set(mprisPlayerIface, "CanPlay", connected && status["playlistlength"] != "0")
+	m.set(mprisPlayerIface, "CanPause", hasTrack)
+	m.set(mprisPlayerIface, "CanSeek", hasTrack && util.ParseFloatDef(status["duration"], 0) > 0)
+
+	// Position changes aren't announced, seeks are
+	pos := secondsToDuration(util.ParseFloatDef(status["elapsed"], 0))
+	playing := status["state"] == "play"
+	now := time.Now()
+	if !m.lastTime.IsZero() {
+		expected := m.lastPos
+		if m.playing {
+			expected += now.Sub(m.lastTime)
+		}
+		if d := pos - expected; d > mprisSeekTolerance || d < -mprisSeekTolerance {
+			errCheck(m.conn.Emit(mprisPath, mprisPlayerIface+".Seeked", pos.Microseconds()), "Failed to emit Seeked signal")
+		}
+	}
+	m.lastPos, m.lastTime, m.playing = pos, now, playing
+	m.props.SetMust(mprisPlayerIface, "Position", pos.Microseconds())
+}
+
+// SetPlaylists updates the list of the stored playlists from the given names
+func (m *Mpris) SetPlaylists(names []string) {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	m.playlistsMutex.Lock()
+	m.playlists = sorted
+	m.playlistsMutex.Unlock()
+	m.set(mprisPlaylistsIface, "PlaylistCount", uint32(len(sorted)))
+}
+
+// SetActivePlaylist updates the name of the stored playlist the queue has been loaded from, empty if none
+func (m *Mpris) SetActivePlaylist(name string) {
+	m.set(mprisPlaylistsIface, "ActivePlaylist", mprisActivePlaylist(name))
+}
+
+// export exports the MPRIS objects and properties on the bus
+func (m *Mpris) export() error {
+	root, player, trackList, playlists := &mprisRoot{m}, &mprisPlayer{m}, &mprisTrackList{m}, &mprisPlaylists{m}
+	if err := m.conn.Export(root, mprisPath, mprisRootIface); err != nil {
+		return err
+	}
+	if err := m.conn.ExportWithMap(player, mprisPlayerMethods, mprisPath, mprisPlayerIface); err != nil {
+		return err
+	}
+	if err := m.conn.Export(trackList, mprisPath, mprisTrackListIface); err != nil {
+		return err
+	}
+	if err := m.conn.Export(playlists, mprisPath, mprisPlaylistsIface); err != nil {
+		return err
+	}
+
+	// Define the properties
+	var err error
+	m.props, err = prop.Export(m.conn, mprisPath, map[string]map[string]*prop.Prop{
+		mprisRootIface: {
+			"CanQuit":             {Value: true, Emit: prop.EmitFalse},
+			"CanRaise":            {Value: true, Emit: prop.EmitFalse},
+			"HasTrackList":        {Value: true, Emit: prop.EmitFalse},
+			"Identity":            {Value: config.AppMetadata.Name, Emit: prop.EmitFalse},
+			"DesktopEntry":        {Value: mprisDesktopEntry, Emit: prop.EmitFalse},
+			"SupportedUriSchemes": {Value: []string{"file", "http", "https"}, Emit: prop.EmitFalse},
+			"SupportedMimeTypes":  {Value: []string{}, Emit: prop.EmitFalse},
+		},
+		mprisPlayerIface: {
+			"PlaybackStatus": {Value: mprisPlaybackStatus(""), Emit: prop.EmitTrue},
+			"LoopStatus":     {Value: mprisLoopStatus(nil), Writable: true, Emit: prop.EmitTrue, Callback: player.onLoopStatusSet},
+			"Rate":           {Value: 1.0, Emit: prop.EmitTrue},
+			"Shuffle":        {Value: false, Writable: true, Emit: prop.EmitTrue, Callback: player.onShuffleSet},
+			"Metadata":       {Value: mprisMetadata(nil, "", ""), Emit: prop.EmitTrue},
+			"Volume":         {Value: 0.0, Writable: true, Emit: prop.EmitTrue, Callback: player.onVolumeSet},
+			"Position":       {Value: int64(0), Emit: prop.EmitFalse},
+			"MinimumRate":    {Value: 1.0, Emit: prop.EmitTrue},
+			"MaximumRate":    {Value: 1.0, Emit: prop.EmitTrue},
+			"CanGoNext":      {Value: false, Emit: prop.EmitTrue},
+			"CanGoPrevious":  {Value: false, Emit: prop.EmitTrue},
+			"CanPlay":        {Value: false, Emit: prop.EmitTrue},
+			"CanPause":       {Value: false, Emit: prop.EmitTrue},
+			"CanSeek":        {Value: false, Emit: prop.EmitTrue},
+			"CanControl":     {Value: true, Emit: prop.EmitFalse},
+		},
+		mprisTrackListIface: {
+			"Tracks":        {Value: []dbus.ObjectPath{}, Emit: prop.EmitInvalidates},
+			"CanEditTracks": {Value: true, Emit: prop.EmitFalse},
+		},
+		mprisPlaylistsIface: {
+			"PlaylistCount":  {Value: uint32(0), Emit: prop.EmitTrue},
+			"Orderings":      {Value: []string{"Alphabetical"}, Emit: prop.EmitFalse},
+			"ActivePlaylist": {Value: mprisActivePlaylist(""), Emit: prop.EmitTrue},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Describe the object for introspection
+	playerMethods := introspect.Methods(player)
+	for i := range playerMethods {
+		if name, ok := mprisPlayerMethods[playerMethods[i].Name]; ok {
+			playerMethods[i].Name = name
+		}
+	}
+	node := &introspect.Node{
+		Name: mprisPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       mprisRootIface,
+				Methods:    introspect.Methods(root),
+				Properties: m.props.Introspection(mprisRootIface),
+			},
+			{
+				Name:       mprisPlayerIface,
+				Methods:    playerMethods,
+				Properties: m.props.Introspection(mprisPlayerIface),
+				Signals:    []introspect.Signal{{Name: "Seeked", Args: []introspect.Arg{{Name: "Position", Type: "x"}}}},
+			},
+			{
+				Name:       mprisTrackListIface,
+				Methods:    introspect.Methods(trackList),
+				Properties: m.props.Introspection(mprisTrackListIface),
+				Signals: []introspect.Signal{
+					{Name: "TrackListReplaced", Args: []introspect.Arg{{Name: "Tracks", Type: "ao"}, {Name: "CurrentTrack", Type: "o"}}},
+					{Name: "TrackAdded", Args: []introspect.Arg{{Name: "Metadata", Type: "a{sv}"}, {Name: "AfterTrack", Type: "o"}}},
+					{Name: "TrackRemoved", Args: []introspect.Arg{{Name: "TrackId", Type: "o"}}},
+					{Name: "TrackMetadataChanged", Args: []introspect.Arg{{Name: "TrackId", Type: "o"}, {Name: "Metadata", Type: "a{sv}"}}},
+				},
+			},
+			{
+				Name:       mprisPlaylistsIface,
+				Methods:    introspect.Methods(playlists),
+				Properties: m.props.Introspection(mprisPlaylistsIface),
+				Signals:    []introspect.Signal{{Name: "PlaylistChanged", Args: []introspect.Arg{{Name: "Playlist", Type: "(oss)"}}}},
+			},
+		},
+	}
+	return m.conn.Export(introspect.NewIntrospectable(node), mprisPath, "org.freedesktop.DBus.Introspectable")
+}
+
+// metadata returns the MPRIS metadata of the given track, which can be nil
+func (m *Mpris) metadata(track mpd.Attrs) map[string]dbus.Variant {
+	artFile := ""
+	if track != nil {
+		artFile = AlbumArtFile(m.albumArtCache, track)
+	}
+	return mprisMetadata(track, artFile, config.GetConfig().MpdMusicDir)
+}
+
+// set updates the value of the given property, if it's changed
+func (m *Mpris) set(iface, name string, value interface{}) {
+	if !reflect.DeepEqual(m.props.GetMust(iface, name), value) {
+		m.props.SetMust(iface, name, value)
+	}
+}
+
+// run executes the given MPD client code, translating its failure into a D-Bus error
+func (m *Mpris) run(f func(client *mpd.Client) error) *dbus.Error {
+	err := errors.New("not connected to MPD")
+	m.connector.IfConnected(func(client *mpd.Client) {
+		err = f(client)
+	})
+	if errCheck(err, "MPRIS request failed") {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// Raise implements org.mpris.MediaPlayer2.Raise
+func (r *mprisRoot) Raise() *dbus.Error {
+	util.WhenIdle("Mpris.Raise()", r.m.onRaise)
+	return nil
+}
+
+// Quit implements org.mpris.MediaPlayer2.Quit
+func (r *mprisRoot) Quit() *dbus.Error {
+	util.WhenIdle("Mpris.Quit()", r.m.onQuit)
+	return nil
+}
+
+// Next implements org.mpris.MediaPlayer2.Player.Next
+func (p *mprisPlayer) Next() *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error { return client.Next() })
+}
+
+// Previous implements org.mpris.MediaPlayer2.Player.Previous
+func (p *mprisPlayer) Previous() *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error { return client.Previous() })
+}
+
+// Pause implements org.mpris.MediaPlayer2.Player.Pause
+func (p *mprisPlayer) Pause() *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error {
+		if p.m.connector.Status()["state"] != "play" {
+			return nil
+		}
+		return client.Pause(true)
+	})
+}
+
+// PlayPause implements org.mpris.MediaPlayer2.Player.PlayPause
+func (p *mprisPlayer) PlayPause() *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error {
+		switch p.m.connector.Status()["state"] {
+		case "pause":
+			return client.Pause(false)
+		case "play":
+			return client.Pause(true)
+		default:
+			return client.Play(-1)
+		}
+	})
+}
+
+// Stop implements org.mpris.MediaPlayer2.Player.Stop
+func (p *mprisPlayer) Stop() *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error { return client.Stop() })
+}
+
+// Play implements org.mpris.MediaPlayer2.Player.Play
+func (p *mprisPlayer) Play() *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error {
+		switch p.m.connector.Status()["state"] {
+		case "pause":
+			return client.Pause(false)
+		case "play":
+			return nil
+		default:
+			return client.Play(-1)
+		}
+	})
+}
+
+// SeekBy implements org.mpris.MediaPlayer2.Player.Seek. Seeking past the end of the track skips to the next one
+func (p *mprisPlayer) SeekBy(offset int64) *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error {
+		status := p.m.connector.Status()
+		if status["songid"] == "" {
+			return nil
+		}
+		pos := secondsToDuration(util.ParseFloatDef(status["elapsed"], 0)) + time.Duration(offset)*time.Microsecond
+		if length := secondsToDuration(util.ParseFloatDef(status["duration"], 0)); length > 0 && pos > length {
+			return client.Next()
+		}
+		if pos < 0 {
+			pos = 0
+		}
+		return client.SeekCur(pos, false)
+	})
+}
+
+// SetPosition implements org.mpris.MediaPlayer2.Player.SetPosition. The request is ignored if the given track isn't
+// the current one anymore or the position is out of range
+func (p *mprisPlayer) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error {
+		status := p.m.connector.Status()
+		pos := time.Duration(position) * time.Microsecond
+		if trackID != mprisTrackID(mpd.Attrs{"Id": status["songid"]}) || pos < 0 ||
+			pos > secondsToDuration(util.ParseFloatDef(status["duration"], 0)) {
+			return nil
+		}
+		return client.SeekCur(pos, false)
+	})
+}
+
+// OpenUri implements org.mpris.MediaPlayer2.Player.OpenUri by appending the given URI to the queue and playing it
+func (p *mprisPlayer) OpenUri(uri string) *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error {
+		id, err := client.AddID(mprisURIToMpd(uri), -1)
+		if err != nil {
+			return err
+		}
+		return client.PlayID(id)
+	})
+}
+
+// GetTracksMetadata implements org.mpris.MediaPlayer2.TrackList.GetTracksMetadata. Unknown tracks are skipped
+func (l *mprisTrackList) GetTracksMetadata(trackIDs []dbus.ObjectPath) ([]map[string]dbus.Variant, *dbus.Error) {
+	l.m.queueMutex.RLock()
+	defer l.m.queueMutex.RUnlock()
+	result := make([]map[string]dbus.Variant, 0, len(trackIDs))
+	for _, id := range trackIDs {
+		if track, ok := l.m.queue[id]; ok {
+			result = append(result, l.m.metadata(track))
+		}
+	}
+	return result, nil
+}
+
+// AddTrack implements org.mpris.MediaPlayer2.TrackList.AddTrack by inserting the given URI after the given track, or
+// at the start of the queue if afterTrack is NoTrack
+func (l *mprisTrackList) AddTrack(uri string, afterTrack dbus.ObjectPath, setAsCurrent bool) *dbus.Error {
+	// Find the insert position
+	pos := 0
+	if afterTrack != mprisNoTrack {
+		l.m.queueMutex.RLock()
+		pos = -1
+		for i, id := range l.m.queueIDs {
+			if id == afterTrack {
+				pos = i + 1
+				break
+			}
+		}
+		l.m.queueMutex.RUnlock()
+		if pos < 0 {
+			return dbus.MakeFailedError(fmt.Errorf("unknown track %s", afterTrack))
+		}
+	}
+
+	return l.m.run(func(client *mpd.Client) error {
+		id, err := client.AddID(mprisURIToMpd(uri), pos)
+		if err != nil || !setAsCurrent {
+			return err
+		}
+		return client.PlayID(id)
+	})
+}
+
+// RemoveTrack implements org.mpris.MediaPlayer2.TrackList.RemoveTrack
+func (l *mprisTrackList) RemoveTrack(trackID dbus.ObjectPath) *dbus.Error {
+	songID, ok := mprisSongID(trackID)
+	if !ok {
+		return dbus.MakeFailedError(fmt.Errorf("invalid track %s", trackID))
+	}
+	return l.m.run(func(client *mpd.Client) error { return client.DeleteID(songID) })
+}
+
+// GoTo implements org.mpris.MediaPlayer2.TrackList.GoTo
+func (l *mprisTrackList) GoTo(trackID dbus.ObjectPath) *dbus.Error {
+	songID, ok := mprisSongID(trackID)
+	if !ok {
+		return dbus.MakeFailedError(fmt.Errorf("invalid track %s", trackID))
+	}
+	return l.m.run(func(client *mpd.Client) error { return client.PlayID(songID) })
+}
+
+// ActivatePlaylist implements org.mpris.MediaPlayer2.Playlists.ActivatePlaylist by replacing the queue with the given
+// stored playlist and starting playback
+func (p *mprisPlaylists) ActivatePlaylist(playlistID dbus.ObjectPath) *dbus.Error {
+	name, ok := mprisPlaylistName(playlistID)
+	if ok {
+		p.m.playlistsMutex.RLock()
+		i := sort.SearchStrings(p.m.playlists, name)
+		ok = i < len(p.m.playlists) && p.m.playlists[i] == name
+		p.m.playlistsMutex.RUnlock()
+	}
+	if !ok {
+		return dbus.MakeFailedError(fmt.Errorf("unknown playlist %s", playlistID))
+	}
+	util.WhenIdle("Mpris.ActivatePlaylist()", p.m.onActivatePlaylist, name)
+	return nil
+}
+
+// GetPlaylists implements org.mpris.MediaPlayer2.Playlists.GetPlaylists. Playlists are always ordered alphabetically,
+// the only supported ordering
+func (p *mprisPlaylists) GetPlaylists(index, maxCount uint32, _ string, reverseOrder bool) ([]mprisPlaylist, *dbus.Error) {
+	p.m.playlistsMutex.RLock()
+	names := append([]string(nil), p.m.playlists...)
+	p.m.playlistsMutex.RUnlock()
+
+	if reverseOrder {
+		for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+			names[i], names[j] = names[j], names[i]
+		}
+	}
+	result := []mprisPlaylist{}
+	for i := int(index); i < len(names) && len(result) < int(maxCount); i++ {
+		result = append(result, mprisPlaylist{ID: mprisPlaylistID(names[i]), Name: names[i]})
+	}
+	return result, nil
+}
+
+// onLoopStatusSet handles setting the LoopStatus property
+func (p *mprisPlayer) onLoopStatusSet(c *prop.Change) *dbus.Error {
+	loop := c.Value.(string)
+	switch loop {
+	case "None", "Track", "Playlist":
+	default:
+		return prop.ErrInvalidArg
+	}
+	return p.m.run(func(client *mpd.Client) error {
+		if err := client.Repeat(loop != "None"); err != nil {
+			return err
+		}
+		return client.Single(loop == "Track")
+	})
+}
+
+// onShuffleSet handles setting the Shuffle property
+func (p *mprisPlayer) onShuffleSet(c *prop.Change) *dbus.Error {
+	return p.m.run(func(client *mpd.Client) error { return client.Random(c.Value.(bool)) })
+}
+
+// onVolumeSet handles setting the Volume property
+func (p *mprisPlayer) onVolumeSet(c *prop.Change) *dbus.Error {
+	vol := int(math.Round(math.Min(math.Max(c.Value.(float64), 0), 1) * 100))
+	return p.m.run(func(client *mpd.Client) error { return client.SetVolume(vol) })
+}
+
+// mprisPlaybackStatus translates the given MPD player state into an MPRIS playback status
+func mprisPlaybackStatus(state string) string {
+	switch state {
+	case "play":
+		return "Playing"
+	case "pause":
+		return "Paused"
+	default:
+		return "Stopped"
+	}
+}
+
+// mprisLoopStatus translates the repeat and single modes in the given MPD status into an MPRIS loop status
+func mprisLoopStatus(status mpd.Attrs) string {
+	switch {
+	case status["repeat"] != "1":
+		return "None"
+	case status["single"] == "1":
+		return "Track"
+	default:
+		return "Playlist"
+	}
+}
+
+// mprisTrackID returns the MPRIS ID of the given track, based on its ID in the MPD queue
+func mprisTrackID(track mpd.Attrs) dbus.ObjectPath {
+	if id := track["Id"]; id != "" {
+		return dbus.ObjectPath(mprisTrackPathBase + id)
+	}
+	return mprisNoTrack
+}
+
+// mprisSongID returns the MPD song ID of the track with the given MPRIS ID, and whether the ID is valid
+func mprisSongID(trackID dbus.ObjectPath) (int, bool) {
+	s := string(trackID)
+	if !strings.HasPrefix(s, mprisTrackPathBase) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(s, mprisTrackPathBase))
+	return id, err == nil && id >= 0
+}
+
+// mprisPlaylistID returns the MPRIS ID of the stored playlist with the given name
+func mprisPlaylistID(name string) dbus.ObjectPath {
+	return dbus.ObjectPath(mprisPlaylistPathBase + hex.EncodeToString([]byte(name)))
+}
+
+// mprisPlaylistName returns the name of the stored playlist with the given MPRIS ID, and whether the ID is valid
+func mprisPlaylistName(playlistID dbus.ObjectPath) (string, bool) {
+	s := string(playlistID)
+	if !strings.HasPrefix(s, mprisPlaylistPathBase) {
+		return "", false
+	}
+	name, err := hex.DecodeString(strings.TrimPrefix(s, mprisPlaylistPathBase))
+	return string(name), err == nil && len(name) > 0
+}
+
+// mprisActivePlaylist returns the MPRIS active playlist value for the stored playlist with the given name, empty if
+// there's none
+func mprisActivePlaylist(name string) mprisMaybePlaylist {
+	if name == "" {
+		return mprisMaybePlaylist{Playlist: mprisPlaylist{ID: "/"}}
+	}
+	return mprisMaybePlaylist{Valid: true, Playlist: mprisPlaylist{ID: mprisPlaylistID(name), Name: name}}
+}
+
+// mprisURIToMpd translates the given URI requested by an MPRIS client into an MPD URI: local files are translated into
+// MPD URIs if they lie within the music directory, other URIs are returned as is
+func mprisURIToMpd(uri string) string {
+	if paths := util.ParseURIList(uri); len(paths) > 0 {
+		if u, ok := util.LocalPathToURI(config.GetConfig().MpdMusicDir, paths[0]); ok {
+			return u
+		}
+	}
+	return uri
+}
+
+// mprisMetadata returns the MPRIS metadata of the given track, which can be nil. artFile is the path of the track's
+// album art file, if any; musicDir is the local path to MPD's music directory, if known
+func mprisMetadata(track mpd.Attrs, artFile, musicDir string) map[string]dbus.Variant {
+	md := map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(mprisTrackID(track))}
+	if track == nil {
+		return md
+	}
+
+	// Display a stream's current song like a regular track
+	a := EnrichStreamAttrs(track)
+	addString := func(key, value string) {
+		if value != "" {
+			md[key] = dbus.MakeVariant(value)
+		}
+	}
+	addList := func(key, value string) {
+		if value != "" {
+			md[key] = dbus.MakeVariant([]string{value})
+		}
+	}
+	addInt := func(key, value string) {
+		// Values like "3/12" are cut at the slash
+		if n := util.AtoiDef(strings.SplitN(value, "/", 2)[0], 0); n > 0 {
+			md[key] = dbus.MakeVariant(int32(n))
+		}
+	}
+	addString("xesam:title", util.Default(a["Name"], a["Title"]))
+	addString("xesam:album", a["Album"])
+	addList("xesam:artist", a["Artist"])
+	addList("xesam:albumArtist", a["AlbumArtist"])
+	if genres := util.SplitTagValues(a["Genre"]); len(genres) > 0 {
+		md["xesam:genre"] = dbus.MakeVariant(genres)
+	}
+	addList("xesam:composer", a["Composer"])
+	addInt("xesam:trackNumber", a["Track"])
+	addInt("xesam:discNumber", a["Disc"])
+	if length := secondsToDuration(util.ParseFloatDef(a["duration"], 0)); length > 0 {
+		md["mpris:length"] = dbus.MakeVariant(length.Microseconds())
+	}
+	if artFile != "" {
+		md["mpris:artUrl"] = dbus.MakeVariant(util.LocalPathToFileURL(artFile))
+	}
+
+	// Prefer the local file's URL over the MPD URI
+	uri := a["file"]
+	if localPath, ok := util.URIToLocalPath(musicDir, uri); ok {
+		uri = util.LocalPathToFileURL(localPath)
+	}
+	addString("xesam:url", uri)
+	return md
+}
+
+// secondsToDuration converts the given number of seconds into a Duration
+func secondsToDuration(secs float64) time.Duration {
+	return time.Duration(secs * float64(time.Second))
+}
@@ -0,0 +1,143 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/godbus/dbus/v5"
+	"reflect"
+	"testing"
+)
+
+func TestMprisLoopStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status mpd.Attrs
+		want   string
+	}{
+		{"no status", nil, "None"},
+		{"no repeat", mpd.Attrs{"repeat": "0", "single": "1"}, "None"},
+		{"repeat", mpd.Attrs{"repeat": "1", "single": "0"}, "Playlist"},
+		{"repeat single", mpd.Attrs{"repeat": "1", "single": "1"}, "Track"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mprisLoopStatus(tt.status); got != tt.want {
+				t.Errorf("mprisLoopStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMprisSongID(t *testing.T) {
+	tests := []struct {
+		name    string
+		trackID dbus.ObjectPath
+		wantID  int
+		wantOk  bool
+	}{
+		{"track", "/com/yktoo/ymuse/track/42", 42, true},
+		{"first track", "/com/yktoo/ymuse/track/0", 0, true},
+		{"no track", mprisNoTrack, 0, false},
+		{"foreign", "/org/example/track/42", 0, false},
+		{"not a number", "/com/yktoo/ymuse/track/x", 0, false},
+		{"negative", "/com/yktoo/ymuse/track/-1", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := mprisSongID(tt.trackID)
+			if ok != tt.wantOk || ok && id != tt.wantID {
+				t.Errorf("mprisSongID() = %v, %v, want %v, %v", id, ok, tt.wantID, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestMprisPlaylistName(t *testing.T) {
+	for _, name := range []string{"Favorites", "Rock & Roll / 80's", "Ünïcödé"} {
+		id := mprisPlaylistID(name)
+		if !id.IsValid() {
+			t.Errorf("mprisPlaylistID(%q) = %q, not a valid object path", name, id)
+		}
+		if got, ok := mprisPlaylistName(id); !ok || got != name {
+			t.Errorf("mprisPlaylistName(%q) = %q, %v, want %q, true", id, got, ok, name)
+		}
+	}
+	for _, id := range []dbus.ObjectPath{"/", "/com/yktoo/ymuse/playlist/", "/com/yktoo/ymuse/playlist/xyz", "/com/yktoo/ymuse/track/41"} {
+		if got, ok := mprisPlaylistName(id); ok {
+			t.Errorf("mprisPlaylistName(%q) = %q, true, want false", id, got)
+		}
+	}
+}
+
+func TestMprisMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		track    mpd.Attrs
+		artFile  string
+		musicDir string
+		want     map[string]dbus.Variant
+	}{
+		{"no track", nil, "", "/music",
+			map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath(mprisNoTrack))}},
+		{"local track",
+			mpd.Attrs{
+				"Id": "12", "file": "Artist/Album/01 Song.flac", "Title": "Song", "Artist": "AC/DC", "Album": "Album",
+				"Track": "1/10", "Disc": "2", "Genre": "Rock; Blues", "duration": "61.5",
+			},
+			"/home/user/.cache/ymuse/covers/abc.img",
+			"/music",
+			map[string]dbus.Variant{
+				"mpris:trackid":     dbus.MakeVariant(dbus.ObjectPath("/com/yktoo/ymuse/track/12")),
+				"mpris:length":      dbus.MakeVariant(int64(61500000)),
+				"mpris:artUrl":      dbus.MakeVariant("file:///home/user/.cache/ymuse/covers/abc.img"),
+				"xesam:title":       dbus.MakeVariant("Song"),
+				"xesam:album":       dbus.MakeVariant("Album"),
+				"xesam:artist":      dbus.MakeVariant([]string{"AC/DC"}),
+				"xesam:genre":       dbus.MakeVariant([]string{"Rock", "Blues"}),
+				"xesam:trackNumber": dbus.MakeVariant(int32(1)),
+				"xesam:discNumber":  dbus.MakeVariant(int32(2)),
+				"xesam:url":         dbus.MakeVariant("file:///music/Artist/Album/01%20Song.flac"),
+			}},
+		{"stream",
+			mpd.Attrs{"Id": "3", "file": "http://radio.example.com/live", "Name": "Radio", "Title": "Artist - Song"},
+			"",
+			"/music",
+			map[string]dbus.Variant{
+				"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/com/yktoo/ymuse/track/3")),
+				"xesam:title":   dbus.MakeVariant("Song"),
+				"xesam:album":   dbus.MakeVariant("Radio"),
+				"xesam:artist":  dbus.MakeVariant([]string{"Artist"}),
+				"xesam:url":     dbus.MakeVariant("http://radio.example.com/live"),
+			}},
+		{"unnamed stream",
+			mpd.Attrs{"Id": "4", "file": "http://radio.example.com/live", "Name": "Radio"},
+			"",
+			"",
+			map[string]dbus.Variant{
+				"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/com/yktoo/ymuse/track/4")),
+				"xesam:title":   dbus.MakeVariant("Radio"),
+				"xesam:url":     dbus.MakeVariant("http://radio.example.com/live"),
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mprisMetadata(tt.track, tt.artFile, tt.musicDir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mprisMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/yktoo/ymuse/internal/util"
+	"regexp"
+	"strings"
+)
+
+const (
+	musicBrainzURL     = "https://musicbrainz.org/ws/2" // Base URL of the MusicBrainz API
+	musicBrainzSiteURL = "https://musicbrainz.org"      // URL of the MusicBrainz website
+)
+
+// musicBrainzIDRegex matches a valid MusicBrainz identifier (MBID)
+var musicBrainzIDRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// MusicBrainzCredit represents an artist credited for a recording or a release
+type MusicBrainzCredit struct {
+	Name       string `json:"name"`       // Name the artist is credited as
+	JoinPhrase string `json:"joinphrase"` // Text joining the credit with the next one, such as " feat. "
+	Artist     struct {
+		ID string `json:"id"` // Artist's MBID
+	} `json:"artist"`
+}
+
+// MusicBrainzRecording represents recording details
+type MusicBrainzRecording struct {
+	ID               string              `json:"id"`                 // Recording's MBID
+	Title            string              `json:"title"`              // Recording title
+	Disambiguation   string              `json:"disambiguation"`     // Comment distinguishing similarly named recordings
+	Length           int                 `json:"length"`             // Length in milliseconds, 0 if unknown
+	FirstReleaseDate string              `json:"first-release-date"` // Date of the earliest release, such as "1991-08-12"
+	ArtistCredit     []MusicBrainzCredit `json:"artist-credit"`      // Credited artists
+}
+
+// MusicBrainzRelease represents release details
+type MusicBrainzRelease struct {
+	ID           string              `json:"id"`            // Release's MBID
+	Title        string              `json:"title"`         // Release title
+	Date         string              `json:"date"`          // Release date, such as "1991-08-12"
+	Country      string              `json:"country"`       // Release country code
+	Status       string              `json:"status"`        // Release status, such as "Official"
+	Barcode      string              `json:"barcode"`       // Release barcode
+	ArtistCredit []MusicBrainzCredit `json:"artist-credit"` // Credited artists
+	LabelInfo    []struct {
+		CatalogNumber string `json:"catalog-number"` // Catalogue number of the release on the label
+		Label         *struct {
+			Name string `json:"name"` // Label name
+		} `json:"label"`
+	} `json:"label-info"` // Labels the release has been issued on
+}
+
+// IsMusicBrainzID returns whether the given string is a valid MusicBrainz identifier
+func IsMusicBrainzID(s string) bool {
+	return musicBrainzIDRegex.MatchString(s)
+}
+
+// MusicBrainzPageURL returns the URL of the MusicBrainz page of the entity of the given type (such as "recording") and
+// MBID
+func MusicBrainzPageURL(entity, id string) string {
+	return fmt.Sprintf("%s/%s/%s", musicBrainzSiteURL, entity, id)
+}
+
+// FetchMusicBrainzRecording retrieves details of the recording with the given MBID from the MusicBrainz API at the given
+// base URL
+func FetchMusicBrainzRecording(baseURL, id string) (*MusicBrainzRecording, error) {
+	r := &MusicBrainzRecording{}
+	if err := fetchMusicBrainz(baseURL, "recording", id, "artist-credits", r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// FetchMusicBrainzRelease retrieves details of the release with the given MBID from the MusicBrainz API at the given
+// base URL
+func FetchMusicBrainzRelease(baseURL, id string) (*MusicBrainzRelease, error) {
+	r := &MusicBrainzRelease{}
+	if err := fetchMusicBrainz(baseURL, "release", id, "artist-credits+labels", r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Labels returns the labels the release has been issued on, along with catalogue numbers, such as "Elektra (61113-2)"
+func (r *MusicBrainzRelease) Labels() string {
+	var labels []string
+	for _, li := range r.LabelInfo {
+		s := ""
+		if li.Label != nil {
+			s = li.Label.Name
+		}
+		if li.CatalogNumber != "" {
+			s = strings.TrimSpace(fmt.Sprintf("%s (%s)", s, li.CatalogNumber))
+		}
+		if s != "" {
+			labels = append(labels, s)
+		}
+	}
+	return strings.Join(labels, ", ")
+}
+
+// fetchMusicBrainz looks up the entity of the given type and MBID, including the given subqueries, and decodes the
+// response into result
+func fetchMusicBrainz(baseURL, entity, id, inc string, result interface{}) error {
+	if !IsMusicBrainzID(id) {
+		return fmt.Errorf("invalid MusicBrainz ID: %q", id)
+	}
+
+	return util.HTTPGetJSON(fmt.Sprintf("%s/%s/%s?fmt=json&inc=%s", baseURL, entity, id, inc), result)
+}
@@ -0,0 +1,93 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/yktoo/ymuse/internal/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testRecordingID = "3d9a7d2e-2a1c-4d66-8e3a-0f7b3c2a9d11"
+	testReleaseID   = "f1b3e2a4-5c6d-4e7f-8a9b-0c1d2e3f4a5b"
+)
+
+func TestIsMusicBrainzID(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{testRecordingID, true},
+		{"", false},
+		{"3D9A7D2E-2A1C-4D66-8E3A-0F7B3C2A9D11", false},
+		{"3d9a7d2e-2a1c-4d66-8e3a-0f7b3c2a9d11/../x", false},
+		{"3d9a7d2e2a1c4d668e3a0f7b3c2a9d11", false},
+	}
+	for _, tt := range tests {
+		if got := IsMusicBrainzID(tt.s); got != tt.want {
+			t.Errorf("IsMusicBrainzID(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFetchMusicBrainz(t *testing.T) {
+	// MusicBrainz requires a meaningful user agent
+	util.SetHTTPUserAgent("Ymuse", "1.0", "https://yktoo.com")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "Ymuse/") || r.URL.Query().Get("fmt") != "json" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/recording/" + testRecordingID:
+			_, _ = w.Write([]byte(`{"id":"` + testRecordingID + `","title":"One","length":446000,"first-release-date":"1988-08-25",
+				"artist-credit":[{"name":"Metallica","joinphrase":"","artist":{"id":"65f4f0c5-ef9e-490c-aee3-909e7ae6b2ab","name":"Metallica"}}]}`))
+		case "/release/" + testReleaseID:
+			_, _ = w.Write([]byte(`{"id":"` + testReleaseID + `","title":"...And Justice for All","date":"1988-08-25","country":"US",
+				"status":"Official","label-info":[{"catalog-number":"60812-2","label":{"name":"Elektra"}},{"catalog-number":"CAT 1","label":null},{"label":{"name":"Vertigo"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	rec, err := FetchMusicBrainzRecording(server.URL, testRecordingID)
+	if err != nil {
+		t.Fatalf("FetchMusicBrainzRecording() error = %v", err)
+	}
+	if rec.Title != "One" || rec.Length != 446000 || len(rec.ArtistCredit) != 1 || rec.ArtistCredit[0].Artist.ID != "65f4f0c5-ef9e-490c-aee3-909e7ae6b2ab" {
+		t.Errorf("FetchMusicBrainzRecording() = %+v", rec)
+	}
+
+	rel, err := FetchMusicBrainzRelease(server.URL, testReleaseID)
+	if err != nil {
+		t.Fatalf("FetchMusicBrainzRelease() error = %v", err)
+	}
+	if got, want := rel.Labels(), "Elektra (60812-2), (CAT 1), Vertigo"; rel.Country != "US" || got != want {
+		t.Errorf("FetchMusicBrainzRelease() = %+v, Labels() = %q, want %q", rel, got, want)
+	}
+
+	// Unknown and invalid IDs fail
+	if _, err := FetchMusicBrainzRelease(server.URL, testRecordingID); err == nil {
+		t.Error("FetchMusicBrainzRelease() succeeded for an unknown release, want error")
+	}
+	if _, err := FetchMusicBrainzRecording(server.URL, "../release/"+testReleaseID); err == nil {
+		t.Error("FetchMusicBrainzRecording() succeeded for an invalid ID, want error")
+	}
+}
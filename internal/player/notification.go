@@ -0,0 +1,104 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"path"
+	"strings"
+)
+
+// trackNotificationID is the ID of the track change notification, so that each new one replaces the previous
+const trackNotificationID = "track"
+
+// TrackNotifier shows a desktop notification whenever the track being played changes
+type TrackNotifier struct {
+	app     *gtk.Application // Application sending the notifications
+	key     string           // Key of the current track, see trackNotificationKey()
+	shown   bool             // Whether the current track has been notified of
+	hasIcon bool             // Whether the notification of the current track displays album art
+}
+
+// NewTrackNotifier creates and returns a new TrackNotifier instance
+func NewTrackNotifier(app *gtk.Application) *TrackNotifier {
+	return &TrackNotifier{app: app}
+}
+
+// Update notifies the notifier of the current track (nil if none). show tells whether a notification is to be shown
+// now, should the track have changed; artFile is the path to the track's album art, empty if it's unavailable (yet).
+// The notification is sent again once the album art missing from it becomes available
+func (n *TrackNotifier) Update(track mpd.Attrs, show bool, artFile string) {
+	key := trackNotificationKey(track)
+	if key != n.key {
+		n.key = key
+		n.shown = false
+	}
+
+	// Skip if there's no track, or it's been notified of already, unless its album art has just become available
+	if key == "" || !show || n.shown && (n.hasIcon || artFile == "") {
+		return
+	}
+
+	title, body := trackNotificationText(track)
+	notification := glib.NotificationNew(title)
+	notification.SetBody(body)
+	if artFile != "" {
+		notification.SetIcon(artFile)
+	}
+	notification.AddButton(glib.Local("Previous"), "app.player.previous")
+	notification.AddButton(glib.Local("Next"), "app.player.next")
+	notification.SetDefaultAction("app.show")
+	n.app.SendNotification(trackNotificationID, notification)
+	n.shown = true
+	n.hasIcon = artFile != ""
+}
+
+// Withdraw removes the notification, if any, from the screen
+func (n *TrackNotifier) Withdraw() {
+	n.app.WithdrawNotification(trackNotificationID)
+}
+
+// trackNotificationKey returns a string identifying the given track for notification purposes, including the current
+// song of a stream. Returns an empty string if there's no track
+func trackNotificationKey(track mpd.Attrs) string {
+	if len(track) == 0 {
+		return ""
+	}
+	return track["Id"] + "\n" + track["file"] + "\n" + track["Title"]
+}
+
+// trackNotificationText returns the title and the body of the notification for the given track: its title, and its
+// artist and album, counting in the current song of a stream
+func trackNotificationText(track mpd.Attrs) (title, body string) {
+	a := EnrichStreamAttrs(track)
+	switch {
+	case a["Title"] != "":
+		title = a["Title"]
+	case a["Name"] != "":
+		title = a["Name"]
+	default:
+		title = path.Base(a["file"])
+	}
+	var parts []string
+	for _, s := range []string{a["Artist"], a["Album"]} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return title, strings.Join(parts, " — ")
+}
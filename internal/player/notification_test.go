@@ -0,0 +1,64 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"testing"
+)
+
+func TestTrackNotificationText(t *testing.T) {
+	tests := []struct {
+		name      string
+		track     mpd.Attrs
+		wantTitle string
+		wantBody  string
+	}{
+		{"track",
+			mpd.Attrs{"file": "a/b.flac", "Title": "Waterloo", "Artist": "ABBA", "Album": "Waterloo"},
+			"Waterloo", "ABBA — Waterloo"},
+		{"no album",
+			mpd.Attrs{"file": "a/b.flac", "Title": "Waterloo", "Artist": "ABBA"},
+			"Waterloo", "ABBA"},
+		{"no tags", mpd.Attrs{"file": "a/b.flac"}, "b.flac", ""},
+		{"stream song",
+			mpd.Attrs{"file": "http://radio/", "Name": "Radio", "Title": "ABBA - Waterloo"},
+			"Waterloo", "ABBA — Radio"},
+		{"stream", mpd.Attrs{"file": "http://radio/", "Name": "Radio"}, "Radio", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, body := trackNotificationText(tt.track)
+			if title != tt.wantTitle || body != tt.wantBody {
+				t.Errorf("trackNotificationText() = %q, %q, want %q, %q", title, body, tt.wantTitle, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTrackNotificationKey(t *testing.T) {
+	if key := trackNotificationKey(nil); key != "" {
+		t.Errorf("trackNotificationKey(nil) = %q, want empty", key)
+	}
+	song1 := mpd.Attrs{"Id": "5", "file": "http://radio/", "Title": "ABBA - Waterloo"}
+	song2 := mpd.Attrs{"Id": "5", "file": "http://radio/", "Title": "ABBA - SOS"}
+	if trackNotificationKey(song1) == trackNotificationKey(song2) {
+		t.Errorf("trackNotificationKey() doesn't tell apart songs of a stream")
+	}
+	if trackNotificationKey(song1) != trackNotificationKey(mpd.Attrs{"Id": "5", "file": "http://radio/", "Title": "ABBA - Waterloo", "Bitrate": "128"}) {
+		t.Errorf("trackNotificationKey() depends on the status info")
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	playlistBackupsMax       = 10                    // Maximum number of backups kept per playlist
+	playlistBackupTimeFormat = "20060102-150405.000" // Format of the timestamp in backup file names
+)
+
+// PlaylistBackup represents a snapshot of a stored playlist's contents, made before a destructive operation
+type PlaylistBackup struct {
+	Name string    // Name of the backed up playlist
+	Time time.Time // Moment the backup has been made
+	File string    // Full path of the backup file
+}
+
+// PlaylistBackups manages playlist backup files in a directory
+type PlaylistBackups struct {
+	dir string // Directory holding the backup files
+}
+
+// NewPlaylistBackups creates and returns a new PlaylistBackups instance, storing backups in the user's data directory
+func NewPlaylistBackups() *PlaylistBackups {
+	return &PlaylistBackups{dir: path.Join(glib.GetUserDataDir(), "ymuse", "playlist-backups")}
+}
+
+// Backup writes the given tracks of the named playlist into a new backup file, dropping the oldest backups of the
+// playlist beyond playlistBackupsMax. Empty playlists aren't backed up as there's nothing to lose
+func (b *PlaylistBackups) Backup(name string, attrs []mpd.Attrs) error {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return err
+	}
+
+	// Write out the tracks
+	file, err := os.Create(path.Join(b.dir, playlistBackupFileName(name, time.Now())))
+	if err != nil {
+		return err
+	}
+	err = WriteM3U(file, attrs, "")
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Drop excess backups of the playlist
+	backups, err := b.List()
+	if err != nil {
+		return err
+	}
+	cnt := 0
+	for _, backup := range backups {
+		if backup.Name == name {
+			if cnt++; cnt > playlistBackupsMax {
+				errCheck(os.Remove(backup.File), "Failed to remove playlist backup")
+			}
+		}
+	}
+	return nil
+}
+
+// List returns all available backups, newest first
+func (b *PlaylistBackups) List() ([]PlaylistBackup, error) {
+	files, err := ioutil.ReadDir(b.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var backups []PlaylistBackup
+	for _, f := range files {
+		if name, t, ok := parsePlaylistBackupFileName(f.Name()); ok && !f.IsDir() {
+			backups = append(backups, PlaylistBackup{Name: name, Time: t, File: path.Join(b.dir, f.Name())})
+		}
+	}
+	sort.SliceStable(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// URIs reads the track URIs stored in the backup
+func (p *PlaylistBackup) URIs() ([]string, error) {
+	file, err := os.Open(p.File)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseM3U(file)
+}
+
+// playlistBackupFileName returns the name of the file to back up the named playlist into at the given moment
+func playlistBackupFileName(name string, t time.Time) string {
+	return url.PathEscape(name) + "@" + t.Format(playlistBackupTimeFormat) + ".m3u"
+}
+
+// parsePlaylistBackupFileName extracts the playlist name and the backup moment from a backup file name
+func parsePlaylistBackupFileName(fileName string) (string, time.Time, bool) {
+	s := strings.TrimSuffix(fileName, ".m3u")
+	i := strings.LastIndex(s, "@")
+	if s == fileName || i < 0 {
+		return "", time.Time{}, false
+	}
+	name, err := url.PathUnescape(s[:i])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	t, err := time.ParseInLocation(playlistBackupTimeFormat, s[i+1:], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return name, t, true
+}
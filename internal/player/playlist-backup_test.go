@@ -0,0 +1,93 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_parsePlaylistBackupFileName(t *testing.T) {
+	moment := time.Date(2020, 5, 17, 13, 45, 12, 345000000, time.Local)
+	for _, name := range []string{"Favorites", "Rock/Metal @ home", "100% jazz", "Ünïcödé"} {
+		t.Run(name, func(t *testing.T) {
+			gotName, gotTime, ok := parsePlaylistBackupFileName(playlistBackupFileName(name, moment))
+			if !ok || gotName != name || !gotTime.Equal(moment) {
+				t.Errorf("round trip = (%q, %v, %v), want (%q, %v, true)", gotName, gotTime, ok, name, moment)
+			}
+		})
+	}
+	for _, fileName := range []string{"Favorites.m3u", "Favorites@yesterday.m3u", "Favorites@20200517-134512.345.txt"} {
+		t.Run(fileName, func(t *testing.T) {
+			if _, _, ok := parsePlaylistBackupFileName(fileName); ok {
+				t.Errorf("parsePlaylistBackupFileName(%q) succeeded, want failure", fileName)
+			}
+		})
+	}
+}
+
+func TestPlaylistBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-backups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b := &PlaylistBackups{dir: dir}
+
+	// No backups initially
+	if backups, err := b.List(); err != nil || len(backups) != 0 {
+		t.Fatalf("List() = (%v, %v), want no backups", backups, err)
+	}
+
+	// Empty playlists aren't backed up
+	if err := b.Backup("Empty", nil); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	// Make more backups than are kept
+	attrs := []mpd.Attrs{{"file": "a/1.mp3"}, {"file": "http://radio/stream"}}
+	for i := 0; i < playlistBackupsMax+2; i++ {
+		if err := b.Backup("Mix", attrs); err != nil {
+			t.Fatalf("Backup() error = %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := b.Backup("Other", attrs[:1]); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	backups, err := b.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(backups) != playlistBackupsMax+1 {
+		t.Fatalf("List() returned %d backups, want %d", len(backups), playlistBackupsMax+1)
+	}
+	if backups[0].Name != "Other" {
+		t.Errorf("newest backup is of %q, want \"Other\"", backups[0].Name)
+	}
+	uris, err := backups[1].URIs()
+	if err != nil {
+		t.Fatalf("URIs() error = %v", err)
+	}
+	if want := []string{"a/1.mp3", "http://radio/stream"}; !reflect.DeepEqual(uris, want) {
+		t.Errorf("URIs() = %v, want %v", uris, want)
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/util"
+	"io"
+	"math"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xspfPlaylist is the part of an XSPF playlist holding track locations
+type xspfPlaylist struct {
+	Tracks []struct {
+		Locations []string `xml:"location"`
+	} `xml:"trackList>track"`
+}
+
+// ParsePlaylist reads the entries (file paths or URLs) of an M3U, PLS or XSPF playlist. The format is determined by the
+// extension of the given file name
+func ParsePlaylist(fileName string, r io.Reader) ([]string, error) {
+	switch ext := strings.ToLower(path.Ext(fileName)); ext {
+	case ".m3u", ".m3u8":
+		return parseM3U(r)
+	case ".pls":
+		return parsePLS(r)
+	case ".xspf":
+		return parseXSPF(r)
+	default:
+		return nil, fmt.Errorf("unsupported playlist format: %s", ext)
+	}
+}
+
+// parseM3U reads the entries of an M3U playlist, skipping comments and extended info
+func parseM3U(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Strip the UTF-8 byte order mark, if any
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parsePLS reads the entries of a PLS playlist, ordered by their numbers
+func parsePLS(r io.Reader) ([]string, error) {
+	files := make(map[int]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Only "FileN=..." lines are of interest
+		key, value := scanner.Text(), ""
+		if i := strings.Index(key, "="); i >= 0 {
+			key, value = strings.TrimSpace(key[:i]), strings.TrimSpace(key[i+1:])
+		}
+		if len(key) > 4 && strings.EqualFold(key[:4], "file") && value != "" {
+			if n, err := strconv.Atoi(key[4:]); err == nil {
+				files[n] = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Order the entries by their numbers
+	nums := make([]int, 0, len(files))
+	for n := range files {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	entries := make([]string, len(nums))
+	for i, n := range nums {
+		entries[i] = files[n]
+	}
+	return entries, nil
+}
+
+// parseXSPF reads the entries of an XSPF playlist, taking the first location of every track. Locations are URIs, so
+// file:// URLs are converted into paths
+func parseXSPF(r io.Reader) ([]string, error) {
+	var pl xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&pl); err != nil {
+		return nil, err
+	}
+	var entries []string
+	for _, t := range pl.Tracks {
+		if len(t.Locations) == 0 {
+			continue
+		}
+		loc := strings.TrimSpace(t.Locations[0])
+		if u, err := url.Parse(loc); err == nil && (u.Scheme == "" || u.Scheme == "file") {
+			loc = u.Path
+		}
+		if loc != "" {
+			entries = append(entries, loc)
+		}
+	}
+	return entries, nil
+}
+
+// MapPlaylistEntries maps the given playlist entries onto MPD URIs. Entries are resolved against the directory of the
+// playlist file (baseDir) and the local music directory; those that still don't match any of the known track URIs are
+// matched by the longest trailing part of their path, which handles playlists made on other devices. Streams are kept
+// as is. Returns the URIs and the entries that couldn't be matched
+func MapPlaylistEntries(entries []string, baseDir, musicDir string, known map[string]bool) (uris, unmatched []string) {
+	for _, e := range entries {
+		if util.IsStreamURI(e) {
+			uris = append(uris, e)
+			continue
+		}
+		if uri, ok := mapPlaylistEntry(e, baseDir, musicDir, known); ok {
+			uris = append(uris, uri)
+		} else {
+			unmatched = append(unmatched, e)
+		}
+	}
+	return
+}
+
+// mapPlaylistEntry maps a single playlist entry onto a known MPD URI
+func mapPlaylistEntry(entry, baseDir, musicDir string, known map[string]bool) (string, bool) {
+	// Convert file:// URLs into paths, and Windows separators into slashes
+	p := entry
+	if u, err := url.Parse(p); err == nil && u.Scheme == "file" {
+		p = u.Path
+	}
+	p = strings.ReplaceAll(p, `\`, "/")
+
+	// Try the path as a local one, then as relative to the music directory
+	var candidates []string
+	local := p
+	if !filepath.IsAbs(local) && baseDir != "" {
+		local = filepath.Join(baseDir, local)
+	}
+	if uri, ok := util.LocalPathToURI(musicDir, local); ok {
+		candidates = append(candidates, uri)
+	}
+	if !path.IsAbs(p) {
+		candidates = append(candidates, path.Clean(p))
+	}
+	for _, c := range candidates {
+		if known[c] {
+			return c, true
+		}
+	}
+
+	// Try ever shorter trailing parts of the path
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i := range parts {
+		if s := strings.Join(parts[i:], "/"); known[s] {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// WriteM3U writes the given playlist tracks in the extended M3U format. The path prefix, if any, is prepended to track
+// URIs (but not to stream URLs), so that the playlist refers to the files where the player it's meant for finds them
+func WriteM3U(w io.Writer, attrs []mpd.Attrs, prefix string) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") && !strings.HasSuffix(prefix, `\`) {
+		prefix += "/"
+	}
+
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintln(bw, "#EXTM3U")
+	for _, a := range attrs {
+		uri := a["file"]
+		if uri == "" {
+			continue
+		}
+
+		// Write the track's length (-1 if unknown) and display title
+		length := -1
+		if d := util.ParseFloatDef(a["duration"], -1); d >= 0 {
+			length = int(math.Round(d))
+		}
+		title := path.Base(uri)
+		if t := a["Title"]; t != "" {
+			title = t
+			if artist := a["Artist"]; artist != "" {
+				title = artist + " - " + t
+			}
+		}
+		_, _ = fmt.Fprintf(bw, "#EXTINF:%d,%s\n", length, title)
+
+		// Write the track's location
+		if !util.IsStreamURI(uri) {
+			uri = prefix + uri
+		}
+		_, _ = fmt.Fprintln(bw, uri)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,128 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bytes"
+	"github.com/fhs/gompd/v2/mpd"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePlaylist(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		data     string
+		want     []string
+		wantErr  bool
+	}{
+		{"m3u", "a.M3U", "\uFEFF#EXTM3U\n#EXTINF:168,ABBA - Waterloo\nABBA/01.flac\n\n  http://example.com/stream  \r\n", []string{"ABBA/01.flac", "http://example.com/stream"}, false},
+		{"pls", "a.pls", "[playlist]\nFile2=b.mp3\nTitle2=B\nfile1 = a.mp3\nNumberOfEntries=2\nVersion=2\n", []string{"a.mp3", "b.mp3"}, false},
+		{"xspf", "a.xspf", `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <trackList>
+    <track><location>file:///home/user/Music/AC%20DC/01.flac</location><title>One</title></track>
+    <track><title>No location</title></track>
+    <track><location>Misc/intro.mp3</location></track>
+    <track><location>http://example.com/stream</location></track>
+  </trackList>
+</playlist>`, []string{"/home/user/Music/AC DC/01.flac", "Misc/intro.mp3", "http://example.com/stream"}, false},
+		{"bad xspf", "a.xspf", "<playlist", nil, true},
+		{"unsupported", "a.txt", "a.mp3", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePlaylist(tt.fileName, strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePlaylist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePlaylist() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapPlaylistEntries(t *testing.T) {
+	known := map[string]bool{
+		"ABBA/Waterloo/01.flac": true,
+		"Misc/intro.mp3":        true,
+		"Queen/Jazz/02.flac":    true,
+	}
+	entries := []string{
+		"/home/user/Music/ABBA/Waterloo/01.flac",
+		"../Misc/intro.mp3",
+		"file:///sdcard/Music/Queen/Jazz/02.flac",
+		`D:\Music\ABBA\Waterloo\01.flac`,
+		"Misc/intro.mp3",
+		"http://example.com/stream",
+		"/home/user/Music/Other/missing.mp3",
+	}
+	uris, unmatched := MapPlaylistEntries(entries, "/home/user/Music/Playlists", "/home/user/Music", known)
+	wantURIs := []string{
+		"ABBA/Waterloo/01.flac",
+		"Misc/intro.mp3",
+		"Queen/Jazz/02.flac",
+		"ABBA/Waterloo/01.flac",
+		"Misc/intro.mp3",
+		"http://example.com/stream",
+	}
+	if !reflect.DeepEqual(uris, wantURIs) {
+		t.Errorf("MapPlaylistEntries() uris = %v, want %v", uris, wantURIs)
+	}
+	if want := []string{"/home/user/Music/Other/missing.mp3"}; !reflect.DeepEqual(unmatched, want) {
+		t.Errorf("MapPlaylistEntries() unmatched = %v, want %v", unmatched, want)
+	}
+}
+
+func TestWriteM3U(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"file": "ABBA/Waterloo/01.flac", "Artist": "ABBA", "Title": "Waterloo", "duration": "168.4"},
+		{"file": "http://example.com/stream"},
+		{"file": "Misc/intro.mp3", "Title": "Intro", "duration": "12.5"},
+	}
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{"no prefix", "", "#EXTM3U\n" +
+			"#EXTINF:168,ABBA - Waterloo\nABBA/Waterloo/01.flac\n" +
+			"#EXTINF:-1,stream\nhttp://example.com/stream\n" +
+			"#EXTINF:13,Intro\nMisc/intro.mp3\n"},
+		{"prefix", "/sdcard/Music", "#EXTM3U\n" +
+			"#EXTINF:168,ABBA - Waterloo\n/sdcard/Music/ABBA/Waterloo/01.flac\n" +
+			"#EXTINF:-1,stream\nhttp://example.com/stream\n" +
+			"#EXTINF:13,Intro\n/sdcard/Music/Misc/intro.mp3\n"},
+		{"prefix with separator", `C:\Music\`, "#EXTM3U\n" +
+			"#EXTINF:168,ABBA - Waterloo\nC:\\Music\\ABBA/Waterloo/01.flac\n" +
+			"#EXTINF:-1,stream\nhttp://example.com/stream\n" +
+			"#EXTINF:13,Intro\nC:\\Music\\Misc/intro.mp3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteM3U(&buf, attrs, tt.prefix); err != nil {
+				t.Fatalf("WriteM3U() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteM3U() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/util"
+	"sync"
+)
+
+// PlaylistStats holds the number of tracks in a stored playlist and their total duration
+type PlaylistStats struct {
+	Tracks   int     // Number of tracks
+	Duration float64 // Total duration in seconds
+}
+
+// Details returns a human-readable summary of the stats
+func (s PlaylistStats) Details() string {
+	return fmt.Sprintf(util.LocalN("%d track, %s", "%d tracks, %s", s.Tracks), s.Tracks, util.FormatSeconds(s.Duration))
+}
+
+// computePlaylistStats calculates the stats of a playlist given its tracks
+func computePlaylistStats(attrs []mpd.Attrs) PlaylistStats {
+	s := PlaylistStats{Tracks: len(attrs)}
+	for _, a := range attrs {
+		s.Duration += util.ParseFloatDef(a["duration"], 0)
+	}
+	return s
+}
+
+// playlistStatsEntry is a cached PlaylistStats along with the playlist's modification time it's valid for
+type playlistStatsEntry struct {
+	modified string
+	stats    PlaylistStats
+}
+
+// PlaylistStatsCache computes stats of stored playlists on demand and caches them until the playlists change. It can
+// be used from any goroutine
+type PlaylistStatsCache struct {
+	connector *Connector                    // Connector to load the playlists through
+	entries   map[string]playlistStatsEntry // Cached stats, keyed by playlist name
+	mutex     sync.Mutex
+}
+
+// NewPlaylistStatsCache creates and returns a new, empty PlaylistStatsCache instance
+func NewPlaylistStatsCache(connector *Connector) *PlaylistStatsCache {
+	return &PlaylistStatsCache{connector: connector, entries: make(map[string]playlistStatsEntry)}
+}
+
+// Stats returns the stats of the playlist with the given name and last modification time (as reported by MPD), loading
+// the playlist if its stats aren't cached yet or it's been modified since. Returns false if the stats are unavailable
+func (x *PlaylistStatsCache) Stats(name, modified string) (PlaylistStats, bool) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	// Check the cache first
+	if e, ok := x.entries[name]; ok && e.modified == modified {
+		return e.stats, true
+	}
+
+	// Load the playlist's tracks
+	var attrs []mpd.Attrs
+	var err error
+	loaded := false
+	x.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.PlaylistContents(name)
+		loaded = true
+	})
+	if !loaded || errCheck(err, "PlaylistStatsCache.Stats(): PlaylistContents() failed") {
+		return PlaylistStats{}, false
+	}
+	stats := computePlaylistStats(attrs)
+	x.entries[name] = playlistStatsEntry{modified: modified, stats: stats}
+	return stats, true
+}
+
+// Invalidate drops all cached stats
+func (x *PlaylistStatsCache) Invalidate() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.entries = make(map[string]playlistStatsEntry)
+}
@@ -0,0 +1,39 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"testing"
+)
+
+func Test_computePlaylistStats(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"file": "a/1.flac", "duration": "180"},
+		{"file": "http://example.com/stream"},
+		{"file": "a/2.flac", "duration": "200.5"},
+	}
+	want := PlaylistStats{Tracks: 3, Duration: 380.5}
+	if got := computePlaylistStats(attrs); got != want {
+		t.Errorf("computePlaylistStats() = %v, want %v", got, want)
+	}
+	if got := computePlaylistStats(nil); got != (PlaylistStats{}) {
+		t.Errorf("computePlaylistStats(nil) = %v, want zero stats", got)
+	}
+	if got := want.Details(); got != "3 tracks, 6:20" {
+		t.Errorf("Details() = %v, want %v", got, "3 tracks, 6:20")
+	}
+}
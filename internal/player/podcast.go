@@ -0,0 +1,212 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/util"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// podcastDateFormats lists the date formats encountered in podcast feeds, RFC 822 variants first
+var podcastDateFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// PodcastEpisode represents a single episode of a podcast
+type PodcastEpisode struct {
+	Title     string    // Episode title
+	URI       string    // URI of the episode's audio file
+	Published time.Time // Publication moment, zero if unknown
+	Duration  float64   // Duration in seconds, 0 if unknown
+}
+
+// PodcastFeed represents the contents of a podcast's RSS feed
+type PodcastFeed struct {
+	Title    string           // Podcast title
+	Episodes []PodcastEpisode // Episodes having audio, the newest first
+}
+
+// rssFeed is the subset of an RSS 2.0 document used for parsing podcast feeds
+type rssFeed struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Items []struct {
+			Title     string `xml:"title"`
+			PubDate   string `xml:"pubDate"`
+			Duration  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+			Enclosure struct {
+				URL  string `xml:"url,attr"`
+				Type string `xml:"type,attr"`
+			} `xml:"enclosure"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// FetchPodcastFeed downloads and parses the podcast feed at the given URL
+func FetchPodcastFeed(url string) (*PodcastFeed, error) {
+	resp, err := util.HTTPGet(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ParsePodcastFeed(resp.Body)
+}
+
+// ParsePodcastFeed parses an RSS podcast feed, skipping items that have no audio enclosure
+func ParsePodcastFeed(r io.Reader) (*PodcastFeed, error) {
+	var rss rssFeed
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := decoder.Decode(&rss); err != nil {
+		return nil, err
+	}
+
+	feed := &PodcastFeed{Title: strings.TrimSpace(rss.Channel.Title)}
+	for _, item := range rss.Channel.Items {
+		uri := strings.TrimSpace(item.Enclosure.URL)
+		if uri == "" || item.Enclosure.Type != "" && !strings.HasPrefix(item.Enclosure.Type, "audio/") {
+			continue
+		}
+		feed.Episodes = append(feed.Episodes, PodcastEpisode{
+			Title:     util.Default(uri, strings.TrimSpace(item.Title)),
+			URI:       uri,
+			Published: parsePodcastDate(item.PubDate),
+			Duration:  parsePodcastDuration(item.Duration),
+		})
+	}
+
+	// Make sure the newest episodes come first
+	sort.SliceStable(feed.Episodes, func(i, j int) bool {
+		return feed.Episodes[i].Published.After(feed.Episodes[j].Published)
+	})
+	return feed, nil
+}
+
+// parsePodcastDate parses a publication date of an episode, returning zero time if it can't be parsed
+func parsePodcastDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, f := range podcastDateFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parsePodcastDuration parses an episode duration given either in seconds or as [[HH:]MM:]SS, returning 0 if it can't
+// be parsed
+func parsePodcastDuration(s string) float64 {
+	secs := 0.0
+	for _, part := range strings.Split(strings.TrimSpace(s), ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0
+		}
+		secs = secs*60 + v
+	}
+	return secs
+}
+
+// PodcastState keeps track of played podcast episodes and stores them in a file
+type PodcastState struct {
+	fileName string          // Full path of the state file
+	played   map[string]bool // Set of URIs of the played episodes
+}
+
+// NewPodcastState creates a new PodcastState instance and loads the previously stored state
+func NewPodcastState() *PodcastState {
+	s := &PodcastState{fileName: path.Join(glib.GetUserDataDir(), "ymuse", "podcasts-played.json")}
+	s.load()
+	return s
+}
+
+// IsPlayed returns whether the episode with the given URI has been played
+func (s *PodcastState) IsPlayed(uri string) bool {
+	return s.played[uri]
+}
+
+// SetPlayed marks the episodes with the given URIs as played or unplayed, and stores the state if it's changed
+func (s *PodcastState) SetPlayed(played bool, uris ...string) error {
+	changed := false
+	for _, uri := range uris {
+		if s.played[uri] != played {
+			if played {
+				s.played[uri] = true
+			} else {
+				delete(s.played, uri)
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// load reads the previously stored state from the state file
+func (s *PodcastState) load() {
+	s.played = make(map[string]bool)
+	data, err := ioutil.ReadFile(s.fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errCheck(err, "Couldn't read podcast state file")
+		}
+		return
+	}
+	var uris []string
+	if errCheck(json.Unmarshal(data, &uris), "json.Unmarshal() failed") {
+		return
+	}
+	for _, uri := range uris {
+		s.played[uri] = true
+	}
+}
+
+// save writes out the state into the state file
+func (s *PodcastState) save() error {
+	uris := make([]string, 0, len(s.played))
+	for uri := range s.played {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	data, err := json.MarshalIndent(uris, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(path.Dir(s.fileName), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.fileName, data, 0600)
+}
@@ -0,0 +1,145 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testPodcastFeed = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title> Test Cast </title>
+    <item>
+      <title>Episode 1</title>
+      <pubDate>Mon, 4 May 2020 10:00:00 +0000</pubDate>
+      <itunes:duration>1:02:03</itunes:duration>
+      <enclosure url="http://cast.example.com/1.mp3" type="audio/mpeg" length="123"/>
+    </item>
+    <item>
+      <title>Video episode</title>
+      <pubDate>Tue, 05 May 2020 10:00:00 +0000</pubDate>
+      <enclosure url="http://cast.example.com/v.mp4" type="video/mp4"/>
+    </item>
+    <item>
+      <title>Announcement without audio</title>
+    </item>
+    <item>
+      <title></title>
+      <pubDate>Wed, 06 May 2020 10:00:00 +0000</pubDate>
+      <itunes:duration>95</itunes:duration>
+      <enclosure url="http://cast.example.com/2.mp3"/>
+    </item>
+  </channel>
+</rss>`
+
+func TestParsePodcastFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testPodcastFeed))
+	}))
+	defer server.Close()
+
+	got, err := FetchPodcastFeed(server.URL + "/feed.xml")
+	if err != nil {
+		t.Fatalf("FetchPodcastFeed() error = %v", err)
+	}
+	want := &PodcastFeed{
+		Title: "Test Cast",
+		Episodes: []PodcastEpisode{
+			{
+				Title:     "http://cast.example.com/2.mp3",
+				URI:       "http://cast.example.com/2.mp3",
+				Published: time.Date(2020, 5, 6, 10, 0, 0, 0, time.UTC),
+				Duration:  95,
+			},
+			{
+				Title:     "Episode 1",
+				URI:       "http://cast.example.com/1.mp3",
+				Published: time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC),
+				Duration:  3723,
+			},
+		},
+	}
+	for i := range got.Episodes {
+		got.Episodes[i].Published = got.Episodes[i].Published.UTC()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FetchPodcastFeed() = %v, want %v", got, want)
+	}
+
+	// Failing requests
+	if _, err := FetchPodcastFeed(server.URL + "/missing.xml"); err == nil {
+		t.Error("FetchPodcastFeed() succeeded for a missing feed, want error")
+	}
+}
+
+func Test_parsePodcastDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"", 0},
+		{"42", 42},
+		{"03:15", 195},
+		{"1:00:01", 3601},
+		{"1:xx", 0},
+		{"-5", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := parsePodcastDuration(tt.s); got != tt.want {
+				t.Errorf("parsePodcastDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodcastState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-podcasts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := path.Join(dir, "ymuse", "podcasts-played.json")
+	s := &PodcastState{fileName: fileName}
+	s.load()
+
+	// Mark some episodes played, then one of them unplayed
+	if err := s.SetPlayed(true, "http://a/1.mp3", "http://a/2.mp3"); err != nil {
+		t.Fatalf("SetPlayed() error = %v", err)
+	}
+	if err := s.SetPlayed(false, "http://a/1.mp3"); err != nil {
+		t.Fatalf("SetPlayed() error = %v", err)
+	}
+
+	// The state is reloaded from the file
+	s = &PodcastState{fileName: fileName}
+	s.load()
+	if s.IsPlayed("http://a/1.mp3") || !s.IsPlayed("http://a/2.mp3") || s.IsPlayed("http://a/3.mp3") {
+		t.Errorf("IsPlayed() gives wrong results for %v", s.played)
+	}
+}
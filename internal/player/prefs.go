@@ -22,6 +22,7 @@ import (
 	"github.com/yktoo/ymuse/internal/config"
 	"github.com/yktoo/ymuse/internal/generated"
 	"github.com/yktoo/ymuse/internal/util"
+	"strings"
 	"sync"
 	"time"
 )
@@ -35,7 +36,7 @@ type queueCol struct {
 // PrefsDialog represents the preferences dialog
 type PrefsDialog struct {
 	PreferencesDialog *gtk.Dialog
-	// General page widgets
+	// Connection page widgets
 	MpdNetworkComboBox          *gtk.ComboBoxText
 	MpdPathEntry                *gtk.Entry
 	MpdPathLabel                *gtk.Label
@@ -48,39 +49,91 @@ type PrefsDialog struct {
 	MpdPasswordEntry            *gtk.Entry
 	MpdAutoConnectCheckButton   *gtk.CheckButton
 	MpdAutoReconnectCheckButton *gtk.CheckButton
+	MpdMusicDirEntry            *gtk.Entry
+	MpdAlbumArtURLEntry         *gtk.Entry
+	MpdAlbumArtCheckButton      *gtk.CheckButton
+	MpdHTTPStreamURLEntry       *gtk.Entry
 	// Interface page widgets
-	QueueToolbarCheckButton            *gtk.CheckButton
-	LibraryDefaultReplaceRadioButton   *gtk.RadioButton
-	LibraryDefaultAppendRadioButton    *gtk.RadioButton
-	PlaylistsDefaultReplaceRadioButton *gtk.RadioButton
-	PlaylistsDefaultAppendRadioButton  *gtk.RadioButton
-	StreamsDefaultReplaceRadioButton   *gtk.RadioButton
-	StreamsDefaultAppendRadioButton    *gtk.RadioButton
+	QueueToolbarCheckButton           *gtk.CheckButton
+	QueueAlbumArtCheckButton          *gtk.CheckButton
+	LibraryDoubleClickComboBox        *gtk.ComboBoxText
+	LibraryMiddleClickComboBox        *gtk.ComboBoxText
+	LibraryShowModifiedCheckButton    *gtk.CheckButton
+	LibraryRecentDaysAdjustment       *gtk.Adjustment
+	LibraryExcludeEntry               *gtk.Entry
+	SearchIgnoreDiacriticsCheckButton *gtk.CheckButton
+	SortIgnoreArticlesCheckButton     *gtk.CheckButton
+	PlaylistsDoubleClickComboBox      *gtk.ComboBoxText
+	PlaylistsMiddleClickComboBox      *gtk.ComboBoxText
+	PlaylistsExportPrefixEntry        *gtk.Entry
+	PlaylistsShowModifiedCheckButton  *gtk.CheckButton
+	StreamsDoubleClickComboBox        *gtk.ComboBoxText
+	StreamsMiddleClickComboBox        *gtk.ComboBoxText
+	TrayIconCheckButton               *gtk.CheckButton
+	CloseToTrayCheckButton            *gtk.CheckButton
+	StartHiddenCheckButton            *gtk.CheckButton
+	AutostartCheckButton              *gtk.CheckButton
+	KeymapComboBox                    *gtk.ComboBoxText
+	ThemeComboBox                     *gtk.ComboBoxText
+	CustomCSSFileEntry                *gtk.Entry
+	GSettingsCheckButton              *gtk.CheckButton
+	// Queue columns page widgets
+	ColumnsListBox *gtk.ListBox
 	// Player page widgets
+	PlayerAutoResumeCheckButton   *gtk.CheckButton
+	PlayerTitleTemplateTextBuffer *gtk.TextBuffer
+	// Covers page widgets
 	PlayerShowAlbumArtTracksCheckButton  *gtk.CheckButton
 	PlayerShowAlbumArtStreamsCheckButton *gtk.CheckButton
-	PlayerTitleTemplateTextBuffer        *gtk.TextBuffer
-	// Columns page widgets
-	ColumnsListBox *gtk.ListBox
+	PlayerAlbumArtFilesEntry             *gtk.Entry
+	PlayerAlbumArtOnlineCheckButton      *gtk.CheckButton
+	AlbumArtCacheSizeAdjustment          *gtk.Adjustment
+	AlbumArtCacheUsageLabel              *gtk.Label
+	AlbumArtCacheClearButton             *gtk.Button
+	// Integrations page widgets
+	ListenBrainzTokenEntry          *gtk.Entry
+	LastFmAPIKeyEntry               *gtk.Entry
+	LastFmSecretEntry               *gtk.Entry
+	LastFmStatusLabel               *gtk.Label
+	LastFmLoginButton               *gtk.Button
+	LastFmLogoutButton              *gtk.Button
+	PlayerNotifyCheckButton         *gtk.CheckButton
+	PlayerNotifyFocusedCheckButton  *gtk.CheckButton
+	PlayerMediaKeysCheckButton      *gtk.CheckButton
+	PlayerInhibitCheckButton        *gtk.CheckButton
+	PlayerPauseOnLockCheckButton    *gtk.CheckButton
+	PlayerResumeOnUnlockCheckButton *gtk.CheckButton
+	PlayerPauseOnSuspendCheckButton *gtk.CheckButton
 
 	// Whether the dialog is initialised
 	initialised bool
+	// Whether the dialog has been closed
+	closed bool
+	// Whether logging in to Last.fm is in progress
+	lastFmLoggingIn bool
 	// Columns, in the same order as in the ColumnsListBox
 	queueColumns []queueCol
 	// Timer for delayed player setting change callback invocation
 	playerSettingChangeTimer *time.Timer
 	playerSettingChangeMutex sync.Mutex
 	// Callbacks
-	onQueueColumnsChanged  func()
-	onPlayerSettingChanged func()
+	onQueueColumnsChanged       func()
+	onLibrarySettingChanged     func()
+	onPlayerSettingChanged      func()
+	onIntegrationSettingChanged func()
+	onAppearanceChanged         func()
 }
 
-// PreferencesDialog creates, shows and disposes of a Preferences dialog instance
-func PreferencesDialog(parent gtk.IWindow, onMpdReconnect, onQueueColumnsChanged, onPlayerSettingChanged func()) {
+// PreferencesDialog creates, shows and disposes of a Preferences dialog instance. Changes are written to the config
+// and applied through the callbacks as soon as they're made
+func PreferencesDialog(parent gtk.IWindow, onMpdReconnect, onQueueColumnsChanged, onLibrarySettingChanged, onPlayerSettingChanged, onIntegrationSettingChanged, onAppearanceChanged func()) {
 	// Create the dialog
 	d := &PrefsDialog{
-		onQueueColumnsChanged:  onQueueColumnsChanged,
-		onPlayerSettingChanged: onPlayerSettingChanged,
+		onQueueColumnsChanged:       onQueueColumnsChanged,
+		onLibrarySettingChanged:     onLibrarySettingChanged,
+		onPlayerSettingChanged:      onPlayerSettingChanged,
+		onIntegrationSettingChanged: onIntegrationSettingChanged,
+		onAppearanceChanged:         onAppearanceChanged,
 	}
 
 	// Load the dialog layout and map the widgets
@@ -109,12 +162,16 @@ func PreferencesDialog(parent gtk.IWindow, onMpdReconnect, onQueueColumnsChanged
 		"on_PreferencesDialog_map":            d.onMap,
 		"on_Setting_change":                   d.onSettingChange,
 		"on_MpdReconnect":                     onMpdReconnect,
+		"on_LastFmLoginButton_clicked":        d.onLastFmLogin,
+		"on_LastFmLogoutButton_clicked":       d.onLastFmLogout,
+		"on_AlbumArtCacheClearButton_clicked": d.onAlbumArtCacheClear,
 		"on_ColumnMoveUpToolButton_clicked":   d.onColumnMoveUp,
 		"on_ColumnMoveDownToolButton_clicked": d.onColumnMoveDown,
 	})
 
 	// Run the dialog
 	d.PreferencesDialog.Run()
+	d.closed = true
 }
 
 func (d *PrefsDialog) onMap() {
@@ -122,7 +179,7 @@ func (d *PrefsDialog) onMap() {
 
 	// Initialise widgets
 	cfg := config.GetConfig()
-	// General page
+	// Connection page
 	d.MpdNetworkComboBox.SetActiveID(cfg.MpdNetwork)
 	d.MpdPathEntry.SetText(cfg.MpdSocketPath)
 	d.MpdHostEntry.SetText(cfg.MpdHost)
@@ -130,20 +187,64 @@ func (d *PrefsDialog) onMap() {
 	d.MpdPasswordEntry.SetText(cfg.MpdPassword)
 	d.MpdAutoConnectCheckButton.SetActive(cfg.MpdAutoConnect)
 	d.MpdAutoReconnectCheckButton.SetActive(cfg.MpdAutoReconnect)
-	d.updateGeneralWidgets()
+	d.MpdMusicDirEntry.SetText(cfg.MpdMusicDir)
+	d.MpdAlbumArtURLEntry.SetText(cfg.MpdAlbumArtURL)
+	d.MpdAlbumArtCheckButton.SetActive(cfg.MpdAlbumArt)
+	d.MpdHTTPStreamURLEntry.SetText(cfg.MpdHTTPStreamURL)
+	d.updateConnectionWidgets()
 	// Interface page
 	d.QueueToolbarCheckButton.SetActive(cfg.QueueToolbar)
-	d.LibraryDefaultReplaceRadioButton.SetActive(cfg.TrackDefaultReplace)
-	d.LibraryDefaultAppendRadioButton.SetActive(!cfg.TrackDefaultReplace)
-	d.PlaylistsDefaultReplaceRadioButton.SetActive(cfg.PlaylistDefaultReplace)
-	d.PlaylistsDefaultAppendRadioButton.SetActive(!cfg.PlaylistDefaultReplace)
-	d.StreamsDefaultReplaceRadioButton.SetActive(cfg.StreamDefaultReplace)
-	d.StreamsDefaultAppendRadioButton.SetActive(!cfg.StreamDefaultReplace)
+	d.QueueAlbumArtCheckButton.SetActive(cfg.QueueAlbumArt)
+	d.LibraryDoubleClickComboBox.SetActiveID(cfg.TrackDoubleClick)
+	d.LibraryMiddleClickComboBox.SetActiveID(cfg.TrackMiddleClick)
+	d.LibraryShowModifiedCheckButton.SetActive(cfg.LibraryShowModified)
+	d.LibraryRecentDaysAdjustment.SetValue(float64(cfg.LibraryRecentDays))
+	d.LibraryExcludeEntry.SetText(strings.Join(cfg.LibraryExcludePatterns, "; "))
+	d.SearchIgnoreDiacriticsCheckButton.SetActive(cfg.SearchIgnoreDiacritics)
+	d.SortIgnoreArticlesCheckButton.SetActive(cfg.SortIgnoreArticles)
+	d.PlaylistsDoubleClickComboBox.SetActiveID(cfg.PlaylistDoubleClick)
+	d.PlaylistsMiddleClickComboBox.SetActiveID(cfg.PlaylistMiddleClick)
+	d.PlaylistsExportPrefixEntry.SetText(cfg.PlaylistExportPrefix)
+	d.PlaylistsShowModifiedCheckButton.SetActive(cfg.PlaylistShowModified)
+	d.StreamsDoubleClickComboBox.SetActiveID(cfg.StreamDoubleClick)
+	d.StreamsMiddleClickComboBox.SetActiveID(cfg.StreamMiddleClick)
+	d.TrayIconCheckButton.SetActive(cfg.TrayIcon)
+	d.CloseToTrayCheckButton.SetActive(cfg.CloseToTray)
+	d.CloseToTrayCheckButton.SetSensitive(cfg.TrayIcon)
+	d.StartHiddenCheckButton.SetActive(cfg.StartHidden)
+	d.StartHiddenCheckButton.SetSensitive(cfg.TrayIcon)
+	d.AutostartCheckButton.SetActive(config.AutostartEnabled())
+	d.KeymapComboBox.SetActiveID(cfg.KeymapProfile)
+	d.ThemeComboBox.SetActiveID(cfg.Theme)
+	d.CustomCSSFileEntry.SetText(cfg.CustomCSSFile)
+	d.GSettingsCheckButton.SetActive(config.GSettingsEnabled())
+	d.GSettingsCheckButton.SetSensitive(config.GSettingsAvailable())
+	// Queue columns page
+	d.populateColumns()
+	// Player page
+	d.PlayerAutoResumeCheckButton.SetActive(cfg.PlayerAutoResume)
+	d.PlayerTitleTemplateTextBuffer.SetText(cfg.PlayerTitleTemplate)
+	// Covers page
 	d.PlayerShowAlbumArtTracksCheckButton.SetActive(cfg.PlayerAlbumArtTracks)
 	d.PlayerShowAlbumArtStreamsCheckButton.SetActive(cfg.PlayerAlbumArtStreams)
-	d.PlayerTitleTemplateTextBuffer.SetText(cfg.PlayerTitleTemplate)
-	// Columns page
-	d.populateColumns()
+	d.PlayerAlbumArtFilesEntry.SetText(strings.Join(cfg.PlayerAlbumArtFiles, "; "))
+	d.PlayerAlbumArtOnlineCheckButton.SetActive(cfg.PlayerAlbumArtOnline)
+	d.AlbumArtCacheSizeAdjustment.SetValue(float64(cfg.AlbumArtCacheSize))
+	d.updateAlbumArtCacheWidgets()
+	// Integrations page
+	d.ListenBrainzTokenEntry.SetText(cfg.ListenBrainzToken)
+	d.LastFmAPIKeyEntry.SetText(cfg.LastFmAPIKey)
+	d.LastFmSecretEntry.SetText(cfg.LastFmSecret)
+	d.updateLastFmWidgets()
+	d.PlayerNotifyCheckButton.SetActive(cfg.NotifyTrackChange)
+	d.PlayerNotifyFocusedCheckButton.SetActive(cfg.NotifySuppressFocused)
+	d.PlayerNotifyFocusedCheckButton.SetSensitive(cfg.NotifyTrackChange)
+	d.PlayerMediaKeysCheckButton.SetActive(cfg.MediaKeys)
+	d.PlayerInhibitCheckButton.SetActive(cfg.InhibitSuspend)
+	d.PlayerPauseOnLockCheckButton.SetActive(cfg.PauseOnLock)
+	d.PlayerResumeOnUnlockCheckButton.SetActive(cfg.ResumeOnUnlock)
+	d.PlayerResumeOnUnlockCheckButton.SetSensitive(cfg.PauseOnLock)
+	d.PlayerPauseOnSuspendCheckButton.SetActive(cfg.PauseOnSuspend)
 	d.initialised = true
 }
 
@@ -266,6 +367,17 @@ func (d *PrefsDialog) notifyColumnsChanged() {
 	d.onQueueColumnsChanged()
 }
 
+// parsePatternList splits the given semicolon-separated list of patterns, trimming whitespace and skipping empty ones
+func parsePatternList(s string) []string {
+	var result []string
+	for _, p := range strings.Split(s, ";") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
 // onColumnMoveUp is a signal handler for the Move up button click
 func (d *PrefsDialog) onColumnMoveUp() {
 	d.moveSelectedColumnRow(true)
@@ -286,7 +398,7 @@ func (d *PrefsDialog) onSettingChange() {
 
 	// Collect settings
 	cfg := config.GetConfig()
-	// General page
+	// Connection page
 	cfg.MpdNetwork = d.MpdNetworkComboBox.GetActiveID()
 	cfg.MpdSocketPath = util.EntryText(d.MpdPathEntry, "")
 	cfg.MpdHost = util.EntryText(d.MpdHostEntry, "")
@@ -296,16 +408,87 @@ func (d *PrefsDialog) onSettingChange() {
 	}
 	cfg.MpdAutoConnect = d.MpdAutoConnectCheckButton.GetActive()
 	cfg.MpdAutoReconnect = d.MpdAutoReconnectCheckButton.GetActive()
-	d.updateGeneralWidgets()
+	cfg.MpdMusicDir = util.EntryText(d.MpdMusicDirEntry, "")
+	cfg.MpdAlbumArtURL = strings.TrimSpace(util.EntryText(d.MpdAlbumArtURLEntry, ""))
+	cfg.MpdAlbumArt = d.MpdAlbumArtCheckButton.GetActive()
+	cfg.MpdHTTPStreamURL = strings.TrimSpace(util.EntryText(d.MpdHTTPStreamURLEntry, ""))
+	d.updateConnectionWidgets()
 	// Interface page
 	if b := d.QueueToolbarCheckButton.GetActive(); b != cfg.QueueToolbar {
 		cfg.QueueToolbar = b
 		d.schedulePlayerSettingChange()
 	}
-	cfg.TrackDefaultReplace = d.LibraryDefaultReplaceRadioButton.GetActive()
-	cfg.PlaylistDefaultReplace = d.PlaylistsDefaultReplaceRadioButton.GetActive()
-	cfg.StreamDefaultReplace = d.StreamsDefaultReplaceRadioButton.GetActive()
-
+	if b := d.QueueAlbumArtCheckButton.GetActive(); b != cfg.QueueAlbumArt {
+		cfg.QueueAlbumArt = b
+		d.onQueueColumnsChanged()
+	}
+	cfg.TrackDoubleClick = d.LibraryDoubleClickComboBox.GetActiveID()
+	cfg.TrackMiddleClick = d.LibraryMiddleClickComboBox.GetActiveID()
+	if b := d.LibraryShowModifiedCheckButton.GetActive(); b != cfg.LibraryShowModified {
+		cfg.LibraryShowModified = b
+		d.onLibrarySettingChanged()
+	}
+	if i := int(d.LibraryRecentDaysAdjustment.GetValue()); i != cfg.LibraryRecentDays {
+		cfg.LibraryRecentDays = i
+		d.onLibrarySettingChanged()
+	}
+	if p := parsePatternList(util.EntryText(d.LibraryExcludeEntry, "")); strings.Join(p, ";") != strings.Join(cfg.LibraryExcludePatterns, ";") {
+		cfg.LibraryExcludePatterns = p
+		d.onLibrarySettingChanged()
+	}
+	if b := d.SearchIgnoreDiacriticsCheckButton.GetActive(); b != cfg.SearchIgnoreDiacritics {
+		cfg.SearchIgnoreDiacritics = b
+		d.onLibrarySettingChanged()
+	}
+	if b := d.SortIgnoreArticlesCheckButton.GetActive(); b != cfg.SortIgnoreArticles {
+		cfg.SortIgnoreArticles = b
+		d.onLibrarySettingChanged()
+	}
+	cfg.PlaylistDoubleClick = d.PlaylistsDoubleClickComboBox.GetActiveID()
+	cfg.PlaylistMiddleClick = d.PlaylistsMiddleClickComboBox.GetActiveID()
+	cfg.PlaylistExportPrefix = util.EntryText(d.PlaylistsExportPrefixEntry, "")
+	if b := d.PlaylistsShowModifiedCheckButton.GetActive(); b != cfg.PlaylistShowModified {
+		cfg.PlaylistShowModified = b
+		d.onLibrarySettingChanged()
+	}
+	cfg.StreamDoubleClick = d.StreamsDoubleClickComboBox.GetActiveID()
+	cfg.StreamMiddleClick = d.StreamsMiddleClickComboBox.GetActiveID()
+	integrationChanged := false
+	if b := d.TrayIconCheckButton.GetActive(); b != cfg.TrayIcon {
+		cfg.TrayIcon = b
+		integrationChanged = true
+	}
+	cfg.CloseToTray = d.CloseToTrayCheckButton.GetActive()
+	d.CloseToTrayCheckButton.SetSensitive(cfg.TrayIcon)
+	cfg.StartHidden = d.StartHiddenCheckButton.GetActive()
+	d.StartHiddenCheckButton.SetSensitive(cfg.TrayIcon)
+	if b := d.AutostartCheckButton.GetActive(); b != config.AutostartEnabled() {
+		if err := config.SetAutostart(b); errCheck(err, "SetAutostart() failed") {
+			util.ErrorDialog(d.PreferencesDialog, fmt.Sprintf(glib.Local("Failed to update the autostart entry: %v"), err))
+		}
+	}
+	cfg.KeymapProfile = d.KeymapComboBox.GetActiveID()
+	theme := d.ThemeComboBox.GetActiveID()
+	cssFile := strings.TrimSpace(util.EntryText(d.CustomCSSFileEntry, ""))
+	if theme != cfg.Theme || cssFile != cfg.CustomCSSFile {
+		cfg.Theme, cfg.CustomCSSFile = theme, cssFile
+		d.onAppearanceChanged()
+	}
+	if b := d.GSettingsCheckButton.GetActive(); b != config.GSettingsEnabled() {
+		if err := config.SetGSettingsEnabled(b); errCheck(err, "SetGSettingsEnabled() failed") {
+			util.ErrorDialog(d.PreferencesDialog, fmt.Sprintf(glib.Local("Failed to switch the settings storage: %v"), err))
+			d.GSettingsCheckButton.SetActive(!b)
+		}
+	}
+	// Player page
+	cfg.PlayerAutoResume = d.PlayerAutoResumeCheckButton.GetActive()
+	if s, err := util.GetTextBufferText(d.PlayerTitleTemplateTextBuffer); !errCheck(err, "util.GetTextBufferText() failed") {
+		if s != cfg.PlayerTitleTemplate {
+			cfg.PlayerTitleTemplate = s
+			d.schedulePlayerSettingChange()
+		}
+	}
+	// Covers page
 	if b := d.PlayerShowAlbumArtTracksCheckButton.GetActive(); b != cfg.PlayerAlbumArtTracks {
 		cfg.PlayerAlbumArtTracks = b
 		d.schedulePlayerSettingChange()
@@ -314,11 +497,46 @@ func (d *PrefsDialog) onSettingChange() {
 		cfg.PlayerAlbumArtStreams = b
 		d.schedulePlayerSettingChange()
 	}
-	if s, err := util.GetTextBufferText(d.PlayerTitleTemplateTextBuffer); !errCheck(err, "util.GetTextBufferText() failed") {
-		if s != cfg.PlayerTitleTemplate {
-			cfg.PlayerTitleTemplate = s
-			d.schedulePlayerSettingChange()
-		}
+	cfg.PlayerAlbumArtFiles = parsePatternList(util.EntryText(d.PlayerAlbumArtFilesEntry, ""))
+	if b := d.PlayerAlbumArtOnlineCheckButton.GetActive(); b != cfg.PlayerAlbumArtOnline {
+		cfg.PlayerAlbumArtOnline = b
+		d.schedulePlayerSettingChange()
+	}
+	cfg.AlbumArtCacheSize = int(d.AlbumArtCacheSizeAdjustment.GetValue())
+	// Integrations page
+	cfg.ListenBrainzToken = strings.TrimSpace(util.EntryText(d.ListenBrainzTokenEntry, ""))
+	apiKey := strings.TrimSpace(util.EntryText(d.LastFmAPIKeyEntry, ""))
+	secret := strings.TrimSpace(util.EntryText(d.LastFmSecretEntry, ""))
+	if apiKey != cfg.LastFmAPIKey || secret != cfg.LastFmSecret {
+		// A session is only valid for the API account it's been obtained with
+		cfg.LastFmAPIKey, cfg.LastFmSecret = apiKey, secret
+		cfg.LastFmUser, cfg.LastFmSessionKey = "", ""
+		integrationChanged = true
+	}
+	d.updateLastFmWidgets()
+	cfg.NotifyTrackChange = d.PlayerNotifyCheckButton.GetActive()
+	cfg.NotifySuppressFocused = d.PlayerNotifyFocusedCheckButton.GetActive()
+	d.PlayerNotifyFocusedCheckButton.SetSensitive(cfg.NotifyTrackChange)
+	if b := d.PlayerMediaKeysCheckButton.GetActive(); b != cfg.MediaKeys {
+		cfg.MediaKeys = b
+		integrationChanged = true
+	}
+	if b := d.PlayerInhibitCheckButton.GetActive(); b != cfg.InhibitSuspend {
+		cfg.InhibitSuspend = b
+		integrationChanged = true
+	}
+	if b := d.PlayerPauseOnLockCheckButton.GetActive(); b != cfg.PauseOnLock {
+		cfg.PauseOnLock = b
+		integrationChanged = true
+	}
+	cfg.ResumeOnUnlock = d.PlayerResumeOnUnlockCheckButton.GetActive()
+	d.PlayerResumeOnUnlockCheckButton.SetSensitive(cfg.PauseOnLock)
+	if b := d.PlayerPauseOnSuspendCheckButton.GetActive(); b != cfg.PauseOnSuspend {
+		cfg.PauseOnSuspend = b
+		integrationChanged = true
+	}
+	if integrationChanged {
+		d.onIntegrationSettingChanged()
 	}
 }
 
@@ -366,8 +584,110 @@ func (d *PrefsDialog) schedulePlayerSettingChange() {
 	})
 }
 
-// updateGeneralWidgets updates widget states on the General tab
-func (d *PrefsDialog) updateGeneralWidgets() {
+// onLastFmLogin starts logging in to Last.fm: requests an authentication token and has the user authorise it in the web
+// browser
+func (d *PrefsDialog) onLastFmLogin() {
+	cfg := config.GetConfig()
+	lastFm := NewLastFm(cfg.LastFmAPIKey, cfg.LastFmSecret, "")
+	d.lastFmLoggingIn = true
+	d.updateLastFmWidgets()
+	go func() {
+		token, err := lastFm.GetToken()
+		util.WhenIdle("PrefsDialog.onLastFmToken()", func() {
+			if !d.closed {
+				d.onLastFmToken(lastFm, token, err)
+			}
+		})
+	}()
+}
+
+// onLastFmToken continues logging in to Last.fm once an authentication token is obtained: once the user has authorised
+// it, exchanges it for a session
+func (d *PrefsDialog) onLastFmToken(lastFm *LastFm, token string, err error) {
+	if err == nil {
+		err = util.ShowURI(lastFm.AuthURL(token))
+	}
+	if errCheck(err, "Failed to obtain Last.fm token") {
+		d.onLastFmLoginDone(err)
+		return
+	}
+
+	// Wait for the user to grant access in the browser
+	if !util.ConfirmDialog(d.PreferencesDialog, glib.Local("Log in to Last.fm"), glib.Local("Allow Ymuse to access your Last.fm account in the web browser, then click OK.")) {
+		d.onLastFmLoginDone(nil)
+		return
+	}
+	go func() {
+		user, key, err := lastFm.GetSession(token)
+		util.WhenIdle("PrefsDialog.onLastFmSession()", func() {
+			if err == nil {
+				cfg := config.GetConfig()
+				cfg.LastFmUser, cfg.LastFmSessionKey = user, key
+			}
+			if !d.closed {
+				d.onLastFmLoginDone(err)
+			}
+		})
+	}()
+}
+
+// onLastFmLoginDone finishes logging in to Last.fm, reporting the error, if any
+func (d *PrefsDialog) onLastFmLoginDone(err error) {
+	d.lastFmLoggingIn = false
+	d.updateLastFmWidgets()
+	if errCheck(err, "Failed to log in to Last.fm") {
+		util.ErrorDialog(d.PreferencesDialog, fmt.Sprintf(glib.Local("Failed to log in to Last.fm: %v"), err))
+		return
+	}
+	d.onIntegrationSettingChanged()
+}
+
+// onLastFmLogout forgets the Last.fm session
+func (d *PrefsDialog) onLastFmLogout() {
+	cfg := config.GetConfig()
+	cfg.LastFmUser, cfg.LastFmSessionKey = "", ""
+	d.updateLastFmWidgets()
+	d.onIntegrationSettingChanged()
+}
+
+// updateLastFmWidgets updates the Last.fm login status and buttons on the Integrations tab
+func (d *PrefsDialog) updateLastFmWidgets() {
+	cfg := config.GetConfig()
+	lastFm := NewLastFmFromConfig()
+	loggedIn := lastFm.IsLoggedIn()
+	switch {
+	case d.lastFmLoggingIn:
+		d.LastFmStatusLabel.SetText(glib.Local("Logging in…"))
+	case loggedIn:
+		d.LastFmStatusLabel.SetText(fmt.Sprintf(glib.Local("Logged in as %s"), cfg.LastFmUser))
+	case lastFm.IsConfigured():
+		d.LastFmStatusLabel.SetText(glib.Local("Not logged in"))
+	default:
+		d.LastFmStatusLabel.SetText(glib.Local("Enter the API key and the shared secret to log in"))
+	}
+	d.LastFmLoginButton.SetVisible(!loggedIn)
+	d.LastFmLoginButton.SetSensitive(lastFm.IsConfigured() && !d.lastFmLoggingIn)
+	d.LastFmLogoutButton.SetVisible(loggedIn)
+}
+
+// onAlbumArtCacheClear removes all cached album art
+func (d *PrefsDialog) onAlbumArtCacheClear() {
+	if err := NewAlbumArtCache().Clear(); errCheck(err, "Failed to clear album art cache") {
+		util.ErrorDialog(d.PreferencesDialog, fmt.Sprintf(glib.Local("Failed to clear the cover cache: %v"), err))
+	}
+	d.updateAlbumArtCacheWidgets()
+}
+
+// updateAlbumArtCacheWidgets displays the current size of the album art cache
+func (d *PrefsDialog) updateAlbumArtCacheWidgets() {
+	size, err := NewAlbumArtCache().Size()
+	errCheck(err, "Failed to get album art cache size")
+	d.AlbumArtCacheUsageLabel.SetText(fmt.Sprintf(glib.Local("%.1f MB used"), float64(size)/(1<<20)))
+	d.AlbumArtCacheClearButton.SetSensitive(size > 0)
+}
+
+// updateConnectionWidgets updates widget states on the Connection tab
+func (d *PrefsDialog) updateConnectionWidgets() {
 	network := d.MpdNetworkComboBox.GetActiveID()
 	unix, tcp := network == "unix", network == "tcp"
 	d.MpdPathEntry.SetVisible(unix)
@@ -0,0 +1,86 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+	"github.com/yktoo/ymuse/internal/util"
+	"html"
+)
+
+// propertyGrid is a helper for populating a grid with sections of name/value pairs
+type propertyGrid struct {
+	grid *gtk.Grid // Grid to populate
+	row  int       // Next row in the grid
+}
+
+// clear removes all the content from the grid
+func (g *propertyGrid) clear() {
+	util.ClearChildren(g.grid.Container)
+	g.row = 0
+}
+
+// addHeader adds a section header to the property grid
+func (g *propertyGrid) addHeader(title string) {
+	if lbl := util.NewLabel(""); lbl != nil {
+		lbl.SetMarkup(fmt.Sprintf("<big><b>%s</b></big>", html.EscapeString(title)))
+		if g.row > 0 {
+			lbl.SetMarginTop(12)
+		}
+		g.grid.Attach(lbl, 0, g.row, 2, 1)
+		g.row++
+	}
+}
+
+// addProperty adds a name/value pair to the property grid, unless the value is empty
+func (g *propertyGrid) addProperty(name, value string) {
+	if value != "" {
+		g.addMarkupProperty(name, html.EscapeString(value))
+	}
+}
+
+// addLink adds a name/value pair to the property grid, with the value linking to the given URI
+func (g *propertyGrid) addLink(name, value, uri string) {
+	g.addMarkupProperty(name, fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(uri), html.EscapeString(value)))
+}
+
+// addMarkupProperty adds a name/value pair to the property grid, with the value given as Pango markup
+func (g *propertyGrid) addMarkupProperty(name, markup string) {
+
+	// Property name
+	lblName := util.NewLabel(name + ":")
+	if lblName == nil {
+		return
+	}
+	lblName.SetYAlign(0)
+	lblName.SetMarkup(fmt.Sprintf("<b>%s:</b>", html.EscapeString(name)))
+	g.grid.Attach(lblName, 0, g.row, 1, 1)
+
+	// Property value
+	lblValue := util.NewLabel("")
+	if lblValue == nil {
+		return
+	}
+	lblValue.SetMarkup(markup)
+	lblValue.SetSelectable(true)
+	lblValue.SetLineWrap(true)
+	lblValue.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+	lblValue.SetHExpand(true)
+	g.grid.Attach(lblValue, 1, g.row, 1, 1)
+	g.row++
+}
@@ -0,0 +1,50 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+// QueueSource represents the stored playlist the queue has been loaded from
+type QueueSource struct {
+	Name string   // Playlist name
+	URIs []string // Tracks of the playlist as they've been loaded into the queue
+}
+
+// Compare checks the given queue tracks against those loaded from the playlist. Returns whether the queue still relates
+// to the playlist, i.e. has any tracks in common with it, and whether the queue has diverged from the playlist, i.e.
+// tracks have been added, removed or moved
+func (s *QueueSource) Compare(queue []string) (related, diverged bool) {
+	loaded := make(map[string]bool, len(s.URIs))
+	for _, uri := range s.URIs {
+		loaded[uri] = true
+	}
+	for _, uri := range queue {
+		if loaded[uri] {
+			related = true
+			break
+		}
+	}
+
+	// An empty playlist relates to an empty queue only
+	if len(s.URIs) == 0 {
+		related = len(queue) == 0
+	}
+
+	// Compare the tracks one by one
+	diverged = len(queue) != len(s.URIs)
+	for i := 0; !diverged && i < len(queue); i++ {
+		diverged = queue[i] != s.URIs[i]
+	}
+	return
+}
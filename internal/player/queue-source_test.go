@@ -0,0 +1,46 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import "testing"
+
+func TestQueueSource_Compare(t *testing.T) {
+	tests := []struct {
+		name         string
+		loaded       []string
+		queue        []string
+		wantRelated  bool
+		wantDiverged bool
+	}{
+		{"same", []string{"a", "b"}, []string{"a", "b"}, true, false},
+		{"track added", []string{"a", "b"}, []string{"a", "b", "c"}, true, true},
+		{"track removed", []string{"a", "b"}, []string{"b"}, true, true},
+		{"tracks moved", []string{"a", "b"}, []string{"b", "a"}, true, true},
+		{"replaced", []string{"a", "b"}, []string{"c", "d"}, false, true},
+		{"cleared", []string{"a", "b"}, nil, false, true},
+		{"empty playlist", nil, nil, true, false},
+		{"empty playlist, tracks added", nil, []string{"a"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &QueueSource{Name: "x", URIs: tt.loaded}
+			related, diverged := s.Compare(tt.queue)
+			if related != tt.wantRelated || diverged != tt.wantDiverged {
+				t.Errorf("Compare() = (%v, %v), want (%v, %v)", related, diverged, tt.wantRelated, tt.wantDiverged)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	radioBrowserURL   = "https://all.api.radio-browser.info/json" // Base URL of the radio-browser.info API
+	radioBrowserLimit = 200                                       // Maximum number of stations returned by a search
+)
+
+// RadioQuery represents search criteria for radio stations. Empty criteria are ignored
+type RadioQuery struct {
+	Name    string // Part of the station name
+	Tag     string // Genre (tag) of the station
+	Country string // Part of the country name
+}
+
+// RadioStation represents a radio station found in the directory
+type RadioStation struct {
+	Name    string `json:"name"`         // Station name
+	URL     string `json:"url_resolved"` // Stream URL, with playlists resolved
+	Tags    string `json:"tags"`         // Comma-separated station tags (genres)
+	Country string `json:"country"`      // Country the station is based in
+	Codec   string `json:"codec"`        // Audio codec, such as "MP3"
+	Bitrate int    `json:"bitrate"`      // Stream bitrate in kbps, 0 if unknown
+}
+
+// SearchRadioStations queries the radio-browser.info directory at the given base URL for working stations matching the
+// query, the most popular ones first
+func SearchRadioStations(baseURL string, q RadioQuery) ([]RadioStation, error) {
+	params := url.Values{}
+	if q.Name != "" {
+		params.Set("name", q.Name)
+	}
+	if q.Tag != "" {
+		params.Set("tag", q.Tag)
+	}
+	if q.Country != "" {
+		params.Set("country", q.Country)
+	}
+	params.Set("hidebroken", "true")
+	params.Set("order", "votes")
+	params.Set("reverse", "true")
+	params.Set("limit", strconv.Itoa(radioBrowserLimit))
+
+	// Run the request and parse the response, skipping stations without a URL
+	var stations []RadioStation
+	if err := util.HTTPGetJSON(baseURL+"/stations/search?"+params.Encode(), &stations); err != nil {
+		return nil, err
+	}
+	result := stations[:0]
+	for _, s := range stations {
+		if s.URL != "" {
+			s.Name = strings.TrimSpace(s.Name)
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// Genre returns the first of the station's tags, if any
+func (s *RadioStation) Genre() string {
+	return strings.TrimSpace(strings.SplitN(s.Tags, ",", 2)[0])
+}
+
+// Details returns a short description of the station, such as "Germany, MP3 128 kbps"
+func (s *RadioStation) Details() string {
+	var parts []string
+	if s.Country != "" {
+		parts = append(parts, s.Country)
+	}
+	format := s.Codec
+	if s.Bitrate > 0 {
+		format = strings.TrimSpace(fmt.Sprintf("%s %d kbps", format, s.Bitrate))
+	}
+	if format != "" {
+		parts = append(parts, format)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// StreamSpec returns a stream specification for bookmarking the station
+func (s *RadioStation) StreamSpec() config.StreamSpec {
+	name := s.Name
+	if name == "" {
+		name = s.URL
+	}
+	return config.StreamSpec{Name: name, URI: s.URL, Genre: s.Genre()}
+}
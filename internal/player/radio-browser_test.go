@@ -0,0 +1,75 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/yktoo/ymuse/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSearchRadioStations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stations/search" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("name") != "jazz" || q.Get("country") != "" || q.Get("hidebroken") != "true" {
+			http.Error(w, "unexpected query: "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"name": " Jazz FM ", "url_resolved": "http://jazz.example.com/", "tags": "jazz,smooth jazz", "country": "United Kingdom", "codec": "MP3", "bitrate": 128},
+			{"name": "Broken", "url_resolved": "", "tags": "jazz"},
+			{"name": "", "url_resolved": "http://anon.example.com/", "tags": "", "codec": "AAC"}
+		]`))
+	}))
+	defer server.Close()
+
+	got, err := SearchRadioStations(server.URL, RadioQuery{Name: "jazz"})
+	if err != nil {
+		t.Fatalf("SearchRadioStations() error = %v", err)
+	}
+	want := []RadioStation{
+		{Name: "Jazz FM", URL: "http://jazz.example.com/", Tags: "jazz,smooth jazz", Country: "United Kingdom", Codec: "MP3", Bitrate: 128},
+		{URL: "http://anon.example.com/", Codec: "AAC"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SearchRadioStations() = %v, want %v", got, want)
+	}
+
+	// Check the conversions
+	if d := got[0].Details(); d != "United Kingdom, MP3 128 kbps" {
+		t.Errorf("Details() = %q", d)
+	}
+	if d := got[1].Details(); d != "AAC" {
+		t.Errorf("Details() = %q", d)
+	}
+	if s := got[0].StreamSpec(); s != (config.StreamSpec{Name: "Jazz FM", URI: "http://jazz.example.com/", Genre: "jazz"}) {
+		t.Errorf("StreamSpec() = %v", s)
+	}
+	if s := got[1].StreamSpec(); s != (config.StreamSpec{Name: "http://anon.example.com/", URI: "http://anon.example.com/"}) {
+		t.Errorf("StreamSpec() = %v", s)
+	}
+
+	// Failing requests
+	if _, err := SearchRadioStations(server.URL, RadioQuery{Name: "rock"}); err == nil {
+		t.Error("SearchRadioStations() succeeded for a bad request, want error")
+	}
+}
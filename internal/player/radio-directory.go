@@ -0,0 +1,153 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/generated"
+	"github.com/yktoo/ymuse/internal/util"
+)
+
+// RadioDirectory represents the dialog for finding radio stations in an online directory
+type RadioDirectory struct {
+	RadioDirectoryDialog       *gtk.MessageDialog
+	RadioDirectoryNameEntry    *gtk.Entry
+	RadioDirectoryGenreEntry   *gtk.Entry
+	RadioDirectoryCountryEntry *gtk.Entry
+	RadioDirectorySearchButton *gtk.Button
+	RadioDirectoryListBox      *gtk.ListBox
+	RadioDirectorySpinner      *gtk.Spinner
+	RadioDirectoryInfoLabel    *gtk.Label
+
+	onPlay     func(uri string)               // Callback for playing a station
+	onBookmark func(stream config.StreamSpec) // Callback for adding a station to the streams
+	searchGen  int                            // Search generation, incremented on every search to discard stale results
+	closed     bool                           // Whether the dialog has been closed
+}
+
+// RadioDirectoryDialog creates, shows and disposes of a radio directory dialog instance
+// onPlay: function to call to play the station with the given URL
+// onBookmark: function to call to add the station to the user's streams
+func RadioDirectoryDialog(parent gtk.IWindow, onPlay func(uri string), onBookmark func(stream config.StreamSpec)) {
+	// Load the dialog layout and map the widgets
+	d := &RadioDirectory{onPlay: onPlay, onBookmark: onBookmark}
+	builder, err := NewBuilder(generated.GetRadioDirectoryGlade())
+	if err == nil {
+		err = builder.BindWidgets(d)
+	}
+
+	// Check for errors
+	if errCheck(err, "RadioDirectoryDialog(): failed to initialise dialog") {
+		util.ErrorDialog(parent, fmt.Sprint(glib.Local("Failed to load UI widgets"), err))
+		return
+	}
+	defer d.RadioDirectoryDialog.Destroy()
+
+	// Map the handlers to callback functions
+	builder.ConnectSignals(map[string]interface{}{
+		"on_RadioDirectorySearch": d.search,
+	})
+
+	// Set up and show the dialog
+	d.RadioDirectoryDialog.SetTransientFor(parent)
+	d.RadioDirectoryDialog.ShowAll()
+	d.RadioDirectorySpinner.Hide()
+	d.RadioDirectoryNameEntry.GrabFocus()
+	d.RadioDirectoryDialog.Run()
+	d.closed = true
+}
+
+// search looks up the stations matching the entered criteria in the background
+func (d *RadioDirectory) search() {
+	query := RadioQuery{
+		Name:    util.EntryText(d.RadioDirectoryNameEntry, ""),
+		Tag:     util.EntryText(d.RadioDirectoryGenreEntry, ""),
+		Country: util.EntryText(d.RadioDirectoryCountryEntry, ""),
+	}
+	if query == (RadioQuery{}) {
+		d.RadioDirectoryInfoLabel.SetText(glib.Local("Enter a name, genre or country to search for"))
+		return
+	}
+
+	// Start the search
+	d.searchGen++
+	gen := d.searchGen
+	util.ClearChildren(d.RadioDirectoryListBox.Container)
+	d.RadioDirectoryInfoLabel.SetText(glib.Local("Searching…"))
+	d.RadioDirectorySpinner.Show()
+	d.RadioDirectorySpinner.Start()
+	go func() {
+		stations, err := SearchRadioStations(radioBrowserURL, query)
+		util.WhenIdle("RadioDirectory.showResults()", func() {
+			// Ignore the results if the dialog is gone or another search has been started
+			if !d.closed && gen == d.searchGen {
+				d.showResults(stations, err)
+			}
+		})
+	}()
+}
+
+// showResults populates the list with the found stations
+func (d *RadioDirectory) showResults(stations []RadioStation, err error) {
+	d.RadioDirectorySpinner.Stop()
+	d.RadioDirectorySpinner.Hide()
+	if errCheck(err, "SearchRadioStations() failed") {
+		d.RadioDirectoryInfoLabel.SetText(fmt.Sprintf(glib.Local("Search failed: %v"), err))
+		return
+	}
+
+	for _, s := range stations {
+		s := s // Make an in-loop copy of the var
+		_, hbx, err := util.NewListBoxRow(
+			d.RadioDirectoryListBox,
+			false,
+			s.StreamSpec().Name,
+			"",
+			"ymuse-stream",
+			util.NewButton("", glib.Local("Play"), "", "ymuse-play-symbolic", func() { d.onPlay(s.URL) }),
+			util.NewButton("", glib.Local("Add to streams"), "", "bookmark-new-symbolic", func(btn *gtk.Button) {
+				d.onBookmark(s.StreamSpec())
+				btn.SetSensitive(false)
+			}))
+		if errCheck(err, "NewListBoxRow() failed") {
+			return
+		}
+
+		// Add the station's genre, country and format
+		if details := s.Details(); details != "" {
+			if g := s.Genre(); g != "" {
+				details = g + ", " + details
+			}
+			if lbl := newLibraryColumnLabel(details, libraryDetailsWidthChars, true); lbl != nil {
+				hbx.PackEnd(lbl, false, false, 0)
+			}
+		}
+	}
+	d.RadioDirectoryListBox.ShowAll()
+
+	// Update the info
+	switch len(stations) {
+	case 0:
+		d.RadioDirectoryInfoLabel.SetText(glib.Local("No stations found"))
+	case 1:
+		d.RadioDirectoryInfoLabel.SetText(glib.Local("One station found"))
+	default:
+		d.RadioDirectoryInfoLabel.SetText(fmt.Sprintf(util.LocalN("%d station found", "%d stations found", len(stations)), len(stations)))
+	}
+}
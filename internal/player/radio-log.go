@@ -0,0 +1,126 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/gotk3/gotk3/glib"
+	"os"
+	"path"
+	"time"
+)
+
+// RadioLogEntry represents a song heard on an Internet radio station
+type RadioLogEntry struct {
+	Time    time.Time // Moment the song has been logged
+	Station string    // Name of the station
+	URI     string    // Stream URI
+	Title   string    // Song title as announced by the station
+}
+
+// RadioLog keeps a list of songs heard on the radio, stored in a file
+type RadioLog struct {
+	fileName string          // Full path of the log file
+	entries  []RadioLogEntry // Logged songs, oldest first
+}
+
+// NewRadioLog creates a new RadioLog instance and loads the previously logged songs
+func NewRadioLog() *RadioLog {
+	l := &RadioLog{fileName: path.Join(glib.GetUserDataDir(), "ymuse", "radio-log.jsonl")}
+	l.load()
+	return l
+}
+
+// Entries returns all logged songs, oldest first
+func (l *RadioLog) Entries() []RadioLogEntry {
+	return l.entries
+}
+
+// IsLast returns whether the given song is the most recently logged one
+func (l *RadioLog) IsLast(uri, title string) bool {
+	n := len(l.entries)
+	return n > 0 && l.entries[n-1].URI == uri && l.entries[n-1].Title == title
+}
+
+// Add logs the given song, unless it's the same as the most recently logged one
+func (l *RadioLog) Add(station, uri, title string) error {
+	if l.IsLast(uri, title) {
+		return nil
+	}
+	e := RadioLogEntry{Time: time.Now(), Station: station, URI: uri, Title: title}
+	if err := l.append(&e); err != nil {
+		return err
+	}
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+// Clear removes all logged songs
+func (l *RadioLog) Clear() error {
+	if err := os.Remove(l.fileName); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l.entries = nil
+	return nil
+}
+
+// load reads the previously logged songs from the log file
+func (l *RadioLog) load() {
+	file, err := os.Open(l.fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errCheck(err, "Couldn't open radio log file")
+		}
+		return
+	}
+	defer file.Close()
+
+	// Each line is a JSON-encoded entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e RadioLogEntry
+		if !errCheck(json.Unmarshal(scanner.Bytes(), &e), "json.Unmarshal() failed") {
+			l.entries = append(l.entries, e)
+		}
+	}
+	errCheck(scanner.Err(), "Failed to read radio log file")
+	log.Debugf("Loaded %d radio log entries", len(l.entries))
+}
+
+// append writes out the given entry to the end of the log file
+func (l *RadioLog) append(e *RadioLogEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(path.Dir(l.fileName), 0755); err != nil {
+		return err
+	}
+
+	// Append the entry
+	file, err := os.OpenFile(l.fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
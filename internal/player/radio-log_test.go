@@ -0,0 +1,61 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRadioLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ymuse-radio-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := path.Join(dir, "ymuse", "radio-log.jsonl")
+	l := &RadioLog{fileName: fileName}
+
+	// Log a few songs, a repeated one is only logged once
+	for _, title := range []string{"A - One", "A - One", "B - Two"} {
+		if err := l.Add("Radio", "http://radio/stream", title); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if !l.IsLast("http://radio/stream", "B - Two") || l.IsLast("http://radio/stream", "A - One") {
+		t.Errorf("IsLast() gives wrong results for %v", l.Entries())
+	}
+
+	// The songs are reloaded from the file
+	l = &RadioLog{fileName: fileName}
+	l.load()
+	entries := l.Entries()
+	if len(entries) != 2 || entries[0].Title != "A - One" || entries[1].Title != "B - Two" || entries[1].Station != "Radio" {
+		t.Fatalf("Entries() = %v, want two songs", entries)
+	}
+
+	// Clearing removes all the songs
+	if err := l.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	l = &RadioLog{fileName: fileName}
+	l.load()
+	if len(l.Entries()) != 0 {
+		t.Errorf("Entries() after Clear() = %v, want none", l.Entries())
+	}
+}
@@ -0,0 +1,302 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/godbus/dbus/v5"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/config"
+	"io"
+	"strings"
+)
+
+// remoteControl is a means of controlling playback from the command line
+type remoteControl interface {
+	play() error
+	pause() error
+	next() error
+	previous() error
+	stop() error
+	add(uris []string) error
+	// status returns the MPD player state (play, pause or stop) and the current track, nil if none
+	status() (string, mpd.Attrs, error)
+	close()
+}
+
+// mprisRemote controls playback through the MPRIS service of the running instance
+type mprisRemote struct {
+	conn *dbus.Conn     // Session bus connection
+	obj  dbus.BusObject // MPRIS object of the running instance
+}
+
+// mpdRemote controls playback by talking to MPD directly
+type mpdRemote struct {
+	client *mpd.Client // MPD client
+}
+
+// IsRemoteCommand returns whether the given command-line verb is a remote-control command
+func IsRemoteCommand(verb string) bool {
+	switch verb {
+	case "play", "pause", "next", "prev", "stop", "add", "status":
+		return true
+	}
+	return false
+}
+
+// RunRemoteCommand executes the given remote-control command with its arguments, through the running instance over
+// D-Bus if there's one, or directly on MPD otherwise. The output of the command, if any, is written to out
+func RunRemoteCommand(out io.Writer, verb string, args []string) error {
+	if !IsRemoteCommand(verb) {
+		return fmt.Errorf("unknown command: %s", verb)
+	}
+	if verb == "add" && len(args) == 0 {
+		return errors.New("no URI to add given")
+	}
+
+	r, err := newRemoteControl()
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	switch verb {
+	case "play":
+		return r.play()
+	case "pause":
+		return r.pause()
+	case "next":
+		return r.next()
+	case "prev":
+		return r.previous()
+	case "stop":
+		return r.stop()
+	case "add":
+		return r.add(args)
+	default:
+		state, track, err := r.status()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, formatRemoteStatus(state, track))
+		return err
+	}
+}
+
+// newRemoteControl returns a remoteControl talking to the running instance, if any, or to MPD otherwise
+func newRemoteControl() (remoteControl, error) {
+	// Look for the running instance on the session bus
+	if conn, err := connectBus(dbus.SessionBusPrivate); err != nil {
+		log.Debugf("Session bus unavailable: %v", err)
+	} else {
+		var running bool
+		if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, mprisBusName).Store(&running); err == nil && running {
+			log.Debug("Controlling the running instance")
+			return &mprisRemote{conn: conn, obj: conn.Object(mprisBusName, mprisPath)}, nil
+		}
+		_ = conn.Close()
+	}
+
+	// Connect to MPD otherwise
+	cfg := config.GetConfig()
+	network, addr := cfg.MpdNetworkAddress()
+	log.Debugf("Connecting to MPD (network=%v, address=%v)", network, addr)
+	client, err := mpd.DialAuthenticated(network, addr, cfg.MpdPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MPD: %v", err)
+	}
+	return &mpdRemote{client: client}, nil
+}
+
+func (r *mprisRemote) play() error {
+	return r.call(mprisPlayerIface + ".Play")
+}
+
+func (r *mprisRemote) pause() error {
+	return r.call(mprisPlayerIface + ".Pause")
+}
+
+func (r *mprisRemote) next() error {
+	return r.call(mprisPlayerIface + ".Next")
+}
+
+func (r *mprisRemote) previous() error {
+	return r.call(mprisPlayerIface + ".Previous")
+}
+
+func (r *mprisRemote) stop() error {
+	return r.call(mprisPlayerIface + ".Stop")
+}
+
+func (r *mprisRemote) add(uris []string) error {
+	// Append the URIs after the last track
+	v, err := r.obj.GetProperty(mprisTrackListIface + ".Tracks")
+	if err != nil {
+		return err
+	}
+	after := dbus.ObjectPath(mprisNoTrack)
+	if tracks, _ := v.Value().([]dbus.ObjectPath); len(tracks) > 0 {
+		after = tracks[len(tracks)-1]
+	}
+
+	// Insert them in reverse order after the same track, since the instance's track list doesn't reflect the
+	// additions immediately
+	for i := len(uris) - 1; i >= 0; i-- {
+		if err := r.call(mprisTrackListIface+".AddTrack", uris[i], after, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *mprisRemote) status() (string, mpd.Attrs, error) {
+	status, err := r.obj.GetProperty(mprisPlayerIface + ".PlaybackStatus")
+	if err != nil {
+		return "", nil, err
+	}
+	metadata, err := r.obj.GetProperty(mprisPlayerIface + ".Metadata")
+	if err != nil {
+		return "", nil, err
+	}
+	s, _ := status.Value().(string)
+	md, _ := metadata.Value().(map[string]dbus.Variant)
+	state, track := mprisToMpdStatus(s, md)
+	return state, track, nil
+}
+
+func (r *mprisRemote) close() {
+	errCheck(r.conn.Close(), "Failed to close session bus connection")
+}
+
+// call invokes the given method of the MPRIS object
+func (r *mprisRemote) call(method string, args ...interface{}) error {
+	return r.obj.Call(method, 0, args...).Err
+}
+
+func (r *mpdRemote) play() error {
+	status, err := r.client.Status()
+	if err != nil {
+		return err
+	}
+	switch status["state"] {
+	case "pause":
+		return r.client.Pause(false)
+	case "play":
+		return nil
+	default:
+		return r.client.Play(-1)
+	}
+}
+
+func (r *mpdRemote) pause() error {
+	return r.client.Pause(true)
+}
+
+func (r *mpdRemote) next() error {
+	return r.client.Next()
+}
+
+func (r *mpdRemote) previous() error {
+	return r.client.Previous()
+}
+
+func (r *mpdRemote) stop() error {
+	return r.client.Stop()
+}
+
+func (r *mpdRemote) add(uris []string) error {
+	for _, uri := range uris {
+		if err := r.client.Add(mprisURIToMpd(uri)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *mpdRemote) status() (string, mpd.Attrs, error) {
+	status, err := r.client.Status()
+	if err != nil {
+		return "", nil, err
+	}
+	var track mpd.Attrs
+	if status["songid"] != "" {
+		if track, err = r.client.CurrentSong(); err != nil {
+			return "", nil, err
+		}
+	}
+	return status["state"], track, nil
+}
+
+func (r *mpdRemote) close() {
+	errCheck(r.client.Close(), "Failed to close MPD connection")
+}
+
+// mprisToMpdStatus translates the given MPRIS playback status and metadata into an MPD player state and track
+// attributes. The returned track is nil if the metadata describes no track
+func mprisToMpdStatus(status string, metadata map[string]dbus.Variant) (string, mpd.Attrs) {
+	var state string
+	switch status {
+	case "Playing":
+		state = "play"
+	case "Paused":
+		state = "pause"
+	default:
+		state = "stop"
+	}
+
+	if id, _ := metadata["mpris:trackid"].Value().(dbus.ObjectPath); id == "" || id == mprisNoTrack {
+		return state, nil
+	}
+	track := mpd.Attrs{}
+	if s, ok := metadata["xesam:title"].Value().(string); ok {
+		track["Title"] = s
+	}
+	if s, ok := metadata["xesam:album"].Value().(string); ok {
+		track["Album"] = s
+	}
+	if s, ok := metadata["xesam:artist"].Value().([]string); ok {
+		track["Artist"] = strings.Join(s, ", ")
+	}
+	if s, ok := metadata["xesam:url"].Value().(string); ok {
+		track["file"] = s
+	}
+	return state, track
+}
+
+// formatRemoteStatus returns a human-readable description of the given MPD player state and current track (nil if
+// none), as printed by the status command
+func formatRemoteStatus(state string, track mpd.Attrs) string {
+	var s string
+	switch state {
+	case "play":
+		s = glib.Local("Playing")
+	case "pause":
+		s = glib.Local("Paused")
+	default:
+		s = glib.Local("Stopped")
+	}
+	if len(track) == 0 {
+		return s
+	}
+	title, details := trackNotificationText(track)
+	s += ": " + title
+	if details != "" {
+		s += "\n" + details
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/godbus/dbus/v5"
+	"reflect"
+	"testing"
+)
+
+func TestIsRemoteCommand(t *testing.T) {
+	for _, verb := range []string{"play", "pause", "next", "prev", "stop", "add", "status"} {
+		if !IsRemoteCommand(verb) {
+			t.Errorf("IsRemoteCommand(%q) = false, want true", verb)
+		}
+	}
+	for _, verb := range []string{"", "previous", "Play", "http://example.com/stream"} {
+		if IsRemoteCommand(verb) {
+			t.Errorf("IsRemoteCommand(%q) = true, want false", verb)
+		}
+	}
+}
+
+func TestMprisToMpdStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		metadata  map[string]dbus.Variant
+		wantState string
+		wantTrack mpd.Attrs
+	}{
+		{"no metadata", "Stopped", nil, "stop", nil},
+		{"no track", "Stopped", mprisMetadata(nil, "", ""), "stop", nil},
+		{"paused", "Paused",
+			map[string]dbus.Variant{
+				"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath(mprisTrackPathBase + "5")),
+				"xesam:title":   dbus.MakeVariant("Song"),
+			},
+			"pause", mpd.Attrs{"Title": "Song"}},
+		{"playing", "Playing",
+			map[string]dbus.Variant{
+				"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath(mprisTrackPathBase + "7")),
+				"xesam:title":   dbus.MakeVariant("Song"),
+				"xesam:album":   dbus.MakeVariant("Album"),
+				"xesam:artist":  dbus.MakeVariant([]string{"Foo", "Bar"}),
+				"xesam:url":     dbus.MakeVariant("http://example.com/song.mp3"),
+			},
+			"play", mpd.Attrs{"Title": "Song", "Album": "Album", "Artist": "Foo, Bar", "file": "http://example.com/song.mp3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, track := mprisToMpdStatus(tt.status, tt.metadata)
+			if state != tt.wantState {
+				t.Errorf("mprisToMpdStatus() state = %v, want %v", state, tt.wantState)
+			}
+			if !reflect.DeepEqual(track, tt.wantTrack) {
+				t.Errorf("mprisToMpdStatus() track = %v, want %v", track, tt.wantTrack)
+			}
+		})
+	}
+}
+
+func TestFormatRemoteStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		track mpd.Attrs
+		want  string
+	}{
+		{"stopped", "stop", nil, "Stopped"},
+		{"unknown state", "", nil, "Stopped"},
+		{"title only", "pause", mpd.Attrs{"Title": "Song"}, "Paused: Song"},
+		{"full", "play", mpd.Attrs{"Title": "Song", "Artist": "Foo", "Album": "Bar"}, "Playing: Song\nFoo — Bar"},
+		{"file", "play", mpd.Attrs{"file": "music/song.mp3"}, "Playing: song.mp3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatRemoteStatus(tt.state, tt.track); got != tt.want {
+				t.Errorf("formatRemoteStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	resumeStickerName    = "ymuse-resume" // Name of the MPD sticker storing a track's resume position
+	resumeMinTrackLength = 20 * 60.0      // Only tracks at least this number of seconds long are resumable
+	resumeMinPos         = 30.0           // Positions closer than this number of seconds to the start aren't stored
+	resumeEndMargin      = 30.0           // Tracks played to this number of seconds before the end are finished
+	resumeSaveStep       = 15.0           // Stored position is updated when playback moves this number of seconds away
+)
+
+// ResumeUpdate describes a change to the stored resume position of a track
+type ResumeUpdate struct {
+	URI string  // URI of the track
+	Pos float64 // Position to store, in seconds, or 0 if the stored position is to be removed
+}
+
+// ResumeTracker keeps track of the play position of the current long track (such as an audiobook or podcast chapter),
+// working out when its stored resume position needs to be updated or removed
+type ResumeTracker struct {
+	uri      string  // URI of the current track
+	length   float64 // Length of the current track in seconds
+	pos      float64 // Last known play position in the current track
+	savedPos float64 // Resume position stored for the current track, 0 if none
+}
+
+// IsResumable returns whether a track of the given length in seconds is long enough to store its resume position
+func IsResumable(length float64) bool {
+	return length >= resumeMinTrackLength
+}
+
+// ParseResumePos parses a resume position stored in a sticker, returning 0 if it's invalid
+func ParseResumePos(s string) float64 {
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 {
+		return f
+	}
+	return 0
+}
+
+// FormatResumePos formats a resume position for storing in a sticker
+func FormatResumePos(pos float64) string {
+	return strconv.FormatFloat(pos, 'f', 1, 64)
+}
+
+// URI returns the URI of the current track
+func (t *ResumeTracker) URI() string {
+	return t.uri
+}
+
+// SetTrack notifies the tracker of a new current track, along with its length and its stored resume position, if
+// any. Returns the final update for the previous track, or nil if there's none
+func (t *ResumeTracker) SetTrack(uri string, length, savedPos float64) *ResumeUpdate {
+	update := t.update(true)
+	t.uri = uri
+	t.length = length
+	t.pos = 0
+	t.savedPos = savedPos
+	return update
+}
+
+// Progress notifies the tracker of the current play position, in seconds. Returns an update for the current track if
+// its resume position needs to be changed, otherwise nil
+func (t *ResumeTracker) Progress(pos float64) *ResumeUpdate {
+	t.pos = pos
+	return t.update(false)
+}
+
+// update works out the change to the resume position of the current track, if any. If final is true, the track isn't
+// going to be played any further, so its last position is stored as is
+func (t *ResumeTracker) update(final bool) *ResumeUpdate {
+	if t.uri == "" || !IsResumable(t.length) {
+		return nil
+	}
+	var pos float64
+	switch {
+	// The track is finished: drop the stored position, if any
+	case t.pos >= t.length-resumeEndMargin:
+		if t.savedPos == 0 {
+			return nil
+		}
+
+	// Too close to the start, keep whatever's stored
+	case t.pos < resumeMinPos:
+		return nil
+
+	// Store the position once it's moved far enough, or when leaving the track
+	case final && t.pos != t.savedPos || math.Abs(t.pos-t.savedPos) >= resumeSaveStep:
+		pos = t.pos
+
+	default:
+		return nil
+	}
+	t.savedPos = pos
+	return &ResumeUpdate{URI: t.uri, Pos: pos}
+}
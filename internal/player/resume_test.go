@@ -0,0 +1,77 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResumeTracker(t *testing.T) {
+	const length = 3600.0
+	tr := &ResumeTracker{}
+	check := func(name string, got, want *ResumeUpdate) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+
+	// Short tracks are never tracked
+	check("SetTrack(short)", tr.SetTrack("song.mp3", 300, 0), nil)
+	check("Progress(short)", tr.Progress(200), nil)
+
+	// Long track: nothing is stored near the start, then the position is stored in steps
+	check("SetTrack(long)", tr.SetTrack("book.mp3", length, 0), nil)
+	check("Progress(10)", tr.Progress(10), nil)
+	check("Progress(40)", tr.Progress(40), &ResumeUpdate{URI: "book.mp3", Pos: 40})
+	check("Progress(45)", tr.Progress(45), nil)
+	check("Progress(60)", tr.Progress(60), &ResumeUpdate{URI: "book.mp3", Pos: 60})
+	check("Progress(70)", tr.Progress(70), nil)
+
+	// Leaving the track stores its last position
+	check("SetTrack(other)", tr.SetTrack("other.mp3", length, 1000), &ResumeUpdate{URI: "book.mp3", Pos: 70})
+	if tr.URI() != "other.mp3" {
+		t.Errorf("URI() = %q, want %q", tr.URI(), "other.mp3")
+	}
+
+	// Finishing the track removes the stored position, once
+	check("Progress(1010)", tr.Progress(1010), nil)
+	check("Progress(end)", tr.Progress(length-10), &ResumeUpdate{URI: "other.mp3"})
+	check("Progress(end again)", tr.Progress(length-5), nil)
+	check("SetTrack(none)", tr.SetTrack("", 0, 0), nil)
+}
+
+func TestParseResumePos(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"", 0},
+		{"abc", 0},
+		{"-12", 0},
+		{"0", 0},
+		{"1234.5", 1234.5},
+		{FormatResumePos(98.76), 98.8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := ParseResumePos(tt.s); got != tt.want {
+				t.Errorf("ParseResumePos() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
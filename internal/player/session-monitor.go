@@ -0,0 +1,209 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"errors"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/godbus/dbus/v5"
+	"github.com/yktoo/ymuse/internal/config"
+	"sync"
+	"syscall"
+)
+
+const (
+	logindBusName      = "org.freedesktop.login1"         // Bus name of systemd-logind
+	logindPath         = "/org/freedesktop/login1"        // Path of the logind manager object
+	logindManagerIface = "org.freedesktop.login1.Manager" // Logind manager interface
+)
+
+// screenSaverIfaces lists the interfaces of the screensaver services announcing screen locks with an ActiveChanged signal
+var screenSaverIfaces = []string{"org.freedesktop.ScreenSaver", "org.gnome.ScreenSaver", "org.mate.ScreenSaver", "org.cinnamon.ScreenSaver"}
+
+// SessionMonitor pauses playback when the screen gets locked or the machine is about to suspend, listening to the
+// screensaver on the D-Bus session bus and to logind on the system bus, and optionally resumes it on unlock
+type SessionMonitor struct {
+	connector   *Connector // Connector to control MPD through
+	sessionConn *dbus.Conn // Session bus connection, nil if unavailable
+	systemConn  *dbus.Conn // System bus connection, nil if unavailable
+	sleepLock   int        // File descriptor of the logind lock delaying suspend, -1 if none
+	sleepMutex  sync.Mutex // Mutex guarding the sleep lock
+	lockPaused  bool       // Whether playback has been paused because of the screen lock
+}
+
+// NewSessionMonitor connects to the session and system buses and starts listening to screen lock and suspend. Fails
+// only if neither bus is available
+func NewSessionMonitor(connector *Connector) (*SessionMonitor, error) {
+	m := &SessionMonitor{connector: connector, sleepLock: -1}
+
+	// Watch the screensaver
+	var sessionErr, systemErr error
+	m.sessionConn, sessionErr = connectBus(dbus.SessionBusPrivate)
+	if sessionErr == nil {
+		for _, iface := range screenSaverIfaces {
+			if sessionErr = m.sessionConn.AddMatchSignal(dbus.WithMatchInterface(iface), dbus.WithMatchMember("ActiveChanged")); sessionErr != nil {
+				break
+			}
+		}
+		if sessionErr != nil {
+			_ = m.sessionConn.Close()
+			m.sessionConn = nil
+		}
+	}
+	if sessionErr == nil {
+		signals := make(chan *dbus.Signal, 10)
+		m.sessionConn.Signal(signals)
+		go m.watchScreenSaver(signals)
+	} else {
+		log.Warningf("Failed to watch screen lock: %v", sessionErr)
+	}
+
+	// Watch logind
+	m.systemConn, systemErr = connectBus(dbus.SystemBusPrivate)
+	if systemErr == nil {
+		systemErr = m.systemConn.AddMatchSignal(
+			dbus.WithMatchSender(logindBusName),
+			dbus.WithMatchObjectPath(logindPath),
+			dbus.WithMatchInterface(logindManagerIface),
+			dbus.WithMatchMember("PrepareForSleep"))
+		if systemErr == nil {
+			systemErr = m.takeSleepLock()
+		}
+		if systemErr != nil {
+			_ = m.systemConn.Close()
+			m.systemConn = nil
+		}
+	}
+	if systemErr == nil {
+		signals := make(chan *dbus.Signal, 10)
+		m.systemConn.Signal(signals)
+		go m.watchSleep(signals)
+	} else {
+		log.Warningf("Failed to watch suspend: %v", systemErr)
+	}
+
+	if m.sessionConn == nil && m.systemConn == nil {
+		return nil, errors.New("neither session nor system bus is available")
+	}
+	return m, nil
+}
+
+// Close stops listening and releases the suspend delay lock
+func (m *SessionMonitor) Close() {
+	if m.sessionConn != nil {
+		errCheck(m.sessionConn.Close(), "Failed to close session bus connection")
+	}
+	if m.systemConn != nil {
+		errCheck(m.systemConn.Close(), "Failed to close system bus connection")
+	}
+	m.releaseSleepLock()
+}
+
+// takeSleepLock asks logind to delay suspend until the lock is released, so that playback can be paused beforehand
+func (m *SessionMonitor) takeSleepLock() error {
+	var fd dbus.UnixFD
+	err := m.systemConn.Object(logindBusName, logindPath).
+		Call(logindManagerIface+".Inhibit", 0, "sleep", config.AppMetadata.Name, "Pause playback", "delay").
+		Store(&fd)
+	if err != nil {
+		return err
+	}
+	m.sleepMutex.Lock()
+	m.sleepLock = int(fd)
+	m.sleepMutex.Unlock()
+	return nil
+}
+
+// releaseSleepLock releases the suspend delay lock, if any
+func (m *SessionMonitor) releaseSleepLock() {
+	m.sleepMutex.Lock()
+	defer m.sleepMutex.Unlock()
+	if m.sleepLock >= 0 {
+		errCheck(syscall.Close(m.sleepLock), "Failed to release sleep lock")
+		m.sleepLock = -1
+	}
+}
+
+// watchScreenSaver pauses playback when the screen gets locked and resumes it on unlock, if configured, until the
+// connection is closed
+func (m *SessionMonitor) watchScreenSaver(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if len(sig.Body) != 1 {
+			continue
+		}
+		active, ok := sig.Body[0].(bool)
+		if !ok {
+			continue
+		}
+		cfg := config.GetConfig()
+		switch {
+		case active && cfg.PauseOnLock:
+			if m.pause("Screen locked") {
+				m.lockPaused = true
+			}
+		case !active && m.lockPaused:
+			m.lockPaused = false
+			if cfg.ResumeOnUnlock {
+				m.resume("Screen unlocked")
+			}
+		}
+	}
+}
+
+// watchSleep pauses playback when the machine is about to suspend, if configured, until the connection is closed
+func (m *SessionMonitor) watchSleep(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if len(sig.Body) != 1 {
+			continue
+		}
+		start, ok := sig.Body[0].(bool)
+		if !ok {
+			continue
+		}
+		if start {
+			// Let the machine suspend once paused
+			if config.GetConfig().PauseOnSuspend {
+				m.pause("Suspending")
+			}
+			m.releaseSleepLock()
+		} else {
+			// Delay the next suspend, too
+			errCheck(m.takeSleepLock(), "Failed to take sleep lock")
+		}
+	}
+}
+
+// pause pauses playback if it's on, returning whether it was
+func (m *SessionMonitor) pause(reason string) bool {
+	paused := false
+	m.connector.IfConnected(func(client *mpd.Client) {
+		if m.connector.Status()["state"] == "play" {
+			log.Debugf("%s: pausing playback", reason)
+			paused = !errCheck(client.Pause(true), "Pause() failed")
+		}
+	})
+	return paused
+}
+
+// resume resumes paused playback
+func (m *SessionMonitor) resume(reason string) {
+	m.connector.IfConnected(func(client *mpd.Client) {
+		if m.connector.Status()["state"] == "pause" {
+			log.Debugf("%s: resuming playback", reason)
+			errCheck(client.Pause(false), "Pause() failed")
+		}
+	})
+}
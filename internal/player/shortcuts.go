@@ -0,0 +1,192 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/util"
+	"html"
+	"strings"
+)
+
+// shortcutArea is an area of the application a keyboard shortcut applies to
+type shortcutArea int
+
+const (
+	saGeneral shortcutArea = iota
+	saPlayer
+	saQueue
+	saLibrary
+	saStreams
+	shortcutAreaCount
+)
+
+// shortcut describes a keyboard shortcut listed in the shortcuts window
+type shortcut struct {
+	area  shortcutArea // Area the shortcut applies to
+	title string       // Untranslated description of the shortcut
+	accel string       // Accelerator(s) of the shortcut, in the format understood by gtk_accelerator_parse()
+}
+
+// actionShortcutTitles maps the names of the application actions having a keyboard shortcut to their (untranslated)
+// descriptions in the shortcuts window
+var actionShortcutTitles = map[string]string{
+	"mpd.connect":            util.LocalNoop("(Re)connect to MPD"),
+	"mpd.disconnect":         util.LocalNoop("Disconnect from MPD"),
+	"mpd.info":               util.LocalNoop("MPD Information"),
+	"prefs":                  util.LocalNoop("Preferences"),
+	"quit":                   util.LocalNoop("Quit"),
+	"about":                  util.LocalNoop("About"),
+	"shortcuts":              util.LocalNoop("Keyboard Shortcuts"),
+	"page.queue":             util.LocalNoop("Switch to Queue tab"),
+	"page.library":           util.LocalNoop("Switch to Library tab"),
+	"page.streams":           util.LocalNoop("Switch to Streams tab"),
+	"page.podcasts":          util.LocalNoop("Switch to Podcasts tab"),
+	"player.previous":        util.LocalNoop("Previous track"),
+	"player.next":            util.LocalNoop("Next track"),
+	"player.stop":            util.LocalNoop("Stop"),
+	"player.play-pause":      util.LocalNoop("Toggle play/pause"),
+	"player.toggle.random":   util.LocalNoop("Toggle random mode"),
+	"player.toggle.repeat":   util.LocalNoop("Toggle repeat mode"),
+	"player.toggle.consume":  util.LocalNoop("Toggle consume mode"),
+	"player.star":            util.LocalNoop("Star or unstar track"),
+	"player.radio.remember":  util.LocalNoop("Remember the song playing on the radio"),
+	"player.love":            util.LocalNoop("Love or unlove track on Last.fm"),
+	"player.similar":         util.LocalNoop("Append tracks similar to the current one"),
+	"queue.now-playing":      util.LocalNoop("Now playing"),
+	"queue.sort.shuffle":     util.LocalNoop("Shuffle the queue"),
+	"queue.save-back":        util.LocalNoop("Save the queue back to its playlist"),
+	"playlist.append-recent": util.LocalNoop("Add selection to the last used playlist"),
+}
+
+// keyShortcuts lists the keyboard shortcuts handled by the widgets themselves rather than by application actions
+var keyShortcuts = []shortcut{
+	{saQueue, util.LocalNoop("Play selection"), "Return"},
+	{saQueue, util.LocalNoop("Toggle play/pause"), "space"},
+	{saQueue, util.LocalNoop("Delete selected"), "Delete"},
+	{saQueue, util.LocalNoop("Open Filter bar"), "<ctrl>F"},
+	{saLibrary, util.LocalNoop("Default action (set in Preferences)"), "Return"},
+	{saLibrary, util.LocalNoop("Replace queue with selection"), "<ctrl>Return"},
+	{saLibrary, util.LocalNoop("Append selection to queue"), "<shift>Return"},
+	{saLibrary, util.LocalNoop("Go a level up"), "BackSpace"},
+	{saLibrary, util.LocalNoop("Rename selected playlist"), "F2"},
+	{saLibrary, util.LocalNoop("Open Search bar"), "<ctrl>F"},
+	{saStreams, util.LocalNoop("Default action (set in Preferences)"), "Return"},
+	{saStreams, util.LocalNoop("Replace queue with selection"), "<ctrl>Return"},
+	{saStreams, util.LocalNoop("Append selection to queue"), "<shift>Return"},
+}
+
+// vimKeyShortcuts lists the keyboard shortcuts added by the Vim-style key binding profile. Sequences of keys are
+// joined with '&'
+var vimKeyShortcuts = []shortcut{
+	{saQueue, util.LocalNoop("Move down/up"), "j k"},
+	{saQueue, util.LocalNoop("Go to the first/last track"), "g&g <shift>g"},
+	{saQueue, util.LocalNoop("Open Filter bar"), "slash"},
+	{saQueue, util.LocalNoop("Cut selected"), "d&d"},
+	{saQueue, util.LocalNoop("Paste after selected or current track"), "p"},
+	{saLibrary, util.LocalNoop("Move down/up"), "j k"},
+	{saLibrary, util.LocalNoop("Go to the first/last item"), "g&g <shift>g"},
+	{saLibrary, util.LocalNoop("Open Search bar"), "slash"},
+	{saStreams, util.LocalNoop("Move down/up"), "j k"},
+	{saStreams, util.LocalNoop("Go to the first/last item"), "g&g <shift>g"},
+}
+
+// title returns the translated title of the area
+func (a shortcutArea) title() string {
+	switch a {
+	case saPlayer:
+		return glib.Local("Player")
+	case saQueue:
+		return glib.Local("Queue")
+	case saLibrary:
+		return glib.Local("Library")
+	case saStreams:
+		return glib.Local("Streams")
+	default:
+		return glib.Local("General")
+	}
+}
+
+// actionShortcut returns the shortcut entry for the application action with the given name and accelerators
+func actionShortcut(action string, accels []string) shortcut {
+	title, ok := actionShortcutTitles[action]
+	if !ok {
+		log.Warningf("No shortcut title for action '%s'", action)
+		title = action
+	}
+	return shortcut{area: actionShortcutArea(action), title: title, accel: strings.Join(accels, " ")}
+}
+
+// actionShortcutArea returns the area the application action with the given name applies to, judging by its prefix
+func actionShortcutArea(action string) shortcutArea {
+	switch strings.SplitN(action, ".", 2)[0] {
+	case "player":
+		return saPlayer
+	case "queue":
+		return saQueue
+	case "library", "playlist":
+		return saLibrary
+	case "stream":
+		return saStreams
+	}
+	return saGeneral
+}
+
+// shortcutsWindowXML returns the GtkBuilder definition of a ShortcutsWindow listing the given shortcuts, grouped by
+// area. Shortcuts keep their relative order within a group
+func shortcutsWindowXML(shortcuts []shortcut) string {
+	var b strings.Builder
+	b.WriteString(`<interface>` +
+		`<requires lib="gtk+" version="3.22"/>` +
+		`<object class="GtkShortcutsWindow" id="ShortcutsWindow">` +
+		`<property name="modal">1</property>` +
+		`<property name="resizable">1</property>` +
+		`<property name="default-width">800</property>` +
+		`<property name="default-height">600</property>`)
+	fmt.Fprintf(&b, `<property name="title">%s</property>`, html.EscapeString(glib.Local("Ymuse shortcuts")))
+	b.WriteString(`<child><object class="GtkShortcutsSection">` +
+		`<property name="section-name">shortcuts</property>`)
+	fmt.Fprintf(&b, `<property name="title">%s</property>`, html.EscapeString(glib.Local("Application shortcuts")))
+
+	// Add a group per area, skipping empty ones
+	for area := saGeneral; area < shortcutAreaCount; area++ {
+		groupStarted := false
+		for _, s := range shortcuts {
+			if s.area != area {
+				continue
+			}
+			if !groupStarted {
+				fmt.Fprintf(&b,
+					`<child><object class="GtkShortcutsGroup"><property name="title">%s</property>`,
+					html.EscapeString(area.title()))
+				groupStarted = true
+			}
+			fmt.Fprintf(&b,
+				`<child><object class="GtkShortcutsShortcut">`+
+					`<property name="title">%s</property>`+
+					`<property name="accelerator">%s</property>`+
+					`</object></child>`,
+				html.EscapeString(glib.Local(s.title)),
+				html.EscapeString(s.accel))
+		}
+		if groupStarted {
+			b.WriteString(`</object></child>`)
+		}
+	}
+	b.WriteString(`</object></child></object></interface>`)
+	return b.String()
+}
@@ -0,0 +1,80 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestActionShortcutArea(t *testing.T) {
+	tests := []struct {
+		action string
+		want   shortcutArea
+	}{
+		{"quit", saGeneral},
+		{"page.queue", saGeneral},
+		{"player.next", saPlayer},
+		{"player.toggle.random", saPlayer},
+		{"queue.now-playing", saQueue},
+		{"library.rename", saLibrary},
+		{"playlist.append-recent", saLibrary},
+		{"stream.add", saStreams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			if got := actionShortcutArea(tt.action); got != tt.want {
+				t.Errorf("actionShortcutArea() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionShortcut(t *testing.T) {
+	got := actionShortcut("player.next", []string{"<Ctrl>Right", "<Ctrl>n"})
+	want := shortcut{area: saPlayer, title: "Next track", accel: "<Ctrl>Right <Ctrl>n"}
+	if got != want {
+		t.Errorf("actionShortcut() = %v, want %v", got, want)
+	}
+
+	// Unknown actions are listed by their name
+	if got := actionShortcut("foo.bar", []string{"F5"}); got.title != "foo.bar" {
+		t.Errorf("actionShortcut() title = %v, want foo.bar", got.title)
+	}
+}
+
+func TestShortcutsWindowXML(t *testing.T) {
+	xml := shortcutsWindowXML([]shortcut{
+		{saQueue, "Delete selected", "Delete"},
+		{saGeneral, "Quit", "<Ctrl>Q"},
+		{saQueue, "Now playing", "<Ctrl>J"},
+	})
+
+	// Groups follow the area order, shortcuts keep their order within a group; empty groups are omitted
+	var titles []string
+	for _, s := range strings.Split(xml, `<property name="title">`)[1:] {
+		titles = append(titles, s[:strings.Index(s, "<")])
+	}
+	want := []string{"Ymuse shortcuts", "Application shortcuts", "General", "Quit", "Queue", "Delete selected", "Now playing"}
+	if strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Errorf("shortcutsWindowXML() titles = %v, want %v", titles, want)
+	}
+
+	// Accelerators are escaped
+	if !strings.Contains(xml, `<property name="accelerator">&lt;Ctrl&gt;Q</property>`) {
+		t.Errorf("shortcutsWindowXML() lacks an escaped accelerator: %v", xml)
+	}
+}
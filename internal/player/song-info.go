@@ -0,0 +1,223 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/generated"
+	"github.com/yktoo/ymuse/internal/util"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// songInfoFileAttrs lists track attributes that are displayed in the File section of the dialog, rather than as tags
+var songInfoFileAttrs = map[string]bool{
+	"file":          true,
+	"Format":        true,
+	"duration":      true,
+	"Time":          true,
+	"Last-Modified": true,
+	"Pos":           true,
+	"Id":            true,
+	"Prio":          true,
+	"Range":         true,
+}
+
+// songInfoWorkAttrs lists track attributes that are displayed in the Work section of the dialog, in the display order
+var songInfoWorkAttrs = []string{"Work", "MovementNumber", "Movement"}
+
+// SongInfo represents the song information dialog
+type SongInfo struct {
+	SongInfoDialog *gtk.MessageDialog
+	SongInfoGrid   *gtk.Grid
+
+	closed bool // Whether the dialog has been closed
+}
+
+// SongInfoDialog creates, shows and disposes of a song information dialog instance
+// attrs: track attributes as returned by MPD
+// comments: track comments (raw tags) as returned by MPD's readcomments command; can be nil
+func SongInfoDialog(parent gtk.IWindow, attrs, comments mpd.Attrs) {
+	// Load the dialog layout and map the widgets
+	d := &SongInfo{}
+	builder, err := NewBuilder(generated.GetSongInfoGlade())
+	if err == nil {
+		err = builder.BindWidgets(d)
+	}
+
+	// Check for errors
+	if errCheck(err, "SongInfoDialog(): failed to initialise dialog") {
+		util.ErrorDialog(parent, fmt.Sprint(glib.Local("Failed to load UI widgets"), err))
+		return
+	}
+	defer d.SongInfoDialog.Destroy()
+	g := &propertyGrid{grid: d.SongInfoGrid}
+
+	// File section
+	g.addHeader(glib.Local("File"))
+	g.addProperty(glib.Local("Path"), attrs["file"])
+	g.addProperty(glib.Local("Format"), attrs["Format"])
+	g.addProperty(glib.Local("Duration"), util.FormatSecondsStr(attrs["duration"]))
+	if t, err := time.Parse(time.RFC3339, attrs["Last-Modified"]); err == nil {
+		g.addProperty(glib.Local("Last modified"), t.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	// Work section, for classical music
+	workAttrs := map[string]bool{}
+	if attrs["Work"] != "" || attrs["Movement"] != "" {
+		g.addHeader(glib.Local("Work"))
+		for _, name := range songInfoWorkAttrs {
+			g.addProperty(name, attrs[name])
+			workAttrs[name] = true
+		}
+	}
+
+	// Tags section: all the remaining attributes, sorted by name
+	g.addHeader(glib.Local("Tags"))
+	for _, name := range sortedAttrNames(attrs) {
+		if !songInfoFileAttrs[name] && !workAttrs[name] {
+			g.addProperty(name, attrs[name])
+		}
+	}
+
+	// Split comments into ReplayGain values and the rest
+	replayGain, other := mpd.Attrs{}, mpd.Attrs{}
+	for name, value := range comments {
+		if strings.HasPrefix(strings.ToUpper(name), "REPLAYGAIN_") {
+			replayGain[name] = value
+		} else {
+			other[name] = value
+		}
+	}
+
+	// ReplayGain section
+	if len(replayGain) > 0 {
+		g.addHeader(glib.Local("ReplayGain"))
+		for _, name := range sortedAttrNames(replayGain) {
+			g.addProperty(name, replayGain[name])
+		}
+	}
+
+	// Comments section
+	if len(other) > 0 {
+		g.addHeader(glib.Local("Comments"))
+		for _, name := range sortedAttrNames(other) {
+			g.addProperty(name, other[name])
+		}
+	}
+
+	// MusicBrainz section, if the track is tagged with MusicBrainz IDs
+	recordingID, releaseID := attrs["MUSICBRAINZ_TRACKID"], attrs["MUSICBRAINZ_ALBUMID"]
+	if IsMusicBrainzID(recordingID) || IsMusicBrainzID(releaseID) {
+		g.addHeader("MusicBrainz")
+		d.loadMusicBrainz(g, recordingID, releaseID)
+	}
+
+	// Set up and show the dialog
+	d.SongInfoDialog.SetTransientFor(parent)
+	d.SongInfoDialog.ShowAll()
+	d.SongInfoDialog.Run()
+	d.closed = true
+}
+
+// loadMusicBrainz fetches the details of the given recording and release from MusicBrainz in the background and adds
+// them to the end of the grid. Invalid IDs are skipped
+func (d *SongInfo) loadMusicBrainz(g *propertyGrid, recordingID, releaseID string) {
+	// Display a placeholder meanwhile
+	lbl := util.NewLabel(glib.Local("Loading…"))
+	if lbl == nil {
+		return
+	}
+	g.grid.Attach(lbl, 0, g.row, 2, 1)
+
+	go func() {
+		var rec *MusicBrainzRecording
+		var rel *MusicBrainzRelease
+		var err error
+		if IsMusicBrainzID(recordingID) {
+			rec, err = FetchMusicBrainzRecording(musicBrainzURL, recordingID)
+		}
+		if err == nil && IsMusicBrainzID(releaseID) {
+			rel, err = FetchMusicBrainzRelease(musicBrainzURL, releaseID)
+		}
+		util.WhenIdle("SongInfo.showMusicBrainz()", func() {
+			// Ignore if the dialog is gone
+			if !d.closed {
+				lbl.Destroy()
+				d.showMusicBrainz(g, rec, rel, err)
+			}
+		})
+	}()
+}
+
+// showMusicBrainz adds the fetched MusicBrainz details to the grid
+func (d *SongInfo) showMusicBrainz(g *propertyGrid, rec *MusicBrainzRecording, rel *MusicBrainzRelease, err error) {
+	if rec != nil {
+		g.addLink(glib.Local("Recording"), rec.Title, MusicBrainzPageURL("recording", rec.ID))
+		g.addProperty(glib.Local("Disambiguation"), rec.Disambiguation)
+		if len(rec.ArtistCredit) > 0 {
+			g.addMarkupProperty(glib.Local("Artist"), musicBrainzCreditMarkup(rec.ArtistCredit))
+		}
+		if rec.Length > 0 {
+			g.addProperty(glib.Local("Length"), util.FormatSeconds(float64(rec.Length)/1000))
+		}
+		g.addProperty(glib.Local("First released"), rec.FirstReleaseDate)
+	}
+	if rel != nil {
+		g.addLink(glib.Local("Release"), rel.Title, MusicBrainzPageURL("release", rel.ID))
+		if len(rel.ArtistCredit) > 0 {
+			g.addMarkupProperty(glib.Local("Release artist"), musicBrainzCreditMarkup(rel.ArtistCredit))
+		}
+		g.addProperty(glib.Local("Release date"), rel.Date)
+		g.addProperty(glib.Local("Country"), rel.Country)
+		g.addProperty(glib.Local("Status"), rel.Status)
+		g.addProperty(glib.Local("Label"), rel.Labels())
+		g.addProperty(glib.Local("Barcode"), rel.Barcode)
+	}
+	if errCheck(err, "Failed to fetch MusicBrainz data") {
+		g.addProperty(glib.Local("Error"), err.Error())
+	}
+	g.grid.ShowAll()
+}
+
+// musicBrainzCreditMarkup returns Pango markup for the given artist credits, each one linking to the artist's page
+func musicBrainzCreditMarkup(credits []MusicBrainzCredit) string {
+	var sb strings.Builder
+	for _, c := range credits {
+		if IsMusicBrainzID(c.Artist.ID) {
+			sb.WriteString(fmt.Sprintf("<a href=\"%s\">%s</a>", MusicBrainzPageURL("artist", c.Artist.ID), html.EscapeString(c.Name)))
+		} else {
+			sb.WriteString(html.EscapeString(c.Name))
+		}
+		sb.WriteString(html.EscapeString(c.JoinPhrase))
+	}
+	return sb.String()
+}
+
+// sortedAttrNames returns the names of the given attributes in alphabetical order
+func sortedAttrNames(attrs mpd.Attrs) []string {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,145 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/yktoo/ymuse/internal/config"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseStreamList reads a list of radio stations from an M3U or PLS playlist. The format is determined by the extension
+// of the given file name. Station names are taken from extended info (titles), genres from #EXTGENRE lines of M3U
+// playlists; stations without a name are named after their URL
+func ParseStreamList(fileName string, r io.Reader) ([]config.StreamSpec, error) {
+	var streams []config.StreamSpec
+	var err error
+	switch ext := strings.ToLower(path.Ext(fileName)); ext {
+	case ".m3u", ".m3u8":
+		streams, err = parseM3UStreams(r)
+	case ".pls":
+		streams, err = parsePLSStreams(r)
+	default:
+		return nil, fmt.Errorf("unsupported playlist format: %s", ext)
+	}
+
+	// Name unnamed stations after their URL
+	for i := range streams {
+		if streams[i].Name == "" {
+			streams[i].Name = streams[i].URI
+		}
+	}
+	return streams, err
+}
+
+// parseM3UStreams reads the stations of an extended M3U playlist
+func parseM3UStreams(r io.Reader) ([]config.StreamSpec, error) {
+	var streams []config.StreamSpec
+	var cur config.StreamSpec
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Strip the UTF-8 byte order mark, if any
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// The title follows the first comma
+			if i := strings.Index(line, ","); i >= 0 {
+				cur.Name = strings.TrimSpace(line[i+1:])
+			}
+		case strings.HasPrefix(line, "#EXTGENRE:"):
+			cur.Genre = strings.TrimSpace(strings.TrimPrefix(line, "#EXTGENRE:"))
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			cur.URI = line
+			streams = append(streams, cur)
+			cur = config.StreamSpec{}
+		}
+	}
+	return streams, scanner.Err()
+}
+
+// parsePLSStreams reads the stations of a PLS playlist, ordered by their numbers
+func parsePLSStreams(r io.Reader) ([]config.StreamSpec, error) {
+	entries := make(map[int]*config.StreamSpec)
+	entry := func(n int) *config.StreamSpec {
+		if entries[n] == nil {
+			entries[n] = &config.StreamSpec{}
+		}
+		return entries[n]
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Only "FileN=..." and "TitleN=..." lines are of interest
+		key, value := scanner.Text(), ""
+		if i := strings.Index(key, "="); i >= 0 {
+			key, value = strings.TrimSpace(key[:i]), strings.TrimSpace(key[i+1:])
+		}
+		if value == "" {
+			continue
+		}
+		lkey := strings.ToLower(key)
+		switch {
+		case strings.HasPrefix(lkey, "file"):
+			if n, err := strconv.Atoi(key[4:]); err == nil {
+				entry(n).URI = value
+			}
+		case strings.HasPrefix(lkey, "title"):
+			if n, err := strconv.Atoi(key[5:]); err == nil {
+				entry(n).Name = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Order the entries by their numbers, skipping those without a URL
+	nums := make([]int, 0, len(entries))
+	for n, e := range entries {
+		if e.URI != "" {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	streams := make([]config.StreamSpec, len(nums))
+	for i, n := range nums {
+		streams[i] = *entries[n]
+	}
+	return streams, nil
+}
+
+// WriteStreamList writes the given radio stations as an extended M3U playlist, with a #EXTGENRE line for stations
+// having a genre
+func WriteStreamList(w io.Writer, streams []config.StreamSpec) error {
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintln(bw, "#EXTM3U")
+	for _, s := range streams {
+		_, _ = fmt.Fprintf(bw, "#EXTINF:-1,%s\n", s.Name)
+		if s.Genre != "" {
+			_, _ = fmt.Fprintf(bw, "#EXTGENRE:%s\n", s.Genre)
+		}
+		_, _ = fmt.Fprintln(bw, s.URI)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,78 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bytes"
+	"github.com/yktoo/ymuse/internal/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStreamList(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		data     string
+		want     []config.StreamSpec
+		wantErr  bool
+	}{
+		{"m3u", "radio.M3U", "\uFEFF#EXTM3U\n#EXTINF:-1,Jazz FM\n#EXTGENRE:Jazz\nhttp://jazz.example.com/\n\nhttp://plain.example.com/\r\n",
+			[]config.StreamSpec{
+				{Name: "Jazz FM", URI: "http://jazz.example.com/", Genre: "Jazz"},
+				{Name: "http://plain.example.com/", URI: "http://plain.example.com/"},
+			}, false},
+		{"pls", "radio.pls", "[playlist]\nFile2=http://b.example.com/\nTitle2=B\nfile1 = http://a.example.com/\nTitle3=No file\nNumberOfEntries=2\n",
+			[]config.StreamSpec{
+				{Name: "http://a.example.com/", URI: "http://a.example.com/"},
+				{Name: "B", URI: "http://b.example.com/"},
+			}, false},
+		{"unsupported", "radio.xspf", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStreamList(tt.fileName, strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStreamList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStreamList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteStreamList(t *testing.T) {
+	streams := []config.StreamSpec{
+		{Name: "Jazz FM", URI: "http://jazz.example.com/", Genre: "Jazz"},
+		{Name: "News", URI: "http://news.example.com/"},
+	}
+	var buf bytes.Buffer
+	if err := WriteStreamList(&buf, streams); err != nil {
+		t.Fatalf("WriteStreamList() error = %v", err)
+	}
+	want := "#EXTM3U\n#EXTINF:-1,Jazz FM\n#EXTGENRE:Jazz\nhttp://jazz.example.com/\n#EXTINF:-1,News\nhttp://news.example.com/\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteStreamList() = %q, want %q", got, want)
+	}
+
+	// Make sure the list survives a round trip
+	got, err := ParseStreamList("radio.m3u", &buf)
+	if err != nil || !reflect.DeepEqual(got, streams) {
+		t.Errorf("ParseStreamList() = (%v, %v), want %v", got, err, streams)
+	}
+}
@@ -0,0 +1,143 @@
+//go:build !nogstreamer
+// +build !nogstreamer
+
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+// #cgo pkg-config: gstreamer-1.0
+// #include <stdlib.h>
+// #include <gst/gst.h>
+//
+// static void set_uri(GstElement *e, const gchar *uri) {
+//     g_object_set(G_OBJECT(e), "uri", uri, NULL);
+// }
+//
+// // Returns the message of the first error posted on the element's bus, if any, to be freed by the caller
+// static gchar *pop_error(GstElement *e) {
+//     GstBus *bus = gst_element_get_bus(e);
+//     GstMessage *msg = gst_bus_pop_filtered(bus, GST_MESSAGE_ERROR);
+//     gst_object_unref(bus);
+//     if (msg == NULL) {
+//         return NULL;
+//     }
+//     GError *err = NULL;
+//     gst_message_parse_error(msg, &err, NULL);
+//     gst_message_unref(msg);
+//     gchar *s = g_strdup(err->message);
+//     g_error_free(err);
+//     return s;
+// }
+import "C"
+import (
+	"errors"
+	"github.com/yktoo/ymuse/internal/util"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// streamListenerAvailable tells whether the application is built with the stream listener
+const streamListenerAvailable = true
+
+// streamStartTimeout is how long a stream is allowed to take to start playing before it's considered failed
+const streamStartTimeout = 10 * time.Second
+
+var gstInitOnce sync.Once
+
+// StreamListener plays MPD's HTTP stream on the local machine using a GStreamer playbin
+type StreamListener struct {
+	playbin *C.GstElement // Playbin element
+	playing bool          // Whether the stream is being played
+	attempt int           // Sequence number of the last playback start, to ignore outdated failures
+	onError func(error)   // Callback for a failure to play the stream
+}
+
+// NewStreamListener creates and returns a new StreamListener instance. onError is invoked on the GTK thread when the
+// stream fails to start playing
+func NewStreamListener(onError func(error)) (*StreamListener, error) {
+	gstInitOnce.Do(func() { C.gst_init(nil, nil) })
+
+	name := C.CString("playbin")
+	defer C.free(unsafe.Pointer(name))
+	playbin := C.gst_element_factory_make(name, nil)
+	if playbin == nil {
+		return nil, errors.New("failed to create GStreamer playbin")
+	}
+	return &StreamListener{playbin: playbin, onError: onError}, nil
+}
+
+// Play starts playing the stream with the given URL
+func (l *StreamListener) Play(url string) {
+	l.Stop()
+	log.Debugf("Listening to %s", url)
+	cURL := C.CString(url)
+	defer C.free(unsafe.Pointer(cURL))
+	C.set_uri(l.playbin, cURL)
+	l.playing = true
+	l.attempt++
+	attempt := l.attempt
+	if C.gst_element_set_state(l.playbin, C.GST_STATE_PLAYING) == C.GST_STATE_CHANGE_FAILURE {
+		l.fail(attempt)
+		return
+	}
+
+	// Wait for the stream to start in the background, it may take a while to connect and buffer. The goroutine holds its
+	// own reference to the playbin as the listener may get closed in the meantime
+	playbin := l.playbin
+	C.gst_object_ref(C.gpointer(unsafe.Pointer(playbin)))
+	go func() {
+		defer C.gst_object_unref(C.gpointer(unsafe.Pointer(playbin)))
+		if C.gst_element_get_state(playbin, nil, nil, C.GstClockTime(streamStartTimeout)) == C.GST_STATE_CHANGE_FAILURE {
+			util.WhenIdle("StreamListener.fail()", l.fail, attempt)
+		}
+	}()
+}
+
+// Stop stops playing the stream
+func (l *StreamListener) Stop() {
+	if l.playing {
+		log.Debug("Stopped listening")
+		C.gst_element_set_state(l.playbin, C.GST_STATE_NULL)
+		l.playing = false
+	}
+}
+
+// IsPlaying returns whether the stream is being played
+func (l *StreamListener) IsPlaying() bool {
+	return l.playing
+}
+
+// Close stops playing the stream and disposes of the playbin
+func (l *StreamListener) Close() {
+	l.Stop()
+	C.gst_object_unref(C.gpointer(unsafe.Pointer(l.playbin)))
+}
+
+// fail stops the playback started by the given attempt and reports the error posted by the playbin
+func (l *StreamListener) fail(attempt int) {
+	// Ignore if the playback has been stopped or restarted in the meantime
+	if !l.playing || attempt != l.attempt {
+		return
+	}
+	err := errors.New("failed to play the stream")
+	if msg := C.pop_error(l.playbin); msg != nil {
+		err = errors.New(C.GoString((*C.char)(msg)))
+		C.g_free(C.gpointer(unsafe.Pointer(msg)))
+	}
+	l.Stop()
+	l.onError(err)
+}
@@ -0,0 +1,46 @@
+//go:build nogstreamer
+// +build nogstreamer
+
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import "errors"
+
+// streamListenerAvailable tells whether the application is built with the stream listener
+const streamListenerAvailable = false
+
+// StreamListener is a stub of the local player of MPD's HTTP stream, for builds without GStreamer
+type StreamListener struct{}
+
+// NewStreamListener always fails as the application is built without GStreamer
+func NewStreamListener(func(error)) (*StreamListener, error) {
+	return nil, errors.New("built without GStreamer support")
+}
+
+// Play does nothing
+func (l *StreamListener) Play(string) {}
+
+// Stop does nothing
+func (l *StreamListener) Stop() {}
+
+// IsPlaying always returns false
+func (l *StreamListener) IsPlaying() bool {
+	return false
+}
+
+// Close does nothing
+func (l *StreamListener) Close() {}
@@ -0,0 +1,40 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"strconv"
+	"strings"
+)
+
+// httpdOutputID returns the ID of MPD's HTTP streaming output among the given outputs, -1 if there's none
+func httpdOutputID(outputs []mpd.Attrs) int {
+	// Prefer the output plugin reported by MPD 0.21+, fall back to the output name otherwise
+	for _, o := range outputs {
+		if plugin, ok := o["plugin"]; ok {
+			if plugin != "httpd" {
+				continue
+			}
+		} else if !strings.Contains(strings.ToLower(o["outputname"]), "http") {
+			continue
+		}
+		if i, err := strconv.Atoi(o["outputid"]); err == nil {
+			return i
+		}
+	}
+	return -1
+}
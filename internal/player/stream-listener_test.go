@@ -0,0 +1,53 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"testing"
+)
+
+func TestHttpdOutputID(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []mpd.Attrs
+		want    int
+	}{
+		{"no outputs", nil, -1},
+		{"by plugin", []mpd.Attrs{
+			{"outputid": "0", "outputname": "Speakers", "plugin": "pulse"},
+			{"outputid": "1", "outputname": "Stream", "plugin": "httpd"},
+		}, 1},
+		{"plugin wins over name", []mpd.Attrs{
+			{"outputid": "0", "outputname": "HTTP to Snapcast", "plugin": "fifo"},
+		}, -1},
+		{"by name", []mpd.Attrs{
+			{"outputid": "0", "outputname": "ALSA"},
+			{"outputid": "3", "outputname": "My HTTP Stream"},
+		}, 3},
+		{"invalid ID", []mpd.Attrs{
+			{"outputid": "x", "outputname": "Stream", "plugin": "httpd"},
+			{"outputid": "2", "outputname": "Stream 2", "plugin": "httpd"},
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := httpdOutputID(tt.outputs); got != tt.want {
+				t.Errorf("httpdOutputID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
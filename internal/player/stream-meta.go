@@ -0,0 +1,55 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/util"
+	"strings"
+)
+
+// streamTitleSeparator separates the artist from the song title in the metadata (ICY title) of most Internet radios
+const streamTitleSeparator = " - "
+
+// EnrichStreamAttrs returns the attributes of an Internet stream completed with the ones derived from its metadata: the
+// artist and title are split off the stream title, and the station name takes the place of the album, so that the
+// current song of a radio is displayed like a regular track. Attributes of other tracks are returned as is
+func EnrichStreamAttrs(attrs mpd.Attrs) mpd.Attrs {
+	title := attrs["Title"]
+	if !util.IsStreamURI(attrs["file"]) || title == "" {
+		return attrs
+	}
+
+	// Make a copy not to affect the original
+	result := make(mpd.Attrs, len(attrs)+2)
+	for k, v := range attrs {
+		result[k] = v
+	}
+
+	// Split "Artist - Title", unless the stream provides the artist separately
+	if result["Artist"] == "" {
+		if i := strings.Index(title, streamTitleSeparator); i > 0 && i+len(streamTitleSeparator) < len(title) {
+			result["Artist"] = strings.TrimSpace(title[:i])
+			result["Title"] = strings.TrimSpace(title[i+len(streamTitleSeparator):])
+		}
+	}
+
+	// Use the station name as the album
+	if result["Album"] == "" && result["Name"] != "" {
+		result["Album"] = result["Name"]
+	}
+	return result
+}
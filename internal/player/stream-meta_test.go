@@ -0,0 +1,63 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"reflect"
+	"testing"
+)
+
+func TestEnrichStreamAttrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs mpd.Attrs
+		want  mpd.Attrs
+	}{
+		{"local file",
+			mpd.Attrs{"file": "a/b.mp3", "Title": "Foo - Bar"},
+			mpd.Attrs{"file": "a/b.mp3", "Title": "Foo - Bar"}},
+		{"stream without title",
+			mpd.Attrs{"file": "http://radio/", "Name": "Radio"},
+			mpd.Attrs{"file": "http://radio/", "Name": "Radio"}},
+		{"stream with artist and title",
+			mpd.Attrs{"file": "http://radio/", "Name": "Radio", "Title": "ABBA - Waterloo"},
+			mpd.Attrs{"file": "http://radio/", "Name": "Radio", "Title": "Waterloo", "Artist": "ABBA", "Album": "Radio"}},
+		{"stream with plain title",
+			mpd.Attrs{"file": "https://radio/", "Title": "Morning show"},
+			mpd.Attrs{"file": "https://radio/", "Title": "Morning show"}},
+		{"stream with separate artist",
+			mpd.Attrs{"file": "http://radio/", "Name": "Radio", "Artist": "AC/DC", "Title": "T.N.T. - Live"},
+			mpd.Attrs{"file": "http://radio/", "Name": "Radio", "Artist": "AC/DC", "Title": "T.N.T. - Live", "Album": "Radio"}},
+		{"dangling separator",
+			mpd.Attrs{"file": "http://radio/", "Title": "Jingle - "},
+			mpd.Attrs{"file": "http://radio/", "Title": "Jingle - "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := make(mpd.Attrs)
+			for k, v := range tt.attrs {
+				orig[k] = v
+			}
+			if got := EnrichStreamAttrs(tt.attrs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnrichStreamAttrs() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.attrs, orig) {
+				t.Errorf("EnrichStreamAttrs() modified its argument: %v", tt.attrs)
+			}
+		})
+	}
+}
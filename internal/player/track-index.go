@@ -0,0 +1,172 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"math/rand"
+	"path"
+	"strings"
+	"sync"
+)
+
+// trackIndexMaxTracks is the maximum number of tracks in the MPD database the track index is built for. Loading bigger
+// databases takes too long and too much memory, so the features relying on the index are unavailable for them
+const trackIndexMaxTracks = 100000
+
+// errTrackIndexTooLarge is returned by TrackIndex.Tracks() when the database exceeds trackIndexMaxTracks
+var errTrackIndexTooLarge = fmt.Errorf("the database holds over %d tracks, too many to index", trackIndexMaxTracks)
+
+// TrackIndex is a locally cached list of all tracks in the MPD database along with their tags, used for matching
+// tracks in ways MPD doesn't support. The index is loaded lazily, once until invalidated (on every database update),
+// and can be used from any goroutine
+type TrackIndex struct {
+	connector *Connector  // Connector to load the tracks through
+	tracks    []mpd.Attrs // Cached tracks, nil if not loaded yet
+	mutex     sync.Mutex
+}
+
+// NewTrackIndex creates and returns a new, empty TrackIndex instance
+func NewTrackIndex(connector *Connector) *TrackIndex {
+	return &TrackIndex{connector: connector}
+}
+
+// Tracks returns all tracks in the database, loading them from MPD if they aren't cached yet. Returns
+// errTrackIndexTooLarge if the database holds over trackIndexMaxTracks tracks
+func (x *TrackIndex) Tracks() ([]mpd.Attrs, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	// Load the tracks if needed
+	if x.tracks == nil {
+		var attrs []mpd.Attrs
+		var err error
+		x.connector.IfConnected(func(client *mpd.Client) {
+			// Check the number of tracks first, so that an oversized database isn't fetched at all
+			var stats mpd.Attrs
+			if stats, err = client.Stats(); err != nil {
+				return
+			}
+			if util.AtoiDef(stats["songs"], 0) > trackIndexMaxTracks {
+				err = errTrackIndexTooLarge
+				return
+			}
+			attrs, err = client.ListAllInfo("/")
+		})
+		if errCheck(err, "TrackIndex.Tracks(): failed to load tracks") {
+			return nil, err
+		}
+		// Don't cache anything while disconnected
+		if attrs == nil {
+			return nil, nil
+		}
+		x.tracks = filterFileAttrs(attrs)
+		log.Debugf("Indexed %d tracks", len(x.tracks))
+	}
+	return x.tracks, nil
+}
+
+// Invalidate drops the cached tracks, so that they're reloaded on the next use
+func (x *TrackIndex) Invalidate() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.tracks = nil
+}
+
+// trackSearchAttrNames returns the names of the attributes to match tracks against, given the name of the selected
+// search attribute, which can be "any"
+func trackSearchAttrNames(attrName string) []string {
+	if attrName != "any" {
+		return []string{attrName}
+	}
+	var names []string
+	for _, id := range config.MpdTrackAttributeIds {
+		if attr := config.MpdTrackAttributes[id]; attr.Searchable {
+			names = append(names, attr.AttrName)
+		}
+	}
+	return names
+}
+
+// MatchTrackAttrs returns the tracks having a value of any of the given attributes accepted by the match function.
+// Attribute names are compared case-insensitively, as MPD does. Track's file is matched both by its full URI and by its
+// base name
+func MatchTrackAttrs(tracks []mpd.Attrs, attrNames []string, match func(s string) bool) []mpd.Attrs {
+	var result []mpd.Attrs
+	for _, a := range tracks {
+		for name, value := range a {
+			if value != "" && attrNameIn(name, attrNames) && (match(value) || name == "file" && match(path.Base(value))) {
+				result = append(result, a)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// MatchSimilarTracks returns the URIs of at most limit tracks corresponding to the given similar tracks and artists:
+// first the tracks matching the similar tracks by artist and title, then a random track by each of the similar artists.
+// Names are compared ignoring case and diacritics. Tracks whose URIs are in exclude are skipped
+func MatchSimilarTracks(tracks []mpd.Attrs, similar []LastFmTrack, artists []string, exclude map[string]bool, limit int) []string {
+	normalise := func(s string) string { return strings.ToLower(util.FoldDiacritics(strings.TrimSpace(s))) }
+
+	// Index the tracks by artist, and by artist and title
+	byArtist := make(map[string][]string)
+	byTrack := make(map[LastFmTrack]string)
+	for _, a := range tracks {
+		uri := a["file"]
+		if exclude[uri] || a["Artist"] == "" {
+			continue
+		}
+		artist := normalise(a["Artist"])
+		byArtist[artist] = append(byArtist[artist], uri)
+		if key := (LastFmTrack{Artist: artist, Title: normalise(a["Title"])}); key.Title != "" && byTrack[key] == "" {
+			byTrack[key] = uri
+		}
+	}
+
+	// Collect the matches, each track only once
+	var result []string
+	picked := make(map[string]bool)
+	add := func(uri string) {
+		if uri != "" && !picked[uri] && len(result) < limit {
+			picked[uri] = true
+			result = append(result, uri)
+		}
+	}
+	for _, t := range similar {
+		add(byTrack[LastFmTrack{Artist: normalise(t.Artist), Title: normalise(t.Title)}])
+	}
+	for _, artist := range artists {
+		if uris := byArtist[normalise(artist)]; len(uris) > 0 {
+			add(uris[rand.Intn(len(uris))])
+		}
+	}
+	return result
+}
+
+// attrNameIn returns whether the given attribute name is in the list, ignoring case
+func attrNameIn(name string, names []string) bool {
+	for _, n := range names {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/util"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatchTrackAttrs(t *testing.T) {
+	tracks := []mpd.Attrs{
+		{"file": "a/1.flac", "Artist": "Blind Guardian", "AlbumArtist": "Blind Guardian", "Title": "Valhalla"},
+		{"file": "b/1.flac", "Artist": "Various", "AlbumArtist": "Blind Guardian Tribute", "Title": "Bright Eyes"},
+		{"file": "blind/2.flac", "Artist": "Metallica", "Title": "One"},
+	}
+	contains := func(substr string) func(string) bool {
+		return func(s string) bool { return strings.Contains(strings.ToLower(s), substr) }
+	}
+	tests := []struct {
+		name      string
+		attrNames []string
+		match     func(string) bool
+		want      []string
+	}{
+		{"single attribute", []string{"Artist"}, contains("blind"), []string{"a/1.flac"}},
+		{"name case ignored", []string{"Albumartist"}, contains("blind"), []string{"a/1.flac", "b/1.flac"}},
+		{"multiple attributes", []string{"Artist", "file"}, contains("blind"), []string{"a/1.flac", "blind/2.flac"}},
+		{"fuzzy", []string{"Title"}, func(s string) bool { return util.FuzzyMatch("vlhl", s) }, []string{"a/1.flac"}},
+		{"file base name", []string{"file"}, regexp.MustCompile(`^2\.flac$`).MatchString, []string{"blind/2.flac"}},
+		{"no match", []string{"Title"}, contains("zzz"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range MatchTrackAttrs(tracks, tt.attrNames, tt.match) {
+				got = append(got, a["file"])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchTrackAttrs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSimilarTracks(t *testing.T) {
+	tracks := []mpd.Attrs{
+		{"file": "a/1.flac", "Artist": "Blind Guardian", "Title": "Valhalla"},
+		{"file": "a/2.flac", "Artist": "Blind Guardian", "Title": "Bright Eyes"},
+		{"file": "b/1.flac", "Artist": "Motörhead", "Title": "Ace of Spades"},
+		{"file": "c/1.flac", "Artist": "Metallica", "Title": "One"},
+		{"file": "d/1.flac", "Title": "No Artist"},
+	}
+	similar := []LastFmTrack{
+		{Artist: "Metallica", Title: "one"},
+		{Artist: "Motorhead", Title: "Ace Of Spades"},
+		{Artist: "Iron Maiden", Title: "Aces High"},
+		{Artist: "Metallica", Title: "One"},
+	}
+	tests := []struct {
+		name    string
+		artists []string
+		exclude map[string]bool
+		limit   int
+		want    []string
+	}{
+		{"tracks only", nil, nil, 10, []string{"c/1.flac", "b/1.flac"}},
+		{"artists add tracks", []string{"Iron Maiden", "Metallica", "Megadeth", "Motorhead"}, nil, 10, []string{"c/1.flac", "b/1.flac"}},
+		{"excluded", nil, map[string]bool{"c/1.flac": true}, 10, []string{"b/1.flac"}},
+		{"limit", nil, nil, 1, []string{"c/1.flac"}},
+		{"no match", []string{"Slayer"}, map[string]bool{"b/1.flac": true, "c/1.flac": true}, 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSimilarTracks(tracks, similar, tt.artists, tt.exclude, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchSimilarTracks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A random track of a similar artist is added after the matched tracks
+	got := MatchSimilarTracks(tracks, similar, []string{"blind guardian"}, nil, 10)
+	if len(got) != 3 || got[2] != "a/1.flac" && got[2] != "a/2.flac" {
+		t.Errorf("MatchSimilarTracks() = %v, want a Blind Guardian track last", got)
+	}
+}
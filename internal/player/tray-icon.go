@@ -0,0 +1,465 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	trayItemID       = "ymuse"                         // Application identifier of the status notifier item
+	trayItemPath     = "/StatusNotifierItem"           // Path of the status notifier item object
+	trayMenuPath     = "/MenuBar"                      // Path of the menu object
+	trayItemIface    = "org.kde.StatusNotifierItem"    // Status notifier item interface
+	trayMenuIface    = "com.canonical.dbusmenu"        // Menu interface
+	trayWatcherName  = "org.kde.StatusNotifierWatcher" // Bus name of the watcher the item is registered with
+	trayWatcherPath  = "/StatusNotifierWatcher"        // Path of the watcher object
+	trayWatcherIface = "org.kde.StatusNotifierWatcher" // Watcher interface
+	trayVolumeStep   = 5                               // Volume change, in percent, per scroll wheel step
+)
+
+// TrayIcon is a status icon in the system tray, published on the D-Bus session bus through the StatusNotifierItem
+// (AppIndicator) protocol, along with its context menu. Updates must happen on the GTK thread, D-Bus calls are served
+// in the background
+type TrayIcon struct {
+	conn      *dbus.Conn       // Session bus connection
+	busName   string           // Bus name the item is registered under
+	itemProps *prop.Properties // Exported properties of the item
+	menuProps *prop.Properties // Exported properties of the menu
+
+	onActivate     func()              // Callback for a click on the icon
+	onAction       func(action string) // Callback for activating an application action from the menu
+	onVolumeChange func(delta int)     // Callback for changing the volume by the given number of percent
+
+	menu         []TrayMenuItem // Menu items, the ID of each being its index + 1
+	menuRevision uint32         // Revision of the menu layout, incremented on every change
+	menuMutex    sync.RWMutex   // Mutex guarding the menu, which is also read by D-Bus calls
+}
+
+// TrayMenuItem describes an item of the tray icon's menu
+type TrayMenuItem struct {
+	Label   string // Item's label, empty for a separator
+	Action  string // Name of the application action the item activates, without the "app." prefix
+	Enabled bool   // Whether the item can be activated
+}
+
+// trayItem implements the org.kde.StatusNotifierItem interface
+type trayItem struct {
+	t *TrayIcon
+}
+
+// trayMenu implements the com.canonical.dbusmenu interface
+type trayMenu struct {
+	t *TrayIcon
+}
+
+// trayPixmap describes an icon image, (iiay) on the bus
+type trayPixmap struct {
+	Width  int32  // Image width in pixels
+	Height int32  // Image height in pixels
+	Data   []byte // ARGB32 image data
+}
+
+// trayToolTip describes the icon's tooltip, (sa(iiay)ss) on the bus
+type trayToolTip struct {
+	IconName    string       // Name of the tooltip icon
+	IconPixmap  []trayPixmap // Images of the tooltip icon
+	Title       string       // Tooltip title
+	Description string       // Tooltip text, may contain basic markup
+}
+
+// trayMenuLayout describes a menu item along with its children, (ia{sv}av) on the bus
+type trayMenuLayout struct {
+	ID         int32                   // Item ID, 0 for the root
+	Properties map[string]dbus.Variant // Item properties
+	Children   []dbus.Variant          // Child items, each being a trayMenuLayout
+}
+
+// trayMenuItemProps describes the properties of a menu item, (ia{sv}) on the bus
+type trayMenuItemProps struct {
+	ID         int32                   // Item ID
+	Properties map[string]dbus.Variant // Item properties
+}
+
+// trayMenuEvent describes an event happening to a menu item, (isvu) on the bus
+type trayMenuEvent struct {
+	ID        int32        // Item ID
+	EventID   string       // Event type, such as "clicked"
+	Data      dbus.Variant // Event-specific data
+	Timestamp uint32       // Moment the event has happened
+}
+
+// NewTrayIcon connects to the session bus, exports the status notifier item with its menu and registers it with the
+// status notifier watcher, which fails if no tray is available. onActivate, onAction and onVolumeChange are invoked
+// on the GTK thread when the user clicks the icon, chooses a menu item and scrolls over the icon, respectively
+func NewTrayIcon(onActivate func(), onAction func(action string), onVolumeChange func(delta int)) (*TrayIcon, error) {
+	conn, err := connectBus(dbus.SessionBusPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TrayIcon{
+		conn:           conn,
+		busName:        fmt.Sprintf("org.kde.StatusNotifierItem-%d-1", os.Getpid()),
+		onActivate:     onActivate,
+		onAction:       onAction,
+		onVolumeChange: onVolumeChange,
+	}
+	if err = t.export(); err == nil {
+		err = t.register()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	log.Debugf("Registered tray icon as %s", t.busName)
+
+	// Register again whenever the watcher gets restarted, for example with the desktop shell
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchSender("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchOption("arg0", trayWatcherName),
+	); !errCheck(err, "Failed to watch status notifier watcher") {
+		signals := make(chan *dbus.Signal, 1)
+		conn.Signal(signals)
+		go t.watch(signals)
+	}
+	return t, nil
+}
+
+// Close removes the icon from the tray and disconnects from the session bus
+func (t *TrayIcon) Close() {
+	errCheck(t.conn.Close(), "Failed to close session bus connection")
+}
+
+// SetToolTip updates the icon's tooltip
+func (t *TrayIcon) SetToolTip(title, description string) {
+	toolTip := trayToolTip{IconName: config.AppMetadata.Icon, IconPixmap: []trayPixmap{}, Title: title, Description: description}
+	if !reflect.DeepEqual(t.itemProps.GetMust(trayItemIface, "ToolTip"), toolTip) {
+		t.itemProps.SetMust(trayItemIface, "ToolTip", toolTip)
+		errCheck(t.conn.Emit(trayItemPath, trayItemIface+".NewToolTip"), "Failed to emit NewToolTip signal")
+	}
+}
+
+// SetMenu updates the items of the icon's menu
+func (t *TrayIcon) SetMenu(items []TrayMenuItem) {
+	t.menuMutex.Lock()
+	changed := !reflect.DeepEqual(items, t.menu)
+	if changed {
+		t.menu = append([]TrayMenuItem(nil), items...)
+		t.menuRevision++
+	}
+	revision := t.menuRevision
+	t.menuMutex.Unlock()
+
+	// Announce the change
+	if changed {
+		errCheck(t.conn.Emit(trayMenuPath, trayMenuIface+".LayoutUpdated", revision, int32(0)), "Failed to emit LayoutUpdated signal")
+	}
+}
+
+// export exports the item and menu objects and properties on the bus
+func (t *TrayIcon) export() error {
+	item, menu := &trayItem{t}, &trayMenu{t}
+	if err := t.conn.Export(item, trayItemPath, trayItemIface); err != nil {
+		return err
+	}
+	if err := t.conn.Export(menu, trayMenuPath, trayMenuIface); err != nil {
+		return err
+	}
+
+	// Define the properties. Hosts learn about changes from the item's own signals rather than from PropertiesChanged
+	var err error
+	noPixmaps := []trayPixmap{}
+	t.itemProps, err = prop.Export(t.conn, trayItemPath, map[string]map[string]*prop.Prop{
+		trayItemIface: {
+			"Category":            {Value: "ApplicationStatus", Emit: prop.EmitFalse},
+			"Id":                  {Value: trayItemID, Emit: prop.EmitFalse},
+			"Title":               {Value: config.AppMetadata.Name, Emit: prop.EmitFalse},
+			"Status":              {Value: "Active", Emit: prop.EmitFalse},
+			"WindowId":            {Value: int32(0), Emit: prop.EmitFalse},
+			"IconName":            {Value: config.AppMetadata.Icon, Emit: prop.EmitFalse},
+			"IconPixmap":          {Value: noPixmaps, Emit: prop.EmitFalse},
+			"OverlayIconName":     {Value: "", Emit: prop.EmitFalse},
+			"OverlayIconPixmap":   {Value: noPixmaps, Emit: prop.EmitFalse},
+			"AttentionIconName":   {Value: "", Emit: prop.EmitFalse},
+			"AttentionIconPixmap": {Value: noPixmaps, Emit: prop.EmitFalse},
+			"AttentionMovieName":  {Value: "", Emit: prop.EmitFalse},
+			"ToolTip":             {Value: trayToolTip{IconName: config.AppMetadata.Icon, IconPixmap: noPixmaps, Title: config.AppMetadata.Name}, Emit: prop.EmitFalse},
+			"ItemIsMenu":          {Value: false, Emit: prop.EmitFalse},
+			"Menu":                {Value: dbus.ObjectPath(trayMenuPath), Emit: prop.EmitFalse},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	t.menuProps, err = prop.Export(t.conn, trayMenuPath, map[string]map[string]*prop.Prop{
+		trayMenuIface: {
+			"Version":       {Value: uint32(3), Emit: prop.EmitFalse},
+			"TextDirection": {Value: textDirection(), Emit: prop.EmitFalse},
+			"Status":        {Value: "normal", Emit: prop.EmitFalse},
+			"IconThemePath": {Value: []string{}, Emit: prop.EmitFalse},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Describe the objects for introspection
+	itemNode := &introspect.Node{
+		Name: trayItemPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       trayItemIface,
+				Methods:    introspect.Methods(item),
+				Properties: t.itemProps.Introspection(trayItemIface),
+				Signals: []introspect.Signal{
+					{Name: "NewTitle"},
+					{Name: "NewIcon"},
+					{Name: "NewAttentionIcon"},
+					{Name: "NewOverlayIcon"},
+					{Name: "NewToolTip"},
+					{Name: "NewStatus", Args: []introspect.Arg{{Name: "status", Type: "s"}}},
+				},
+			},
+		},
+	}
+	if err := t.conn.Export(introspect.NewIntrospectable(itemNode), trayItemPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return err
+	}
+	menuNode := &introspect.Node{
+		Name: trayMenuPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       trayMenuIface,
+				Methods:    introspect.Methods(menu),
+				Properties: t.menuProps.Introspection(trayMenuIface),
+				Signals: []introspect.Signal{
+					{Name: "ItemsPropertiesUpdated", Args: []introspect.Arg{{Name: "updatedProps", Type: "a(ia{sv})"}, {Name: "removedProps", Type: "a(ias)"}}},
+					{Name: "LayoutUpdated", Args: []introspect.Arg{{Name: "revision", Type: "u"}, {Name: "parent", Type: "i"}}},
+					{Name: "ItemActivationRequested", Args: []introspect.Arg{{Name: "id", Type: "i"}, {Name: "timestamp", Type: "u"}}},
+				},
+			},
+		},
+	}
+	return t.conn.Export(introspect.NewIntrospectable(menuNode), trayMenuPath, "org.freedesktop.DBus.Introspectable")
+}
+
+// register claims the item's bus name and registers the item with the status notifier watcher
+func (t *TrayIcon) register() error {
+	if _, err := t.conn.RequestName(t.busName, dbus.NameFlagDoNotQueue); err != nil {
+		return err
+	}
+	return t.conn.Object(trayWatcherName, trayWatcherPath).Call(trayWatcherIface+".RegisterStatusNotifierItem", 0, t.busName).Err
+}
+
+// watch registers the item again whenever a new status notifier watcher appears on the bus, until the connection is
+// closed
+func (t *TrayIcon) watch(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if sig.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(sig.Body) != 3 {
+			continue
+		}
+		if newOwner, ok := sig.Body[2].(string); ok && newOwner != "" {
+			errCheck(t.register(), "Failed to register tray icon")
+		}
+	}
+}
+
+// menuItem returns a copy of the menu item with the given ID
+func (t *TrayIcon) menuItem(id int32) (TrayMenuItem, bool) {
+	t.menuMutex.RLock()
+	defer t.menuMutex.RUnlock()
+	if id < 1 || int(id) > len(t.menu) {
+		return TrayMenuItem{}, false
+	}
+	return t.menu[id-1], true
+}
+
+// activateMenuItem invokes the action of the menu item with the given ID, if it's enabled
+func (t *TrayIcon) activateMenuItem(id int32) {
+	if item, ok := t.menuItem(id); ok && item.Enabled && item.Action != "" {
+		util.WhenIdle("TrayIcon.activateMenuItem()", t.onAction, item.Action)
+	}
+}
+
+// Activate implements org.kde.StatusNotifierItem.Activate
+func (i *trayItem) Activate(_, _ int32) *dbus.Error {
+	util.WhenIdle("TrayIcon.Activate()", i.t.onActivate)
+	return nil
+}
+
+// SecondaryActivate implements org.kde.StatusNotifierItem.SecondaryActivate (a middle click) by toggling playback
+func (i *trayItem) SecondaryActivate(_, _ int32) *dbus.Error {
+	util.WhenIdle("TrayIcon.SecondaryActivate()", i.t.onAction, "player.play-pause")
+	return nil
+}
+
+// ContextMenu implements org.kde.StatusNotifierItem.ContextMenu. Hosts display the exported menu themselves
+func (i *trayItem) ContextMenu(_, _ int32) *dbus.Error {
+	return nil
+}
+
+// Scroll implements org.kde.StatusNotifierItem.Scroll by changing the volume. Hosts differ in the magnitude of delta,
+// so only its sign is taken into account
+func (i *trayItem) Scroll(delta int32, orientation string) *dbus.Error {
+	if !strings.EqualFold(orientation, "vertical") || delta == 0 {
+		return nil
+	}
+	step := trayVolumeStep
+	if delta < 0 {
+		step = -step
+	}
+	util.WhenIdle("TrayIcon.Scroll()", i.t.onVolumeChange, step)
+	return nil
+}
+
+// GetLayout implements com.canonical.dbusmenu.GetLayout
+func (m *trayMenu) GetLayout(parentID, recursionDepth int32, propertyNames []string) (uint32, trayMenuLayout, *dbus.Error) {
+	m.t.menuMutex.RLock()
+	defer m.t.menuMutex.RUnlock()
+	layout, err := trayMenuItemLayout(m.t.menu, parentID, recursionDepth, propertyNames)
+	if err != nil {
+		return 0, trayMenuLayout{}, dbus.MakeFailedError(err)
+	}
+	return m.t.menuRevision, layout, nil
+}
+
+// GetGroupProperties implements com.canonical.dbusmenu.GetGroupProperties. An empty ids list stands for all items
+func (m *trayMenu) GetGroupProperties(ids []int32, propertyNames []string) ([]trayMenuItemProps, *dbus.Error) {
+	m.t.menuMutex.RLock()
+	defer m.t.menuMutex.RUnlock()
+	if len(ids) == 0 {
+		for i := range m.t.menu {
+			ids = append(ids, int32(i+1))
+		}
+	}
+	result := make([]trayMenuItemProps, 0, len(ids))
+	for _, id := range ids {
+		if props, ok := trayMenuItemProperties(m.t.menu, id, propertyNames); ok {
+			result = append(result, trayMenuItemProps{ID: id, Properties: props})
+		}
+	}
+	return result, nil
+}
+
+// GetProperty implements com.canonical.dbusmenu.GetProperty
+func (m *trayMenu) GetProperty(id int32, name string) (dbus.Variant, *dbus.Error) {
+	m.t.menuMutex.RLock()
+	defer m.t.menuMutex.RUnlock()
+	if props, ok := trayMenuItemProperties(m.t.menu, id, []string{name}); ok {
+		if v, ok := props[name]; ok {
+			return v, nil
+		}
+	}
+	return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("no property %s on item %d", name, id))
+}
+
+// Event implements com.canonical.dbusmenu.Event
+func (m *trayMenu) Event(id int32, eventID string, _ dbus.Variant, _ uint32) *dbus.Error {
+	if eventID == "clicked" {
+		m.t.activateMenuItem(id)
+	}
+	return nil
+}
+
+// EventGroup implements com.canonical.dbusmenu.EventGroup, returning the IDs of the items that weren't found
+func (m *trayMenu) EventGroup(events []trayMenuEvent) ([]int32, *dbus.Error) {
+	idErrors := []int32{}
+	for _, e := range events {
+		if _, ok := m.t.menuItem(e.ID); !ok {
+			idErrors = append(idErrors, e.ID)
+		} else if e.EventID == "clicked" {
+			m.t.activateMenuItem(e.ID)
+		}
+	}
+	return idErrors, nil
+}
+
+// AboutToShow implements com.canonical.dbusmenu.AboutToShow. The menu is always up to date, so no update is needed
+func (m *trayMenu) AboutToShow(_ int32) (bool, *dbus.Error) {
+	return false, nil
+}
+
+// AboutToShowGroup implements com.canonical.dbusmenu.AboutToShowGroup
+func (m *trayMenu) AboutToShowGroup(_ []int32) ([]int32, []int32, *dbus.Error) {
+	return []int32{}, []int32{}, nil
+}
+
+// trayMenuItemLayout returns the layout of the given menu starting from the item with the given ID (0 for the root),
+// including the children up to the given depth (-1 for all)
+func trayMenuItemLayout(items []TrayMenuItem, id, depth int32, propertyNames []string) (trayMenuLayout, error) {
+	props, ok := trayMenuItemProperties(items, id, propertyNames)
+	if !ok {
+		return trayMenuLayout{}, fmt.Errorf("no menu item with ID %d", id)
+	}
+	layout := trayMenuLayout{ID: id, Properties: props, Children: []dbus.Variant{}}
+
+	// Only the root has children
+	if id == 0 && depth != 0 {
+		for i := range items {
+			child, _ := trayMenuItemLayout(items, int32(i+1), depth-1, propertyNames)
+			layout.Children = append(layout.Children, dbus.MakeVariant(child))
+		}
+	}
+	return layout, nil
+}
+
+// trayMenuItemProperties returns the dbusmenu properties of the item with the given ID (0 for the root), limited to
+// the given names unless the list is empty
+func trayMenuItemProperties(items []TrayMenuItem, id int32, propertyNames []string) (map[string]dbus.Variant, bool) {
+	props := make(map[string]dbus.Variant)
+	switch {
+	case id == 0:
+		props["children-display"] = dbus.MakeVariant("submenu")
+	case id < 0 || int(id) > len(items):
+		return nil, false
+	case items[id-1].Label == "":
+		props["type"] = dbus.MakeVariant("separator")
+	default:
+		item := items[id-1]
+		// Underscores denote mnemonics in dbusmenu labels
+		props["label"] = dbus.MakeVariant(strings.ReplaceAll(item.Label, "_", "__"))
+		props["enabled"] = dbus.MakeVariant(item.Enabled)
+	}
+
+	// Filter the properties, if needed
+	if len(propertyNames) > 0 {
+		wanted := make(map[string]bool, len(propertyNames))
+		for _, name := range propertyNames {
+			wanted[name] = true
+		}
+		for name := range props {
+			if !wanted[name] {
+				delete(props, name)
+			}
+		}
+	}
+	return props, true
+}
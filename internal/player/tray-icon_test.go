@@ -0,0 +1,76 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/godbus/dbus/v5"
+	"reflect"
+	"testing"
+)
+
+func TestTrayMenuItemLayout(t *testing.T) {
+	items := []TrayMenuItem{
+		{Label: "Play", Action: "player.play-pause", Enabled: true},
+		{},
+		{Label: "Rock_n_Roll", Action: "quit"},
+	}
+	play := trayMenuLayout{
+		ID:         1,
+		Properties: map[string]dbus.Variant{"label": dbus.MakeVariant("Play"), "enabled": dbus.MakeVariant(true)},
+		Children:   []dbus.Variant{},
+	}
+	separator := trayMenuLayout{
+		ID:         2,
+		Properties: map[string]dbus.Variant{"type": dbus.MakeVariant("separator")},
+		Children:   []dbus.Variant{},
+	}
+	rock := trayMenuLayout{
+		ID:         3,
+		Properties: map[string]dbus.Variant{"label": dbus.MakeVariant("Rock__n__Roll"), "enabled": dbus.MakeVariant(false)},
+		Children:   []dbus.Variant{},
+	}
+	rootProps := map[string]dbus.Variant{"children-display": dbus.MakeVariant("submenu")}
+	tests := []struct {
+		name          string
+		id            int32
+		depth         int32
+		propertyNames []string
+		want          trayMenuLayout
+		wantErr       bool
+	}{
+		{"whole menu", 0, -1, nil,
+			trayMenuLayout{ID: 0, Properties: rootProps, Children: []dbus.Variant{
+				dbus.MakeVariant(play), dbus.MakeVariant(separator), dbus.MakeVariant(rock)}}, false},
+		{"root only", 0, 0, nil, trayMenuLayout{ID: 0, Properties: rootProps, Children: []dbus.Variant{}}, false},
+		{"single item", 3, -1, nil, rock, false},
+		{"selected properties", 1, -1, []string{"label", "visible"},
+			trayMenuLayout{ID: 1, Properties: map[string]dbus.Variant{"label": dbus.MakeVariant("Play")}, Children: []dbus.Variant{}}, false},
+		{"unknown item", 4, -1, nil, trayMenuLayout{}, true},
+		{"negative ID", -1, -1, nil, trayMenuLayout{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trayMenuItemLayout(items, tt.id, tt.depth, tt.propertyNames)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("trayMenuItemLayout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trayMenuItemLayout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	httpTimeout      = 30 * time.Second // Timeout of outgoing HTTP requests
+	httpErrorBodyMax = 4 << 10          // Maximum number of bytes of an error response body kept in HTTPError
+)
+
+// httpUserAgent is the User-Agent header value sent with outgoing HTTP requests
+var httpUserAgent = "Ymuse"
+
+// SetHTTPUserAgent sets up the User-Agent header sent with outgoing HTTP requests to identify the application by name,
+// version and URL, as some services (eg. MusicBrainz) require
+func SetHTTPUserAgent(name, version, url string) {
+	httpUserAgent = fmt.Sprintf("%s/%s ( %s )", name, version, url)
+}
+
+// httpClient is the client all outgoing HTTP requests are run with
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// HTTPError is returned when a server responds to a request with a non-2xx status
+type HTTPError struct {
+	URL        string // URL of the failed request, without the query
+	Status     string // Response status, such as "404 Not Found"
+	StatusCode int    // Response status code
+	Body       string // Start of the response body, which often explains the failure
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("request to %s failed: %s", e.URL, e.Status)
+}
+
+// IsHTTPNotFound returns whether the given error reports a 404 response
+func IsHTTPNotFound(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// HTTPDo runs the given request with the application's User-Agent and timeout. If the server responds with a non-2xx
+// status, the response is discarded and an *HTTPError returned; otherwise it's up to the caller to close the body
+func HTTPDo(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", httpUserAgent)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, httpErrorBodyMax))
+		u := *req.URL
+		u.RawQuery = ""
+		return nil, &HTTPError{
+			URL:        u.String(),
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return resp, nil
+}
+
+// HTTPGet runs a GET request for the given URL. See HTTPDo for details
+func HTTPGet(uri string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	return HTTPDo(req)
+}
+
+// HTTPGetJSON runs a GET request for the given URL and decodes the JSON response into result
+func HTTPGetJSON(uri string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := HTTPDo(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(result)
+}
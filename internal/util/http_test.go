@@ -0,0 +1,58 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPGetJSON(t *testing.T) {
+	SetHTTPUserAgent("Ymuse", "1.0", "https://yktoo.com")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("User-Agent") != "Ymuse/1.0 ( https://yktoo.com )":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/ok":
+			_, _ = w.Write([]byte(`{"name":"Ymuse"}`))
+		default:
+			http.Error(w, "no such thing", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	if err := HTTPGetJSON(server.URL+"/ok", &result); err != nil || result.Name != "Ymuse" {
+		t.Errorf("HTTPGetJSON() = %+v, %v, want Ymuse, nil", result, err)
+	}
+
+	// Error statuses are reported as HTTPError, along with the response body
+	err := HTTPGetJSON(server.URL+"/missing?q=1", &result)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.URL != server.URL+"/missing" || httpErr.Body != "no such thing" {
+		t.Errorf("HTTPGetJSON() error = %#v, want HTTPError", err)
+	}
+	if !IsHTTPNotFound(err) {
+		t.Errorf("IsHTTPNotFound(%v) = false, want true", err)
+	}
+	if IsHTTPNotFound(errors.New("404")) {
+		t.Error("IsHTTPNotFound() = true for a non-HTTP error, want false")
+	}
+}
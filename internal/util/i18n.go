@@ -0,0 +1,75 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+// #cgo pkg-config: glib-2.0
+// #include <stdlib.h>
+// #include <glib.h>
+import "C"
+import (
+	"github.com/gotk3/gotk3/glib"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+// LocaleDirEnv is the environment variable overriding the directory compiled translations are looked up in
+const LocaleDirEnv = "YMUSE_LOCALE_DIR"
+
+// systemLocaleDir is the directory compiled translations are looked up in when they aren't found elsewhere
+const systemLocaleDir = "/usr/share/locale"
+
+// InitI18n initialises the gettext engine for the given domain. Compiled translations are looked up in the directory
+// given by LocaleDirEnv, then in share/locale under the executable's installation prefix (eg. /usr/local/share/locale
+// for /usr/local/bin/ymuse), then in the system's locale directory
+func InitI18n(domain string) {
+	var dirs []string
+	if dir := os.Getenv(LocaleDirEnv); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if exe, err := os.Executable(); err == nil {
+		if exe, err := filepath.EvalSymlinks(exe); err == nil {
+			dirs = append(dirs, filepath.Join(filepath.Dir(filepath.Dir(exe)), "share", "locale"))
+		}
+	}
+	glib.InitI18n(domain, findLocaleDir(domain, dirs))
+}
+
+// findLocaleDir returns the first of the given directories containing compiled translations for the given domain, or
+// the system's locale directory if none does
+func findLocaleDir(domain string, dirs []string) string {
+	for _, dir := range dirs {
+		if files, _ := filepath.Glob(filepath.Join(dir, "*", "LC_MESSAGES", domain+".mo")); len(files) > 0 {
+			return dir
+		}
+	}
+	return systemLocaleDir
+}
+
+// LocalNoop returns the given string as is. It only marks the string for extraction into the .pot file, for strings that
+// are translated later with glib.Local(), such as those in static tables
+func LocalNoop(s string) string {
+	return s
+}
+
+// LocalN localises a string containing a number using gettext, picking the plural form appropriate for n in the
+// current language. singular and plural are the English forms, used when there's no translation
+func LocalN(singular, plural string, n int) string {
+	cSingular, cPlural := C.CString(singular), C.CString(plural)
+	defer C.free(unsafe.Pointer(cSingular))
+	defer C.free(unsafe.Pointer(cPlural))
+	return C.GoString((*C.char)(C.g_dngettext(nil, (*C.gchar)(cSingular), (*C.gchar)(cPlural), C.gulong(n))))
+}
@@ -0,0 +1,66 @@
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindLocaleDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "ymuse-locale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// Only the "full" dir contains the domain's translations
+	empty, other, full := filepath.Join(root, "empty"), filepath.Join(root, "other"), filepath.Join(root, "full")
+	for _, file := range []string{
+		filepath.Join(other, "nl", "LC_MESSAGES", "other.mo"),
+		filepath.Join(full, "nl", "LC_MESSAGES", "ymuse.mo"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dirs []string
+		want string
+	}{
+		{"no dirs", nil, systemLocaleDir},
+		{"no translations", []string{empty, other, filepath.Join(root, "missing")}, systemLocaleDir},
+		{"found", []string{empty, other, full}, full},
+		{"first found", []string{full, empty}, full},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findLocaleDir("ymuse", tt.dirs); got != tt.want {
+				t.Errorf("findLocaleDir() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
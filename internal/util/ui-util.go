@@ -15,14 +15,25 @@
 
 package util
 
+// #cgo pkg-config: gtk+-3.0
+// #include <stdlib.h>
+// #include <gtk/gtk.h>
+import "C"
 import (
+	"errors"
 	"fmt"
+	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/gotk3/gotk3/pango"
 	"html"
+	"strings"
+	"unsafe"
 )
 
+// listBoxRowLabelName is the name of the main label in list box rows created with NewListBoxRow
+const listBoxRowLabelName = "ListBoxRowLabel"
+
 // WhenIdle schedules a function call on GLib's main loop thread
 func WhenIdle(name string, f interface{}, args ...interface{}) {
 	_, err := glib.IdleAdd(f, args...)
@@ -143,6 +154,7 @@ func NewListBoxRow(listBox *gtk.ListBox, useMarkup bool, label, name, icon strin
 	if err != nil {
 		return nil, nil, err
 	}
+	lbl.SetName(listBoxRowLabelName)
 	lbl.SetXAlign(0)
 	lbl.SetEllipsize(pango.ELLIPSIZE_END)
 	if useMarkup {
@@ -157,10 +169,91 @@ func NewListBoxRow(listBox *gtk.ListBox, useMarkup bool, label, name, icon strin
 	return row, hbx, nil
 }
 
-// ListBoxScrollToSelected scrolls the provided list box so that the selected row is centered in the window
+// EditListBoxRowLabel temporarily replaces the label of a row created with NewListBoxRow with an entry holding the given
+// text, allowing to edit it in place. onDone is called with the new text once the user presses Enter, provided it's
+// non-empty and differs from the original one; Escape or moving the focus away cancels editing
+func EditListBoxRowLabel(row *gtk.ListBoxRow, text string, onDone func(newText string)) {
+	child, err := row.GetChild()
+	if errCheck(err, "GetChild() failed") {
+		return
+	}
+	hbx, ok := child.(*gtk.Box)
+	if !ok {
+		return
+	}
+
+	// Find the label and its position in the box
+	var lbl *gtk.Widget
+	pos := 0
+	hbx.GetChildren().Foreach(func(item interface{}) {
+		if wdg := item.(*gtk.Widget); lbl == nil {
+			if name, _ := wdg.GetName(); name == listBoxRowLabelName {
+				lbl = wdg
+			} else {
+				pos++
+			}
+		}
+	})
+	if lbl == nil {
+		return
+	}
+
+	// Create an entry in place of the label
+	entry, err := gtk.EntryNew()
+	if errCheck(err, "EntryNew() failed") {
+		return
+	}
+	entry.SetText(text)
+	hbx.PackStart(entry, true, true, 0)
+	hbx.ReorderChild(entry, pos)
+	lbl.Hide()
+	entry.Show()
+	entry.GrabFocus()
+	entry.SelectRegion(0, -1)
+
+	// finish removes the entry and shows the label again. The entry is destroyed later since it's being called from the
+	// entry's own signal handlers
+	done := false
+	finish := func() {
+		if !done {
+			done = true
+			lbl.Show()
+			WhenIdle("EditListBoxRowLabel()", entry.Destroy)
+		}
+	}
+	_, _ = entry.Connect("activate", func() {
+		newText, err := entry.GetText()
+		finish()
+		if !errCheck(err, "GetText() failed") && newText != "" && newText != text {
+			onDone(newText)
+		}
+	})
+	_, _ = entry.Connect("key-press-event", func(_ *gtk.Entry, event *gdk.Event) bool {
+		if gdk.EventKeyNewFromEvent(event).KeyVal() == gdk.KEY_Escape {
+			finish()
+			return true
+		}
+		return false
+	})
+	_, _ = entry.Connect("focus-out-event", finish)
+}
+
+// ListBoxSelectedRow returns the (first) selected row in the provided list box, regardless of its selection mode, or nil
+// if there's no selection
+func ListBoxSelectedRow(listBox *gtk.ListBox) *gtk.ListBoxRow {
+	if listBox.GetSelectionMode() != gtk.SELECTION_MULTIPLE {
+		return listBox.GetSelectedRow()
+	}
+	if rows := listBox.GetSelectedRows(); rows != nil && rows.Length() > 0 {
+		return rows.NthData(0).(*gtk.ListBoxRow)
+	}
+	return nil
+}
+
+// ListBoxScrollToSelected scrolls the provided list box so that the (first) selected row is centered in the window
 func ListBoxScrollToSelected(listBox *gtk.ListBox) {
 	// If there's selection
-	if row := listBox.GetSelectedRow(); row != nil {
+	if row := ListBoxSelectedRow(listBox); row != nil {
 		// Convert the row's Y coordinate into the list box's coordinate
 		if _, y, _ := row.TranslateCoordinates(listBox, 0, 0); y >= 0 {
 			// Scroll the vertical adjustment to center the row in the viewport
@@ -256,9 +349,99 @@ func EntryText(entry *gtk.Entry, def string) string {
 	return s
 }
 
+// SetEntryError marks an entry as having an invalid value or removes such a mark
+func SetEntryError(entry *gtk.Entry, isError bool) {
+	if ctx, err := entry.GetStyleContext(); !errCheck(err, "SetEntryError(): GetStyleContext() failed") {
+		if isError {
+			ctx.AddClass("error")
+		} else {
+			ctx.RemoveClass("error")
+		}
+	}
+}
+
+// OpenFileDialog shows a dialog for choosing an existing file. The file name patterns, if any, are offered as a filter.
+// Returns the chosen file name and whether it's been confirmed
+func OpenFileDialog(parent gtk.IWindow, title string, patterns ...string) (string, bool) {
+	return fileDialog(parent, gtk.FILE_CHOOSER_ACTION_OPEN, title, "", "Open", patterns)
+}
+
+// SaveFileDialog shows a dialog for choosing a file to save to, proposing the given file name. The file name patterns, if
+// any, are offered as a filter. Returns the chosen file name and whether it's been confirmed
+func SaveFileDialog(parent gtk.IWindow, title, fileName string, patterns ...string) (string, bool) {
+	return fileDialog(parent, gtk.FILE_CHOOSER_ACTION_SAVE, title, fileName, "Save", patterns)
+}
+
+// fileDialog shows a file chooser dialog performing the given action
+func fileDialog(parent gtk.IWindow, action gtk.FileChooserAction, title, fileName, okButton string, patterns []string) (string, bool) {
+	dlg, err := gtk.FileChooserDialogNewWith2Buttons(
+		title,
+		parent,
+		action,
+		"Cancel", gtk.RESPONSE_CANCEL,
+		okButton, gtk.RESPONSE_ACCEPT)
+	if errCheck(err, "FileChooserDialogNewWith2Buttons() failed") {
+		return "", false
+	}
+	defer dlg.Destroy()
+	dlg.SetDefaultResponse(gtk.RESPONSE_ACCEPT)
+	if action == gtk.FILE_CHOOSER_ACTION_SAVE {
+		dlg.SetDoOverwriteConfirmation(true)
+		dlg.SetCurrentName(fileName)
+	}
+
+	// Add a filter for the given patterns
+	if len(patterns) > 0 {
+		if filter, err := gtk.FileFilterNew(); !errCheck(err, "FileFilterNew() failed") {
+			filter.SetName(strings.Join(patterns, ", "))
+			for _, p := range patterns {
+				filter.AddPattern(p)
+			}
+			dlg.AddFilter(filter)
+		}
+	}
+
+	// Run the dialog
+	if dlg.Run() == gtk.RESPONSE_ACCEPT {
+		return dlg.GetFilename(), true
+	}
+	return "", false
+}
+
+// DragDestUnset stops the given widget from accepting drops, reverting DragDestSet()
+func DragDestUnset(widget gtk.IWidget) {
+	C.gtk_drag_dest_unset((*C.GtkWidget)(unsafe.Pointer(widget.ToWidget().Native())))
+}
+
+// ShowURI opens the given URI (such as a file:// URL) in the user's default application for it
+func ShowURI(uri string) error {
+	cURI := C.CString(uri)
+	defer C.free(unsafe.Pointer(cURI))
+	var gErr *C.GError
+	if C.gtk_show_uri_on_window(nil, cURI, C.GDK_CURRENT_TIME, &gErr) == C.FALSE {
+		defer C.g_error_free(gErr)
+		return errors.New(C.GoString((*C.char)(gErr.message)))
+	}
+	return nil
+}
+
 // ErrorDialog shows an error message dialog
 func ErrorDialog(parent gtk.IWindow, text string) {
 	dlg := gtk.MessageDialogNew(parent, gtk.DIALOG_MODAL, gtk.MESSAGE_ERROR, gtk.BUTTONS_OK, text)
 	defer dlg.Destroy()
 	dlg.Run()
 }
+
+// InfoDialog shows an informational message dialog
+func InfoDialog(parent gtk.IWindow, text string) {
+	dlg := gtk.MessageDialogNew(parent, gtk.DIALOG_MODAL, gtk.MESSAGE_INFO, gtk.BUTTONS_OK, text)
+	defer dlg.Destroy()
+	dlg.Run()
+}
+
+// WarningDialog shows a warning message dialog
+func WarningDialog(parent gtk.IWindow, text string) {
+	dlg := gtk.MessageDialogNew(parent, gtk.DIALOG_MODAL, gtk.MESSAGE_WARNING, gtk.BUTTONS_OK, text)
+	defer dlg.Destroy()
+	dlg.Run()
+}
@@ -20,11 +20,45 @@ import (
 	"github.com/fhs/gompd/v2/mpd"
 	"github.com/gotk3/gotk3/glib"
 	"html/template"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 )
 
+// TagValueSeparators lists the characters separating multiple values stored in a single tag, such as "Rock; Pop"
+const TagValueSeparators = ";/|"
+
+// LeadingArticles lists the articles ignored at the start of names when sorting, such as in "The Beatles"
+var LeadingArticles = []string{"the", "a", "an", "die", "der", "das", "le", "la", "les", "l'", "el", "los", "las", "il"}
+
+// diacriticFolds maps letters with diacritics to their plain transliterations
+var diacriticFolds = map[rune]string{}
+
+func init() {
+	for plain, letters := range map[string]string{
+		"A": "ÀÁÂÃÄÅĀĂĄǍȀȂ", "a": "àáâãäåāăąǎȁȃ", "AE": "Æ", "ae": "æ",
+		"C": "ÇĆĈĊČ", "c": "çćĉċč", "D": "ĎĐÐ", "d": "ďđð",
+		"E": "ÈÉÊËĒĔĖĘĚȄȆ", "e": "èéêëēĕėęěȅȇ", "G": "ĜĞĠĢ", "g": "ĝğġģ", "H": "ĤĦ", "h": "ĥħ",
+		"I": "ÌÍÎÏĨĪĬĮİȈȊ", "i": "ìíîïĩīĭįıȉȋ", "J": "Ĵ", "j": "ĵ", "K": "Ķ", "k": "ķ",
+		"L": "ĹĻĽĿŁ", "l": "ĺļľŀł", "N": "ÑŃŅŇ", "n": "ñńņňŉ",
+		"O": "ÒÓÔÕÖØŌŎŐǑȌȎ", "o": "òóôõöøōŏőǒȍȏ", "OE": "Œ", "oe": "œ",
+		"R": "ŔŖŘ", "r": "ŕŗř", "S": "ŚŜŞŠȘ", "s": "śŝşšș", "ss": "ß",
+		"T": "ŢŤŦȚ", "t": "ţťŧț", "TH": "Þ", "th": "þ",
+		"U": "ÙÚÛÜŨŪŬŮŰŲǓǕǗǙǛ", "u": "ùúûüũūŭůűųǔǖǘǚǜ", "W": "Ŵ", "w": "ŵ",
+		"Y": "ÝŶŸ", "y": "ýÿŷ", "Z": "ŹŻŽ", "z": "źżž",
+	} {
+		for _, r := range letters {
+			diacriticFolds[r] = plain
+		}
+	}
+}
+
 var (
 	locDay  string
 	locDays string
@@ -99,3 +133,234 @@ func MapAttrsToSlice(attrs []mpd.Attrs, attr string) []string {
 	}
 	return r
 }
+
+// PrependRecent returns a copy of the most-recently-used list with the given item moved (or added) to the front, and at
+// most max items in total
+func PrependRecent(list []string, item string, max int) []string {
+	result := []string{item}
+	for _, s := range list {
+		if len(result) >= max {
+			break
+		}
+		if s != item {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// DuplicateIndices returns the indices of the items repeating an earlier item in the list, in descending order, so that
+// they can be deleted one by one without affecting the remaining indices
+func DuplicateIndices(list []string) []int {
+	var result []int
+	seen := make(map[string]bool, len(list))
+	for i, s := range list {
+		if seen[s] {
+			result = append(result, i)
+		}
+		seen[s] = true
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+	return result
+}
+
+// SplitTagValues splits the given tag value into individual values, trimming whitespace and skipping empty values
+func SplitTagValues(s string) []string {
+	var result []string
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool { return strings.ContainsRune(TagValueSeparators, r) }) {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// FormatTagValues formats a (possibly multi-value) tag value for display, separating individual values with commas
+func FormatTagValues(s string) string {
+	return strings.Join(SplitTagValues(s), ", ")
+}
+
+// NewPatternMatcher returns a function checking whether a string matches the given pattern, ignoring case. The pattern
+// is a regular expression if regex is true, a fuzzy pattern (see FuzzyMatch) if fuzzy is true, and a plain substring
+// otherwise. If foldDiacritics is true, both the pattern and the string are transliterated before comparison, so that
+// "Dvorak" matches "Dvořák"
+func NewPatternMatcher(pattern string, fuzzy, regex, foldDiacritics bool) (func(s string) bool, error) {
+	fold := func(s string) string { return s }
+	if foldDiacritics {
+		fold = FoldDiacritics
+	}
+	pattern = fold(pattern)
+	switch {
+	case regex:
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(s string) bool { return re.MatchString(fold(s)) }, nil
+	case fuzzy:
+		return func(s string) bool { return FuzzyMatch(pattern, fold(s)) }, nil
+	}
+	pattern = strings.ToLower(pattern)
+	return func(s string) bool { return strings.Contains(strings.ToLower(fold(s)), pattern) }, nil
+}
+
+// FoldDiacritics transliterates letters with diacritics in the given string into plain Latin ones, for example
+// "Dvořák" into "Dvorak" or "Straße" into "Strasse"
+func FoldDiacritics(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if plain, ok := diacriticFolds[r]; ok {
+			sb.WriteString(plain)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// FuzzyMatch returns whether the given string matches the pattern fuzzily, ignoring case: every word of the pattern
+// must match some word of the string, which means they start with the same character and the rest of the pattern's word
+// characters appear in the string's word in the same order (so "blnd gdn" matches "Blind Guardian")
+func FuzzyMatch(pattern, s string) bool {
+	words := fuzzyWords(s)
+	for _, pw := range fuzzyWords(pattern) {
+		matched := false
+		for _, sw := range words {
+			if matched = isFuzzyWordMatch(pw, sw); matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// fuzzyWords splits the given string into lowercase words consisting of letters and digits
+func fuzzyWords(s string) [][]rune {
+	var result [][]rune
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		result = append(result, []rune(w))
+	}
+	return result
+}
+
+// isFuzzyWordMatch returns whether the pattern word starts with the same rune as the given word, and all its other runes
+// appear in the word in the same order
+func isFuzzyWordMatch(pattern, word []rune) bool {
+	if len(pattern) == 0 || len(word) == 0 || pattern[0] != word[0] {
+		return false
+	}
+	i := 1
+	for _, r := range word[1:] {
+		if i < len(pattern) && r == pattern[i] {
+			i++
+		}
+	}
+	return i == len(pattern)
+}
+
+// StripLeadingArticle returns the given name without the leading article (see LeadingArticles), if any. A name
+// consisting only of an article is returned unchanged
+func StripLeadingArticle(name string) string {
+	lower := strings.ToLower(name)
+	for _, article := range LeadingArticles {
+		// Articles ending with an apostrophe are joined with the next word, others are followed by a space
+		prefix := article
+		if !strings.HasSuffix(article, "'") {
+			prefix += " "
+		}
+		if strings.HasPrefix(lower, prefix) && len(strings.TrimSpace(name[len(prefix):])) > 0 {
+			return strings.TrimSpace(name[len(prefix):])
+		}
+	}
+	return name
+}
+
+// AudioFormatBadge returns a short description of a track's audio format, such as "FLAC 24/96" or "DSD64", given the
+// track's URI and MPD's Format attribute ("samplerate:bits:channels"). The resolution is only given for lossless codecs
+func AudioFormatBadge(uri, format string) string {
+	codec := strings.ToLower(strings.TrimPrefix(path.Ext(uri), "."))
+	parts := strings.Split(format, ":")
+
+	// DSD streams are reported as "dsd64:2" etc.
+	if strings.HasPrefix(parts[0], "dsd") {
+		return strings.ToUpper(parts[0])
+	}
+	switch codec {
+	case "":
+		return ""
+	case "dsf", "dff":
+		return "DSD"
+	case "oga":
+		codec = "ogg"
+	case "aif":
+		codec = "aiff"
+	}
+	badge := strings.ToUpper(codec)
+
+	// Add bit depth and sample rate in kHz for lossless formats
+	switch codec {
+	case "flac", "wav", "aiff", "ape", "wv", "alac":
+		if len(parts) >= 2 {
+			if rate, err := strconv.Atoi(parts[0]); err == nil && rate > 0 {
+				if _, err := strconv.Atoi(parts[1]); err == nil {
+					badge += fmt.Sprintf(" %s/%s", parts[1], strconv.FormatFloat(float64(rate)/1000, 'f', -1, 64))
+				}
+			}
+		}
+	}
+	return badge
+}
+
+// URIToLocalPath translates the given MPD URI into a local file path within the provided music directory. Returns false
+// if the music directory or the URI isn't given, the URI is a stream or it points outside the music directory
+func URIToLocalPath(musicDir, uri string) (string, bool) {
+	if musicDir == "" || uri == "" || IsStreamURI(uri) {
+		return "", false
+	}
+	p := filepath.Join(musicDir, filepath.FromSlash(uri))
+	if _, ok := LocalPathToURI(musicDir, p); !ok {
+		return "", false
+	}
+	return p, true
+}
+
+// LocalPathToFileURL converts the given absolute local path into a file:// URL
+func LocalPathToFileURL(localPath string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(localPath)}).String()
+}
+
+// ParseURIList parses the content of a text/uri-list (RFC 2483) into a slice of local file paths. Comment lines and
+// URIs that don't refer to local files are skipped
+func ParseURIList(data string) []string {
+	var result []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if u, err := url.Parse(line); err == nil && u.Scheme == "file" && u.Path != "" {
+			result = append(result, u.Path)
+		}
+	}
+	return result
+}
+
+// LocalPathToURI translates the given local file path into an MPD URI relative to the provided music directory. Returns
+// false if the path doesn't lie within the music directory
+func LocalPathToURI(musicDir, localPath string) (string, bool) {
+	if musicDir == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(filepath.Clean(musicDir), filepath.Clean(localPath))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	// The music directory itself is represented by an empty URI
+	if rel == "." {
+		rel = ""
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -218,3 +218,290 @@ func TestMapAttrsToSlice(t *testing.T) {
 		})
 	}
 }
+
+func TestPrependRecent(t *testing.T) {
+	tests := []struct {
+		name string
+		list []string
+		item string
+		max  int
+		want []string
+	}{
+		{"empty list", nil, "a", 3, []string{"a"}},
+		{"new item", []string{"b", "c"}, "a", 3, []string{"a", "b", "c"}},
+		{"existing item", []string{"b", "a", "c"}, "a", 3, []string{"a", "b", "c"}},
+		{"first item", []string{"a", "b"}, "a", 3, []string{"a", "b"}},
+		{"limited", []string{"b", "c", "d"}, "a", 3, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrependRecent(tt.list, tt.item, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PrependRecent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateIndices(t *testing.T) {
+	tests := []struct {
+		name string
+		list []string
+		want []int
+	}{
+		{"empty", nil, nil},
+		{"no duplicates", []string{"a", "b", "c"}, nil},
+		{"adjacent", []string{"a", "a", "b"}, []int{1}},
+		{"scattered", []string{"a", "b", "a", "c", "b", "a"}, []int{5, 4, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DuplicateIndices(tt.list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DuplicateIndices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTagValues(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"blank", " ; ", nil},
+		{"single value", "Jazz", []string{"Jazz"}},
+		{"semicolons", "Rock; Pop;Folk", []string{"Rock", "Pop", "Folk"}},
+		{"mixed separators", "Rock/Pop | Soul", []string{"Rock", "Pop", "Soul"}},
+		{"empty values", ";Rock;;", []string{"Rock"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitTagValues(tt.s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitTagValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"empty pattern", "", "Anything", true},
+		{"empty string", "a", "", false},
+		{"exact", "blind guardian", "Blind Guardian", true},
+		{"omitted letters", "blnd gdnce", "Blind Guardance", true},
+		{"word order", "grdn blnd", "Blind Guardian", true},
+		{"punctuation", "ac dc", "AC/DC", true},
+		{"wrong first letter", "lind", "Blind Guardian", false},
+		{"wrong order", "bnld", "Blind Guardian", false},
+		{"missing word", "blnd mtlca", "Blind Guardian", false},
+		{"non-ASCII", "dvřk", "Dvořák", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FuzzyMatch(tt.pattern, tt.s); got != tt.want {
+				t.Errorf("FuzzyMatch(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPatternMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		fuzzy   bool
+		regex   bool
+		fold    bool
+		s       string
+		want    bool
+		wantErr bool
+	}{
+		{"substring", "GUARD", false, false, false, "Blind Guardian", true, false},
+		{"substring mismatch", "blnd", false, false, false, "Blind Guardian", false, false},
+		{"fuzzy", "blnd", true, false, false, "Blind Guardian", true, false},
+		{"regex", `^\d{4} - `, false, true, false, "1999 - Intro.flac", true, false},
+		{"regex mismatch", `^\d{4} - `, false, true, false, "01 - Intro.flac", false, false},
+		{"regex case ignored", "^blind", false, true, false, "Blind Guardian", true, false},
+		{"regex takes precedence", "^b.*n$", true, true, false, "Blind Guardian", true, false},
+		{"invalid regex", "(", false, true, false, "", false, true},
+		{"diacritics respected", "dvorak", false, false, false, "Antonín Dvořák", false, false},
+		{"diacritics folded", "dvorak", false, false, true, "Antonín Dvořák", true, false},
+		{"diacritics folded in pattern", "DVOŘÁK", false, false, true, "Dvorak", true, false},
+		{"diacritics folded fuzzy", "antnn dvrk", true, false, true, "Antonín Dvořák", true, false},
+		{"diacritics folded regex", "^dvo.ak$", false, true, true, "Dvořák", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := NewPatternMatcher(tt.pattern, tt.fuzzy, tt.regex, tt.fold)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPatternMatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && match(tt.s) != tt.want {
+				t.Errorf("NewPatternMatcher(%q)(%q) = %v, want %v", tt.pattern, tt.s, !tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldDiacritics(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"Plain ASCII", "Plain ASCII"},
+		{"Dvořák", "Dvorak"},
+		{"Motörhead", "Motorhead"},
+		{"Straße", "Strasse"},
+		{"Ænima", "AEnima"},
+		{"Łódź", "Lodz"},
+		{"Чайковский", "Чайковский"},
+	}
+	for _, tt := range tests {
+		if got := FoldDiacritics(tt.s); got != tt.want {
+			t.Errorf("FoldDiacritics(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStripLeadingArticle(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", ""},
+		{"Beatles", "Beatles"},
+		{"The Beatles", "Beatles"},
+		{"the the", "the"},
+		{"A Tribe Called Quest", "Tribe Called Quest"},
+		{"Die Toten Hosen", "Toten Hosen"},
+		{"L'Arc-en-Ciel", "Arc-en-Ciel"},
+		{"The", "The"},
+		{"Theatre of Tragedy", "Theatre of Tragedy"},
+		{"ABBA", "ABBA"},
+	}
+	for _, tt := range tests {
+		if got := StripLeadingArticle(tt.name); got != tt.want {
+			t.Errorf("StripLeadingArticle(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAudioFormatBadge(t *testing.T) {
+	tests := []struct {
+		uri    string
+		format string
+		want   string
+	}{
+		{"", "", ""},
+		{"a/b", "44100:16:2", ""},
+		{"a/1.flac", "44100:16:2", "FLAC 16/44.1"},
+		{"a/1.FLAC", "96000:24:2", "FLAC 24/96"},
+		{"a/1.flac", "", "FLAC"},
+		{"a/1.wav", "48000:f:2", "WAV"},
+		{"a/1.mp3", "44100:24:2", "MP3"},
+		{"a/1.oga", "48000:f:2", "OGG"},
+		{"a/1.dsf", "dsd128:2", "DSD128"},
+		{"a/1.dff", "", "DSD"},
+	}
+	for _, tt := range tests {
+		if got := AudioFormatBadge(tt.uri, tt.format); got != tt.want {
+			t.Errorf("AudioFormatBadge(%q, %q) = %q, want %q", tt.uri, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestParseURIList(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"comment only", "# comment\r\n", nil},
+		{"single file", "file:///home/user/Music/song.mp3\r\n", []string{"/home/user/Music/song.mp3"}},
+		{"escaped chars", "file:///home/user/Music/Some%20Album/01%20%23one.flac", []string{"/home/user/Music/Some Album/01 #one.flac"}},
+		{"non-local URI", "http://example.com/song.mp3\r\nfile:///a/b\r\n", []string{"/a/b"}},
+		{"multiple files", "file:///a\r\n# x\r\nfile:///b/c\r\n", []string{"/a", "/b/c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseURIList(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseURIList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURIToLocalPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		musicDir string
+		uri      string
+		want     string
+		wantOK   bool
+	}{
+		{"no music dir", "", "a.mp3", "", false},
+		{"no URI", "/home/user/Music", "", "", false},
+		{"file in root", "/home/user/Music", "a.mp3", "/home/user/Music/a.mp3", true},
+		{"nested file", "/home/user/Music/", "Artist/Album/a.mp3", "/home/user/Music/Artist/Album/a.mp3", true},
+		{"stream", "/home/user/Music", "http://example.com/stream", "", false},
+		{"outside", "/home/user/Music", "../Videos/a.mp4", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := URIToLocalPath(tt.musicDir, tt.uri)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("URIToLocalPath() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLocalPathToFileURL(t *testing.T) {
+	tests := []struct {
+		localPath string
+		want      string
+	}{
+		{"/home/user/Music", "file:///home/user/Music"},
+		{"/home/user/Music/AC DC/50% #1", "file:///home/user/Music/AC%20DC/50%25%20%231"},
+	}
+	for _, tt := range tests {
+		if got := LocalPathToFileURL(tt.localPath); got != tt.want {
+			t.Errorf("LocalPathToFileURL(%q) = %q, want %q", tt.localPath, got, tt.want)
+		}
+	}
+}
+
+func TestLocalPathToURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		musicDir  string
+		localPath string
+		want      string
+		wantOK    bool
+	}{
+		{"no music dir", "", "/home/user/Music/a.mp3", "", false},
+		{"file in root", "/home/user/Music", "/home/user/Music/a.mp3", "a.mp3", true},
+		{"nested file", "/home/user/Music/", "/home/user/Music/Artist/Album/a.mp3", "Artist/Album/a.mp3", true},
+		{"music dir itself", "/home/user/Music", "/home/user/Music/", "", true},
+		{"outside", "/home/user/Music", "/home/user/Videos/a.mp4", "", false},
+		{"sibling with common prefix", "/home/user/Music", "/home/user/Music2/a.mp3", "", false},
+		{"parent", "/home/user/Music", "/home/user", "", false},
+		{"dotted name", "/home/user/Music", "/home/user/Music/..hidden/a.mp3", "..hidden/a.mp3", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LocalPathToURI(tt.musicDir, tt.localPath)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("LocalPathToURI() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
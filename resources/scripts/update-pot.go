@@ -0,0 +1,276 @@
+//go:build ignore
+// +build ignore
+
+/*
+ *   Copyright 2020 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// update-pot.go extracts translatable strings from the .glade and .go files into the .pot file. Unlike xgettext, it
+// understands Go string literals: raw (backquoted) strings and strings concatenated with "+" are extracted whole.
+//
+// Usage: go run update-pot.go <root dir>
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// potHeader is the header of the generated .pot file, with the creation date placeholder
+const potHeader = `# Ymuse MPD client
+# Copyright (C) 2020-2021 Dmitry Kann
+# This file is distributed under the same license as the Ymuse package.
+#
+#, fuzzy
+msgid ""
+msgstr ""
+"Project-Id-Version: PACKAGE VERSION\n"
+"Report-Msgid-Bugs-To: \n"
+"POT-Creation-Date: %s\n"
+"PO-Revision-Date: YEAR-MO-DA HO:MI+ZONE\n"
+"Last-Translator: FULL NAME <EMAIL@ADDRESS>\n"
+"Language-Team: LANGUAGE <LL@li.org>\n"
+"Language: \n"
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Plural-Forms: nplurals=INTEGER; plural=EXPRESSION;\n"
+`
+
+// message is a translatable string
+type message struct {
+	context string // Message context, if any
+	id      string // Message ID, ie. the (singular) source string
+	plural  string // Plural source string, if any
+}
+
+// catalog is a set of messages, keyed by context and ID
+type catalog map[string]*message
+
+// add adds a message to the catalog, or updates the plural of an already present one
+func (c catalog) add(context, id, plural string) {
+	if id == "" {
+		return
+	}
+	key := context + "\x04" + id
+	if m, ok := c[key]; ok {
+		if m.plural == "" {
+			m.plural = plural
+		}
+		return
+	}
+	c[key] = &message{context: context, id: id, plural: plural}
+}
+
+// extractGlade extracts the translatable element texts from a .glade file
+func (c catalog) extractGlade(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := xml.NewDecoder(f)
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", fileName, err)
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || attrValue(start, "translatable") != "yes" {
+			continue
+		}
+		var element struct {
+			Text string `xml:",chardata"`
+		}
+		if err := decoder.DecodeElement(&element, &start); err != nil {
+			return fmt.Errorf("%s: %v", fileName, err)
+		}
+		c.add(attrValue(start, "context"), element.Text, "")
+	}
+}
+
+// extractGo extracts the strings passed to Local(), LocalNoop() and LocalN() from a .go file
+func (c catalog) extractGo(fileName string) error {
+	file, err := parser.ParseFile(token.NewFileSet(), fileName, nil, 0)
+	if err != nil {
+		return err
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var name string
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			name = fun.Name
+		case *ast.SelectorExpr:
+			name = fun.Sel.Name
+		}
+		switch {
+		case (name == "Local" || name == "LocalNoop") && len(call.Args) == 1:
+			if id, ok := stringValue(call.Args[0]); ok {
+				c.add("", id, "")
+			}
+		case name == "LocalN" && len(call.Args) == 3:
+			id, ok1 := stringValue(call.Args[0])
+			plural, ok2 := stringValue(call.Args[1])
+			if ok1 && ok2 {
+				c.add("", id, plural)
+			}
+		}
+		return true
+	})
+	return nil
+}
+
+// write writes out the catalog in the .pot format, sorted by message ID
+func (c catalog) write(w io.Writer) error {
+	messages := make([]*message, 0, len(c))
+	for _, m := range c {
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].id != messages[j].id {
+			return messages[i].id < messages[j].id
+		}
+		return messages[i].context < messages[j].context
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, potHeader, time.Now().Format("2006-01-02 15:04-0700"))
+	for _, m := range messages {
+		b.WriteString("\n")
+		if m.context != "" {
+			writePOString(&b, "msgctxt", m.context)
+		}
+		writePOString(&b, "msgid", m.id)
+		if m.plural == "" {
+			b.WriteString("msgstr \"\"\n")
+		} else {
+			writePOString(&b, "msgid_plural", m.plural)
+			b.WriteString("msgstr[0] \"\"\nmsgstr[1] \"\"\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// attrValue returns the value of the element's attribute with the given name, or an empty string if there's none
+func attrValue(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// stringValue returns the value of a string literal expression, which can be a concatenation of literals
+func stringValue(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			if s, err := strconv.Unquote(e.Value); err == nil {
+				return s, true
+			}
+		}
+	case *ast.ParenExpr:
+		return stringValue(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			x, okX := stringValue(e.X)
+			y, okY := stringValue(e.Y)
+			return x + y, okX && okY
+		}
+	}
+	return "", false
+}
+
+// writePOString writes out a keyword followed by the quoted string. Multiline strings are split into lines, like
+// xgettext does
+func writePOString(b *strings.Builder, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 1 {
+		fmt.Fprintf(b, "%s \"\"\n", keyword)
+	} else {
+		b.WriteString(keyword + " ")
+	}
+	for _, line := range lines {
+		b.WriteString(`"` + poEscaper.Replace(line) + "\"\n")
+	}
+}
+
+// poEscaper escapes special characters in .po strings
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatalf("Usage: go run %s <root dir>", filepath.Base(os.Args[0]))
+	}
+	rootDir := os.Args[1]
+
+	// Collect messages from all relevant files
+	c := catalog{}
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case info.IsDir():
+			// Skip generated code and hidden dirs
+			if path != rootDir && (info.Name() == "generated" || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+		case strings.HasSuffix(path, ".glade"):
+			return c.extractGlade(path)
+		case strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go"):
+			return c.extractGo(path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Write out the .pot file
+	potFile := filepath.Join(rootDir, "resources", "i18n", "ymuse.pot")
+	f, err := os.Create(potFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := c.write(f); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Written %d messages to %s", len(c), potFile)
+}
@@ -20,12 +20,17 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/op/go-logging"
 	"github.com/yktoo/ymuse/internal/config"
 	"github.com/yktoo/ymuse/internal/player"
+	"github.com/yktoo/ymuse/internal/util"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"unsafe"
 )
 
 var log = logging.MustGetLogger("main")
@@ -38,19 +43,26 @@ var (
 
 func main() {
 	// Initialise the gettext engine
-	glib.InitI18n("ymuse", "/usr/share/locale/")
+	util.InitI18n("ymuse")
 
 	// Process command line
-	verbInfo := flag.Bool("v", false, glib.Local("verbose logging"))
-	verbDebug := flag.Bool("vv", false, glib.Local("more verbose logging"))
+	opts := defineOptions(flag.CommandLine)
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(
+			flag.CommandLine.Output(),
+			glib.Local("Usage: %[1]s [options] [URI...]\n       %[1]s [options] play|pause|next|prev|stop|status\n       %[1]s [options] add URI...\n\nOptions:\n"),
+			os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	args := flag.Args()
 
 	// Init logging
 	logLevel := logging.WARNING
 	switch {
-	case *verbDebug:
+	case *opts.verbDebug:
 		logLevel = logging.DEBUG
-	case *verbInfo:
+	case *opts.verbInfo:
 		logLevel = logging.INFO
 	}
 	logging.SetFormatter(logging.MustStringFormatter(`%{time:15:04:05.000} %{level:-5s} %{module} %{message}`))
@@ -59,30 +71,139 @@ func main() {
 	// Init application metadata
 	config.AppMetadata.Version = version
 	config.AppMetadata.BuildDate = date
+	util.SetHTTPUserAgent(config.AppMetadata.Name, config.AppMetadata.Version, config.AppMetadata.URL)
 
 	// Start the app
 	log.Infof(glib.Local("Ymuse version %s; %s; released %s"), version, commit, date)
 
-	// Create Gtk Application, change appID to your application domain name reversed.
-	application, err := gtk.ApplicationNew(config.AppMetadata.ID, glib.APPLICATION_FLAGS_NONE)
+	// Execute the remote-control command, if any
+	if len(args) > 0 && player.IsRemoteCommand(args[0]) {
+		if err := player.RunRemoteCommand(os.Stdout, args[0], argURIs("", args[1:])); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Create Gtk Application. If an instance is already running, the application hands the command line over to it and
+	// exits with the status it returns
+	flags := glib.APPLICATION_HANDLES_OPEN | glib.APPLICATION_HANDLES_COMMAND_LINE
+	application, err := gtk.ApplicationNew(config.AppMetadata.ID, flags)
 	if err != nil {
 		log.Fatal("Could not create application", err)
 	}
 
 	// Setup the application
-	if _, err = application.Connect("activate", onActivate); err != nil {
-		log.Fatal("Failed to connect activation signal", err)
+	signals := map[string]interface{}{
+		"activate":     onActivate,
+		"open":         onOpen,
+		"command-line": onCommandLine,
+	}
+	for name, handler := range signals {
+		if _, err := application.Connect(name, handler); err != nil {
+			log.Fatalf("Failed to connect %s signal: %v", name, err)
+		}
 	}
 
 	// Run the application
-	os.Exit(application.Run(nil))
+	os.Exit(application.Run(os.Args))
 }
 
-func onActivate(application *gtk.Application) {
-	// Create the main window
-	if window, err := player.NewMainWindow(application); err != nil {
+// options holds the values of the command-line options
+type options struct {
+	verbInfo  *bool
+	verbDebug *bool
+	toggle    *bool
+	daemon    *bool
+}
+
+// defineOptions defines the command-line options in the given flag set
+func defineOptions(fs *flag.FlagSet) *options {
+	return &options{
+		verbInfo:  fs.Bool("v", false, glib.Local("verbose logging")),
+		verbDebug: fs.Bool("vv", false, glib.Local("more verbose logging")),
+		toggle:    fs.Bool("toggle", false, glib.Local("show or hide the window of the running instance")),
+		daemon: fs.Bool("daemon", false,
+			glib.Local("run in the background, only showing a mini player on toggling the window")),
+	}
+}
+
+// mainWindow is the application's only window, nil until the application is started up
+var mainWindow *player.MainWindow
+
+// startUp creates and shows the main window
+func startUp(application *gtk.Application, daemon bool) {
+	window, err := player.NewMainWindow(application)
+	if err != nil {
 		log.Fatal("Could not create application window", err)
-	} else {
+	}
+	mainWindow = window
+	switch {
+	case daemon:
+		window.RunDaemon()
+	case config.GetConfig().StartHidden:
+		window.ShowHidden()
+	default:
 		window.Show()
 	}
 }
+
+// onCommandLine handles the command line in the primary instance: the one it's been started with, or the one of an
+// instance started later, which has handed it over
+func onCommandLine(application *gtk.Application, cmdLine *player.ApplicationCommandLine) int {
+	// The arguments have already been validated by the instance they come from
+	fs := flag.NewFlagSet(config.AppMetadata.Name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	opts := defineOptions(fs)
+	if err := fs.Parse(cmdLine.Arguments()); err != nil {
+		log.Warningf("Failed to parse command line: %v", err)
+		return 1
+	}
+
+	switch {
+	case mainWindow == nil:
+		startUp(application, *opts.daemon)
+	case *opts.toggle:
+		mainWindow.ToggleWindow()
+	case fs.NArg() == 0:
+		mainWindow.Present()
+	}
+	mainWindow.OpenURIs(argURIs(cmdLine.Cwd(), fs.Args()))
+	return 0
+}
+
+// onActivate handles activation of the application from outside, eg. over D-Bus, by raising the window
+func onActivate(application *gtk.Application) {
+	if mainWindow == nil {
+		startUp(application, false)
+		return
+	}
+	mainWindow.Present()
+}
+
+// onOpen handles a request to play files made from outside, eg. by a file manager over D-Bus
+func onOpen(application *gtk.Application, files unsafe.Pointer, n int) {
+	if mainWindow == nil {
+		startUp(application, false)
+	}
+	mainWindow.OpenURIs(player.FileURIs(files, n))
+}
+
+// argURIs translates the given command-line arguments into URIs: paths of existing local files, relative to dir (or to
+// the current directory if it's empty), are converted into file:// URLs, anything else is taken as is
+func argURIs(dir string, args []string) []string {
+	uris := make([]string, 0, len(args))
+	for _, arg := range args {
+		path := arg
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			if abs, err := filepath.Abs(path); err == nil {
+				arg = util.LocalPathToFileURL(abs)
+			}
+		}
+		uris = append(uris, arg)
+	}
+	return uris
+}